@@ -2,8 +2,13 @@ package api
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
 
 	"gosuda.org/boilerplate/internal/application"
 	"gosuda.org/boilerplate/internal/domain"
@@ -12,21 +17,27 @@ import (
 
 // Handlers implements the API endpoints
 type Handlers struct {
-	postService  *application.PostService
-	debugService *application.DebugService
-	errorHandler *middleware.ErrorHandlerMiddleware
+	postService        *application.PostService
+	authorService      *application.AuthorService
+	debugService       *application.DebugService
+	replicationService *application.ReplicationService
+	errorHandler       *middleware.ErrorHandlerMiddleware
 }
 
 // NewHandlers creates new API handlers
 func NewHandlers(
 	postService *application.PostService,
+	authorService *application.AuthorService,
 	debugService *application.DebugService,
+	replicationService *application.ReplicationService,
 	errorHandler *middleware.ErrorHandlerMiddleware,
 ) *Handlers {
 	return &Handlers{
-		postService:  postService,
-		debugService: debugService,
-		errorHandler: errorHandler,
+		postService:        postService,
+		authorService:      authorService,
+		debugService:       debugService,
+		replicationService: replicationService,
+		errorHandler:       errorHandler,
 	}
 }
 
@@ -86,6 +97,69 @@ func (h *Handlers) GetPprofProfile(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
+// GetBackup handles GET /debug/backup
+func (h *Handlers) GetBackup(w http.ResponseWriter, r *http.Request) {
+	compress := false
+	if gzipStr := r.URL.Query().Get("gzip"); gzipStr != "" {
+		parsed, err := strconv.ParseBool(gzipStr)
+		if err != nil {
+			h.errorHandler.HandleError(w, r, &domain.ValidationError{
+				Field:   "gzip",
+				Message: "gzip must be a boolean",
+			})
+			return
+		}
+		compress = parsed
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="store-`+time.Now().UTC().Format("20060102T150405Z")+`.bak"`)
+
+	cw := &countingWriter{w: w}
+	if err := h.debugService.Backup(r.Context(), cw, compress); err != nil {
+		// Once streaming has started the status can no longer change; the
+		// truncated backup fails its checksum on restore
+		if cw.n == 0 {
+			w.Header().Del("Content-Disposition")
+			h.errorHandler.HandleError(w, r, err)
+		}
+		return
+	}
+}
+
+// StreamReplication handles GET /replication/stream
+func (h *Handlers) StreamReplication(w http.ResponseWriter, r *http.Request) {
+	var after int64
+	if afterStr := r.URL.Query().Get("after"); afterStr != "" {
+		parsed, err := strconv.ParseInt(afterStr, 10, 64)
+		if err != nil {
+			h.errorHandler.HandleError(w, r, &domain.ValidationError{
+				Field:   "after",
+				Message: "after must be a revision number",
+			})
+			return
+		}
+		after = parsed
+	}
+
+	// The stream stays open for as long as the follower is connected, so the
+	// server's write timeout must not apply
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+
+	cw := &countingWriter{w: w}
+	if err := h.replicationService.Stream(r.Context(), cw, rc.Flush, after); err != nil {
+		// Once streaming has started the status can no longer change; the
+		// follower reconnects and resumes
+		if cw.n == 0 {
+			h.errorHandler.HandleError(w, r, err)
+		}
+		return
+	}
+}
+
 // ListPosts handles GET /posts
 func (h *Handlers) ListPosts(w http.ResponseWriter, r *http.Request) {
 	cursor := r.URL.Query().Get("cursor")
@@ -98,7 +172,12 @@ func (h *Handlers) ListPosts(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	posts, err := h.postService.ListPosts(r.Context(), cursor, limit)
+	filter := application.PostFilter{
+		AuthorID: r.URL.Query().Get("author"),
+		Tag:      r.URL.Query().Get("tag"),
+	}
+
+	posts, err := h.postService.ListPosts(r.Context(), filter, cursor, limit)
 	if err != nil {
 		h.errorHandler.HandleError(w, r, err)
 		return
@@ -109,6 +188,42 @@ func (h *Handlers) ListPosts(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(posts)
 }
 
+// SearchPosts handles GET /posts/search
+func (h *Handlers) SearchPosts(w http.ResponseWriter, r *http.Request) {
+	cursor := r.URL.Query().Get("cursor")
+	limitStr := r.URL.Query().Get("limit")
+
+	limit := 20 // default
+	if limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil {
+			limit = parsedLimit
+		}
+	}
+
+	results, err := h.postService.SearchPosts(r.Context(), r.URL.Query().Get("q"), cursor, limit)
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results)
+}
+
+// ListTags handles GET /tags
+func (h *Handlers) ListTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.postService.ListTags(r.Context())
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tags)
+}
+
 // CreatePost handles POST /posts
 func (h *Handlers) CreatePost(w http.ResponseWriter, r *http.Request) {
 	var req domain.CreatePostRequest
@@ -127,14 +242,15 @@ func (h *Handlers) CreatePost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", formatETag(post.Version))
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(post)
 }
 
 // GetPost handles GET /posts/{id}
 func (h *Handlers) GetPost(w http.ResponseWriter, r *http.Request) {
-	// Extract ID from URL path
-	id := r.URL.Path[len("/posts/"):]
+	// Extract ID from the routed path, which excludes any tenant prefix
+	id := chi.URLParam(r, "id")
 	if id == "" {
 		h.errorHandler.HandleError(w, r, &domain.ValidationError{
 			Field:   "id",
@@ -149,6 +265,15 @@ func (h *Handlers) GetPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	etag := formatETag(post.Version)
+	w.Header().Set("ETag", etag)
+
+	// Let clients revalidate cached copies without transferring the post
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(post)
@@ -156,8 +281,8 @@ func (h *Handlers) GetPost(w http.ResponseWriter, r *http.Request) {
 
 // UpdatePost handles PUT /posts/{id}
 func (h *Handlers) UpdatePost(w http.ResponseWriter, r *http.Request) {
-	// Extract ID from URL path
-	id := r.URL.Path[len("/posts/"):]
+	// Extract ID from the routed path, which excludes any tenant prefix
+	id := chi.URLParam(r, "id")
 	if id == "" {
 		h.errorHandler.HandleError(w, r, &domain.ValidationError{
 			Field:   "id",
@@ -175,21 +300,28 @@ func (h *Handlers) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post, err := h.postService.UpdatePost(r.Context(), id, &req)
+	expectedVersion, err := parseIfMatch(r.Header.Get("If-Match"), "post", id)
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	post, err := h.postService.UpdatePost(r.Context(), id, &req, expectedVersion)
 	if err != nil {
 		h.errorHandler.HandleError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", formatETag(post.Version))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(post)
 }
 
 // DeletePost handles DELETE /posts/{id}
 func (h *Handlers) DeletePost(w http.ResponseWriter, r *http.Request) {
-	// Extract ID from URL path
-	id := r.URL.Path[len("/posts/"):]
+	// Extract ID from the routed path, which excludes any tenant prefix
+	id := chi.URLParam(r, "id")
 	if id == "" {
 		h.errorHandler.HandleError(w, r, &domain.ValidationError{
 			Field:   "id",
@@ -198,7 +330,281 @@ func (h *Handlers) DeletePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.postService.DeletePost(r.Context(), id)
+	expectedVersion, err := parseIfMatch(r.Header.Get("If-Match"), "post", id)
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	err = h.postService.DeletePost(r.Context(), id, expectedVersion)
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListPostRevisions handles GET /posts/{id}/revisions
+func (h *Handlers) ListPostRevisions(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the routed path, which excludes any tenant prefix
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		h.errorHandler.HandleError(w, r, &domain.ValidationError{
+			Field:   "id",
+			Message: "post ID is required",
+		})
+		return
+	}
+
+	revisions, err := h.postService.ListRevisions(r.Context(), id)
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// GetPostRevision handles GET /posts/{id}/revisions/{revision}
+func (h *Handlers) GetPostRevision(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the routed path, which excludes any tenant prefix
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		h.errorHandler.HandleError(w, r, &domain.ValidationError{
+			Field:   "id",
+			Message: "post ID is required",
+		})
+		return
+	}
+
+	revisionNumber, err := parseRevision(chi.URLParam(r, "revision"), "revision")
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	revision, err := h.postService.GetRevision(r.Context(), id, revisionNumber)
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(revision)
+}
+
+// DiffPostRevisions handles GET /posts/{id}/diff
+func (h *Handlers) DiffPostRevisions(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the routed path, which excludes any tenant prefix
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		h.errorHandler.HandleError(w, r, &domain.ValidationError{
+			Field:   "id",
+			Message: "post ID is required",
+		})
+		return
+	}
+
+	from, err := parseRevision(r.URL.Query().Get("from"), "from")
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	// Without to, the diff runs to the current revision
+	to := 0
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		if to, err = parseRevision(toStr, "to"); err != nil {
+			h.errorHandler.HandleError(w, r, err)
+			return
+		}
+	}
+
+	diff, err := h.postService.DiffRevisions(r.Context(), id, from, to)
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(diff)
+}
+
+// RestorePostRevision handles POST /posts/{id}/revisions/{revision}/restore
+func (h *Handlers) RestorePostRevision(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the routed path, which excludes any tenant prefix
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		h.errorHandler.HandleError(w, r, &domain.ValidationError{
+			Field:   "id",
+			Message: "post ID is required",
+		})
+		return
+	}
+
+	revision, err := parseRevision(chi.URLParam(r, "revision"), "revision")
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	expectedVersion, err := parseIfMatch(r.Header.Get("If-Match"), "post", id)
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	post, err := h.postService.RestoreRevision(r.Context(), id, revision, expectedVersion)
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", formatETag(post.Version))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(post)
+}
+
+// ListAuthors handles GET /authors
+func (h *Handlers) ListAuthors(w http.ResponseWriter, r *http.Request) {
+	cursor := r.URL.Query().Get("cursor")
+	limitStr := r.URL.Query().Get("limit")
+
+	limit := 20 // default
+	if limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil {
+			limit = parsedLimit
+		}
+	}
+
+	authors, err := h.authorService.ListAuthors(r.Context(), cursor, limit)
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(authors)
+}
+
+// CreateAuthor handles POST /authors
+func (h *Handlers) CreateAuthor(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateAuthorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.errorHandler.HandleError(w, r, &domain.ValidationError{
+			Field:   "body",
+			Message: "invalid JSON body",
+		})
+		return
+	}
+
+	credentials, err := h.authorService.CreateAuthor(r.Context(), &req)
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	// The response carries the author's token, which must not be cached
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("ETag", formatETag(credentials.Version))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(credentials)
+}
+
+// GetAuthor handles GET /authors/{id}
+func (h *Handlers) GetAuthor(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		h.errorHandler.HandleError(w, r, &domain.ValidationError{
+			Field:   "id",
+			Message: "author ID is required",
+		})
+		return
+	}
+
+	author, err := h.authorService.GetAuthor(r.Context(), id)
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	etag := formatETag(author.Version)
+	w.Header().Set("ETag", etag)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(author)
+}
+
+// UpdateAuthor handles PUT /authors/{id}
+func (h *Handlers) UpdateAuthor(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		h.errorHandler.HandleError(w, r, &domain.ValidationError{
+			Field:   "id",
+			Message: "author ID is required",
+		})
+		return
+	}
+
+	var req domain.UpdateAuthorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.errorHandler.HandleError(w, r, &domain.ValidationError{
+			Field:   "body",
+			Message: "invalid JSON body",
+		})
+		return
+	}
+
+	expectedVersion, err := parseIfMatch(r.Header.Get("If-Match"), "author", id)
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	author, err := h.authorService.UpdateAuthor(r.Context(), id, &req, expectedVersion)
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", formatETag(author.Version))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(author)
+}
+
+// DeleteAuthor handles DELETE /authors/{id}
+func (h *Handlers) DeleteAuthor(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		h.errorHandler.HandleError(w, r, &domain.ValidationError{
+			Field:   "id",
+			Message: "author ID is required",
+		})
+		return
+	}
+
+	expectedVersion, err := parseIfMatch(r.Header.Get("If-Match"), "author", id)
+	if err != nil {
+		h.errorHandler.HandleError(w, r, err)
+		return
+	}
+
+	err = h.authorService.DeleteAuthor(r.Context(), id, expectedVersion)
 	if err != nil {
 		h.errorHandler.HandleError(w, r, err)
 		return
@@ -218,4 +624,72 @@ func (h *Handlers) GetHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(status)
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// parseRevision parses a revision number given in field
+func parseRevision(value, field string) (int, error) {
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision < 1 {
+		return 0, &domain.ValidationError{
+			Field:   field,
+			Message: "revision must be a positive number",
+		}
+	}
+	return revision, nil
+}
+
+// formatETag formats a post or author version as a strong entity tag
+func formatETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header matches the given entity
+// tag, using the weak comparison required for that header
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseIfMatch extracts the version of a resource required by an If-Match
+// header. It returns 0 when the header is absent or "*", meaning no version
+// check. Only a single strong entity tag is supported; anything else cannot
+// match.
+func parseIfMatch(header, resource, id string) (int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return 0, &domain.PreconditionFailedError{ID: id, Resource: resource}
+	}
+
+	version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, &domain.PreconditionFailedError{ID: id, Resource: resource}
+	}
+
+	return version, nil
 }
\ No newline at end of file
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"gosuda.org/boilerplate/internal/application"
+	"gosuda.org/boilerplate/internal/config"
+	"gosuda.org/boilerplate/internal/domain"
+	"gosuda.org/boilerplate/internal/infrastructure"
+	"gosuda.org/boilerplate/internal/middleware"
+)
+
+// testAdminToken is the admin token of the test server
+const testAdminToken = "admin-token"
+
+// newTestServer routes the author endpoints as the server does, backed by a
+// fresh memory store
+func newTestServer(t *testing.T) http.Handler {
+	t.Helper()
+	store := infrastructure.NewMemoryStore()
+	t.Cleanup(func() { store.Close() })
+
+	logger, err := infrastructure.NewLogger(&config.LoggingConfig{Level: "error", Format: "text", Output: "stderr"})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	errorHandler := middleware.NewErrorHandlerMiddleware(logger)
+
+	authorService := application.NewAuthorService(store, false)
+	postService := application.NewPostService(logger, store, application.PostQuotas{}, 0)
+	handlers := NewHandlers(postService, authorService, nil, nil, errorHandler)
+	identity := middleware.NewIdentityMiddleware(&config.AuthorsConfig{AdminToken: testAdminToken}, authorService, errorHandler)
+
+	r := chi.NewRouter()
+	r.Route("/authors", func(r chi.Router) {
+		r.Use(identity.Handler)
+		r.Get("/", handlers.ListAuthors)
+		r.Post("/", handlers.CreateAuthor)
+		r.Get("/{id}", handlers.GetAuthor)
+		r.Put("/{id}", handlers.UpdateAuthor)
+		r.Delete("/{id}", handlers.DeleteAuthor)
+	})
+	return r
+}
+
+// do sends a request with an optional bearer token and JSON body
+func do(t *testing.T, h http.Handler, method, target, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// createTestAuthor creates an author with the admin token and returns its
+// credentials
+func createTestAuthor(t *testing.T, h http.Handler, name, email string) domain.AuthorCredentials {
+	t.Helper()
+	body, _ := json.Marshal(domain.CreateAuthorRequest{Name: name, Email: email})
+	rec := do(t, h, http.MethodPost, "/authors", testAdminToken, string(body))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 creating author, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var credentials domain.AuthorCredentials
+	if err := json.NewDecoder(rec.Body).Decode(&credentials); err != nil {
+		t.Fatalf("Failed to decode credentials: %v", err)
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Error("Expected the response carrying a token not to be cached")
+	}
+	return credentials
+}
+
+func TestAuthorHandlers_Authentication(t *testing.T) {
+	h := newTestServer(t)
+	ada := createTestAuthor(t, h, "Ada", "")
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{"anonymous", "", http.StatusOK},
+		{"admin token", "Bearer " + testAdminToken, http.StatusOK},
+		{"author token", "Bearer " + ada.Token, http.StatusOK},
+		{"not a bearer token", "Basic " + ada.Token, http.StatusUnauthorized},
+		{"empty token", "Bearer ", http.StatusUnauthorized},
+		{"wrong secret", "Bearer " + ada.ID + ".secret", http.StatusUnauthorized},
+		{"unknown author", "Bearer author-0.secret", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/authors", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body)
+			}
+			if tt.status == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Error("Expected a rejected token to ask for a bearer token")
+			}
+		})
+	}
+
+	// Only admins may create authors
+	body := `{"name":"Grace"}`
+	if rec := do(t, h, http.MethodPost, "/authors", "", body); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 creating an author anonymously, got %d", rec.Code)
+	}
+	if rec := do(t, h, http.MethodPost, "/authors", ada.Token, body); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 creating an author as an author, got %d", rec.Code)
+	}
+}
+
+func TestAuthorHandlers_Ownership(t *testing.T) {
+	h := newTestServer(t)
+	ada := createTestAuthor(t, h, "Ada", "")
+	grace := createTestAuthor(t, h, "Grace", "")
+	body := `{"name":"Ada Lovelace"}`
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		status int
+	}{
+		{"anonymous update", http.MethodPut, "", http.StatusUnauthorized},
+		{"anonymous delete", http.MethodDelete, "", http.StatusUnauthorized},
+		{"update by another author", http.MethodPut, grace.Token, http.StatusForbidden},
+		{"delete by another author", http.MethodDelete, grace.Token, http.StatusForbidden},
+		{"update by the author", http.MethodPut, ada.Token, http.StatusOK},
+		{"update by an admin", http.MethodPut, testAdminToken, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(t, h, tt.method, "/authors/"+ada.ID, tt.token, body)
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	// Deleting an author revokes their token
+	if rec := do(t, h, http.MethodDelete, "/authors/"+ada.ID, ada.Token, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 deleting the author, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(t, h, http.MethodGet, "/authors", ada.Token, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the deleted author's token to be rejected, got %d", rec.Code)
+	}
+
+	if rec := do(t, h, http.MethodDelete, "/authors/"+grace.ID, testAdminToken, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected admins to delete any author, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestAuthorHandlers_ListHidesEmail(t *testing.T) {
+	h := newTestServer(t)
+	ada := createTestAuthor(t, h, "Ada", "ada@example.com")
+	grace := createTestAuthor(t, h, "Grace", "grace@example.com")
+
+	tests := []struct {
+		name    string
+		token   string
+		visible map[string]bool
+	}{
+		{"anonymous", "", map[string]bool{}},
+		{"author", ada.Token, map[string]bool{ada.ID: true}},
+		{"admin", testAdminToken, map[string]bool{ada.ID: true, grace.ID: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(t, h, http.MethodGet, "/authors", tt.token, "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+			}
+
+			var list domain.AuthorList
+			if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+				t.Fatalf("Failed to decode authors: %v", err)
+			}
+			if len(list.Authors) != 2 {
+				t.Fatalf("Expected 2 authors, got %d", len(list.Authors))
+			}
+			for _, author := range list.Authors {
+				if (author.Email != "") != tt.visible[author.ID] {
+					t.Errorf("Expected email of %s visible %v, got %q", author.ID, tt.visible[author.ID], author.Email)
+				}
+			}
+		})
+	}
+}
\ No newline at end of file
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"gosuda.org/boilerplate/internal/application"
+	"gosuda.org/boilerplate/internal/domain"
+	"gosuda.org/boilerplate/internal/infrastructure"
+)
+
+// command is a maintenance subcommand run against the configured store
+// instead of starting the server
+type command struct {
+	usage string
+	run   func(ctx context.Context, store domain.Store, args []string) error
+}
+
+// commands lists the available subcommands by name
+var commands = map[string]command{
+	"backup": {
+		usage: "backup -o FILE [-gzip]   write a backup of the store to FILE (- for stdout)",
+		run:   runBackup,
+	},
+	"restore": {
+		usage: "restore -i FILE          restore a backup from FILE (- for stdin) into an empty store",
+		run:   runRestore,
+	},
+	"reencrypt": {
+		usage: "reencrypt                rewrite every value not encrypted with the active key",
+		run:   runReencrypt,
+	},
+	"check": {
+		usage: "check [-repair] [-quarantine]  verify stored records, optionally repairing or quarantining bad ones",
+		run:   runCheck,
+	},
+	"migrate": {
+		usage: "migrate                  rewrite every record not stored at the current schema version",
+		run:   runMigrate,
+	},
+	"compact": {
+		usage: "compact                  reclaim space taken by overwritten, deleted and expired keys",
+		run:   runCompact,
+	},
+}
+
+// runCommand runs the named subcommand and closes the store, returning the
+// process exit code
+func runCommand(name string, args []string, store domain.Store, logger infrastructure.LoggerInterface) int {
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q. Available commands:\n", name)
+		names := make([]string, 0, len(commands))
+		for n := range commands {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			fmt.Fprintf(os.Stderr, "  %s\n", commands[n].usage)
+		}
+		store.Close()
+		return 2
+	}
+
+	err := cmd.run(context.Background(), store, args)
+	if closeErr := store.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		logger.Error("Command failed", "command", name, "error", err)
+		return 1
+	}
+	return 0
+}
+
+// runBackup implements the backup subcommand
+func runBackup(ctx context.Context, store domain.Store, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	output := flags.String("o", "", "file to write the backup to, or - for stdout")
+	compress := flags.Bool("gzip", false, "gzip-compress the backup")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *output == "" {
+		return fmt.Errorf("an output file is required (-o)")
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	count, err := infrastructure.Backup(ctx, store, w, infrastructure.BackupOptions{Compress: *compress})
+	if err != nil {
+		return err
+	}
+
+	if f, ok := w.(*os.File); ok && f != os.Stdout {
+		if err := f.Sync(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Backed up %d entries\n", count)
+	return nil
+}
+
+// runRestore implements the restore subcommand
+func runRestore(ctx context.Context, store domain.Store, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	input := flags.String("i", "", "file to read the backup from, or - for stdin")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *input == "" {
+		return fmt.Errorf("an input file is required (-i)")
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "-" {
+		f, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	count, err := infrastructure.Restore(ctx, store, r)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Restored %d entries\n", count)
+	return nil
+}
+
+// runReencrypt implements the reencrypt subcommand
+func runReencrypt(ctx context.Context, store domain.Store, args []string) error {
+	flags := flag.NewFlagSet("reencrypt", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	encrypted, ok := store.(*infrastructure.EncryptedStore)
+	if !ok {
+		return fmt.Errorf("storage encryption is not configured")
+	}
+
+	count, err := encrypted.Reencrypt(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Re-encrypted %d entries with key %q\n", count, encrypted.Keyring().ActiveKeyID())
+	return nil
+}
+
+// runCheck implements the check subcommand. It fails if it finds issues it
+// did not resolve, other than unknown keys.
+func runCheck(ctx context.Context, store domain.Store, args []string) error {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	repair := flags.Bool("repair", false, "delete orphaned index entries, rebuild missing ones and correct counters")
+	quarantine := flags.Bool("quarantine", false, "move corrupt records and orphaned index entries under quarantine:")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := application.NewIntegrityChecker(store).Check(ctx, application.IntegrityOptions{
+		Repair:     *repair,
+		Quarantine: *quarantine,
+	})
+	if report != nil {
+		for _, issue := range report.Issues {
+			action := issue.Action
+			if action == "" {
+				action = "-"
+			}
+			fmt.Printf("%-14s %-11s %s: %s\n", issue.Kind, action, issue.Key, issue.Detail)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	unresolved := 0
+	for _, issue := range report.Issues {
+		if issue.Action == "" && issue.Kind != application.IssueUnknown {
+			unresolved++
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Checked %d entries, found %d issues\n", report.Checked, len(report.Issues))
+	if unresolved > 0 {
+		return fmt.Errorf("%d issues left unresolved", unresolved)
+	}
+	return nil
+}
+
+// runMigrate implements the migrate subcommand
+func runMigrate(ctx context.Context, store domain.Store, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	count, err := application.MigrateRecords(ctx, store)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Migrated %d records to the current schema\n", count)
+	return nil
+}
+
+// runCompact implements the compact subcommand
+func runCompact(ctx context.Context, store domain.Store, args []string) error {
+	flags := flag.NewFlagSet("compact", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	compactor, ok := store.(domain.Compactor)
+	if !ok {
+		return domain.ErrNotCompactable
+	}
+
+	stats, err := compactor.Compact(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Compacted store from %d to %d bytes, removing %d expired entries\n",
+		stats.BytesBefore, stats.BytesAfter, stats.Expired)
+	return nil
+}
\ No newline at end of file
@@ -35,11 +35,55 @@ func main() {
 	}
 
 	// Initialize storage
-	store := infrastructure.NewMemoryStore()
+	store, err := infrastructure.NewStore(&cfg.Storage)
+	if err != nil {
+		logger.Error("Failed to initialize storage", "error", err)
+		os.Exit(1)
+	}
+
+	// Run a maintenance command instead of the server if one was given
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1], os.Args[2:], store, logger))
+	}
+
+	// Set up replication. Replication reads and writes the store directly so
+	// that its traffic is not counted as application storage operations.
+	var leader *infrastructure.ReplicationLeader
+	var follower *infrastructure.Follower
+	switch cfg.Replication.Role {
+	case "leader":
+		leader = infrastructure.NewReplicationLeader(store, cfg.Replication.HeartbeatInterval)
+	case "follower":
+		follower = infrastructure.NewFollower(store, logger, infrastructure.FollowerOptions{
+			LeaderURL:     cfg.Replication.LeaderURL,
+			Token:         cfg.Replication.Token,
+			RetryInterval: cfg.Replication.RetryInterval,
+			MaxLag:        cfg.Replication.MaxLag,
+		})
+	}
+
+	// Record storage metrics and log every storage operation
+	store = infrastructure.NewInstrumentedStore(store, logger)
 
 	// Initialize services
-	postService := application.NewPostService(store)
-	debugService := application.NewDebugService(logger, store)
+	postQuotas := application.PostQuotas{
+		MaxPosts: cfg.Tenancy.Quota.MaxPosts,
+		Tenants:  make(map[string]int, len(cfg.Tenancy.Tenants)),
+	}
+	for tenant, quota := range cfg.Tenancy.Tenants {
+		postQuotas.Tenants[tenant] = quota.MaxPosts
+	}
+	postService := application.NewPostService(logger, store, postQuotas, cfg.Posts.MaxRevisions)
+	authorService := application.NewAuthorService(store, cfg.Authors.OpenRegistration)
+	replicationService := application.NewReplicationService(logger, leader, follower)
+	debugService := application.NewDebugService(logger, store, replicationService)
+
+	// Publish scheduled posts where posts are written; followers receive the
+	// published posts from their leader
+	var scheduler *application.PostScheduler
+	if follower == nil {
+		scheduler = application.NewPostScheduler(postService, logger, cfg.Posts.SchedulerInterval)
+	}
 
 	// Initialize middleware
 	requestIDMiddleware := middleware.NewRequestIDMiddleware()
@@ -47,9 +91,13 @@ func main() {
 	recoveryMiddleware := middleware.NewRecoveryMiddleware(logger)
 	corsMiddleware := middleware.NewCORSMiddleware(&cfg.CORS)
 	errorHandlerMiddleware := middleware.NewErrorHandlerMiddleware(logger)
+	authMiddleware := middleware.NewAuthMiddleware(cfg.Debug.Token, errorHandlerMiddleware)
+	replicationAuthMiddleware := middleware.NewAuthMiddleware(cfg.Replication.Token, errorHandlerMiddleware)
+	tenantMiddleware := middleware.NewTenantMiddleware(&cfg.Tenancy, errorHandlerMiddleware)
+	identityMiddleware := middleware.NewIdentityMiddleware(&cfg.Authors, authorService, errorHandlerMiddleware)
 
 	// Initialize handlers
-	handlers := api.NewHandlers(postService, debugService, errorHandlerMiddleware)
+	handlers := api.NewHandlers(postService, authorService, debugService, replicationService, errorHandlerMiddleware)
 
 	// Create router
 	r := chi.NewRouter()
@@ -66,21 +114,79 @@ func main() {
 	r.Use(chimiddleware.Logger)
 	r.Use(chimiddleware.Recoverer)
 
+	// Resolve the tenant before routing, since the path resolver strips the
+	// tenant prefix from the routed path
+	if cfg.Tenancy.Enabled {
+		r.Use(tenantMiddleware.Handler)
+	}
+
 	// API routes
 	r.Route("/debug", func(r chi.Router) {
 		r.Get("/metrics", handlers.GetMetrics)
 		r.Post("/logs", handlers.SetLogLevel)
 		r.Get("/pprof/*", handlers.GetPprofProfile)
+		r.With(authMiddleware.Handler).Get("/backup", handlers.GetBackup)
 	})
 
 	r.Route("/posts", func(r chi.Router) {
+		// Posts always belong to a tenant when tenancy is enabled
+		if cfg.Tenancy.Enabled {
+			r.Use(tenantMiddleware.Require)
+		}
+
+		// Followers cannot write to their replicated store
+		if follower != nil {
+			r.Use(middleware.NewReplicaMiddleware(&cfg.Replication, errorHandlerMiddleware).Handler)
+		}
+
+		// Writes are authorized against the caller's author token
+		r.Use(identityMiddleware.Handler)
+
 		r.Get("/", handlers.ListPosts)
 		r.Post("/", handlers.CreatePost)
+		r.Get("/search", handlers.SearchPosts)
 		r.Get("/{id}", handlers.GetPost)
 		r.Put("/{id}", handlers.UpdatePost)
 		r.Delete("/{id}", handlers.DeletePost)
+		r.Get("/{id}/revisions", handlers.ListPostRevisions)
+		r.Get("/{id}/revisions/{revision}", handlers.GetPostRevision)
+		r.Post("/{id}/revisions/{revision}/restore", handlers.RestorePostRevision)
+		r.Get("/{id}/diff", handlers.DiffPostRevisions)
 	})
 
+	r.Route("/authors", func(r chi.Router) {
+		// Authors belong to a tenant like their posts
+		if cfg.Tenancy.Enabled {
+			r.Use(tenantMiddleware.Require)
+		}
+
+		if follower != nil {
+			r.Use(middleware.NewReplicaMiddleware(&cfg.Replication, errorHandlerMiddleware).Handler)
+		}
+
+		r.Use(identityMiddleware.Handler)
+
+		r.Get("/", handlers.ListAuthors)
+		r.Post("/", handlers.CreateAuthor)
+		r.Get("/{id}", handlers.GetAuthor)
+		r.Put("/{id}", handlers.UpdateAuthor)
+		r.Delete("/{id}", handlers.DeleteAuthor)
+	})
+
+	r.Route("/tags", func(r chi.Router) {
+		// Tags are counted over the posts of a tenant
+		if cfg.Tenancy.Enabled {
+			r.Use(tenantMiddleware.Require)
+		}
+
+		r.Get("/", handlers.ListTags)
+	})
+
+	// Replication stream for followers
+	if leader != nil {
+		r.With(replicationAuthMiddleware.Handler).Get("/replication/stream", handlers.StreamReplication)
+	}
+
 	// Health check
 	r.Get("/health", handlers.GetHealth)
 
@@ -93,6 +199,12 @@ func main() {
 		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
+	// End replication streams when shutting down, since followers never
+	// disconnect on their own
+	if leader != nil {
+		server.RegisterOnShutdown(func() { leader.Close() })
+	}
+
 	// Log startup
 	logger.LogStartup("1.0.0", "development", cfg)
 
@@ -120,6 +232,14 @@ func main() {
 		logger.Error("Server shutdown error", "error", err)
 	}
 
+	// Stop replicating and publishing before closing the store they write to
+	if follower != nil {
+		follower.Close()
+	}
+	if scheduler != nil {
+		scheduler.Close()
+	}
+
 	// Close storage
 	if err := store.Close(); err != nil {
 		logger.Error("Storage close error", "error", err)
@@ -0,0 +1,302 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// AuthorService handles business logic for authors and authenticates them.
+// Each author authenticates with a bearer token of the form
+// "<author ID>.<secret>" issued when the author is created; only a hash of
+// the secret is stored.
+type AuthorService struct {
+	store            domain.Store
+	authors          *Repository[domain.Author]
+	openRegistration bool
+}
+
+// NewAuthorService creates a new author service. With openRegistration,
+// anyone may create an author; otherwise only admins may.
+func NewAuthorService(store domain.Store, openRegistration bool) *AuthorService {
+	s := &AuthorService{
+		store:            store,
+		openRegistration: openRegistration,
+	}
+
+	s.authors = NewRepository(store, RepositoryOptions[domain.Author]{
+		Schema: authorSchema,
+		Key: func(id string) string {
+			return authorKey("", id)
+		},
+		NotFound: func(id string) error {
+			return &domain.AuthorNotFoundError{ID: id}
+		},
+		OnDelete: s.deleteCredentials,
+	})
+
+	return s
+}
+
+// CreateAuthor creates a new author and returns it with its token, which is
+// not stored and cannot be retrieved again
+func (s *AuthorService) CreateAuthor(ctx context.Context, req *domain.CreateAuthorRequest) (*domain.AuthorCredentials, error) {
+	if caller := domain.CallerFromContext(ctx); !s.openRegistration && !caller.Admin {
+		if !caller.Authenticated() {
+			return nil, &domain.UnauthorizedError{Message: "creating authors requires the admin token"}
+		}
+		return nil, &domain.ForbiddenError{Message: "only admins may create authors"}
+	}
+
+	// Validate request
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, &domain.StorageError{Err: err}
+	}
+
+	author := domain.NewAuthor("", req.Name, req.Email, req.Bio)
+
+	// Store the token's hash with the author, so an author never exists
+	// without a way to authenticate
+	err = createUnique(generateAuthorID, func(id string) error {
+		author.ID = id
+		version, err := s.authors.CreateWith(ctx, id, author, func(tx domain.Tx, ns string) error {
+			if err := tx.Set(authorCredentialsKey(ns, id), hashSecret(secret)); err != nil {
+				return &domain.StorageError{Err: err}
+			}
+			return nil
+		})
+		author.Version = version
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.AuthorCredentials{
+		Author: *author,
+		Token:  author.ID + "." + secret,
+	}, nil
+}
+
+// GetAuthor retrieves an author by ID. The author's email is only included
+// for the author and admins.
+func (s *AuthorService) GetAuthor(ctx context.Context, id string) (*domain.Author, error) {
+	if err := validateAuthorID(id); err != nil {
+		return nil, err
+	}
+
+	author, version, err := s.authors.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	author.Version = version
+	author.HidePrivateFields(domain.CallerFromContext(ctx))
+
+	return author, nil
+}
+
+// ListAuthors retrieves a paginated list of authors in ID order, which is
+// also the order they were created in. Emails are only included for the
+// caller's own author, or for every author if the caller is an admin.
+func (s *AuthorService) ListAuthors(ctx context.Context, cursor string, limit int) (*domain.AuthorList, error) {
+	params := NewPaginationParams(cursor, limit)
+	if err := ValidatePaginationParams(params.Cursor, params.Limit); err != nil {
+		return nil, &domain.ValidationError{
+			Field:   "pagination",
+			Message: err.Error(),
+		}
+	}
+
+	var startAfter string
+	if params.Cursor != "" {
+		cursorObj, err := DecodeCursor(params.Cursor)
+		if err != nil || cursorObj == nil || cursorObj.ID == "" {
+			return nil, &domain.PaginationError{Cursor: params.Cursor}
+		}
+		startAfter = cursorObj.ID
+	}
+
+	entities, err := s.authors.List(ctx, domain.ScanOptions{
+		StartAfter: startAfter,
+		Limit:      params.Limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(entities) > params.Limit
+	if hasMore {
+		entities = entities[:params.Limit]
+	}
+
+	caller := domain.CallerFromContext(ctx)
+	authors := make([]domain.Author, 0, len(entities))
+	for _, entity := range entities {
+		entity.Value.Version = entity.Version
+		entity.Value.HidePrivateFields(caller)
+		authors = append(authors, *entity.Value)
+	}
+
+	var nextCursor string
+	if hasMore && len(authors) > 0 {
+		last := authors[len(authors)-1]
+		nextCursor, err = CreateNextCursor(last.ID, last.CreatedAt, params.Limit)
+		if err != nil {
+			return nil, &domain.StorageError{Err: err}
+		}
+	}
+
+	return &domain.AuthorList{
+		Authors:    authors,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// UpdateAuthor updates an author. Authors may only update themselves, unless
+// the caller is an admin. If expectedVersion is non-zero the update only
+// succeeds while the author is still at that version.
+func (s *AuthorService) UpdateAuthor(ctx context.Context, id string, req *domain.UpdateAuthorRequest, expectedVersion int64) (*domain.Author, error) {
+	if err := validateAuthorID(id); err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, id, "update this author"); err != nil {
+		return nil, err
+	}
+
+	// Validate request
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	author, version, err := s.authors.Update(ctx, id, expectedVersion, func(author *domain.Author) error {
+		author.Update(req.Name, req.Email, req.Bio)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	author.Version = version
+
+	return author, nil
+}
+
+// DeleteAuthor deletes an author and revokes their token. Their posts are
+// kept; only admins can modify them afterwards. Authors may only delete
+// themselves, unless the caller is an admin.
+func (s *AuthorService) DeleteAuthor(ctx context.Context, id string, expectedVersion int64) error {
+	if err := validateAuthorID(id); err != nil {
+		return err
+	}
+	if err := authorize(ctx, id, "delete this author"); err != nil {
+		return err
+	}
+
+	return s.authors.Delete(ctx, id, expectedVersion)
+}
+
+// Authenticate returns the ID of the author a token belongs to in the tenant
+// namespace of ctx
+func (s *AuthorService) Authenticate(ctx context.Context, token string) (string, error) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok || validateAuthorID(id) != nil {
+		return "", &domain.UnauthorizedError{Message: "invalid author token"}
+	}
+
+	var stored string
+	err := s.store.GetTyped(ctx, authorCredentialsKey(domain.TenantKeyPrefix(ctx), id), &stored)
+	if err == domain.ErrKeyNotFound {
+		return "", &domain.UnauthorizedError{Message: "invalid author token"}
+	}
+	if err != nil {
+		return "", &domain.StorageError{Err: err}
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(stored)) != 1 {
+		return "", &domain.UnauthorizedError{Message: "invalid author token"}
+	}
+
+	return id, nil
+}
+
+// deleteCredentials revokes the token of a deleted author
+func (s *AuthorService) deleteCredentials(ctx context.Context, tx domain.Tx, ns, id string, author *domain.Author) error {
+	if err := tx.Delete(authorCredentialsKey(ns, id)); err != nil && err != domain.ErrKeyNotFound {
+		return &domain.StorageError{Err: err}
+	}
+	return nil
+}
+
+// authorize checks that the caller in ctx may act on behalf of the author
+// with the given ID
+func authorize(ctx context.Context, authorID, action string) error {
+	caller := domain.CallerFromContext(ctx)
+	if !caller.Authenticated() {
+		return &domain.UnauthorizedError{Message: "an author token is required to " + action}
+	}
+	if !caller.CanActAs(authorID) {
+		return &domain.ForbiddenError{Message: "only its author or an admin may " + action}
+	}
+	return nil
+}
+
+// validateAuthorID validates an author ID
+func validateAuthorID(id string) error {
+	if id == "" {
+		return &domain.ValidationError{
+			Field:   "id",
+			Message: "author ID is required",
+		}
+	}
+
+	if !isValidPostID(id) {
+		return &domain.ValidationError{
+			Field:   "id",
+			Message: "invalid author ID format",
+		}
+	}
+
+	return nil
+}
+
+// authorKey generates a storage key for an author in the tenant namespace ns
+func authorKey(ns, id string) string {
+	return fmt.Sprintf("%sauthors:%s", ns, id)
+}
+
+// authorCredentialsKey generates the key holding the hash of an author's
+// token secret in the tenant namespace ns
+func authorCredentialsKey(ns, id string) string {
+	return fmt.Sprintf("%sauthor-credentials:%s", ns, id)
+}
+
+// generateAuthorID generates a unique author ID
+func generateAuthorID() string {
+	return fmt.Sprintf("author-%d", time.Now().UnixNano())
+}
+
+// generateSecret generates the secret part of an author token
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashSecret hashes a token secret for storage
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
\ No newline at end of file
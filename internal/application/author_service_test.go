@@ -0,0 +1,181 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gosuda.org/boilerplate/internal/domain"
+	"gosuda.org/boilerplate/internal/infrastructure"
+)
+
+// asAdmin returns a context for requests by an admin
+func asAdmin() context.Context {
+	return domain.WithCaller(context.Background(), domain.Caller{Admin: true})
+}
+
+// newTestAuthorService creates an author service backed by a fresh memory store
+func newTestAuthorService(t *testing.T, openRegistration bool) *AuthorService {
+	t.Helper()
+	store := infrastructure.NewMemoryStore()
+	t.Cleanup(func() { store.Close() })
+	return NewAuthorService(store, openRegistration)
+}
+
+// createAuthor creates an author as an admin, failing the test on error
+func createAuthor(t *testing.T, s *AuthorService, name, email string) *domain.AuthorCredentials {
+	t.Helper()
+	credentials, err := s.CreateAuthor(asAdmin(), &domain.CreateAuthorRequest{Name: name, Email: email})
+	if err != nil {
+		t.Fatalf("Failed to create author %s: %v", name, err)
+	}
+	return credentials
+}
+
+func TestAuthorService_CreateAuthor(t *testing.T) {
+	req := &domain.CreateAuthorRequest{Name: "Ada"}
+
+	closed := newTestAuthorService(t, false)
+	var unauthorized *domain.UnauthorizedError
+	if _, err := closed.CreateAuthor(context.Background(), req); !errors.As(err, &unauthorized) {
+		t.Errorf("Expected anonymous callers to be unauthorized, got %v", err)
+	}
+	var forbidden *domain.ForbiddenError
+	if _, err := closed.CreateAuthor(asAuthor("", "author-1"), req); !errors.As(err, &forbidden) {
+		t.Errorf("Expected authors to be forbidden, got %v", err)
+	}
+	if _, err := closed.CreateAuthor(asAdmin(), req); err != nil {
+		t.Errorf("Expected admins to create authors, got %v", err)
+	}
+
+	open := newTestAuthorService(t, true)
+	credentials, err := open.CreateAuthor(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected anyone to create authors with open registration, got %v", err)
+	}
+	if credentials.ID == "" || credentials.Token == "" || credentials.Version == 0 {
+		t.Errorf("Expected an ID, token and version, got %+v", credentials)
+	}
+}
+
+func TestAuthorService_Authenticate(t *testing.T) {
+	s := newTestAuthorService(t, false)
+	ada := createAuthor(t, s, "Ada", "")
+	grace := createAuthor(t, s, "Grace", "")
+
+	id, err := s.Authenticate(context.Background(), ada.Token)
+	if err != nil || id != ada.ID {
+		t.Fatalf("Expected the token to authenticate %s, got %q, %v", ada.ID, id, err)
+	}
+
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		token string
+	}{
+		{"empty", context.Background(), ""},
+		{"no secret", context.Background(), ada.ID},
+		{"invalid ID", context.Background(), "not an id.secret"},
+		{"unknown author", context.Background(), "author-0.secret"},
+		{"wrong secret", context.Background(), ada.ID + ".secret"},
+		{"other author's secret", context.Background(), ada.ID + grace.Token[len(grace.ID):]},
+		{"other tenant", domain.WithTenant(context.Background(), "acme"), ada.Token},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var unauthorized *domain.UnauthorizedError
+			if _, err := s.Authenticate(tt.ctx, tt.token); !errors.As(err, &unauthorized) {
+				t.Errorf("Expected the token to be rejected, got %v", err)
+			}
+		})
+	}
+
+	// Deleting an author revokes their token
+	if err := s.DeleteAuthor(asAuthor("", ada.ID), ada.ID, 0); err != nil {
+		t.Fatalf("Failed to delete author: %v", err)
+	}
+	var unauthorized *domain.UnauthorizedError
+	if _, err := s.Authenticate(context.Background(), ada.Token); !errors.As(err, &unauthorized) {
+		t.Errorf("Expected the token of a deleted author to be revoked, got %v", err)
+	}
+	if id, err := s.Authenticate(context.Background(), grace.Token); err != nil || id != grace.ID {
+		t.Errorf("Expected other tokens to remain valid, got %q, %v", id, err)
+	}
+}
+
+func TestAuthorService_Authorization(t *testing.T) {
+	s := newTestAuthorService(t, false)
+	ada := createAuthor(t, s, "Ada", "")
+	grace := createAuthor(t, s, "Grace", "")
+	update := &domain.UpdateAuthorRequest{Name: "Ada Lovelace"}
+
+	var unauthorized *domain.UnauthorizedError
+	if _, err := s.UpdateAuthor(context.Background(), ada.ID, update, 0); !errors.As(err, &unauthorized) {
+		t.Errorf("Expected anonymous updates to be unauthorized, got %v", err)
+	}
+	if err := s.DeleteAuthor(context.Background(), ada.ID, 0); !errors.As(err, &unauthorized) {
+		t.Errorf("Expected anonymous deletes to be unauthorized, got %v", err)
+	}
+
+	var forbidden *domain.ForbiddenError
+	if _, err := s.UpdateAuthor(asAuthor("", grace.ID), ada.ID, update, 0); !errors.As(err, &forbidden) {
+		t.Errorf("Expected updates by another author to be forbidden, got %v", err)
+	}
+	if err := s.DeleteAuthor(asAuthor("", grace.ID), ada.ID, 0); !errors.As(err, &forbidden) {
+		t.Errorf("Expected deletes by another author to be forbidden, got %v", err)
+	}
+
+	author, err := s.UpdateAuthor(asAuthor("", ada.ID), ada.ID, update, 0)
+	if err != nil || author.Name != "Ada Lovelace" {
+		t.Errorf("Expected authors to update themselves, got %+v, %v", author, err)
+	}
+	if _, err := s.UpdateAuthor(asAdmin(), ada.ID, &domain.UpdateAuthorRequest{Name: "Ada"}, 0); err != nil {
+		t.Errorf("Expected admins to update any author, got %v", err)
+	}
+	if err := s.DeleteAuthor(asAdmin(), grace.ID, 0); err != nil {
+		t.Errorf("Expected admins to delete any author, got %v", err)
+	}
+	if err := s.DeleteAuthor(asAuthor("", ada.ID), ada.ID, 0); err != nil {
+		t.Errorf("Expected authors to delete themselves, got %v", err)
+	}
+}
+
+func TestAuthorService_HidesEmail(t *testing.T) {
+	s := newTestAuthorService(t, false)
+	ada := createAuthor(t, s, "Ada", "ada@example.com")
+	grace := createAuthor(t, s, "Grace", "grace@example.com")
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		visible map[string]bool
+	}{
+		{"anonymous", context.Background(), map[string]bool{}},
+		{"author", asAuthor("", ada.ID), map[string]bool{ada.ID: true}},
+		{"admin", asAdmin(), map[string]bool{ada.ID: true, grace.ID: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := s.ListAuthors(tt.ctx, "", 10)
+			if err != nil {
+				t.Fatalf("Failed to list authors: %v", err)
+			}
+			if len(list.Authors) != 2 {
+				t.Fatalf("Expected 2 authors, got %d", len(list.Authors))
+			}
+			for _, author := range list.Authors {
+				if (author.Email != "") != tt.visible[author.ID] {
+					t.Errorf("Listing: expected email of %s visible %v, got %q", author.ID, tt.visible[author.ID], author.Email)
+				}
+
+				got, err := s.GetAuthor(tt.ctx, author.ID)
+				if err != nil {
+					t.Fatalf("Failed to get author: %v", err)
+				}
+				if (got.Email != "") != tt.visible[author.ID] {
+					t.Errorf("Getting: expected email of %s visible %v, got %q", author.ID, tt.visible[author.ID], got.Email)
+				}
+			}
+		})
+	}
+}
\ No newline at end of file
@@ -3,6 +3,9 @@ package application
 import (
 	"context"
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
 
 	"gosuda.org/boilerplate/internal/domain"
 	"gosuda.org/boilerplate/internal/infrastructure"
@@ -10,15 +13,17 @@ import (
 
 // DebugService handles debug-related operations
 type DebugService struct {
-	logger infrastructure.LoggerInterface
-	store  domain.Store
+	logger      infrastructure.LoggerInterface
+	store       domain.Store
+	replication *ReplicationService
 }
 
 // NewDebugService creates a new debug service
-func NewDebugService(logger infrastructure.LoggerInterface, store domain.Store) *DebugService {
+func NewDebugService(logger infrastructure.LoggerInterface, store domain.Store, replication *ReplicationService) *DebugService {
 	return &DebugService{
-		logger: logger,
-		store:  store,
+		logger:      logger,
+		store:       store,
+		replication: replication,
 	}
 }
 
@@ -47,9 +52,15 @@ func (s *DebugService) GetLogLevel(ctx context.Context) string {
 
 // GetMetrics returns application metrics
 func (s *DebugService) GetMetrics(ctx context.Context) (string, error) {
-	// In a real implementation, this would return Prometheus metrics
-	// For now, we'll return a simple metrics format
-	metrics := fmt.Sprintf(`# HELP app_requests_total Total number of requests
+	var stats domain.StoreStats
+	if provider, ok := s.store.(domain.StatsProvider); ok {
+		stats = provider.Stats()
+	}
+
+	// In a real implementation, request metrics would come from a Prometheus
+	// registry. Storage metrics come from the store's stats.
+	var b strings.Builder
+	b.WriteString(`# HELP app_requests_total Total number of requests
 # TYPE app_requests_total counter
 app_requests_total{method="GET",path="/posts"} 0
 app_requests_total{method="POST",path="/posts"} 0
@@ -57,23 +68,109 @@ app_requests_total{method="GET",path="/posts/{id}"} 0
 app_requests_total{method="PUT",path="/posts/{id}"} 0
 app_requests_total{method="DELETE",path="/posts/{id}"} 0
 
-# HELP app_storage_operations_total Total number of storage operations
-# TYPE app_storage_operations_total counter
-app_storage_operations_total{operation="get"} 0
-app_storage_operations_total{operation="set"} 0
-app_storage_operations_total{operation="delete"} 0
-app_storage_operations_total{operation="list"} 0
+`)
+
+	writeStorageOperationMetrics(&b, stats.Operations)
 
-# HELP app_storage_items_current Current number of items in storage
+	if status := s.replication.Status(); status != nil {
+		writeReplicationMetrics(&b, status)
+	}
+
+	fmt.Fprintf(&b, `# HELP app_storage_items_current Current number of items in storage
 # TYPE app_storage_items_current gauge
-app_storage_items_current 0
+app_storage_items_current %d
+
+# HELP app_storage_bytes_current Current size of stored keys and values in bytes
+# TYPE app_storage_bytes_current gauge
+app_storage_bytes_current %d
+
+# HELP app_storage_expired_keys_total Total number of keys removed after their TTL elapsed
+# TYPE app_storage_expired_keys_total counter
+app_storage_expired_keys_total %d
+
+# HELP app_storage_evicted_keys_total Total number of keys evicted to stay within storage limits
+# TYPE app_storage_evicted_keys_total counter
+app_storage_evicted_keys_total %d
 
 # HELP app_log_level_current Current log level
 # TYPE app_log_level_current gauge
 app_log_level_current{level="%s"} 1
-`, s.logger.GetLevel().String())
+`, stats.Items, stats.Bytes, stats.ExpiredKeys, stats.EvictedKeys, s.logger.GetLevel().String())
 
-	return metrics, nil
+	return b.String(), nil
+}
+
+// writeStorageOperationMetrics renders per-operation storage counters and
+// latency histograms
+func writeStorageOperationMetrics(b *strings.Builder, operations []domain.OperationStats) {
+	if len(operations) == 0 {
+		return
+	}
+
+	b.WriteString("# HELP app_storage_operations_total Total number of storage operations\n")
+	b.WriteString("# TYPE app_storage_operations_total counter\n")
+	for _, op := range operations {
+		fmt.Fprintf(b, "app_storage_operations_total{operation=%q} %d\n", op.Operation, op.Count)
+	}
+
+	b.WriteString("\n# HELP app_storage_operation_errors_total Total number of failed storage operations\n")
+	b.WriteString("# TYPE app_storage_operation_errors_total counter\n")
+	for _, op := range operations {
+		fmt.Fprintf(b, "app_storage_operation_errors_total{operation=%q} %d\n", op.Operation, op.Errors)
+	}
+
+	b.WriteString("\n# HELP app_storage_operation_duration_seconds Storage operation latency\n")
+	b.WriteString("# TYPE app_storage_operation_duration_seconds histogram\n")
+	for _, op := range operations {
+		for i, bound := range op.LatencyBounds {
+			fmt.Fprintf(b, "app_storage_operation_duration_seconds_bucket{operation=%q,le=\"%s\"} %d\n",
+				op.Operation, strconv.FormatFloat(bound.Seconds(), 'g', -1, 64), op.LatencyBuckets[i])
+		}
+		fmt.Fprintf(b, "app_storage_operation_duration_seconds_bucket{operation=%q,le=\"+Inf\"} %d\n", op.Operation, op.Count)
+		fmt.Fprintf(b, "app_storage_operation_duration_seconds_sum{operation=%q} %s\n",
+			op.Operation, strconv.FormatFloat(op.LatencySum.Seconds(), 'g', -1, 64))
+		fmt.Fprintf(b, "app_storage_operation_duration_seconds_count{operation=%q} %d\n", op.Operation, op.Count)
+	}
+	b.WriteString("\n")
+}
+
+// writeReplicationMetrics renders the replication role, revisions and lag
+func writeReplicationMetrics(b *strings.Builder, status *domain.ReplicationStatus) {
+	connected := 0
+	if status.Connected {
+		connected = 1
+	}
+
+	fmt.Fprintf(b, `# HELP app_replication_role Replication role of this instance
+# TYPE app_replication_role gauge
+app_replication_role{role="%s"} 1
+
+# HELP app_replication_followers_current Number of followers streaming from this leader
+# TYPE app_replication_followers_current gauge
+app_replication_followers_current %d
+
+# HELP app_replication_connected Whether this follower is streaming from its leader
+# TYPE app_replication_connected gauge
+app_replication_connected %d
+
+# HELP app_replication_applied_revision Last leader revision applied to this instance
+# TYPE app_replication_applied_revision gauge
+app_replication_applied_revision %d
+
+# HELP app_replication_leader_revision Latest revision of the leader as last reported
+# TYPE app_replication_leader_revision gauge
+app_replication_leader_revision %d
+
+# HELP app_replication_lag_revisions Number of leader revisions not yet applied
+# TYPE app_replication_lag_revisions gauge
+app_replication_lag_revisions %d
+
+# HELP app_replication_lag_seconds Time since this instance was last known to be in sync with its leader
+# TYPE app_replication_lag_seconds gauge
+app_replication_lag_seconds %s
+
+`, status.Role, status.Followers, connected, status.AppliedRevision, status.LeaderRevision,
+		status.LagRevisions, strconv.FormatFloat(status.LagSeconds, 'g', -1, 64))
 }
 
 // GetPprofProfile returns pprof profile data
@@ -115,18 +212,41 @@ func (s *DebugService) GetHealthStatus(ctx context.Context) (*HealthStatus, erro
 		},
 	}
 
+	// Check replication, if this instance replicates
+	replicationHealthy := true
+	if replication := s.replication.Status(); replication != nil {
+		replicationHealthy = replication.Healthy
+		status.Replication = replication
+		status.Checks["replication"] = HealthCheck{
+			Status:  replicationHealthy,
+			Message: replicationMessage(replication),
+		}
+	}
+
 	// Determine overall status
-	if !storageHealthy || !loggerHealthy {
+	if !storageHealthy || !loggerHealthy || !replicationHealthy {
 		status.Status = "unhealthy"
 	}
 
 	return status, nil
 }
 
+// Backup streams a point-in-time backup of the store to w
+func (s *DebugService) Backup(ctx context.Context, w io.Writer, compress bool) error {
+	count, err := infrastructure.Backup(ctx, s.store, w, infrastructure.BackupOptions{Compress: compress})
+	if err != nil {
+		return &domain.StorageError{Err: err}
+	}
+
+	s.logger.Info("Store backup completed", "entries", count, "compressed", compress)
+	return nil
+}
+
 // HealthStatus represents the application health status
 type HealthStatus struct {
-	Status string                 `json:"status"`
-	Checks map[string]HealthCheck `json:"checks,omitempty"`
+	Status      string                    `json:"status"`
+	Checks      map[string]HealthCheck    `json:"checks,omitempty"`
+	Replication *domain.ReplicationStatus `json:"replication,omitempty"`
 }
 
 // HealthCheck represents a health check result
@@ -135,6 +255,22 @@ type HealthCheck struct {
 	Message string `json:"message"`
 }
 
+// replicationMessage describes the replication status for the health check
+func replicationMessage(status *domain.ReplicationStatus) string {
+	switch {
+	case status.Role == domain.RoleLeader:
+		return fmt.Sprintf("Leading %d followers", status.Followers)
+	case !status.Connected && status.LastError == "":
+		return "Connecting to leader"
+	case !status.Connected:
+		return "Disconnected from leader: " + status.LastError
+	case !status.Healthy:
+		return fmt.Sprintf("Lagging %.1fs behind leader", status.LagSeconds)
+	default:
+		return "Following leader"
+	}
+}
+
 // validateLogLevel validates a log level string
 func validateLogLevel(level string) error {
 	validLevels := map[string]bool{
@@ -180,19 +316,20 @@ func validatePprofProfile(profile string) error {
 
 // checkStorageHealth checks if the storage is healthy
 func (s *DebugService) checkStorageHealth(ctx context.Context) error {
-	// Try to perform a simple operation to check storage health
-	testKey := "health:test"
+	// Try to perform a simple operation to check storage health, within the
+	// tenant's namespace so concurrent checks for other tenants do not interfere
+	testKey := domain.TenantKeyPrefix(ctx) + "health:test"
 	testValue := "test"
 	
-	if err := s.store.Set(testKey, testValue); err != nil {
+	if err := s.store.Set(ctx, testKey, testValue); err != nil {
 		return err
 	}
 	
-	if _, err := s.store.Get(testKey); err != nil {
+	if _, err := s.store.Get(ctx, testKey); err != nil {
 		return err
 	}
 	
-	if err := s.store.Delete(testKey); err != nil {
+	if err := s.store.Delete(ctx, testKey); err != nil {
 		return err
 	}
 	
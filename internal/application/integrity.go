@@ -0,0 +1,754 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// quarantinePrefix is prepended to the keys of records the integrity checker
+// moves aside. Quarantined records are not checked again.
+const quarantinePrefix = "quarantine:"
+
+// Kinds of integrity issues
+const (
+	// IssueCorrupt is a value that does not decode as the type its key implies
+	IssueCorrupt = "corrupt"
+	// IssueOrphan is an index entry whose post is missing or does not match
+	// it, a revision of a missing post, or the credentials of a missing author
+	IssueOrphan = "orphan"
+	// IssueMissingIndex is a post without one of its index entries
+	IssueMissingIndex = "missing-index"
+	// IssueCountMismatch is a post or tag counter or the search statistics
+	// differing from the posts they count
+	IssueCountMismatch = "count-mismatch"
+	// IssueUnknown is a key this application does not write
+	IssueUnknown = "unknown"
+)
+
+// Actions taken on integrity issues
+const (
+	ActionRepaired    = "repaired"
+	ActionQuarantined = "quarantined"
+)
+
+// IntegrityOptions selects what the integrity checker does about the issues
+// it finds. By default it only reports them.
+type IntegrityOptions struct {
+	// Repair deletes orphaned index entries, rebuilds missing ones and
+	// corrects post and tag counters and the search statistics. Posts
+	// written before an index existed are added to it this way.
+	Repair bool
+
+	// Quarantine moves corrupt records and orphaned index entries to
+	// "quarantine:"-prefixed keys, where they can be inspected
+	Quarantine bool
+}
+
+// IntegrityIssue describes a problem with a stored record
+type IntegrityIssue struct {
+	Key    string
+	Kind   string
+	Detail string
+
+	// Action is what was done about the issue, or empty if it was left alone
+	Action string
+}
+
+// IntegrityReport summarises an integrity check
+type IntegrityReport struct {
+	// Checked is the number of keys checked
+	Checked int
+	Issues  []IntegrityIssue
+}
+
+// IntegrityChecker verifies that every record in the store decodes as the
+// type its key implies, e.g. posts:* as domain.Post, that posts, their index
+// entries, post and tag counters, search statistics and the schedule of
+// posts to publish agree, and that post revisions and author credentials
+// belong to an existing post or author. It is meant to be run while the
+// server is stopped.
+type IntegrityChecker struct {
+	store domain.Store
+}
+
+// NewIntegrityChecker creates a new integrity checker
+func NewIntegrityChecker(store domain.Store) *IntegrityChecker {
+	return &IntegrityChecker{
+		store: store,
+	}
+}
+
+// storedRecord identifies a record as it was when the check read it
+type storedRecord struct {
+	key     string
+	id      string
+	version int64
+	value   []byte
+}
+
+// indexedPost holds the fields of a post its index entries are derived from
+type indexedPost struct {
+	createdAt time.Time
+	authorID  string
+	tags      []string
+	post      *domain.Post
+}
+
+// namespaceRecords collects the post and author records of one tenant namespace
+type namespaceRecords struct {
+	posts          map[string]indexedPost
+	corruptPosts   map[string]bool
+	index          []storedRecord
+	authorIndex    []storedRecord
+	tagIndex       []storedRecord
+	searchIndex    []storedRecord
+	revisions      []storedRecord
+	count          *int
+	tagCounts      map[string]int
+	searchStats    *searchStats
+	authors        map[string]bool
+	corruptAuthors map[string]bool
+	credentials    []storedRecord
+}
+
+// Check walks every key in the store and reports the issues it finds,
+// repairing or quarantining them as opts selects
+func (c *IntegrityChecker) Check(ctx context.Context, opts IntegrityOptions) (*IntegrityReport, error) {
+	report := &IntegrityReport{}
+	namespaces := make(map[string]*namespaceRecords)
+	var schedule []scheduleEntry
+	var corrupt []storedRecord
+	var corruptDetails []string
+
+	it, err := c.store.Iterate(ctx, domain.ScanOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	for it.Next() {
+		key := it.Key()
+		if strings.HasPrefix(key, quarantinePrefix) {
+			continue
+		}
+		report.Checked++
+
+		ns, rest := domain.SplitTenantKey(key)
+		records := namespaces[ns]
+		if records == nil {
+			records = &namespaceRecords{
+				posts:          make(map[string]indexedPost),
+				corruptPosts:   make(map[string]bool),
+				authors:        make(map[string]bool),
+				corruptAuthors: make(map[string]bool),
+				tagCounts:      make(map[string]int),
+			}
+			namespaces[ns] = records
+		}
+
+		record := storedRecord{key: key, version: it.Version(), value: append([]byte(nil), it.Value()...)}
+		var problem string
+
+		switch {
+		case strings.HasPrefix(rest, "posts:"):
+			id := strings.TrimPrefix(rest, "posts:")
+			var stored any
+			var post *domain.Post
+			err := it.Decode(&stored)
+			if err == nil {
+				post, err = decodePost(stored)
+			}
+			if err != nil {
+				problem = "does not decode as a post: " + err.Error()
+			} else if post.ID != id {
+				problem = fmt.Sprintf("holds post %q", post.ID)
+			} else {
+				records.posts[id] = indexedPost{createdAt: post.CreatedAt, authorID: post.AuthorID, tags: post.Tags, post: post}
+			}
+			if problem != "" {
+				records.corruptPosts[id] = true
+			}
+		case strings.HasPrefix(rest, postsCreatedIndexPrefix), strings.HasPrefix(rest, postsAuthorIndexPrefix),
+			strings.HasPrefix(rest, postsTagIndexPrefix):
+			var id string
+			if err := it.Decode(&id); err != nil {
+				problem = "does not decode as a post ID: " + err.Error()
+			} else if !strings.HasSuffix(rest, ":"+id) {
+				problem = fmt.Sprintf("points to post %q", id)
+			} else {
+				record.id = id
+				switch {
+				case strings.HasPrefix(rest, postsCreatedIndexPrefix):
+					records.index = append(records.index, record)
+				case strings.HasPrefix(rest, postsAuthorIndexPrefix):
+					records.authorIndex = append(records.authorIndex, record)
+				default:
+					records.tagIndex = append(records.tagIndex, record)
+				}
+			}
+		case rest == postCountKey(""):
+			var count int
+			if err := it.Decode(&count); err != nil {
+				problem = "does not decode as a post count: " + err.Error()
+			} else {
+				records.count = &count
+			}
+		case strings.HasPrefix(rest, searchTermsPrefix):
+			var posting searchPosting
+			_, id, ok := splitSearchTermKey(strings.TrimPrefix(rest, searchTermsPrefix))
+			if err := it.Decode(&posting); err != nil {
+				problem = "does not decode as a search posting: " + err.Error()
+			} else if !ok {
+				problem = "does not name a term and post"
+			} else {
+				record.id = id
+				records.searchIndex = append(records.searchIndex, record)
+			}
+		case rest == searchStatsKey(""):
+			var stats searchStats
+			if err := it.Decode(&stats); err != nil {
+				problem = "does not decode as search statistics: " + err.Error()
+			} else {
+				records.searchStats = &stats
+			}
+		case ns == "" && strings.HasPrefix(rest, scheduledPostsPrefix):
+			var entry scheduledPost
+			if err := it.Decode(&entry); err != nil {
+				problem = "does not decode as a schedule entry: " + err.Error()
+			} else {
+				record.id = entry.ID
+				schedule = append(schedule, scheduleEntry{record: record, post: entry})
+			}
+		case strings.HasPrefix(rest, postRevisionsPrefix):
+			postID, number, ok := splitPostRevisionID(strings.TrimPrefix(rest, postRevisionsPrefix))
+			var stored any
+			var revision *domain.PostRevision
+			err := it.Decode(&stored)
+			if err == nil {
+				revision, err = decodeEntity[domain.PostRevision](postRevisionSchema, stored)
+			}
+			if err != nil {
+				problem = "does not decode as a post revision: " + err.Error()
+			} else if !ok {
+				problem = "does not name a post and revision"
+			} else if revision.PostID != postID || revision.Revision != number {
+				problem = fmt.Sprintf("holds revision %d of post %q", revision.Revision, revision.PostID)
+			} else {
+				record.id = postID
+				records.revisions = append(records.revisions, record)
+			}
+		case strings.HasPrefix(rest, tagCountsPrefix):
+			var count int
+			if err := it.Decode(&count); err != nil {
+				problem = "does not decode as a tag count: " + err.Error()
+			} else {
+				records.tagCounts[strings.TrimPrefix(rest, tagCountsPrefix)] = count
+			}
+		case strings.HasPrefix(rest, "authors:"):
+			id := strings.TrimPrefix(rest, "authors:")
+			var stored any
+			var author *domain.Author
+			err := it.Decode(&stored)
+			if err == nil {
+				author, err = decodeEntity[domain.Author](authorSchema, stored)
+			}
+			if err != nil {
+				problem = "does not decode as an author: " + err.Error()
+				records.corruptAuthors[id] = true
+			} else if author.ID != id {
+				problem = fmt.Sprintf("holds author %q", author.ID)
+				records.corruptAuthors[id] = true
+			} else {
+				records.authors[id] = true
+			}
+		case strings.HasPrefix(rest, "author-credentials:"):
+			var hash string
+			if err := it.Decode(&hash); err != nil {
+				problem = "does not decode as author credentials: " + err.Error()
+			} else {
+				record.id = strings.TrimPrefix(rest, "author-credentials:")
+				records.credentials = append(records.credentials, record)
+			}
+		case rest == "health:test":
+			// Written and removed by health checks
+		default:
+			report.Issues = append(report.Issues, IntegrityIssue{Key: key, Kind: IssueUnknown, Detail: "not written by this application"})
+		}
+
+		if problem != "" {
+			corrupt = append(corrupt, record)
+			corruptDetails = append(corruptDetails, problem)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, record := range corrupt {
+		issue := IntegrityIssue{Key: record.key, Kind: IssueCorrupt, Detail: corruptDetails[i]}
+		if opts.Quarantine {
+			if err := c.quarantine(ctx, record); err != nil {
+				return report, err
+			}
+			issue.Action = ActionQuarantined
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+
+	names := make([]string, 0, len(namespaces))
+	for ns := range namespaces {
+		names = append(names, ns)
+	}
+	sort.Strings(names)
+
+	for _, ns := range names {
+		issues, err := c.checkPosts(ctx, ns, namespaces[ns], opts)
+		report.Issues = append(report.Issues, issues...)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	issues, err := c.checkSchedule(ctx, names, namespaces, schedule, opts)
+	report.Issues = append(report.Issues, issues...)
+	if err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// scheduleEntry is an entry of the schedule of posts to publish
+type scheduleEntry struct {
+	record storedRecord
+	post   scheduledPost
+}
+
+// checkSchedule reports schedule entries that do not match a scheduled post
+// and scheduled posts missing from the schedule, deleting or rebuilding them
+// as opts selects. The schedule spans all tenant namespaces, listed in names.
+func (c *IntegrityChecker) checkSchedule(ctx context.Context, names []string, namespaces map[string]*namespaceRecords, schedule []scheduleEntry, opts IntegrityOptions) ([]IntegrityIssue, error) {
+	var issues []IntegrityIssue
+	scheduled := make(map[string]bool)
+
+	for _, entry := range schedule {
+		tenantCtx := domain.WithTenant(ctx, entry.post.Tenant)
+		records := namespaces[domain.TenantKeyPrefix(tenantCtx)]
+		if records == nil {
+			records = &namespaceRecords{}
+		}
+
+		post, ok := records.posts[entry.post.ID]
+		var detail string
+		switch {
+		case ok && scheduledPostKey(tenantCtx, entry.post.ID, post.post) == entry.record.key:
+			scheduled[entry.record.key] = true
+			continue
+		case ok:
+			detail = fmt.Sprintf("post %s is not scheduled for then", entry.post.ID)
+		case records.corruptPosts[entry.post.ID] && !opts.Quarantine:
+			// The post is reported as corrupt and stays where it is
+			continue
+		default:
+			detail = fmt.Sprintf("post %s does not exist", entry.post.ID)
+		}
+
+		issue := IntegrityIssue{Key: entry.record.key, Kind: IssueOrphan, Detail: detail}
+		if err := c.resolveOrphan(ctx, entry.record, &issue, opts); err != nil {
+			return issues, err
+		}
+		issues = append(issues, issue)
+	}
+
+	for _, ns := range names {
+		records := namespaces[ns]
+		tenantCtx := domain.WithTenant(ctx, tenantForPrefix(ns))
+
+		ids := make([]string, 0, len(records.posts))
+		for id := range records.posts {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			post := records.posts[id].post
+			key := scheduledPostKey(tenantCtx, id, post)
+			if key == "" || scheduled[key] {
+				continue
+			}
+
+			issue := IntegrityIssue{Key: postKey(ns, id), Kind: IssueMissingIndex, Detail: "no schedule entry " + key}
+			if opts.Repair {
+				err := c.store.Update(ctx, func(tx domain.Tx) error {
+					if _, err := tx.Version(postKey(ns, id)); err != nil {
+						return err
+					}
+					return schedulePost(tenantCtx, tx, id, post)
+				})
+				if err != nil && err != domain.ErrKeyNotFound {
+					return issues, err
+				}
+				issue.Action = ActionRepaired
+			}
+			issues = append(issues, issue)
+		}
+	}
+
+	return issues, nil
+}
+
+// tenantForPrefix returns the tenant whose key namespace is ns
+func tenantForPrefix(ns string) string {
+	tenant, _ := strings.CutPrefix(ns, "tenant:")
+	return strings.TrimSuffix(tenant, ":")
+}
+
+// postIndex describes one of the indexes of posts
+type postIndex struct {
+	entries []storedRecord
+
+	// expected returns the entries a post should have, by key, if any
+	expected func(id string, post indexedPost) map[string]any
+}
+
+// checkPosts cross-checks the posts, index entries, post counter, post
+// revisions and author credentials of a tenant namespace
+func (c *IntegrityChecker) checkPosts(ctx context.Context, ns string, records *namespaceRecords, opts IntegrityOptions) ([]IntegrityIssue, error) {
+	var issues []IntegrityIssue
+
+	indexes := []postIndex{
+		{records.index, func(id string, post indexedPost) map[string]any {
+			return map[string]any{postCreatedIndexKey(ns, post.createdAt, id): id}
+		}},
+		{records.authorIndex, func(id string, post indexedPost) map[string]any {
+			if post.authorID == "" {
+				return nil
+			}
+			return map[string]any{postAuthorIndexKey(ns, post.authorID, post.createdAt, id): id}
+		}},
+		{records.tagIndex, func(id string, post indexedPost) map[string]any {
+			entries := make(map[string]any, len(post.tags))
+			for _, tag := range post.tags {
+				entries[postTagIndexKey(ns, tag, post.createdAt, id)] = id
+			}
+			return entries
+		}},
+		{records.searchIndex, func(id string, post indexedPost) map[string]any {
+			postings := buildSearchPostings(post.post)
+			entries := make(map[string]any, len(postings))
+			for term, posting := range postings {
+				entries[searchTermKey(ns, term, id)] = posting
+			}
+			return entries
+		}},
+	}
+
+	for _, index := range indexes {
+		indexIssues, err := c.checkIndex(ctx, ns, records, index, opts)
+		issues = append(issues, indexIssues...)
+		if err != nil {
+			return issues, err
+		}
+	}
+
+	// Corrupt posts keep counting against the quota until they are quarantined
+	expected := len(records.posts)
+	if !opts.Quarantine {
+		expected += len(records.corruptPosts)
+	}
+
+	if records.count != nil && *records.count != expected {
+		issue := IntegrityIssue{
+			Key:    postCountKey(ns),
+			Kind:   IssueCountMismatch,
+			Detail: fmt.Sprintf("counts %d posts, found %d", *records.count, expected),
+		}
+		if opts.Repair {
+			if err := c.store.Set(ctx, postCountKey(ns), expected); err != nil {
+				return issues, err
+			}
+			issue.Action = ActionRepaired
+		}
+		issues = append(issues, issue)
+	}
+
+	// The contents of corrupt posts are unknown, so tag counters and search
+	// statistics can only be checked once those posts are quarantined
+	if opts.Quarantine || len(records.corruptPosts) == 0 {
+		tagIssues, err := c.checkTagCounts(ctx, ns, records, opts)
+		issues = append(issues, tagIssues...)
+		if err != nil {
+			return issues, err
+		}
+
+		if issue, err := c.checkSearchStats(ctx, ns, records, opts); issue != nil || err != nil {
+			if issue != nil {
+				issues = append(issues, *issue)
+			}
+			if err != nil {
+				return issues, err
+			}
+		}
+	}
+
+	for _, entry := range records.revisions {
+		if _, ok := records.posts[entry.id]; ok || (records.corruptPosts[entry.id] && !opts.Quarantine) {
+			continue
+		}
+
+		issue := IntegrityIssue{Key: entry.key, Kind: IssueOrphan, Detail: fmt.Sprintf("post %s does not exist", entry.id)}
+		if err := c.resolveOrphan(ctx, entry, &issue, opts); err != nil {
+			return issues, err
+		}
+		issues = append(issues, issue)
+	}
+
+	for _, entry := range records.credentials {
+		if records.authors[entry.id] || (records.corruptAuthors[entry.id] && !opts.Quarantine) {
+			continue
+		}
+
+		issue := IntegrityIssue{Key: entry.key, Kind: IssueOrphan, Detail: fmt.Sprintf("author %s does not exist", entry.id)}
+		if err := c.resolveOrphan(ctx, entry, &issue, opts); err != nil {
+			return issues, err
+		}
+		issues = append(issues, issue)
+	}
+
+	return issues, nil
+}
+
+// checkTagCounts reports tag counters that differ from the number of posts
+// carrying their tag, correcting them if opts selects repairs
+func (c *IntegrityChecker) checkTagCounts(ctx context.Context, ns string, records *namespaceRecords, opts IntegrityOptions) ([]IntegrityIssue, error) {
+	var issues []IntegrityIssue
+
+	expected := make(map[string]int)
+	for _, post := range records.posts {
+		for _, tag := range countedTags(post.post) {
+			expected[tag]++
+		}
+	}
+
+	tags := make([]string, 0, len(expected)+len(records.tagCounts))
+	for tag := range expected {
+		tags = append(tags, tag)
+	}
+	for tag := range records.tagCounts {
+		if _, ok := expected[tag]; !ok {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+
+	for _, tag := range tags {
+		count, ok := records.tagCounts[tag]
+		if ok && count == expected[tag] {
+			continue
+		}
+
+		issue := IntegrityIssue{
+			Key:    tagCountKey(ns, tag),
+			Kind:   IssueCountMismatch,
+			Detail: fmt.Sprintf("counts %d posts tagged %q, found %d", count, tag, expected[tag]),
+		}
+		if opts.Repair {
+			var err error
+			if expected[tag] == 0 {
+				err = c.store.Delete(ctx, tagCountKey(ns, tag))
+			} else {
+				err = c.store.Set(ctx, tagCountKey(ns, tag), expected[tag])
+			}
+			if err != nil && err != domain.ErrKeyNotFound {
+				return issues, err
+			}
+			issue.Action = ActionRepaired
+		}
+		issues = append(issues, issue)
+	}
+
+	return issues, nil
+}
+
+// checkSearchStats reports search statistics that differ from the posts,
+// correcting them if opts selects repairs
+func (c *IntegrityChecker) checkSearchStats(ctx context.Context, ns string, records *namespaceRecords, opts IntegrityOptions) (*IntegrityIssue, error) {
+	var expected searchStats
+	for _, post := range records.posts {
+		expected.Posts++
+		expected.TitleLength += len(tokenizeSearchText(post.post.Title))
+		expected.ContentLength += len(tokenizeSearchText(post.post.Content))
+	}
+
+	var stored searchStats
+	if records.searchStats != nil {
+		stored = *records.searchStats
+	}
+	if stored == expected {
+		return nil, nil
+	}
+
+	issue := &IntegrityIssue{
+		Key:    searchStatsKey(ns),
+		Kind:   IssueCountMismatch,
+		Detail: fmt.Sprintf("counts %d posts for search, found %d", stored.Posts, expected.Posts),
+	}
+	if opts.Repair {
+		var err error
+		if expected.Posts == 0 {
+			err = c.store.Delete(ctx, searchStatsKey(ns))
+		} else {
+			err = c.store.Set(ctx, searchStatsKey(ns), expected)
+		}
+		if err != nil && err != domain.ErrKeyNotFound {
+			return issue, err
+		}
+		issue.Action = ActionRepaired
+	}
+	return issue, nil
+}
+
+// checkIndex reports entries of a post index that do not match a post and
+// posts missing from the index, deleting or rebuilding them as opts selects
+func (c *IntegrityChecker) checkIndex(ctx context.Context, ns string, records *namespaceRecords, index postIndex, opts IntegrityOptions) ([]IntegrityIssue, error) {
+	var issues []IntegrityIssue
+	indexed := make(map[string]bool)
+
+	// Expected entries are derived once per post
+	expected := make(map[string]map[string]any)
+	expectedEntries := func(id string, post indexedPost) map[string]any {
+		entries, ok := expected[id]
+		if !ok {
+			entries = index.expected(id, post)
+			expected[id] = entries
+		}
+		return entries
+	}
+
+	for _, entry := range index.entries {
+		post, ok := records.posts[entry.id]
+		var detail string
+		switch {
+		case ok:
+			if _, match := expectedEntries(entry.id, post)[entry.key]; match {
+				indexed[entry.key] = true
+				continue
+			}
+			detail = fmt.Sprintf("post %s does not match the entry", entry.id)
+		case records.corruptPosts[entry.id] && !opts.Quarantine:
+			// The post is reported as corrupt and stays where it is
+			continue
+		default:
+			detail = fmt.Sprintf("post %s does not exist", entry.id)
+		}
+
+		issue := IntegrityIssue{Key: entry.key, Kind: IssueOrphan, Detail: detail}
+		if err := c.resolveOrphan(ctx, entry, &issue, opts); err != nil {
+			return issues, err
+		}
+		issues = append(issues, issue)
+	}
+
+	// Missing entries are reported and rebuilt together per post
+	missing := make(map[string]map[string]any)
+	for id, post := range records.posts {
+		for key, value := range expectedEntries(id, post) {
+			if indexed[key] {
+				continue
+			}
+			if missing[id] == nil {
+				missing[id] = make(map[string]any)
+			}
+			missing[id][key] = value
+		}
+	}
+
+	ids := make([]string, 0, len(missing))
+	for id := range missing {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		entries := missing[id]
+		keys := make([]string, 0, len(entries))
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		detail := "no index entry " + keys[0]
+		if len(keys) > 1 {
+			detail = fmt.Sprintf("no index entries %s and %d more", keys[0], len(keys)-1)
+		}
+
+		issue := IntegrityIssue{Key: postKey(ns, id), Kind: IssueMissingIndex, Detail: detail}
+		if opts.Repair {
+			err := c.store.Update(ctx, func(tx domain.Tx) error {
+				if _, err := tx.Version(postKey(ns, id)); err != nil {
+					return err
+				}
+				for _, key := range keys {
+					if err := tx.Set(key, entries[key]); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil && err != domain.ErrKeyNotFound {
+				return issues, err
+			}
+			issue.Action = ActionRepaired
+		}
+		issues = append(issues, issue)
+	}
+
+	return issues, nil
+}
+
+// resolveOrphan quarantines or deletes an orphaned record as opts selects
+func (c *IntegrityChecker) resolveOrphan(ctx context.Context, entry storedRecord, issue *IntegrityIssue, opts IntegrityOptions) error {
+	switch {
+	case opts.Quarantine:
+		if err := c.quarantine(ctx, entry); err != nil {
+			return err
+		}
+		issue.Action = ActionQuarantined
+	case opts.Repair:
+		err := c.store.CompareAndDelete(ctx, entry.key, entry.version)
+		if err != nil && err != domain.ErrKeyNotFound && err != domain.ErrVersionMismatch {
+			return err
+		}
+		issue.Action = ActionRepaired
+	}
+	return nil
+}
+
+// quarantine moves a record to its quarantine key unless it has changed
+// since it was read. The stored bytes are copied as they are, so an encrypted
+// record stays bound to its original key.
+func (c *IntegrityChecker) quarantine(ctx context.Context, record storedRecord) error {
+	err := c.store.Update(ctx, func(tx domain.Tx) error {
+		version, err := tx.Version(record.key)
+		if err != nil {
+			return err
+		}
+		if version != record.version {
+			return domain.ErrVersionMismatch
+		}
+
+		if err := tx.Set(quarantinePrefix+record.key, domain.RawValue(record.value)); err != nil {
+			return err
+		}
+		return tx.Delete(record.key)
+	})
+	if err == domain.ErrKeyNotFound || err == domain.ErrVersionMismatch {
+		return nil
+	}
+	return err
+}
\ No newline at end of file
@@ -0,0 +1,281 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// checkIntegrity runs an integrity check and summarises each issue it
+// reports as "kind key action"
+func checkIntegrity(t *testing.T, store domain.Store, opts IntegrityOptions) []string {
+	t.Helper()
+	report, err := NewIntegrityChecker(store).Check(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Failed to check integrity: %v", err)
+	}
+	issues := make([]string, len(report.Issues))
+	for i, issue := range report.Issues {
+		issues[i] = strings.TrimSpace(fmt.Sprintf("%s %s %s", issue.Kind, issue.Key, issue.Action))
+	}
+	return issues
+}
+
+func TestIntegrityChecker_Check(t *testing.T) {
+	// Each case damages the records of tenant acme, while the intact post
+	// outside any tenant must not be reported
+	ns := domain.TenantKeyPrefix(asAuthor("acme", "author-1"))
+	orphanKey := postCreatedIndexKey(ns, time.Unix(1, 0), "post-0")
+
+	// A schedule entry left behind for a post that is not scheduled
+	staleAt := time.Unix(1, 0)
+	staleKey := func(post *domain.Post) string {
+		return scheduledPostKey(domain.WithTenant(context.Background(), "acme"), post.ID, &domain.Post{Status: domain.PostScheduled, PublishAt: &staleAt})
+	}
+	scheduleStale := func(store domain.Store, post *domain.Post) error {
+		return store.Set(context.Background(), staleKey(post), scheduledPost{Tenant: "acme", ID: post.ID, PublishAt: staleAt})
+	}
+
+	tests := []struct {
+		name     string
+		damage   func(store domain.Store, post *domain.Post) error
+		opts     IntegrityOptions
+		expected func(post *domain.Post) []string
+
+		// quarantined is the key expected to be moved to quarantine
+		quarantined func(post *domain.Post) string
+	}{
+		{
+			name:     "intact",
+			damage:   func(store domain.Store, post *domain.Post) error { return nil },
+			expected: func(post *domain.Post) []string { return []string{} },
+		},
+		{
+			name: "corrupt post",
+			damage: func(store domain.Store, post *domain.Post) error {
+				return store.Set(context.Background(), postKey(ns, post.ID), "not a post")
+			},
+			expected: func(post *domain.Post) []string {
+				return []string{"corrupt " + postKey(ns, post.ID)}
+			},
+		},
+		{
+			name: "corrupt post quarantined",
+			damage: func(store domain.Store, post *domain.Post) error {
+				return store.Set(context.Background(), postKey(ns, post.ID), "not a post")
+			},
+			opts: IntegrityOptions{Repair: true, Quarantine: true},
+			expected: func(post *domain.Post) []string {
+				return []string{
+					"corrupt " + postKey(ns, post.ID) + " quarantined",
+					"orphan " + postCreatedIndexKey(ns, post.CreatedAt, post.ID) + " quarantined",
+					"orphan " + postAuthorIndexKey(ns, post.AuthorID, post.CreatedAt, post.ID) + " quarantined",
+					"orphan " + postTagIndexKey(ns, "go", post.CreatedAt, post.ID) + " quarantined",
+					"orphan " + searchTermKey(ns, "content", post.ID) + " quarantined",
+					"orphan " + searchTermKey(ns, "title", post.ID) + " quarantined",
+					"count-mismatch " + postCountKey(ns) + " repaired",
+					"count-mismatch " + tagCountKey(ns, "go") + " repaired",
+					"count-mismatch " + searchStatsKey(ns) + " repaired",
+				}
+			},
+			quarantined: func(post *domain.Post) string { return postKey(ns, post.ID) },
+		},
+		{
+			name: "orphan index entry repaired",
+			damage: func(store domain.Store, post *domain.Post) error {
+				return store.Set(context.Background(), orphanKey, "post-0")
+			},
+			opts:     IntegrityOptions{Repair: true},
+			expected: func(post *domain.Post) []string { return []string{"orphan " + orphanKey + " repaired"} },
+		},
+		{
+			name: "orphan index entry quarantined",
+			damage: func(store domain.Store, post *domain.Post) error {
+				return store.Set(context.Background(), orphanKey, "post-0")
+			},
+			opts:        IntegrityOptions{Quarantine: true},
+			expected:    func(post *domain.Post) []string { return []string{"orphan " + orphanKey + " quarantined"} },
+			quarantined: func(post *domain.Post) string { return orphanKey },
+		},
+		{
+			name: "missing index entry",
+			damage: func(store domain.Store, post *domain.Post) error {
+				return store.Delete(context.Background(), postCreatedIndexKey(ns, post.CreatedAt, post.ID))
+			},
+			opts: IntegrityOptions{Repair: true},
+			expected: func(post *domain.Post) []string {
+				return []string{"missing-index " + postKey(ns, post.ID) + " repaired"}
+			},
+		},
+		{
+			name: "missing author index entry",
+			damage: func(store domain.Store, post *domain.Post) error {
+				return store.Delete(context.Background(), postAuthorIndexKey(ns, post.AuthorID, post.CreatedAt, post.ID))
+			},
+			opts: IntegrityOptions{Repair: true},
+			expected: func(post *domain.Post) []string {
+				return []string{"missing-index " + postKey(ns, post.ID) + " repaired"}
+			},
+		},
+		{
+			name: "missing tag index entry",
+			damage: func(store domain.Store, post *domain.Post) error {
+				return store.Delete(context.Background(), postTagIndexKey(ns, "go", post.CreatedAt, post.ID))
+			},
+			opts: IntegrityOptions{Repair: true},
+			expected: func(post *domain.Post) []string {
+				return []string{"missing-index " + postKey(ns, post.ID) + " repaired"}
+			},
+		},
+		{
+			name: "tag count mismatch",
+			damage: func(store domain.Store, post *domain.Post) error {
+				return store.Set(context.Background(), tagCountKey(ns, "go"), 5)
+			},
+			opts: IntegrityOptions{Repair: true},
+			expected: func(post *domain.Post) []string {
+				return []string{"count-mismatch " + tagCountKey(ns, "go") + " repaired"}
+			},
+		},
+		{
+			name: "count of an unused tag",
+			damage: func(store domain.Store, post *domain.Post) error {
+				return store.Set(context.Background(), tagCountKey(ns, "rust"), 1)
+			},
+			opts: IntegrityOptions{Repair: true},
+			expected: func(post *domain.Post) []string {
+				return []string{"count-mismatch " + tagCountKey(ns, "rust") + " repaired"}
+			},
+		},
+		{
+			name: "missing search index entry",
+			damage: func(store domain.Store, post *domain.Post) error {
+				return store.Delete(context.Background(), searchTermKey(ns, "title", post.ID))
+			},
+			opts: IntegrityOptions{Repair: true},
+			expected: func(post *domain.Post) []string {
+				return []string{"missing-index " + postKey(ns, post.ID) + " repaired"}
+			},
+		},
+		{
+			name: "search stats mismatch",
+			damage: func(store domain.Store, post *domain.Post) error {
+				return store.Set(context.Background(), searchStatsKey(ns), searchStats{Posts: 5})
+			},
+			opts: IntegrityOptions{Repair: true},
+			expected: func(post *domain.Post) []string {
+				return []string{"count-mismatch " + searchStatsKey(ns) + " repaired"}
+			},
+		},
+		{
+			name:     "stale schedule entry repaired",
+			damage:   scheduleStale,
+			opts:     IntegrityOptions{Repair: true},
+			expected: func(post *domain.Post) []string { return []string{"orphan " + staleKey(post) + " repaired"} },
+		},
+		{
+			name:        "stale schedule entry quarantined",
+			damage:      scheduleStale,
+			opts:        IntegrityOptions{Quarantine: true},
+			expected:    func(post *domain.Post) []string { return []string{"orphan " + staleKey(post) + " quarantined"} },
+			quarantined: staleKey,
+		},
+		{
+			name: "post count mismatch",
+			damage: func(store domain.Store, post *domain.Post) error {
+				return store.Set(context.Background(), postCountKey(ns), 5)
+			},
+			opts:     IntegrityOptions{Repair: true},
+			expected: func(post *domain.Post) []string { return []string{"count-mismatch " + postCountKey(ns) + " repaired"} },
+		},
+		{
+			name: "revision of a missing post",
+			damage: func(store domain.Store, post *domain.Post) error {
+				revision, err := schemas.Wrap(postRevisionSchema, &domain.PostRevision{PostID: "post-0", Revision: 1})
+				if err != nil {
+					return err
+				}
+				return store.Set(context.Background(), postRevisionKey(ns, "post-0", 1), revision)
+			},
+			opts: IntegrityOptions{Repair: true},
+			expected: func(post *domain.Post) []string {
+				return []string{"orphan " + postRevisionKey(ns, "post-0", 1) + " repaired"}
+			},
+		},
+		{
+			name: "corrupt revision",
+			damage: func(store domain.Store, post *domain.Post) error {
+				return store.Set(context.Background(), postRevisionKey(ns, post.ID, 1), "not a revision")
+			},
+			expected: func(post *domain.Post) []string {
+				return []string{"corrupt " + postRevisionKey(ns, post.ID, 1)}
+			},
+		},
+		{
+			name: "credentials of a missing author",
+			damage: func(store domain.Store, post *domain.Post) error {
+				return store.Set(context.Background(), authorCredentialsKey(ns, "author-0"), "hash")
+			},
+			opts: IntegrityOptions{Repair: true},
+			expected: func(post *domain.Post) []string {
+				return []string{"orphan " + authorCredentialsKey(ns, "author-0") + " repaired"}
+			},
+		},
+		{
+			name: "unknown key",
+			damage: func(store domain.Store, post *domain.Post) error {
+				return store.Set(context.Background(), ns+"mystery", "value")
+			},
+			opts:     IntegrityOptions{Repair: true, Quarantine: true},
+			expected: func(post *domain.Post) []string { return []string{"unknown " + ns + "mystery"} },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, store := newTestPostService(t, PostQuotas{}, 0)
+			createPosts(t, s, asAuthor("", "author-1"), 1)
+			posts := make([]*domain.Post, 2)
+			for i := range posts {
+				posts[i] = createPost(t, s, asAuthor("acme", "author-1"), &domain.CreatePostRequest{Title: "Title", Content: "Content", Tags: []string{"go"}})
+			}
+			if _, err := NewAuthorService(store, true).CreateAuthor(asAuthor("acme", ""), &domain.CreateAuthorRequest{Name: "Ada"}); err != nil {
+				t.Fatalf("Failed to create author: %v", err)
+			}
+
+			if err := tt.damage(store, posts[0]); err != nil {
+				t.Fatalf("Failed to damage the store: %v", err)
+			}
+			expected := tt.expected(posts[0])
+			if issues := checkIntegrity(t, store, tt.opts); !reflect.DeepEqual(issues, expected) {
+				t.Fatalf("Expected issues %q, got %q", expected, issues)
+			}
+
+			// Issues that were acted on are gone from the next check, and
+			// the others are reported again
+			remaining := []string{}
+			for _, issue := range expected {
+				if !strings.HasSuffix(issue, " "+ActionRepaired) && !strings.HasSuffix(issue, " "+ActionQuarantined) {
+					remaining = append(remaining, issue)
+				}
+			}
+			if issues := checkIntegrity(t, store, IntegrityOptions{}); !reflect.DeepEqual(issues, remaining) {
+				t.Errorf("Expected issues %q to remain, got %q", remaining, issues)
+			}
+
+			if tt.quarantined != nil {
+				key := tt.quarantined(posts[0])
+				if _, err := store.Get(context.Background(), quarantinePrefix+key); err != nil {
+					t.Errorf("Expected %s to be quarantined, got %v", key, err)
+				}
+				if _, err := store.Get(context.Background(), key); err != domain.ErrKeyNotFound {
+					t.Errorf("Expected %s to be moved, got %v", key, err)
+				}
+			}
+		})
+	}
+}
\ No newline at end of file
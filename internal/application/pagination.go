@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"time"
 
 	"gosuda.org/boilerplate/internal/domain"
 )
@@ -29,10 +30,18 @@ const (
 	MaxLimit     = 100
 )
 
-// Cursor represents a pagination cursor
+// Cursor represents a keyset pagination cursor pointing at the last item of
+// the previous page. Author, Tag and Query record the filter or search query
+// the page was listed with, so the cursor is only accepted for the same one.
+// Search results are ordered by Score rather than CreatedAt.
 type Cursor struct {
-	ID    string `json:"id"`
-	Limit int    `json:"limit"`
+	ID        string  `json:"id"`
+	CreatedAt int64   `json:"createdAt,omitempty"`
+	Score     float64 `json:"score,omitempty"`
+	Limit     int     `json:"limit"`
+	Author    string  `json:"author,omitempty"`
+	Tag       string  `json:"tag,omitempty"`
+	Query     string  `json:"query,omitempty"`
 }
 
 // NewPaginationParams creates new pagination parameters with defaults
@@ -124,14 +133,15 @@ func ValidatePaginationParams(cursor string, limit int) error {
 }
 
 // CreateNextCursor creates a next cursor for pagination
-func CreateNextCursor(lastID string, limit int) (string, error) {
+func CreateNextCursor(lastID string, lastCreatedAt time.Time, limit int) (string, error) {
 	if lastID == "" {
 		return "", nil
 	}
 
 	cursor := &Cursor{
-		ID:    lastID,
-		Limit: limit,
+		ID:        lastID,
+		CreatedAt: lastCreatedAt.UnixNano(),
+		Limit:     limit,
 	}
 
 	return EncodeCursor(cursor)
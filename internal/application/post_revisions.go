@@ -0,0 +1,219 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// postRevisionsPrefix is the key prefix of the stored revisions of posts
+const postRevisionsPrefix = "post-revisions:"
+
+// ListRevisions returns the revisions of a post on behalf of its author or an
+// admin, newest first, starting with the post as it is now
+func (s *PostService) ListRevisions(ctx context.Context, id string) (*domain.PostRevisionList, error) {
+	post, err := s.revisedPost(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	entities, err := s.revisions.List(ctx, domain.ScanOptions{Prefix: id + ":", Reverse: true})
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]domain.PostRevision, 0, len(entities)+1)
+	revisions = append(revisions, post.Snapshot())
+	for _, entity := range entities {
+		revisions = append(revisions, *entity.Value)
+	}
+
+	return &domain.PostRevisionList{Revisions: revisions}, nil
+}
+
+// GetRevision returns a revision of a post on behalf of its author or an
+// admin. The post's current revision is returned as it is now.
+func (s *PostService) GetRevision(ctx context.Context, id string, revision int) (*domain.PostRevision, error) {
+	post, err := s.revisedPost(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.revision(ctx, post, revision)
+}
+
+// DiffRevisions returns the line-level changes to a post's title and content
+// from one of its revisions to another on behalf of its author or an admin.
+// A zero to compares against the current revision.
+func (s *PostService) DiffRevisions(ctx context.Context, id string, from, to int) (*domain.PostDiff, error) {
+	post, err := s.revisedPost(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if to == 0 {
+		to = post.Revision
+	}
+
+	fromRevision, err := s.revision(ctx, post, from)
+	if err != nil {
+		return nil, err
+	}
+	toRevision, err := s.revision(ctx, post, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.PostDiff{
+		PostID:  id,
+		From:    from,
+		To:      to,
+		Title:   domain.DiffLines(fromRevision.Title, toRevision.Title),
+		Content: domain.DiffLines(fromRevision.Content, toRevision.Content),
+	}, nil
+}
+
+// RestoreRevision updates a post to the title, content and tags of one of its
+// revisions on behalf of its author or an admin. The post keeps its status,
+// and the restore is recorded as a new revision like any other update. If
+// expectedVersion is non-zero the post is only restored while it is still at
+// that version.
+func (s *PostService) RestoreRevision(ctx context.Context, id string, revision int, expectedVersion int64) (*domain.Post, error) {
+	restored, err := s.GetRevision(ctx, id, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	// An empty list, unlike nil, removes tags the post gained since
+	tags := restored.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return s.UpdatePost(ctx, id, &domain.UpdatePostRequest{
+		Title:   restored.Title,
+		Content: restored.Content,
+		Tags:    tags,
+	}, expectedVersion)
+}
+
+// revisedPost returns a post whose revisions the caller may see: its author
+// and admins may
+func (s *PostService) revisedPost(ctx context.Context, id string) (*domain.Post, error) {
+	post, err := s.GetPost(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, post.AuthorID, "see the revisions of this post"); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// revision returns a revision of post, which is the post itself at its
+// current revision
+func (s *PostService) revision(ctx context.Context, post *domain.Post, revision int) (*domain.PostRevision, error) {
+	if revision < 1 {
+		return nil, &domain.ValidationError{
+			Field:   "revision",
+			Message: "revision must be a positive number",
+		}
+	}
+	if revision == post.Revision {
+		current := post.Snapshot()
+		return &current, nil
+	}
+	if revision > post.Revision {
+		return nil, &domain.RevisionNotFoundError{PostID: post.ID, Revision: revision}
+	}
+
+	stored, _, err := s.revisions.Get(ctx, postRevisionID(post.ID, revision))
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// recordRevision stores the revision of a post an update replaced. Updates
+// that do not advance the post's revision, such as publishing it on
+// schedule, leave no revision.
+func (s *PostService) recordRevision(tx domain.Tx, ns, id string, old, updated *domain.Post) error {
+	if updated.Revision == old.Revision {
+		return nil
+	}
+
+	revision := old.Snapshot()
+	if err := s.revisions.put(tx, postRevisionKey(ns, id, old.Revision), &revision); err != nil {
+		return &domain.StorageError{Err: err}
+	}
+	return nil
+}
+
+// pruneRevisions deletes the stored revisions of a post numbered below
+// before. It runs after the write that made them obsolete, which succeeded
+// whether or not pruning does, so failures are only logged: revisions it
+// misses are kept longer than necessary until the next update of the post
+// prunes them again, or check --repair removes those of deleted posts.
+func (s *PostService) pruneRevisions(ctx context.Context, id string, before int) {
+	if err := s.deleteRevisions(ctx, id, before); err != nil {
+		s.logger.Warn("Failed to prune post revisions", "post", id, "tenant", domain.TenantID(ctx), "error", err)
+	}
+}
+
+// deleteRevisions deletes the stored revisions of a post numbered below before
+func (s *PostService) deleteRevisions(ctx context.Context, id string, before int) error {
+	prefix := domain.TenantKeyPrefix(ctx) + postRevisionsPrefix + id + ":"
+
+	it, err := s.store.Iterate(ctx, domain.ScanOptions{Prefix: prefix})
+	if err != nil {
+		return err
+	}
+
+	var obsolete []string
+	for it.Next() {
+		revision, err := strconv.Atoi(strings.TrimPrefix(it.Key(), prefix))
+		if err == nil && revision >= before {
+			break
+		}
+		obsolete = append(obsolete, it.Key())
+	}
+	err = it.Err()
+	it.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range obsolete {
+		if err := s.store.Delete(ctx, key); err != nil && err != domain.ErrKeyNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// postRevisionID generates the ID a revision is stored under, which sorts
+// the revisions of a post in order
+func postRevisionID(postID string, revision int) string {
+	return fmt.Sprintf("%s:%010d", postID, revision)
+}
+
+// splitPostRevisionID splits the ID of a stored revision into its post ID
+// and revision number
+func splitPostRevisionID(id string) (string, int, bool) {
+	sep := strings.LastIndexByte(id, ':')
+	if sep < 0 {
+		return "", 0, false
+	}
+	revision, err := strconv.Atoi(id[sep+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return id[:sep], revision, true
+}
+
+// postRevisionKey generates the storage key of a revision of a post in the
+// tenant namespace ns
+func postRevisionKey(ns, postID string, revision int) string {
+	return ns + postRevisionsPrefix + postRevisionID(postID, revision)
+}
\ No newline at end of file
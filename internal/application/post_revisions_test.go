@@ -0,0 +1,242 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gosuda.org/boilerplate/internal/domain"
+	"gosuda.org/boilerplate/internal/infrastructure"
+)
+
+// revisionNumbers returns the numbers of the revisions of a post, newest first
+func revisionNumbers(t *testing.T, s *PostService, ctx context.Context, id string) []int {
+	t.Helper()
+	list, err := s.ListRevisions(ctx, id)
+	if err != nil {
+		t.Fatalf("Failed to list revisions: %v", err)
+	}
+	numbers := make([]int, len(list.Revisions))
+	for i, revision := range list.Revisions {
+		numbers[i] = revision.Revision
+	}
+	return numbers
+}
+
+// storedRevisions returns the keys of the stored revisions of a post
+func storedRevisions(t *testing.T, store domain.Store, id string) []string {
+	t.Helper()
+	entries, err := domain.ListTyped[domain.RawValue](context.Background(), store, domain.ScanOptions{Prefix: postRevisionsPrefix + id + ":"})
+	if err != nil {
+		t.Fatalf("Failed to list stored revisions: %v", err)
+	}
+	keys := make([]string, len(entries))
+	for i, entry := range entries {
+		keys[i] = entry.Key
+	}
+	return keys
+}
+
+func TestPostService_Revisions(t *testing.T) {
+	s, _ := newTestPostService(t, PostQuotas{}, 0)
+	ctx := asAuthor("", "author-1")
+
+	post := createPost(t, s, ctx, &domain.CreatePostRequest{Title: "First", Content: "one\ntwo", Tags: []string{"a"}})
+	updatePost(t, s, ctx, post.ID, &domain.UpdatePostRequest{Title: "Second", Content: "one\n2\nthree", Tags: []string{"b"}})
+	updatePost(t, s, ctx, post.ID, &domain.UpdatePostRequest{Title: "Third", Content: "one\n2\nthree", Status: domain.PostDraft})
+
+	if numbers := revisionNumbers(t, s, ctx, post.ID); !reflect.DeepEqual(numbers, []int{3, 2, 1}) {
+		t.Errorf("Expected revisions 3, 2 and 1, got %v", numbers)
+	}
+
+	first, err := s.GetRevision(ctx, post.ID, 1)
+	if err != nil {
+		t.Fatalf("Failed to get revision: %v", err)
+	}
+	if first.Title != "First" || first.Content != "one\ntwo" || !reflect.DeepEqual(first.Tags, []string{"a"}) || first.Status != domain.PostPublished {
+		t.Errorf("Expected the post as created, got %+v", first)
+	}
+
+	var notFound *domain.RevisionNotFoundError
+	if _, err := s.GetRevision(ctx, post.ID, 4); !errors.As(err, &notFound) || notFound.Revision != 4 {
+		t.Errorf("Expected RevisionNotFoundError, got %v", err)
+	}
+	var validation *domain.ValidationError
+	if _, err := s.GetRevision(ctx, post.ID, 0); !errors.As(err, &validation) {
+		t.Errorf("Expected a validation error for revision 0, got %v", err)
+	}
+
+	// Only the author and admins see revisions, even of published posts
+	updatePost(t, s, ctx, post.ID, &domain.UpdatePostRequest{Title: "Third", Content: "one\n2\nthree", Status: domain.PostPublished})
+	var unauthorized *domain.UnauthorizedError
+	if _, err := s.ListRevisions(context.Background(), post.ID); !errors.As(err, &unauthorized) {
+		t.Errorf("Expected anonymous callers to be unauthorized, got %v", err)
+	}
+	var forbidden *domain.ForbiddenError
+	if _, err := s.GetRevision(asAuthor("", "author-2"), post.ID, 1); !errors.As(err, &forbidden) {
+		t.Errorf("Expected other authors to be forbidden, got %v", err)
+	}
+	if _, err := s.ListRevisions(asAdmin(), post.ID); err != nil {
+		t.Errorf("Expected admins to see revisions, got %v", err)
+	}
+}
+
+func TestPostService_DiffRevisions(t *testing.T) {
+	s, _ := newTestPostService(t, PostQuotas{}, 0)
+	ctx := asAuthor("", "author-1")
+
+	post := createPost(t, s, ctx, &domain.CreatePostRequest{Title: "Title", Content: "one\ntwo\nthree"})
+	updatePost(t, s, ctx, post.ID, &domain.UpdatePostRequest{Title: "Title", Content: "one\n2\nthree\nfour"})
+	updatePost(t, s, ctx, post.ID, &domain.UpdatePostRequest{Title: "New title", Content: "one\n2\nthree\nfour"})
+
+	diff, err := s.DiffRevisions(ctx, post.ID, 1, 2)
+	if err != nil {
+		t.Fatalf("Failed to diff revisions: %v", err)
+	}
+	expected := []domain.DiffLine{
+		{Op: domain.DiffEqual, Text: "one"},
+		{Op: domain.DiffDelete, Text: "two"},
+		{Op: domain.DiffInsert, Text: "2"},
+		{Op: domain.DiffEqual, Text: "three"},
+		{Op: domain.DiffInsert, Text: "four"},
+	}
+	if diff.From != 1 || diff.To != 2 || !reflect.DeepEqual(diff.Content, expected) {
+		t.Errorf("Expected the content diff %v, got %+v", expected, diff)
+	}
+	if !reflect.DeepEqual(diff.Title, []domain.DiffLine{{Op: domain.DiffEqual, Text: "Title"}}) {
+		t.Errorf("Expected an unchanged title, got %v", diff.Title)
+	}
+
+	// Without a target, revisions are compared against the current one
+	diff, err = s.DiffRevisions(ctx, post.ID, 2, 0)
+	if err != nil {
+		t.Fatalf("Failed to diff revisions: %v", err)
+	}
+	expectedTitle := []domain.DiffLine{{Op: domain.DiffDelete, Text: "Title"}, {Op: domain.DiffInsert, Text: "New title"}}
+	if diff.To != 3 || !reflect.DeepEqual(diff.Title, expectedTitle) || len(diff.Content) != 4 {
+		t.Errorf("Expected the title change to revision 3, got %+v", diff)
+	}
+
+	var notFound *domain.RevisionNotFoundError
+	if _, err := s.DiffRevisions(ctx, post.ID, 1, 5); !errors.As(err, &notFound) {
+		t.Errorf("Expected RevisionNotFoundError, got %v", err)
+	}
+}
+
+func TestPostService_RestoreRevision(t *testing.T) {
+	s, _ := newTestPostService(t, PostQuotas{}, 0)
+	ctx := asAuthor("", "author-1")
+
+	post := createPost(t, s, ctx, &domain.CreatePostRequest{Title: "Original", Content: "Content", Tags: []string{"go"}})
+	updatePost(t, s, ctx, post.ID, &domain.UpdatePostRequest{Title: "Tagged", Content: "Changed", Tags: []string{"go", "db"}})
+	updated := updatePost(t, s, ctx, post.ID, &domain.UpdatePostRequest{Title: "Archived", Content: "Changed", Status: domain.PostArchived})
+
+	var precondition *domain.PreconditionFailedError
+	if _, err := s.RestoreRevision(ctx, post.ID, 1, updated.Version+1); !errors.As(err, &precondition) {
+		t.Errorf("Expected a stale version to be rejected, got %v", err)
+	}
+	var notFound *domain.PostNotFoundError
+	if _, err := s.RestoreRevision(asAuthor("", "author-2"), post.ID, 1, 0); !errors.As(err, &notFound) {
+		t.Errorf("Expected an archived post to be hidden from other authors, got %v", err)
+	}
+
+	restored, err := s.RestoreRevision(ctx, post.ID, 1, updated.Version)
+	if err != nil {
+		t.Fatalf("Failed to restore revision: %v", err)
+	}
+
+	// The restore is a new revision that keeps the post's status, and
+	// removes the tags it gained since
+	if restored.Title != "Original" || restored.Content != "Content" || !reflect.DeepEqual(restored.Tags, []string{"go"}) ||
+		restored.Status != domain.PostArchived || restored.Revision != 4 {
+		t.Errorf("Expected revision 1 restored as revision 4, got %+v", restored)
+	}
+	if numbers := revisionNumbers(t, s, ctx, post.ID); !reflect.DeepEqual(numbers, []int{4, 3, 2, 1}) {
+		t.Errorf("Expected the restore to be recorded as a revision, got %v", numbers)
+	}
+}
+
+func TestPostService_RevisionRetention(t *testing.T) {
+	s, store := newTestPostService(t, PostQuotas{}, 2)
+	ctx := asAuthor("", "author-1")
+
+	post := createPost(t, s, ctx, &domain.CreatePostRequest{Title: "Title", Content: "Revision 1"})
+	for i := 2; i <= 5; i++ {
+		updatePost(t, s, ctx, post.ID, &domain.UpdatePostRequest{Title: "Title", Content: "Revision " + string(rune('0'+i))})
+	}
+
+	if numbers := revisionNumbers(t, s, ctx, post.ID); !reflect.DeepEqual(numbers, []int{5, 4, 3}) {
+		t.Errorf("Expected the current revision and the 2 before it, got %v", numbers)
+	}
+	var notFound *domain.RevisionNotFoundError
+	if _, err := s.GetRevision(ctx, post.ID, 2); !errors.As(err, &notFound) {
+		t.Errorf("Expected a pruned revision not to be found, got %v", err)
+	}
+	if revision, err := s.GetRevision(ctx, post.ID, 3); err != nil || revision.Content != "Revision 3" {
+		t.Errorf("Expected revision 3 to be kept, got %+v, %v", revision, err)
+	}
+
+	// Deleting a post deletes its revisions
+	if err := s.DeletePost(ctx, post.ID, 0); err != nil {
+		t.Fatalf("Failed to delete post: %v", err)
+	}
+	if keys := storedRevisions(t, store, post.ID); len(keys) != 0 {
+		t.Errorf("Expected the revisions of a deleted post to be deleted, got %v", keys)
+	}
+}
+
+// failingDeleteStore fails to delete keys starting with prefix
+type failingDeleteStore struct {
+	domain.Store
+	prefix string
+}
+
+func (s *failingDeleteStore) Delete(ctx context.Context, key string) error {
+	if strings.HasPrefix(key, s.prefix) {
+		return errors.New("disk full")
+	}
+	return s.Store.Delete(ctx, key)
+}
+
+// recordingLogger records the warnings logged through it
+type recordingLogger struct {
+	infrastructure.LoggerInterface
+	warnings []string
+}
+
+func (l *recordingLogger) Warn(msg string, args ...any) {
+	l.warnings = append(l.warnings, msg)
+}
+
+func TestPostService_PruneFailuresAreLogged(t *testing.T) {
+	memory := infrastructure.NewMemoryStore()
+	t.Cleanup(func() { memory.Close() })
+	store := &failingDeleteStore{Store: memory, prefix: postRevisionsPrefix}
+	logger := &recordingLogger{LoggerInterface: newTestLogger(t)}
+	s := NewPostService(logger, store, PostQuotas{}, 1)
+	ctx := asAuthor("", "author-1")
+
+	post := createPost(t, s, ctx, &domain.CreatePostRequest{Title: "Title", Content: "Content"})
+	updatePost(t, s, ctx, post.ID, &domain.UpdatePostRequest{Title: "Title", Content: "Second"})
+	if len(logger.warnings) != 0 {
+		t.Fatalf("Expected nothing to prune yet, got %v", logger.warnings)
+	}
+
+	// Writes succeed even though their obsolete revisions cannot be deleted
+	updatePost(t, s, ctx, post.ID, &domain.UpdatePostRequest{Title: "Title", Content: "Third"})
+	if len(logger.warnings) != 1 {
+		t.Errorf("Expected the failure to prune to be logged, got %v", logger.warnings)
+	}
+	if keys := storedRevisions(t, memory, post.ID); len(keys) != 2 {
+		t.Errorf("Expected the revisions to be kept, got %v", keys)
+	}
+
+	if err := s.DeletePost(ctx, post.ID, 0); err != nil {
+		t.Fatalf("Expected the post to be deleted, got %v", err)
+	}
+	if len(logger.warnings) != 2 {
+		t.Errorf("Expected the failure to prune to be logged, got %v", logger.warnings)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,185 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gosuda.org/boilerplate/internal/domain"
+	"gosuda.org/boilerplate/internal/infrastructure"
+)
+
+// scheduledPostsPrefix is the key prefix of the schedule of posts to
+// publish. Unlike other indexes the schedule lies outside tenant namespaces,
+// so the posts of every tenant can be found in order of their publish time.
+const scheduledPostsPrefix = "scheduled-posts:"
+
+// scheduledPost is an entry of the schedule
+type scheduledPost struct {
+	Tenant    string    `json:"tenant,omitempty"`
+	ID        string    `json:"id"`
+	PublishAt time.Time `json:"publishAt"`
+}
+
+// errNotDue aborts publishing a post that is no longer due
+var errNotDue = errors.New("post is not due")
+
+// PostScheduler publishes scheduled posts once their publish time has come,
+// checking every interval. It writes to the store, so it must not run on
+// followers.
+type PostScheduler struct {
+	posts    *PostService
+	logger   infrastructure.LoggerInterface
+	interval time.Duration
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewPostScheduler creates a scheduler and starts it in the background. Call
+// Close to stop it.
+func NewPostScheduler(posts *PostService, logger infrastructure.LoggerInterface, interval time.Duration) *PostScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &PostScheduler{
+		posts:    posts,
+		logger:   logger,
+		interval: interval,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	go s.run(ctx)
+	return s
+}
+
+// Close stops the scheduler
+func (s *PostScheduler) Close() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// run publishes due posts every interval until ctx is done
+func (s *PostScheduler) run(ctx context.Context) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		published, err := s.posts.PublishDue(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			s.logger.Error("Failed to publish scheduled posts", "error", err)
+		}
+		if published > 0 {
+			s.logger.Info("Published scheduled posts", "count", published)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishDue publishes the scheduled posts of every tenant whose publish time
+// is not after now and returns how many it published
+func (s *PostService) PublishDue(ctx context.Context, now time.Time) (int, error) {
+	due, err := s.duePosts(ctx, now)
+	if err != nil {
+		return 0, &domain.StorageError{Err: err}
+	}
+
+	published := 0
+	for _, entry := range due {
+		tenantCtx := domain.WithTenant(ctx, entry.Value.Tenant)
+		_, _, err := s.posts.Update(tenantCtx, entry.Value.ID, 0, func(post *domain.Post) error {
+			if post.Status != domain.PostScheduled || post.PublishAt.After(now) {
+				return errNotDue
+			}
+			return post.Transition(domain.PostPublished, nil, now)
+		})
+
+		var notFound *domain.PostNotFoundError
+		if errors.As(err, &notFound) || errors.Is(err, errNotDue) {
+			// The entry was left behind by a post that was deleted or
+			// rescheduled. Entries still in use have moved on, so this
+			// fails harmlessly for them.
+			err = s.store.CompareAndDelete(ctx, entry.Key, entry.Version)
+			if err != nil && err != domain.ErrKeyNotFound && err != domain.ErrVersionMismatch {
+				return published, &domain.StorageError{Err: err}
+			}
+			continue
+		}
+		if err != nil {
+			return published, err
+		}
+		published++
+	}
+
+	return published, nil
+}
+
+// duePosts returns the schedule entries whose publish time is not after now
+func (s *PostService) duePosts(ctx context.Context, now time.Time) ([]domain.KeyValue[scheduledPost], error) {
+	it, err := s.store.Iterate(ctx, domain.ScanOptions{Prefix: scheduledPostsPrefix})
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	var due []domain.KeyValue[scheduledPost]
+	for it.Next() {
+		var entry scheduledPost
+		if err := it.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("key %s: %w", it.Key(), err)
+		}
+		if entry.PublishAt.After(now) {
+			break
+		}
+		due = append(due, domain.KeyValue[scheduledPost]{Key: it.Key(), Value: entry, Version: it.Version()})
+	}
+
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+// schedulePost adds a scheduled post to the schedule
+func schedulePost(ctx context.Context, tx domain.Tx, id string, post *domain.Post) error {
+	key := scheduledPostKey(ctx, id, post)
+	if key == "" {
+		return nil
+	}
+
+	entry := scheduledPost{Tenant: domain.TenantID(ctx), ID: id, PublishAt: *post.PublishAt}
+	if err := tx.Set(key, entry); err != nil {
+		return &domain.StorageError{Err: err}
+	}
+	return nil
+}
+
+// unschedulePost removes a scheduled post from the schedule
+func unschedulePost(ctx context.Context, tx domain.Tx, id string, post *domain.Post) error {
+	key := scheduledPostKey(ctx, id, post)
+	if key == "" {
+		return nil
+	}
+
+	if err := tx.Delete(key); err != nil && err != domain.ErrKeyNotFound {
+		return &domain.StorageError{Err: err}
+	}
+	return nil
+}
+
+// scheduledPostKey generates the schedule key of a post of the tenant in
+// ctx, or returns "" if the post is not scheduled. The timestamp is
+// zero-padded so that key order matches time order.
+func scheduledPostKey(ctx context.Context, id string, post *domain.Post) string {
+	if post.Status != domain.PostScheduled || post.PublishAt == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s%020d:%s:%s", scheduledPostsPrefix, post.PublishAt.UnixNano(), domain.TenantID(ctx), id)
+}
\ No newline at end of file
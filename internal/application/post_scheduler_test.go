@@ -0,0 +1,121 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// scheduleKeys returns the keys of the schedule in order
+func scheduleKeys(t *testing.T, store domain.Store) []string {
+	t.Helper()
+	entries, err := domain.ListTyped[scheduledPost](context.Background(), store, domain.ScanOptions{Prefix: scheduledPostsPrefix})
+	if err != nil {
+		t.Fatalf("Failed to list schedule: %v", err)
+	}
+	keys := make([]string, len(entries))
+	for i, entry := range entries {
+		keys[i] = entry.Key
+	}
+	return keys
+}
+
+func TestPostService_PublishDue(t *testing.T) {
+	s, store := newTestPostService(t, PostQuotas{}, 0)
+	ctx := asAuthor("acme", "author-1")
+
+	// Publish times lie ahead of the clock, as scheduling requires, and
+	// PublishDue is run at fixed times after them
+	base := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+	schedule := func(title string, at time.Time) *domain.Post {
+		return createPost(t, s, ctx, &domain.CreatePostRequest{Title: title, Content: "Content", Status: domain.PostScheduled, PublishAt: &at})
+	}
+
+	due := schedule("Due", base)
+	later := schedule("Later", base.Add(2*time.Hour))
+	rescheduled := schedule("Rescheduled", base)
+	deleted := schedule("Deleted", base)
+
+	rescheduledAt := base.Add(3 * time.Hour)
+	updatePost(t, s, ctx, rescheduled.ID, &domain.UpdatePostRequest{Title: "Rescheduled", Content: "Content", Status: domain.PostScheduled, PublishAt: &rescheduledAt})
+	if err := s.DeletePost(ctx, deleted.ID, 0); err != nil {
+		t.Fatalf("Failed to delete post: %v", err)
+	}
+	if keys := scheduleKeys(t, store); len(keys) != 3 {
+		t.Fatalf("Expected rescheduling and deleting to update the schedule, got %v", keys)
+	}
+
+	// Entries left behind by posts deleted or unscheduled without updating
+	// the schedule are removed without publishing anything
+	published := createPost(t, s, ctx, &domain.CreatePostRequest{Title: "Published", Content: "Content"})
+	tenantCtx := domain.WithTenant(context.Background(), "acme")
+	stale := []*domain.Post{
+		{ID: "post-1", Status: domain.PostScheduled, PublishAt: &base},
+		{ID: published.ID, Status: domain.PostScheduled, PublishAt: &base},
+	}
+	for _, post := range stale {
+		key := scheduledPostKey(tenantCtx, post.ID, post)
+		if err := store.Set(context.Background(), key, scheduledPost{Tenant: "acme", ID: post.ID, PublishAt: base}); err != nil {
+			t.Fatalf("Failed to add stale entry: %v", err)
+		}
+	}
+
+	now := base.Add(time.Minute)
+	count, err := s.PublishDue(context.Background(), now)
+	if err != nil {
+		t.Fatalf("Failed to publish due posts: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 post to be published, got %d", count)
+	}
+
+	post, err := s.GetPost(ctx, due.ID)
+	if err != nil {
+		t.Fatalf("Failed to get post: %v", err)
+	}
+	if post.Status != domain.PostPublished || post.PublishAt != nil || post.PublishedAt == nil || !post.PublishedAt.Equal(now) {
+		t.Errorf("Expected the due post published at %v, got %+v", now, post)
+	}
+	for _, id := range []string{later.ID, rescheduled.ID} {
+		if post, err := s.GetPost(ctx, id); err != nil || post.Status != domain.PostScheduled {
+			t.Errorf("Expected post %s to stay scheduled, got %+v, %v", id, post, err)
+		}
+	}
+	if post, err := s.GetPost(ctx, published.ID); err != nil || post.Revision != 1 {
+		t.Errorf("Expected a stale entry to leave its post alone, got %+v, %v", post, err)
+	}
+
+	expected := []string{
+		scheduledPostKey(ctx, later.ID, later),
+		scheduledPostKey(tenantCtx, rescheduled.ID, &domain.Post{Status: domain.PostScheduled, PublishAt: &rescheduledAt}),
+	}
+	if keys := scheduleKeys(t, store); len(keys) != 2 || keys[0] != expected[0] || keys[1] != expected[1] {
+		t.Errorf("Expected only the entries of posts not yet due to remain, got %v", keys)
+	}
+
+	// Running again at the same time finds nothing to do
+	if count, err := s.PublishDue(context.Background(), now); err != nil || count != 0 {
+		t.Errorf("Expected nothing left to publish, got %d, %v", count, err)
+	}
+
+	// Posts are published as their times come
+	for _, tt := range []struct {
+		at time.Time
+		id string
+	}{
+		{base.Add(2 * time.Hour), later.ID},
+		{rescheduledAt, rescheduled.ID},
+	} {
+		if count, err := s.PublishDue(context.Background(), tt.at); err != nil || count != 1 {
+			t.Fatalf("Expected post %s to be published at %v, got %d, %v", tt.id, tt.at, count, err)
+		}
+		if post, err := s.GetPost(ctx, tt.id); err != nil || post.Status != domain.PostPublished || !post.PublishedAt.Equal(tt.at) {
+			t.Errorf("Expected post %s published at %v, got %+v, %v", tt.id, tt.at, post, err)
+		}
+	}
+	if keys := scheduleKeys(t, store); len(keys) != 0 {
+		t.Errorf("Expected the schedule to be empty, got %v", keys)
+	}
+}
\ No newline at end of file
@@ -3,66 +3,148 @@ package application
 import (
 	"context"
 	"fmt"
+	"math"
 	"sort"
 	"time"
 
 	"gosuda.org/boilerplate/internal/domain"
+	"gosuda.org/boilerplate/internal/infrastructure"
 )
 
-// PostService handles business logic for posts
+// PostService handles business logic for posts. Posts are stored under the
+// key namespace of the tenant in the request context, so tenants never see
+// each other's posts. Each post belongs to the author who created it, and only
+// that author or an admin may modify it or see it before it is published.
+// Each update keeps the post as it was as a revision, up to maxRevisions per
+// post unless that is zero.
 type PostService struct {
-	store domain.Store
+	logger       infrastructure.LoggerInterface
+	store        domain.Store
+	posts        *Repository[domain.Post]
+	revisions    *Repository[domain.PostRevision]
+	quotas       PostQuotas
+	maxRevisions int
+}
+
+// PostQuotas limits how many posts each tenant may have
+type PostQuotas struct {
+	// MaxPosts applies to tenants without an entry in Tenants; zero means no limit
+	MaxPosts int
+	Tenants  map[string]int
+}
+
+// PostFilter narrows down the posts listed by ListPosts. At most one of its
+// fields may be set.
+type PostFilter struct {
+	// AuthorID lists only the posts of this author
+	AuthorID string
+
+	// Tag lists only the posts carrying this tag
+	Tag string
+}
+
+// maxPosts returns the post limit of a tenant
+func (q PostQuotas) maxPosts(tenant string) int {
+	if limit, ok := q.Tenants[tenant]; ok {
+		return limit
+	}
+	return q.MaxPosts
 }
 
 // NewPostService creates a new post service
-func NewPostService(store domain.Store) *PostService {
-	return &PostService{
-		store: store,
+func NewPostService(logger infrastructure.LoggerInterface, store domain.Store, quotas PostQuotas, maxRevisions int) *PostService {
+	s := &PostService{
+		logger:       logger,
+		store:        store,
+		quotas:       quotas,
+		maxRevisions: maxRevisions,
 	}
+
+	s.posts = NewRepository(store, RepositoryOptions[domain.Post]{
+		Schema: postSchema,
+		Key: func(id string) string {
+			return postKey("", id)
+		},
+		NotFound: func(id string) error {
+			return &domain.PostNotFoundError{ID: id}
+		},
+		OnCreate: s.indexPost,
+		OnUpdate: s.postUpdated,
+		OnDelete: s.unindexPost,
+	})
+
+	s.revisions = NewRepository(store, RepositoryOptions[domain.PostRevision]{
+		Schema: postRevisionSchema,
+		Key: func(id string) string {
+			return postRevisionsPrefix + id
+		},
+		NotFound: func(id string) error {
+			postID, revision, _ := splitPostRevisionID(id)
+			return &domain.RevisionNotFoundError{PostID: postID, Revision: revision}
+		},
+	})
+
+	return s
 }
 
-// CreatePost creates a new post
+// CreatePost creates a new post by the author the caller authenticated as
 func (s *PostService) CreatePost(ctx context.Context, req *domain.CreatePostRequest) (*domain.Post, error) {
+	caller := domain.CallerFromContext(ctx)
+	if caller.AuthorID == "" {
+		return nil, &domain.UnauthorizedError{Message: "an author token is required to create a post"}
+	}
+
 	// Validate request
 	if err := req.Validate(); err != nil {
 		return nil, err
 	}
 
-	// Generate ID
-	id := generatePostID()
-
 	// Create post
-	post := domain.NewPost(id, req.Title, req.Content)
+	post := domain.NewPost("", caller.AuthorID, req.Title, req.Content, req.Tags)
+	if err := post.Transition(req.Status, req.PublishAt, post.CreatedAt); err != nil {
+		return nil, err
+	}
 
-	// Store post
-	key := postKey(id)
-	if err := s.store.Set(key, post); err != nil {
+	if err := s.ensurePostCounter(ctx, domain.TenantKeyPrefix(ctx)); err != nil {
 		return nil, &domain.StorageError{Err: err}
 	}
 
+	err := createUnique(generatePostID, func(id string) error {
+		post.ID = id
+		version, err := s.posts.Create(ctx, id, post)
+		post.Version = version
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return post, nil
 }
 
-// GetPost retrieves a post by ID
+// GetPost retrieves a post by ID. Posts that are not published are only
+// found by their author and admins.
 func (s *PostService) GetPost(ctx context.Context, id string) (*domain.Post, error) {
 	if err := validatePostID(id); err != nil {
 		return nil, err
 	}
 
-	key := postKey(id)
-	var post domain.Post
-	if err := s.store.GetTyped(key, &post); err != nil {
-		if err == domain.ErrKeyNotFound {
-			return nil, &domain.PostNotFoundError{ID: id}
-		}
-		return nil, &domain.StorageError{Err: err}
+	post, version, err := s.posts.Get(ctx, id)
+	if err != nil {
+		return nil, err
 	}
+	if !post.VisibleTo(domain.CallerFromContext(ctx)) {
+		return nil, &domain.PostNotFoundError{ID: id}
+	}
+	post.Version = version
 
-	return &post, nil
+	return post, nil
 }
 
-// UpdatePost updates an existing post
-func (s *PostService) UpdatePost(ctx context.Context, id string, req *domain.UpdatePostRequest) (*domain.Post, error) {
+// UpdatePost updates an existing post on behalf of its author or an admin,
+// keeping the post as it was as a revision. If expectedVersion is non-zero the
+// update only succeeds while the post is still at that version.
+func (s *PostService) UpdatePost(ctx context.Context, id string, req *domain.UpdatePostRequest, expectedVersion int64) (*domain.Post, error) {
 	if err := validatePostID(id); err != nil {
 		return nil, err
 	}
@@ -72,46 +154,50 @@ func (s *PostService) UpdatePost(ctx context.Context, id string, req *domain.Upd
 		return nil, err
 	}
 
-	// Get existing post
-	key := postKey(id)
-	var post domain.Post
-	if err := s.store.GetTyped(key, &post); err != nil {
-		if err == domain.ErrKeyNotFound {
-			return nil, &domain.PostNotFoundError{ID: id}
+	post, version, err := s.posts.Update(ctx, id, expectedVersion, func(post *domain.Post) error {
+		if !post.VisibleTo(domain.CallerFromContext(ctx)) {
+			return &domain.PostNotFoundError{ID: id}
 		}
-		return nil, &domain.StorageError{Err: err}
+		if err := authorize(ctx, post.AuthorID, "update this post"); err != nil {
+			return err
+		}
+		post.Update(req.Title, req.Content, req.Tags)
+		if req.Status != "" {
+			return post.Transition(req.Status, req.PublishAt, time.Now())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	post.Version = version
 
-	// Update post
-	post.Update(req.Title, req.Content)
-
-	// Store updated post
-	if err := s.store.Set(key, &post); err != nil {
-		return nil, &domain.StorageError{Err: err}
+	if s.maxRevisions > 0 {
+		s.pruneRevisions(ctx, id, post.Revision-s.maxRevisions)
 	}
 
-	return &post, nil
+	return post, nil
 }
 
-// DeletePost deletes a post by ID
-func (s *PostService) DeletePost(ctx context.Context, id string) error {
+// DeletePost deletes a post by ID on behalf of its author or an admin. If
+// expectedVersion is non-zero the post is only deleted while it is still at
+// that version.
+func (s *PostService) DeletePost(ctx context.Context, id string, expectedVersion int64) error {
 	if err := validatePostID(id); err != nil {
 		return err
 	}
 
-	key := postKey(id)
-	if err := s.store.Delete(key); err != nil {
-		if err == domain.ErrKeyNotFound {
-			return &domain.PostNotFoundError{ID: id}
-		}
-		return &domain.StorageError{Err: err}
+	if err := s.posts.Delete(ctx, id, expectedVersion); err != nil {
+		return err
 	}
 
+	s.pruneRevisions(ctx, id, math.MaxInt)
 	return nil
 }
 
-// ListPosts retrieves a paginated list of posts
-func (s *PostService) ListPosts(ctx context.Context, cursor string, limit int) (*domain.PostList, error) {
+// ListPosts retrieves a paginated list of the posts matching filter that the
+// caller may see, newest first
+func (s *PostService) ListPosts(ctx context.Context, filter PostFilter, cursor string, limit int) (*domain.PostList, error) {
 	// Parse and validate pagination parameters
 	params := NewPaginationParams(cursor, limit)
 	if err := ValidatePaginationParams(params.Cursor, params.Limit); err != nil {
@@ -121,66 +207,401 @@ func (s *PostService) ListPosts(ctx context.Context, cursor string, limit int) (
 		}
 	}
 
-	// Get all posts
-	values, err := s.store.List("posts:")
-	if err != nil {
-		return nil, &domain.StorageError{Err: err}
-	}
+	ns := domain.TenantKeyPrefix(ctx)
 
-	// Convert to posts
-	var posts []domain.Post
-	for _, value := range values {
-		if post, ok := value.(domain.Post); ok {
-			posts = append(posts, post)
+	// All indexes order posts by creation time
+	prefix := ns + postsCreatedIndexPrefix
+	indexKey := func(createdAt time.Time, id string) string {
+		return postCreatedIndexKey(ns, createdAt, id)
+	}
+	switch {
+	case filter.AuthorID != "" && filter.Tag != "":
+		return nil, &domain.ValidationError{
+			Field:   "tag",
+			Message: "posts cannot be filtered by author and tag at once",
+		}
+	case filter.AuthorID != "":
+		if err := validateAuthorID(filter.AuthorID); err != nil {
+			return nil, err
+		}
+		prefix = postAuthorIndexPrefix(ns, filter.AuthorID)
+		indexKey = func(createdAt time.Time, id string) string {
+			return postAuthorIndexKey(ns, filter.AuthorID, createdAt, id)
+		}
+	case filter.Tag != "":
+		tags, err := domain.NormalizeTags([]string{filter.Tag})
+		if err != nil {
+			return nil, err
+		}
+		filter.Tag = tags[0]
+		prefix = postTagIndexPrefix(ns, filter.Tag)
+		indexKey = func(createdAt time.Time, id string) string {
+			return postTagIndexKey(ns, filter.Tag, createdAt, id)
 		}
 	}
 
-	// Sort by creation date (newest first)
-	sort.Slice(posts, func(i, j int) bool {
-		return posts[i].CreatedAt.After(posts[j].CreatedAt)
-	})
-
-	// Apply pagination
-	startIndex := 0
+	// Resume after the last post of the previous page, which must have been
+	// listed with the same filter
+	var startAfter string
 	if params.Cursor != "" {
 		cursorObj, err := DecodeCursor(params.Cursor)
-		if err != nil {
+		if err != nil || cursorObj == nil || cursorObj.CreatedAt == 0 ||
+			cursorObj.Author != filter.AuthorID || cursorObj.Tag != filter.Tag {
 			return nil, &domain.PaginationError{Cursor: params.Cursor}
 		}
-		if cursorObj != nil {
-			// Find the post with the cursor ID
-			for i, post := range posts {
-				if post.ID == cursorObj.ID {
-					startIndex = i + 1
-					break
-				}
+		startAfter = indexKey(time.Unix(0, cursorObj.CreatedAt), cursorObj.ID)
+	}
+
+	// Walk the index newest first until a page of posts the caller may see
+	// is found, plus one to learn whether another page follows
+	caller := domain.CallerFromContext(ctx)
+	posts := make([]domain.Post, 0, params.Limit+1)
+	for len(posts) <= params.Limit {
+		ids, err := domain.ListTyped[string](ctx, s.store, domain.ScanOptions{
+			Prefix:     prefix,
+			StartAfter: startAfter,
+			Limit:      params.Limit + 1,
+			Reverse:    true,
+		})
+		if err != nil {
+			return nil, &domain.StorageError{Err: err}
+		}
+		if len(ids) == 0 {
+			break
+		}
+		startAfter = ids[len(ids)-1].Key
+
+		postIDs := make([]string, len(ids))
+		for i, indexEntry := range ids {
+			postIDs[i] = indexEntry.Value
+		}
+
+		// Posts deleted since the index was scanned are skipped
+		entities, err := s.posts.GetMany(ctx, postIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entity := range entities {
+			if !entity.Value.VisibleTo(caller) {
+				continue
 			}
+			entity.Value.Version = entity.Version
+			posts = append(posts, *entity.Value)
 		}
-	}
 
-	endIndex := startIndex + params.Limit
-	if endIndex > len(posts) {
-		endIndex = len(posts)
+		if len(ids) <= params.Limit {
+			break
+		}
 	}
 
-	// Get the page of posts
-	pagePosts := posts[startIndex:endIndex]
+	hasMore := len(posts) > params.Limit
+	if hasMore {
+		posts = posts[:params.Limit]
+	}
 
 	// Create next cursor
 	var nextCursor string
-	if endIndex < len(posts) {
-		nextCursor, err = CreateNextCursor(posts[endIndex-1].ID, params.Limit)
+	if hasMore && len(posts) > 0 {
+		last := posts[len(posts)-1]
+		var err error
+		nextCursor, err = EncodeCursor(&Cursor{
+			ID:        last.ID,
+			CreatedAt: last.CreatedAt.UnixNano(),
+			Limit:     params.Limit,
+			Author:    filter.AuthorID,
+			Tag:       filter.Tag,
+		})
 		if err != nil {
 			return nil, &domain.StorageError{Err: err}
 		}
 	}
 
 	return &domain.PostList{
-		Posts:     pagePosts,
+		Posts:      posts,
 		NextCursor: nextCursor,
 	}, nil
 }
 
+// ListTags returns the tags of published posts with the number of published
+// posts carrying each, most used first
+func (s *PostService) ListTags(ctx context.Context) (*domain.TagList, error) {
+	ns := domain.TenantKeyPrefix(ctx)
+	prefix := ns + tagCountsPrefix
+
+	counts, err := domain.ListTyped[int](ctx, s.store, domain.ScanOptions{Prefix: prefix})
+	if err != nil {
+		return nil, &domain.StorageError{Err: err}
+	}
+
+	tags := make([]domain.TagCount, 0, len(counts))
+	for _, count := range counts {
+		if count.Value <= 0 {
+			continue
+		}
+		tags = append(tags, domain.TagCount{
+			Tag:   count.Key[len(prefix):],
+			Posts: count.Value,
+		})
+	}
+
+	// Counts are listed in tag order, which breaks ties
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].Posts > tags[j].Posts
+	})
+
+	return &domain.TagList{Tags: tags}, nil
+}
+
+// indexPost adds a new post to the creation-time, author, tag and search
+// indexes and the tenant's post count, enforcing the tenant's quota
+func (s *PostService) indexPost(ctx context.Context, tx domain.Tx, ns, id string, post *domain.Post) error {
+	var count int
+	if err := tx.GetTyped(postCountKey(ns), &count); err != nil && err != domain.ErrKeyNotFound {
+		return &domain.StorageError{Err: err}
+	}
+
+	tenant := domain.TenantID(ctx)
+	if maxPosts := s.quotas.maxPosts(tenant); maxPosts > 0 && count >= maxPosts {
+		return &domain.QuotaExceededError{Tenant: tenant, MaxPosts: maxPosts}
+	}
+
+	if err := tx.Set(postCreatedIndexKey(ns, post.CreatedAt, id), id); err != nil {
+		return &domain.StorageError{Err: err}
+	}
+
+	if post.AuthorID != "" {
+		if err := tx.Set(postAuthorIndexKey(ns, post.AuthorID, post.CreatedAt, id), id); err != nil {
+			return &domain.StorageError{Err: err}
+		}
+	}
+
+	if err := tagPost(tx, ns, id, post, post.Tags); err != nil {
+		return err
+	}
+	if err := countTags(tx, ns, countedTags(post), 1); err != nil {
+		return err
+	}
+
+	if err := schedulePost(ctx, tx, id, post); err != nil {
+		return err
+	}
+
+	if err := indexSearch(tx, ns, id, post); err != nil {
+		return err
+	}
+
+	if err := tx.Set(postCountKey(ns), count+1); err != nil {
+		return &domain.StorageError{Err: err}
+	}
+
+	return nil
+}
+
+// postUpdated records the revision an update replaced and reindexes the post
+func (s *PostService) postUpdated(ctx context.Context, tx domain.Tx, ns, id string, old, updated *domain.Post) error {
+	if err := s.recordRevision(tx, ns, id, old, updated); err != nil {
+		return err
+	}
+	return s.reindexPost(ctx, tx, ns, id, old, updated)
+}
+
+// reindexPost moves an updated post between tag index entries and tag counts
+// as its tags or status change, reschedules it and reindexes its words for
+// search if its title or content changed
+func (s *PostService) reindexPost(ctx context.Context, tx domain.Tx, ns, id string, old, updated *domain.Post) error {
+	if err := untagPost(tx, ns, id, old, missingTags(old.Tags, updated.Tags)); err != nil {
+		return err
+	}
+	if err := tagPost(tx, ns, id, updated, missingTags(updated.Tags, old.Tags)); err != nil {
+		return err
+	}
+
+	oldCounted, updatedCounted := countedTags(old), countedTags(updated)
+	if err := countTags(tx, ns, missingTags(oldCounted, updatedCounted), -1); err != nil {
+		return err
+	}
+	if err := countTags(tx, ns, missingTags(updatedCounted, oldCounted), 1); err != nil {
+		return err
+	}
+
+	if scheduledPostKey(ctx, id, old) != scheduledPostKey(ctx, id, updated) {
+		if err := unschedulePost(ctx, tx, id, old); err != nil {
+			return err
+		}
+		if err := schedulePost(ctx, tx, id, updated); err != nil {
+			return err
+		}
+	}
+
+	if old.Title == updated.Title && old.Content == updated.Content {
+		return nil
+	}
+	if err := unindexSearch(tx, ns, id, old); err != nil {
+		return err
+	}
+	return indexSearch(tx, ns, id, updated)
+}
+
+// unindexPost checks that the caller may delete a post, then removes it from
+// the creation-time, author, tag and search indexes and the tenant's post count
+func (s *PostService) unindexPost(ctx context.Context, tx domain.Tx, ns, id string, post *domain.Post) error {
+	if !post.VisibleTo(domain.CallerFromContext(ctx)) {
+		return &domain.PostNotFoundError{ID: id}
+	}
+	if err := authorize(ctx, post.AuthorID, "delete this post"); err != nil {
+		return err
+	}
+
+	if err := tx.Delete(postCreatedIndexKey(ns, post.CreatedAt, id)); err != nil && err != domain.ErrKeyNotFound {
+		return &domain.StorageError{Err: err}
+	}
+
+	if post.AuthorID != "" {
+		err := tx.Delete(postAuthorIndexKey(ns, post.AuthorID, post.CreatedAt, id))
+		if err != nil && err != domain.ErrKeyNotFound {
+			return &domain.StorageError{Err: err}
+		}
+	}
+
+	if err := untagPost(tx, ns, id, post, post.Tags); err != nil {
+		return err
+	}
+	if err := countTags(tx, ns, countedTags(post), -1); err != nil {
+		return err
+	}
+
+	if err := unschedulePost(ctx, tx, id, post); err != nil {
+		return err
+	}
+
+	if err := unindexSearch(tx, ns, id, post); err != nil {
+		return err
+	}
+
+	var count int
+	if err := tx.GetTyped(postCountKey(ns), &count); err != nil {
+		if err == domain.ErrKeyNotFound {
+			return nil
+		}
+		return &domain.StorageError{Err: err}
+	}
+
+	if err := tx.Set(postCountKey(ns), max(count-1, 0)); err != nil {
+		return &domain.StorageError{Err: err}
+	}
+
+	return nil
+}
+
+// tagPost adds a post to the tag index under tags
+func tagPost(tx domain.Tx, ns, id string, post *domain.Post, tags []string) error {
+	for _, tag := range tags {
+		if err := tx.Set(postTagIndexKey(ns, tag, post.CreatedAt, id), id); err != nil {
+			return &domain.StorageError{Err: err}
+		}
+	}
+	return nil
+}
+
+// untagPost removes a post from the tag index under tags
+func untagPost(tx domain.Tx, ns, id string, post *domain.Post, tags []string) error {
+	for _, tag := range tags {
+		err := tx.Delete(postTagIndexKey(ns, tag, post.CreatedAt, id))
+		if err != nil && err != domain.ErrKeyNotFound {
+			return &domain.StorageError{Err: err}
+		}
+	}
+	return nil
+}
+
+// countedTags returns the tags a post counts towards: its tags once it is
+// published, none before
+func countedTags(post *domain.Post) []string {
+	if post.Status != domain.PostPublished {
+		return nil
+	}
+	return post.Tags
+}
+
+// countTags adds delta to the post counts of tags
+func countTags(tx domain.Tx, ns string, tags []string, delta int) error {
+	for _, tag := range tags {
+		if err := addTagCount(tx, ns, tag, delta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addTagCount adds delta to the number of posts carrying a tag, removing the
+// counter once no post carries the tag
+func addTagCount(tx domain.Tx, ns, tag string, delta int) error {
+	var count int
+	if err := tx.GetTyped(tagCountKey(ns, tag), &count); err != nil && err != domain.ErrKeyNotFound {
+		return &domain.StorageError{Err: err}
+	}
+
+	count += delta
+	if count <= 0 {
+		if err := tx.Delete(tagCountKey(ns, tag)); err != nil && err != domain.ErrKeyNotFound {
+			return &domain.StorageError{Err: err}
+		}
+		return nil
+	}
+
+	if err := tx.Set(tagCountKey(ns, tag), count); err != nil {
+		return &domain.StorageError{Err: err}
+	}
+	return nil
+}
+
+// missingTags returns the tags in tags that are not in other
+func missingTags(tags, other []string) []string {
+	var missing []string
+	for _, tag := range tags {
+		found := false
+		for _, o := range other {
+			if o == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, tag)
+		}
+	}
+	return missing
+}
+
+// ensurePostCounter creates the post counter of a tenant namespace if it does
+// not exist yet, e.g. for posts created before posts were counted, by counting
+// the creation-time index
+func (s *PostService) ensurePostCounter(ctx context.Context, ns string) error {
+	var count int
+	err := s.store.GetTyped(ctx, postCountKey(ns), &count)
+	if err != domain.ErrKeyNotFound {
+		return err
+	}
+
+	keys, err := s.store.Scan(ctx, domain.ScanOptions{Prefix: ns + postsCreatedIndexPrefix})
+	if err != nil {
+		return err
+	}
+
+	// Another request may have created the counter in the meantime
+	_, err = s.store.CompareAndSwap(ctx, postCountKey(ns), len(keys), 0)
+	if err != nil && err != domain.ErrVersionMismatch {
+		return err
+	}
+	return nil
+}
+
+// decodePost upgrades a post as read from the store to the current schema
+func decodePost(stored any) (*domain.Post, error) {
+	return decodeEntity[domain.Post](postSchema, stored)
+}
+
 // validatePostID validates a post ID
 func validatePostID(id string) error {
 	if id == "" {
@@ -212,9 +633,61 @@ func isValidPostID(id string) bool {
 	return true
 }
 
-// postKey generates a storage key for a post
-func postKey(id string) string {
-	return fmt.Sprintf("posts:%s", id)
+// postKey generates a storage key for a post in the tenant namespace ns
+func postKey(ns, id string) string {
+	return fmt.Sprintf("%sposts:%s", ns, id)
+}
+
+// postsCreatedIndexPrefix is the key prefix of the creation-time index
+const postsCreatedIndexPrefix = "posts-by-created:"
+
+// postCreatedIndexKey generates the index key ordering a post by creation time.
+// The timestamp is zero-padded so that key order matches time order.
+func postCreatedIndexKey(ns string, createdAt time.Time, id string) string {
+	return fmt.Sprintf("%s%s%020d:%s", ns, postsCreatedIndexPrefix, createdAt.UnixNano(), id)
+}
+
+// postsAuthorIndexPrefix is the key prefix of the author index
+const postsAuthorIndexPrefix = "posts-by-author:"
+
+// postAuthorIndexPrefix returns the key prefix of an author's entries in the
+// author index
+func postAuthorIndexPrefix(ns, authorID string) string {
+	return ns + postsAuthorIndexPrefix + authorID + ":"
+}
+
+// postAuthorIndexKey generates the index key ordering an author's posts by
+// creation time
+func postAuthorIndexKey(ns, authorID string, createdAt time.Time, id string) string {
+	return fmt.Sprintf("%s%020d:%s", postAuthorIndexPrefix(ns, authorID), createdAt.UnixNano(), id)
+}
+
+// postsTagIndexPrefix is the key prefix of the tag index
+const postsTagIndexPrefix = "posts-by-tag:"
+
+// postTagIndexPrefix returns the key prefix of a tag's entries in the tag index
+func postTagIndexPrefix(ns, tag string) string {
+	return ns + postsTagIndexPrefix + tag + ":"
+}
+
+// postTagIndexKey generates the index key ordering the posts carrying a tag by
+// creation time
+func postTagIndexKey(ns, tag string, createdAt time.Time, id string) string {
+	return fmt.Sprintf("%s%020d:%s", postTagIndexPrefix(ns, tag), createdAt.UnixNano(), id)
+}
+
+// tagCountsPrefix is the key prefix of the per-tag post counters
+const tagCountsPrefix = "tag-counts:"
+
+// tagCountKey generates the key counting the posts carrying a tag in the
+// tenant namespace ns
+func tagCountKey(ns, tag string) string {
+	return ns + tagCountsPrefix + tag
+}
+
+// postCountKey generates the key counting the posts in the tenant namespace ns
+func postCountKey(ns string) string {
+	return ns + "posts-count"
 }
 
 // generatePostID generates a unique post ID
@@ -0,0 +1,244 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"gosuda.org/boilerplate/internal/config"
+	"gosuda.org/boilerplate/internal/domain"
+	"gosuda.org/boilerplate/internal/infrastructure"
+)
+
+// newTestLogger creates a logger that only logs errors
+func newTestLogger(t *testing.T) infrastructure.LoggerInterface {
+	t.Helper()
+	logger, err := infrastructure.NewLogger(&config.LoggingConfig{Level: "error", Format: "text", Output: "stderr"})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	return logger
+}
+
+// newTestPostService creates a post service backed by a fresh memory store
+func newTestPostService(t *testing.T, quotas PostQuotas, maxRevisions int) (*PostService, domain.Store) {
+	t.Helper()
+	store := infrastructure.NewMemoryStore()
+	t.Cleanup(func() { store.Close() })
+	return NewPostService(newTestLogger(t), store, quotas, maxRevisions), store
+}
+
+// asAuthor returns a context for requests by an author of a tenant
+func asAuthor(tenant, authorID string) context.Context {
+	ctx := domain.WithCaller(context.Background(), domain.Caller{AuthorID: authorID})
+	if tenant != "" {
+		ctx = domain.WithTenant(ctx, tenant)
+	}
+	return ctx
+}
+
+// createPosts creates n posts, failing the test on the first error
+func createPosts(t *testing.T, s *PostService, ctx context.Context, n int) []*domain.Post {
+	t.Helper()
+	posts := make([]*domain.Post, n)
+	for i := range posts {
+		post, err := s.CreatePost(ctx, &domain.CreatePostRequest{Title: "Title", Content: "Content"})
+		if err != nil {
+			t.Fatalf("Failed to create post %d: %v", i+1, err)
+		}
+		posts[i] = post
+	}
+	return posts
+}
+
+func TestPostService_TenantQuotas(t *testing.T) {
+	s, _ := newTestPostService(t, PostQuotas{MaxPosts: 2, Tenants: map[string]int{"big": 3, "unlimited": 0}}, 0)
+
+	tests := []struct {
+		tenant string
+		limit  int
+	}{
+		{"acme", 2},
+		{"big", 3},
+	}
+	for _, tt := range tests {
+		ctx := asAuthor(tt.tenant, "author-1")
+		createPosts(t, s, ctx, tt.limit)
+
+		_, err := s.CreatePost(ctx, &domain.CreatePostRequest{Title: "Title", Content: "Content"})
+		var quotaErr *domain.QuotaExceededError
+		if !errors.As(err, &quotaErr) || quotaErr.Tenant != tt.tenant || quotaErr.MaxPosts != tt.limit {
+			t.Errorf("Expected tenant %s to be limited to %d posts, got %v", tt.tenant, tt.limit, err)
+		}
+	}
+
+	// A zero quota for a tenant lifts the default limit
+	createPosts(t, s, asAuthor("unlimited", "author-1"), 5)
+
+	// Deleting a post frees up room under the quota
+	ctx := asAuthor("acme", "author-1")
+	list, err := s.ListPosts(ctx, PostFilter{}, "", 10)
+	if err != nil {
+		t.Fatalf("Failed to list posts: %v", err)
+	}
+	if err := s.DeletePost(ctx, list.Posts[0].ID, 0); err != nil {
+		t.Fatalf("Failed to delete post: %v", err)
+	}
+	createPosts(t, s, ctx, 1)
+}
+
+// createPost creates a post, failing the test on error
+func createPost(t *testing.T, s *PostService, ctx context.Context, req *domain.CreatePostRequest) *domain.Post {
+	t.Helper()
+	post, err := s.CreatePost(ctx, req)
+	if err != nil {
+		t.Fatalf("Failed to create post %q: %v", req.Title, err)
+	}
+	return post
+}
+
+// updatePost updates a post, failing the test on error
+func updatePost(t *testing.T, s *PostService, ctx context.Context, id string, req *domain.UpdatePostRequest) *domain.Post {
+	t.Helper()
+	post, err := s.UpdatePost(ctx, id, req, 0)
+	if err != nil {
+		t.Fatalf("Failed to update post %s: %v", id, err)
+	}
+	return post
+}
+
+func TestPostService_TagCounts(t *testing.T) {
+	s, _ := newTestPostService(t, PostQuotas{}, 0)
+	ctx := asAuthor("", "author-1")
+
+	expectTags := func(step string, expected ...domain.TagCount) {
+		t.Helper()
+		list, err := s.ListTags(ctx)
+		if err != nil {
+			t.Fatalf("%s: failed to list tags: %v", step, err)
+		}
+		if len(list.Tags) != len(expected) || (len(expected) > 0 && !reflect.DeepEqual(list.Tags, expected)) {
+			t.Errorf("%s: expected tags %v, got %v", step, expected, list.Tags)
+		}
+	}
+
+	p1 := createPost(t, s, ctx, &domain.CreatePostRequest{Title: "One", Content: "Content", Tags: []string{"go", "db"}, Status: domain.PostPublished})
+	p2 := createPost(t, s, ctx, &domain.CreatePostRequest{Title: "Two", Content: "Content", Tags: []string{"go"}, Status: domain.PostPublished})
+	p3 := createPost(t, s, ctx, &domain.CreatePostRequest{Title: "Three", Content: "Content", Tags: []string{"go", "wip"}, Status: domain.PostDraft})
+	expectTags("create", domain.TagCount{Tag: "go", Posts: 2}, domain.TagCount{Tag: "db", Posts: 1})
+
+	updatePost(t, s, ctx, p1.ID, &domain.UpdatePostRequest{Title: "One", Content: "Content", Tags: []string{"go", "web"}})
+	expectTags("replace tags", domain.TagCount{Tag: "go", Posts: 2}, domain.TagCount{Tag: "web", Posts: 1})
+
+	updatePost(t, s, ctx, p1.ID, &domain.UpdatePostRequest{Title: "One again", Content: "Content"})
+	expectTags("keep tags", domain.TagCount{Tag: "go", Posts: 2}, domain.TagCount{Tag: "web", Posts: 1})
+
+	updatePost(t, s, ctx, p2.ID, &domain.UpdatePostRequest{Title: "Two", Content: "Content", Tags: []string{}})
+	expectTags("remove tags", domain.TagCount{Tag: "go", Posts: 1}, domain.TagCount{Tag: "web", Posts: 1})
+
+	updatePost(t, s, ctx, p3.ID, &domain.UpdatePostRequest{Title: "Three", Content: "Content", Status: domain.PostPublished})
+	expectTags("publish", domain.TagCount{Tag: "go", Posts: 2}, domain.TagCount{Tag: "web", Posts: 1}, domain.TagCount{Tag: "wip", Posts: 1})
+
+	updatePost(t, s, ctx, p1.ID, &domain.UpdatePostRequest{Title: "One", Content: "Content", Status: domain.PostArchived})
+	expectTags("archive", domain.TagCount{Tag: "go", Posts: 1}, domain.TagCount{Tag: "wip", Posts: 1})
+
+	// Tags changed while a post is not published are counted once it is
+	updatePost(t, s, ctx, p1.ID, &domain.UpdatePostRequest{Title: "One", Content: "Content", Tags: []string{"db"}})
+	expectTags("retag archived", domain.TagCount{Tag: "go", Posts: 1}, domain.TagCount{Tag: "wip", Posts: 1})
+	updatePost(t, s, ctx, p1.ID, &domain.UpdatePostRequest{Title: "One", Content: "Content", Status: domain.PostPublished})
+	expectTags("republish", domain.TagCount{Tag: "db", Posts: 1}, domain.TagCount{Tag: "go", Posts: 1}, domain.TagCount{Tag: "wip", Posts: 1})
+
+	if err := s.DeletePost(ctx, p3.ID, 0); err != nil {
+		t.Fatalf("Failed to delete post: %v", err)
+	}
+	expectTags("delete", domain.TagCount{Tag: "db", Posts: 1})
+
+	// Deleting a post that is not published leaves the counts alone
+	updatePost(t, s, ctx, p1.ID, &domain.UpdatePostRequest{Title: "One", Content: "Content", Status: domain.PostDraft})
+	if err := s.DeletePost(ctx, p1.ID, 0); err != nil {
+		t.Fatalf("Failed to delete post: %v", err)
+	}
+	expectTags("delete draft")
+
+	// Scheduled posts are counted once they are published
+	now := time.Now()
+	publishAt := now.Add(time.Hour)
+	createPost(t, s, ctx, &domain.CreatePostRequest{Title: "Later", Content: "Content", Tags: []string{"go", "news"}, Status: domain.PostScheduled, PublishAt: &publishAt})
+	expectTags("schedule")
+	if published, err := s.PublishDue(context.Background(), now.Add(2*time.Hour)); err != nil || published != 1 {
+		t.Fatalf("Expected the scheduled post to be published, got %d, %v", published, err)
+	}
+	expectTags("publish scheduled", domain.TagCount{Tag: "go", Posts: 1}, domain.TagCount{Tag: "news", Posts: 1})
+}
+
+func TestPostService_ListPostsVisibility(t *testing.T) {
+	s, _ := newTestPostService(t, PostQuotas{}, 0)
+	ada, grace := asAuthor("", "author-1"), asAuthor("", "author-2")
+	publishAt := time.Now().Add(time.Hour)
+
+	create := func(ctx context.Context, title string, status domain.PostStatus) string {
+		req := &domain.CreatePostRequest{Title: title, Content: "Content", Tags: []string{"go"}, Status: status}
+		if status == domain.PostScheduled {
+			req.PublishAt = &publishAt
+		}
+		return createPost(t, s, ctx, req).ID
+	}
+
+	adaPublished := create(ada, "Published", domain.PostPublished)
+	adaDraft := create(ada, "Draft", domain.PostDraft)
+	adaScheduled := create(ada, "Scheduled", domain.PostScheduled)
+	adaArchived := create(ada, "Archived", domain.PostPublished)
+	updatePost(t, s, ada, adaArchived, &domain.UpdatePostRequest{Title: "Archived", Content: "Content", Status: domain.PostArchived})
+	gracePublished := create(grace, "Published", domain.PostPublished)
+	graceDraft := create(grace, "Draft", domain.PostDraft)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		filter   PostFilter
+		expected []string
+	}{
+		{"anonymous", context.Background(), PostFilter{}, []string{gracePublished, adaPublished}},
+		{"author", ada, PostFilter{}, []string{gracePublished, adaArchived, adaScheduled, adaDraft, adaPublished}},
+		{"admin", asAdmin(), PostFilter{}, []string{graceDraft, gracePublished, adaArchived, adaScheduled, adaDraft, adaPublished}},
+		{"anonymous by author", context.Background(), PostFilter{AuthorID: "author-1"}, []string{adaPublished}},
+		{"other author by author", grace, PostFilter{AuthorID: "author-1"}, []string{adaPublished}},
+		{"author by themselves", ada, PostFilter{AuthorID: "author-1"}, []string{adaArchived, adaScheduled, adaDraft, adaPublished}},
+		{"anonymous by tag", context.Background(), PostFilter{Tag: "Go"}, []string{gracePublished, adaPublished}},
+		{"author by tag", grace, PostFilter{Tag: "go"}, []string{graceDraft, gracePublished, adaPublished}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Page one post at a time, so pages must skip hidden posts
+			var ids []string
+			cursor := ""
+			for {
+				list, err := s.ListPosts(tt.ctx, tt.filter, cursor, 1)
+				if err != nil {
+					t.Fatalf("Failed to list posts: %v", err)
+				}
+				for _, post := range list.Posts {
+					ids = append(ids, post.ID)
+				}
+				if list.NextCursor == "" {
+					break
+				}
+				cursor = list.NextCursor
+			}
+
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+
+	// Hidden posts are not found by ID either
+	for _, id := range []string{adaDraft, adaScheduled, adaArchived} {
+		var notFound *domain.PostNotFoundError
+		if _, err := s.GetPost(grace, id); !errors.As(err, &notFound) {
+			t.Errorf("Expected post %s to be hidden from other authors, got %v", id, err)
+		}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,69 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"gosuda.org/boilerplate/internal/domain"
+	"gosuda.org/boilerplate/internal/infrastructure"
+)
+
+// ReplicationService exposes this instance's replication role: streaming
+// changes to followers when it is a leader and reporting replication status
+type ReplicationService struct {
+	logger   infrastructure.LoggerInterface
+	leader   *infrastructure.ReplicationLeader
+	follower *infrastructure.Follower
+}
+
+// NewReplicationService creates a new replication service. leader and
+// follower are nil unless this instance has that role.
+func NewReplicationService(
+	logger infrastructure.LoggerInterface,
+	leader *infrastructure.ReplicationLeader,
+	follower *infrastructure.Follower,
+) *ReplicationService {
+	return &ReplicationService{
+		logger:   logger,
+		leader:   leader,
+		follower: follower,
+	}
+}
+
+// Stream streams the store's changes after the given revision to a follower
+func (s *ReplicationService) Stream(ctx context.Context, w io.Writer, flush func() error, after int64) error {
+	if s.leader == nil {
+		return &domain.StorageError{Err: fmt.Errorf("this instance is not a replication leader")}
+	}
+
+	if after < 0 {
+		return &domain.ValidationError{
+			Field:   "after",
+			Message: "after must not be negative",
+		}
+	}
+
+	s.logger.Info("Follower connected", "after", after)
+	err := s.leader.Stream(ctx, w, flush, after)
+	s.logger.Info("Follower disconnected", "error", err)
+
+	if err != nil {
+		return &domain.StorageError{Err: err}
+	}
+	return nil
+}
+
+// Status returns the replication status, or nil if this instance does not replicate
+func (s *ReplicationService) Status() *domain.ReplicationStatus {
+	var status domain.ReplicationStatus
+	switch {
+	case s.leader != nil:
+		status = s.leader.Status()
+	case s.follower != nil:
+		status = s.follower.Status()
+	default:
+		return nil
+	}
+	return &status
+}
\ No newline at end of file
@@ -0,0 +1,382 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// Repository stores entities of type T in a Store. Each entity is kept under
+// a key derived from its ID within the tenant namespace of the request
+// context, wrapped in an envelope of its schema and upgraded when read.
+//
+// Store errors are mapped to domain errors consistently: a missing entity to
+// the repository's not-found error, a version conflict to
+// PreconditionFailedError, a full store to StoreFullError and anything else
+// to StorageError. Creating an entity whose ID is taken fails with
+// errEntityExists, which createUnique retries with another ID. Errors
+// returned by hooks and update functions are passed through unchanged, so
+// they should be domain errors.
+type Repository[T any] struct {
+	store domain.Store
+	opts  RepositoryOptions[T]
+}
+
+// RepositoryOptions configures a Repository
+type RepositoryOptions[T any] struct {
+	// Schema names the schema entities are stored in
+	Schema string
+
+	// Key maps an ID to its key within a tenant namespace. Key("") must be
+	// the prefix shared by every entity's key.
+	Key func(id string) string
+
+	// NotFound returns the error reported for a missing entity
+	NotFound func(id string) error
+
+	// OnCreate, OnUpdate and OnDelete maintain records that accompany an
+	// entity, such as index entries, in the transaction that writes it. ns is
+	// the tenant key prefix the entity is stored under. A hook returning an
+	// error rejects the write.
+	OnCreate func(ctx context.Context, tx domain.Tx, ns, id string, entity *T) error
+	OnUpdate func(ctx context.Context, tx domain.Tx, ns, id string, old, updated *T) error
+	OnDelete func(ctx context.Context, tx domain.Tx, ns, id string, entity *T) error
+}
+
+// Entity is a stored entity together with its ID and version
+type Entity[T any] struct {
+	ID      string
+	Version int64
+	Value   *T
+}
+
+// errEntityExists aborts creating an entity whose ID is taken
+var errEntityExists = errors.New("entity already exists")
+
+// maxCreateAttempts bounds how many IDs createUnique tries
+const maxCreateAttempts = 5
+
+// createUnique calls create with a new ID from newID until it does not fail
+// with errEntityExists. IDs derived from the clock are taken when another
+// entity was created at the same instant.
+func createUnique(newID func() string, create func(id string) error) error {
+	for attempt := 1; ; attempt++ {
+		err := create(newID())
+		if err != errEntityExists {
+			return err
+		}
+		if attempt == maxCreateAttempts {
+			return &domain.StorageError{Err: err}
+		}
+	}
+}
+
+// passthroughError carries an error from a hook or update function out of a
+// transaction without it being mapped
+type passthroughError struct {
+	err error
+}
+
+func (e passthroughError) Error() string {
+	return e.err.Error()
+}
+
+// NewRepository creates a new repository
+func NewRepository[T any](store domain.Store, opts RepositoryOptions[T]) *Repository[T] {
+	return &Repository[T]{
+		store: store,
+		opts:  opts,
+	}
+}
+
+// Get retrieves an entity and its version by ID
+func (r *Repository[T]) Get(ctx context.Context, id string) (*T, int64, error) {
+	key := r.key(ctx, id)
+
+	var version int64
+	entity, err := r.decode(func(value any) error {
+		var err error
+		version, err = r.store.GetTypedVersion(ctx, key, value)
+		return err
+	})
+	if err != nil {
+		return nil, 0, r.mapError(id, err)
+	}
+	return entity, version, nil
+}
+
+// GetMany retrieves the entities with the given IDs in order, skipping those
+// that do not exist
+func (r *Repository[T]) GetMany(ctx context.Context, ids []string) ([]Entity[T], error) {
+	entities := make([]Entity[T], 0, len(ids))
+	for _, id := range ids {
+		key := r.key(ctx, id)
+
+		var version int64
+		entity, err := r.decode(func(value any) error {
+			var err error
+			version, err = r.store.GetTypedVersion(ctx, key, value)
+			return err
+		})
+		if err == domain.ErrKeyNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, r.mapError(id, err)
+		}
+		entities = append(entities, Entity[T]{ID: id, Version: version, Value: entity})
+	}
+	return entities, nil
+}
+
+// List returns entities in ID order. opts.StartAfter is an ID and
+// opts.Prefix, if set, restricts the listing to IDs starting with it.
+func (r *Repository[T]) List(ctx context.Context, opts domain.ScanOptions) ([]Entity[T], error) {
+	prefix := r.key(ctx, "")
+	if opts.StartAfter != "" {
+		opts.StartAfter = prefix + opts.StartAfter
+	}
+	opts.Prefix = prefix + opts.Prefix
+
+	it, err := r.store.Iterate(ctx, opts)
+	if err != nil {
+		return nil, &domain.StorageError{Err: err}
+	}
+	defer it.Close()
+
+	var entities []Entity[T]
+	for it.Next() {
+		entity, err := r.decode(it.Decode)
+		if err != nil {
+			return nil, &domain.StorageError{Err: fmt.Errorf("key %s: %w", it.Key(), err)}
+		}
+
+		entities = append(entities, Entity[T]{ID: it.Key()[len(prefix):], Version: it.Version(), Value: entity})
+	}
+
+	if err := it.Err(); err != nil {
+		return nil, &domain.StorageError{Err: err}
+	}
+
+	return entities, nil
+}
+
+// Create stores a new entity and returns its version. It fails if an entity
+// with the same ID exists.
+func (r *Repository[T]) Create(ctx context.Context, id string, entity *T) (int64, error) {
+	return r.CreateWith(ctx, id, entity, nil)
+}
+
+// CreateWith works like Create and also runs fn, if not nil, after OnCreate in
+// the transaction that stores the entity, to write records that only this
+// call knows about
+func (r *Repository[T]) CreateWith(ctx context.Context, id string, entity *T, fn func(tx domain.Tx, ns string) error) (int64, error) {
+	ns := domain.TenantKeyPrefix(ctx)
+	key := ns + r.opts.Key(id)
+
+	var version int64
+	err := r.store.Update(ctx, func(tx domain.Tx) error {
+		if _, err := tx.Version(key); err != domain.ErrKeyNotFound {
+			if err == nil {
+				return errEntityExists
+			}
+			return err
+		}
+
+		if r.opts.OnCreate != nil {
+			if err := r.opts.OnCreate(ctx, tx, ns, id, entity); err != nil {
+				return passthroughError{err}
+			}
+		}
+
+		if fn != nil {
+			if err := fn(tx, ns); err != nil {
+				return passthroughError{err}
+			}
+		}
+
+		if err := r.put(tx, key, entity); err != nil {
+			return err
+		}
+
+		var err error
+		version, err = tx.Version(key)
+		return err
+	})
+	if err != nil {
+		return 0, r.mapError(id, err)
+	}
+
+	return version, nil
+}
+
+// Update reads an entity, applies fn to it and stores the result atomically,
+// returning the updated entity and its version. If expectedVersion is
+// non-zero the update only succeeds while the entity is at that version.
+func (r *Repository[T]) Update(ctx context.Context, id string, expectedVersion int64, fn func(entity *T) error) (*T, int64, error) {
+	ns := domain.TenantKeyPrefix(ctx)
+	key := ns + r.opts.Key(id)
+
+	var updated *T
+	var version int64
+	err := r.store.Update(ctx, func(tx domain.Tx) error {
+		old, err := r.get(tx, key, expectedVersion)
+		if err != nil {
+			return err
+		}
+
+		// Decode again rather than copy, so fn cannot modify old through
+		// shared slices or maps
+		if updated, err = r.get(tx, key, 0); err != nil {
+			return err
+		}
+
+		if err := fn(updated); err != nil {
+			return passthroughError{err}
+		}
+
+		if r.opts.OnUpdate != nil {
+			if err := r.opts.OnUpdate(ctx, tx, ns, id, old, updated); err != nil {
+				return passthroughError{err}
+			}
+		}
+
+		if err := r.put(tx, key, updated); err != nil {
+			return err
+		}
+
+		version, err = tx.Version(key)
+		return err
+	})
+	if err != nil {
+		return nil, 0, r.mapError(id, err)
+	}
+
+	return updated, version, nil
+}
+
+// Delete removes an entity. If expectedVersion is non-zero the entity is
+// only deleted while it is at that version.
+func (r *Repository[T]) Delete(ctx context.Context, id string, expectedVersion int64) error {
+	ns := domain.TenantKeyPrefix(ctx)
+	key := ns + r.opts.Key(id)
+
+	err := r.store.Update(ctx, func(tx domain.Tx) error {
+		entity, err := r.get(tx, key, expectedVersion)
+		if err != nil {
+			return err
+		}
+
+		if r.opts.OnDelete != nil {
+			if err := r.opts.OnDelete(ctx, tx, ns, id, entity); err != nil {
+				return passthroughError{err}
+			}
+		}
+
+		return tx.Delete(key)
+	})
+	if err != nil {
+		return r.mapError(id, err)
+	}
+
+	return nil
+}
+
+// key returns the key of an entity in the tenant namespace of ctx
+func (r *Repository[T]) key(ctx context.Context, id string) string {
+	return domain.TenantKeyPrefix(ctx) + r.opts.Key(id)
+}
+
+// get reads an entity in a transaction, returning ErrVersionMismatch if
+// expectedVersion is non-zero and differs from the entity's version
+func (r *Repository[T]) get(tx domain.Tx, key string, expectedVersion int64) (*T, error) {
+	entity, err := r.decode(func(value any) error {
+		return tx.GetTyped(key, value)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if expectedVersion != 0 {
+		version, err := tx.Version(key)
+		if err != nil {
+			return nil, err
+		}
+		if version != expectedVersion {
+			return nil, domain.ErrVersionMismatch
+		}
+	}
+
+	return entity, nil
+}
+
+// put writes an entity in a transaction, in an envelope at the current
+// version of its schema
+func (r *Repository[T]) put(tx domain.Tx, key string, entity *T) error {
+	envelope, err := schemas.Wrap(r.opts.Schema, entity)
+	if err != nil {
+		return err
+	}
+	return tx.Set(key, envelope)
+}
+
+// mapError maps an error from the store to a domain error
+func (r *Repository[T]) mapError(id string, err error) error {
+	var passthrough passthroughError
+	switch {
+	case errors.As(err, &passthrough):
+		return passthrough.err
+	case err == errEntityExists:
+		return errEntityExists
+	case err == domain.ErrKeyNotFound:
+		return r.opts.NotFound(id)
+	case err == domain.ErrVersionMismatch:
+		return &domain.PreconditionFailedError{ID: id, Resource: r.opts.Schema}
+	case err == domain.ErrStoreFull:
+		return &domain.StoreFullError{Err: err}
+	default:
+		return &domain.StorageError{Err: err}
+	}
+}
+
+// typedEnvelope is an envelope decoded with its data in the entity type
+type typedEnvelope[T any] struct {
+	Schema        string `json:"schema"`
+	SchemaVersion int    `json:"schemaVersion"`
+	Data          *T     `json:"data"`
+}
+
+// decode decodes an entity with read, which decodes the stored record into
+// the value it is given. Envelopes at the current version of the schema are
+// decoded straight into the entity type. Anything else, such as a record at
+// an older version or one written by a codec that keeps envelopes as generic
+// documents, is read again without a type and upgraded.
+func (r *Repository[T]) decode(read func(value any) error) (*T, error) {
+	var envelope typedEnvelope[T]
+	err := read(&envelope)
+	if err == nil && envelope.Data != nil && envelope.Schema == r.opts.Schema &&
+		envelope.SchemaVersion == schemas.Version(r.opts.Schema) {
+		return envelope.Data, nil
+	}
+	if err == domain.ErrKeyNotFound {
+		return nil, err
+	}
+
+	var stored any
+	if err := read(&stored); err != nil {
+		return nil, err
+	}
+	return decodeEntity[T](r.opts.Schema, stored)
+}
+
+// decodeEntity upgrades an entity as read from the store to the current
+// version of its schema
+func decodeEntity[T any](schema string, stored any) (*T, error) {
+	var entity T
+	if _, err := schemas.Unwrap(schema, stored, &entity); err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
\ No newline at end of file
@@ -0,0 +1,316 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gosuda.org/boilerplate/internal/domain"
+	"gosuda.org/boilerplate/internal/infrastructure"
+)
+
+// testNote is the entity stored by the repositories under test
+type testNote struct {
+	Text string   `json:"text"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+// newNoteRepository creates a repository of notes
+func newNoteRepository(store domain.Store, opts RepositoryOptions[testNote]) *Repository[testNote] {
+	opts.Schema = "note"
+	opts.Key = func(id string) string {
+		return "notes:" + id
+	}
+	opts.NotFound = func(id string) error {
+		return &domain.PostNotFoundError{ID: id}
+	}
+	return NewRepository(store, opts)
+}
+
+func TestRepository_CRUD(t *testing.T) {
+	for _, codec := range testCodecs {
+		t.Run(codec.Name(), func(t *testing.T) {
+			ctx := context.Background()
+			repo := newNoteRepository(newTestStore(t, codec), RepositoryOptions[testNote]{})
+
+			v1, err := repo.Create(ctx, "n1", &testNote{Text: "first", Tags: []string{"a"}})
+			if err != nil {
+				t.Fatalf("Failed to create note: %v", err)
+			}
+			if _, err := repo.Create(ctx, "n2", &testNote{Text: "second"}); err != nil {
+				t.Fatalf("Failed to create note: %v", err)
+			}
+			if _, err := repo.Create(ctx, "n1", &testNote{Text: "again"}); err != errEntityExists {
+				t.Errorf("Expected errEntityExists for a taken ID, got %v", err)
+			}
+
+			note, version, err := repo.Get(ctx, "n1")
+			if err != nil {
+				t.Fatalf("Failed to get note: %v", err)
+			}
+			if note.Text != "first" || len(note.Tags) != 1 || version != v1 {
+				t.Errorf("Expected the created note at version %d, got %+v at version %d", v1, note, version)
+			}
+
+			var notFound *domain.PostNotFoundError
+			if _, _, err := repo.Get(ctx, "missing"); !errors.As(err, &notFound) || notFound.ID != "missing" {
+				t.Errorf("Expected PostNotFoundError, got %v", err)
+			}
+
+			var precondition *domain.PreconditionFailedError
+			_, _, err = repo.Update(ctx, "n1", v1+100, func(note *testNote) error {
+				note.Text = "stale"
+				return nil
+			})
+			if !errors.As(err, &precondition) || precondition.ID != "n1" {
+				t.Errorf("Expected PreconditionFailedError for a stale version, got %v", err)
+			}
+
+			updated, v2, err := repo.Update(ctx, "n1", v1, func(note *testNote) error {
+				note.Text = "updated"
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Failed to update note: %v", err)
+			}
+			if updated.Text != "updated" || v2 == v1 {
+				t.Errorf("Expected the update at a new version, got %+v at version %d", updated, v2)
+			}
+
+			entities, err := repo.List(ctx, domain.ScanOptions{})
+			if err != nil {
+				t.Fatalf("Failed to list notes: %v", err)
+			}
+			if len(entities) != 2 || entities[0].ID != "n1" || entities[0].Version != v2 ||
+				entities[0].Value.Text != "updated" || entities[1].ID != "n2" {
+				t.Errorf("Expected n1 and n2 in order, got %+v", entities)
+			}
+
+			entities, err = repo.List(ctx, domain.ScanOptions{StartAfter: "n1"})
+			if err != nil || len(entities) != 1 || entities[0].ID != "n2" {
+				t.Errorf("Expected n2 after n1, got %+v, %v", entities, err)
+			}
+
+			entities, err = repo.GetMany(ctx, []string{"n2", "missing", "n1"})
+			if err != nil || len(entities) != 2 || entities[0].ID != "n2" || entities[1].ID != "n1" {
+				t.Errorf("Expected n2 and n1 in order, got %+v, %v", entities, err)
+			}
+
+			if err := repo.Delete(ctx, "n1", v1); !errors.As(err, &precondition) {
+				t.Errorf("Expected PreconditionFailedError deleting at a stale version, got %v", err)
+			}
+			if err := repo.Delete(ctx, "n1", v2); err != nil {
+				t.Fatalf("Failed to delete note: %v", err)
+			}
+			if _, _, err := repo.Get(ctx, "n1"); !errors.As(err, &notFound) {
+				t.Errorf("Expected the deleted note to be gone, got %v", err)
+			}
+			if err := repo.Delete(ctx, "n1", 0); !errors.As(err, &notFound) {
+				t.Errorf("Expected PostNotFoundError deleting a missing note, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRepository_Hooks(t *testing.T) {
+	store := newTestStore(t, infrastructure.JSONCodec)
+	errRejected := &domain.ValidationError{Field: "text", Message: "rejected"}
+
+	repo := newNoteRepository(store, RepositoryOptions[testNote]{
+		OnCreate: func(ctx context.Context, tx domain.Tx, ns, id string, note *testNote) error {
+			if note.Text == "" {
+				return errRejected
+			}
+			return tx.Set(ns+"note-index:"+note.Text, id)
+		},
+		OnUpdate: func(ctx context.Context, tx domain.Tx, ns, id string, old, updated *testNote) error {
+			if err := tx.Delete(ns + "note-index:" + old.Text); err != nil {
+				return err
+			}
+			return tx.Set(ns+"note-index:"+updated.Text, id)
+		},
+		OnDelete: func(ctx context.Context, tx domain.Tx, ns, id string, note *testNote) error {
+			return tx.Delete(ns + "note-index:" + note.Text)
+		},
+	})
+
+	ctx := domain.WithTenant(context.Background(), "acme")
+	ns := domain.TenantKeyPrefix(ctx)
+	indexed := func(text string) bool {
+		_, err := store.Get(ctx, ns+"note-index:"+text)
+		return err == nil
+	}
+
+	// Errors from hooks are passed through and reject the write
+	if _, err := repo.Create(ctx, "empty", &testNote{}); err != errRejected {
+		t.Errorf("Expected the hook's error, got %v", err)
+	}
+	if _, _, err := repo.Get(ctx, "empty"); err == nil {
+		t.Error("Expected a rejected note not to be stored")
+	}
+
+	if _, err := repo.Create(ctx, "n1", &testNote{Text: "first"}); err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if !indexed("first") {
+		t.Error("Expected OnCreate to index the note in the tenant namespace")
+	}
+
+	if _, _, err := repo.Update(ctx, "n1", 0, func(note *testNote) error {
+		note.Text = "second"
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to update note: %v", err)
+	}
+	if indexed("first") || !indexed("second") {
+		t.Error("Expected OnUpdate to see the old and the updated note")
+	}
+
+	if err := repo.Delete(ctx, "n1", 0); err != nil {
+		t.Fatalf("Failed to delete note: %v", err)
+	}
+	if indexed("second") {
+		t.Error("Expected OnDelete to remove the index entry")
+	}
+
+	// Notes of other tenants are kept apart
+	if _, _, err := repo.Get(context.Background(), "n1"); err == nil {
+		t.Error("Expected the note not to be found outside its tenant")
+	}
+}
+
+func TestRepository_SchemaUpgrade(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	legacy := &domain.Post{
+		ID:        "legacy",
+		AuthorID:  "author-1",
+		Title:     "Legacy",
+		Content:   "Written before records were versioned",
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+
+	for _, codec := range testCodecs {
+		t.Run(codec.Name(), func(t *testing.T) {
+			ctx := context.Background()
+			store := newTestStore(t, codec)
+			repo := NewRepository(store, RepositoryOptions[domain.Post]{
+				Schema: postSchema,
+				Key: func(id string) string {
+					return postKey("", id)
+				},
+				NotFound: func(id string) error {
+					return &domain.PostNotFoundError{ID: id}
+				},
+			})
+
+			// A post stored directly, as version 1, and one in an envelope
+			// at version 2, before posts had revisions
+			if err := store.Set(ctx, postKey("", "legacy"), legacy); err != nil {
+				t.Fatalf("Failed to store legacy post: %v", err)
+			}
+			v2 := domain.Envelope{
+				Schema:        postSchema,
+				SchemaVersion: 2,
+				Data: map[string]any{
+					"id":       "v2",
+					"authorId": "author-1",
+					"title":    "Draft",
+					"status":   string(domain.PostDraft),
+				},
+			}
+			if err := store.Set(ctx, postKey("", "v2"), v2); err != nil {
+				t.Fatalf("Failed to store version 2 post: %v", err)
+			}
+			if _, err := repo.Create(ctx, "current", domain.NewPost("current", "author-1", "Current", "Content", nil)); err != nil {
+				t.Fatalf("Failed to create post: %v", err)
+			}
+
+			post, _, err := repo.Get(ctx, "legacy")
+			if err != nil {
+				t.Fatalf("Failed to get legacy post: %v", err)
+			}
+			if post.Title != "Legacy" || post.Status != domain.PostPublished || post.Revision != 1 ||
+				post.PublishedAt == nil || !post.PublishedAt.Equal(createdAt) {
+				t.Errorf("Expected the legacy post published at its creation at revision 1, got %+v", post)
+			}
+
+			post, _, err = repo.Get(ctx, "v2")
+			if err != nil {
+				t.Fatalf("Failed to get version 2 post: %v", err)
+			}
+			if post.Status != domain.PostDraft || post.Revision != 1 {
+				t.Errorf("Expected the version 2 post to keep its status at revision 1, got %+v", post)
+			}
+
+			migrated, err := MigrateRecords(ctx, store)
+			if err != nil {
+				t.Fatalf("Failed to migrate records: %v", err)
+			}
+			if migrated != 2 {
+				t.Errorf("Expected 2 outdated records to be rewritten, got %d", migrated)
+			}
+			if migrated, err := MigrateRecords(ctx, store); err != nil || migrated != 0 {
+				t.Errorf("Expected nothing left to migrate, got %d, %v", migrated, err)
+			}
+
+			entities, err := repo.List(ctx, domain.ScanOptions{})
+			if err != nil {
+				t.Fatalf("Failed to list posts: %v", err)
+			}
+			if len(entities) != 3 {
+				t.Fatalf("Expected 3 posts, got %d", len(entities))
+			}
+			for _, entity := range entities {
+				var stored any
+				if err := store.GetTyped(ctx, postKey("", entity.ID), &stored); err != nil {
+					t.Fatalf("Failed to read post %s: %v", entity.ID, err)
+				}
+				doc, err := toDocument(stored)
+				if err != nil || doc["schemaVersion"] != float64(schemas.Version(postSchema)) {
+					t.Errorf("Expected post %s at the current schema version, got %v", entity.ID, doc)
+				}
+				if entity.Value.ID != entity.ID || entity.Value.Revision != 1 {
+					t.Errorf("Expected post %s to read back after migration, got %+v", entity.ID, entity.Value)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateUnique(t *testing.T) {
+	var tried []string
+	taken := map[string]bool{"id-1": true, "id-2": true}
+	next := 0
+	newID := func() string {
+		next++
+		return "id-" + string(rune('0'+next))
+	}
+
+	err := createUnique(newID, func(id string) error {
+		tried = append(tried, id)
+		if taken[id] {
+			return errEntityExists
+		}
+		return nil
+	})
+	if err != nil || len(tried) != 3 || tried[2] != "id-3" {
+		t.Errorf("Expected taken IDs to be replaced, tried %v: %v", tried, err)
+	}
+
+	attempts := 0
+	err = createUnique(func() string { return "same" }, func(id string) error {
+		attempts++
+		return errEntityExists
+	})
+	var storageErr *domain.StorageError
+	if !errors.As(err, &storageErr) || attempts != maxCreateAttempts {
+		t.Errorf("Expected a storage error after %d attempts, got %v after %d", maxCreateAttempts, err, attempts)
+	}
+
+	boom := errors.New("boom")
+	if err := createUnique(newID, func(id string) error { return boom }); err != boom {
+		t.Errorf("Expected other errors to be returned as is, got %v", err)
+	}
+}
\ No newline at end of file
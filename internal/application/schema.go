@@ -0,0 +1,234 @@
+package application
+
+import (
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// Entity schemas stored by the application
+const (
+	postSchema         = "post"
+	postRevisionSchema = "post-revision"
+	authorSchema       = "author"
+)
+
+// schemas holds the upgrades of every entity schema the application stores.
+// When the shape of a stored entity changes, register an upgrade from its
+// previous version here; records are upgraded as they are read and can be
+// rewritten in bulk with MigrateRecords.
+var schemas = NewSchemaRegistry()
+
+func init() {
+	// The gob codec can only decode a stored record without knowing its type
+	// in a process that has registered the types it may hold: envelopes, and
+	// posts, which were stored directly, by pointer, before they were wrapped
+	// in envelopes
+	gob.Register(domain.Envelope{})
+	gob.Register(&domain.Post{})
+
+	// Version 2 of posts added their lifecycle status. Posts used to be
+	// public as soon as they were created.
+	schemas.Register(postSchema, 1, func(data map[string]any) error {
+		data["status"] = string(domain.PostPublished)
+		if createdAt, ok := data["createdAt"]; ok {
+			data["publishedAt"] = createdAt
+		}
+		return nil
+	})
+
+	// Version 3 of posts numbered their revisions. Earlier posts had no
+	// history, so they are at their first revision.
+	schemas.Register(postSchema, 2, func(data map[string]any) error {
+		data["revision"] = 1
+		return nil
+	})
+}
+
+// Upgrade converts an entity's data in place from one schema version to the next
+type Upgrade func(data map[string]any) error
+
+// SchemaRegistry holds the upgrades between the versions of entity schemas.
+// Version 1 of every schema is the entity as it was stored before records
+// were versioned, without an envelope.
+type SchemaRegistry struct {
+	upgrades map[string][]Upgrade
+}
+
+// NewSchemaRegistry creates an empty schema registry
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{
+		upgrades: make(map[string][]Upgrade),
+	}
+}
+
+// Register adds the upgrade of a schema from version from to version from+1.
+// Upgrades must be registered in order, starting from version 1.
+func (r *SchemaRegistry) Register(schema string, from int, upgrade Upgrade) {
+	if from != r.Version(schema) {
+		panic(fmt.Sprintf("schema %s: upgrade from version %d registered at version %d", schema, from, r.Version(schema)))
+	}
+	r.upgrades[schema] = append(r.upgrades[schema], upgrade)
+}
+
+// Version returns the current version of a schema
+func (r *SchemaRegistry) Version(schema string) int {
+	return len(r.upgrades[schema]) + 1
+}
+
+// Wrap puts an entity in an envelope at the current version of its schema
+func (r *SchemaRegistry) Wrap(schema string, entity any) (domain.Envelope, error) {
+	data, err := toDocument(entity)
+	if err != nil {
+		return domain.Envelope{}, err
+	}
+
+	return domain.Envelope{
+		Schema:        schema,
+		SchemaVersion: r.Version(schema),
+		Data:          data,
+	}, nil
+}
+
+// Unwrap decodes a stored record into entity, upgrading it to the current
+// version of its schema. stored is the record as decoded without a type: an
+// envelope, or an entity written before records were versioned. Unwrap
+// reports whether the record is outdated and should be rewritten.
+func (r *SchemaRegistry) Unwrap(schema string, stored any, entity any) (bool, error) {
+	doc, err := toDocument(stored)
+	if err != nil {
+		return false, err
+	}
+
+	version, data := 1, doc
+	_, enveloped := doc["schemaVersion"]
+	if enveloped {
+		var envelope domain.Envelope
+		if err := fromDocument(doc, &envelope); err != nil {
+			return false, err
+		}
+		if envelope.Schema != schema {
+			return false, fmt.Errorf("record has schema %q, expected %q", envelope.Schema, schema)
+		}
+		version, data = envelope.SchemaVersion, envelope.Data
+	}
+
+	current := r.Version(schema)
+	if version < 1 || version > current {
+		return false, fmt.Errorf("unsupported version %d of schema %s, expected at most %d", version, schema, current)
+	}
+
+	for v := version; v < current; v++ {
+		if err := r.upgrades[schema][v-1](data); err != nil {
+			return false, fmt.Errorf("failed to upgrade %s from version %d: %w", schema, v, err)
+		}
+	}
+
+	if err := fromDocument(data, entity); err != nil {
+		return false, err
+	}
+
+	return !enveloped || version < current, nil
+}
+
+// MigrateRecords rewrites every stored record that is not in an envelope at
+// the current version of its schema and returns the number rewritten.
+// Records are rewritten with CompareAndSwap, so one changed concurrently is
+// left to that write, and rewritten records lose their TTL.
+func MigrateRecords(ctx context.Context, store domain.Store) (int, error) {
+	it, err := store.Iterate(ctx, domain.ScanOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer it.Close()
+
+	count := 0
+	for it.Next() {
+		schema := schemaForKey(it.Key())
+		if schema == "" {
+			continue
+		}
+
+		var stored any
+		if err := it.Decode(&stored); err != nil {
+			return count, err
+		}
+
+		// Decode into a generic document: fields are carried over as stored
+		var entity map[string]any
+		outdated, err := schemas.Unwrap(schema, stored, &entity)
+		if err != nil {
+			return count, fmt.Errorf("failed to migrate key %s: %w", it.Key(), err)
+		}
+		if !outdated {
+			continue
+		}
+
+		envelope, err := schemas.Wrap(schema, entity)
+		if err != nil {
+			return count, err
+		}
+
+		_, err = store.CompareAndSwap(ctx, it.Key(), envelope, it.Version())
+		if err == domain.ErrVersionMismatch || err == domain.ErrKeyNotFound {
+			continue
+		}
+		if err != nil {
+			return count, fmt.Errorf("failed to rewrite key %s: %w", it.Key(), err)
+		}
+		count++
+	}
+
+	if err := it.Err(); err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// schemaForKey returns the schema of the records stored under a key, or ""
+// for keys that do not hold versioned entities
+func schemaForKey(key string) string {
+	_, rest := domain.SplitTenantKey(key)
+	switch {
+	case strings.HasPrefix(rest, "posts:"):
+		return postSchema
+	case strings.HasPrefix(rest, postRevisionsPrefix):
+		return postRevisionSchema
+	case strings.HasPrefix(rest, "authors:"):
+		return authorSchema
+	default:
+		return ""
+	}
+}
+
+// toDocument converts a value to a generic JSON object
+func toDocument(value any) (map[string]any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode record: %w", err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil || doc == nil {
+		return nil, fmt.Errorf("record is not an object")
+	}
+	return doc, nil
+}
+
+// fromDocument converts a generic JSON object to the value pointed to by value
+func fromDocument(doc map[string]any, value any) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode record: %w", err)
+	}
+
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("failed to decode record: %w", err)
+	}
+	return nil
+}
\ No newline at end of file
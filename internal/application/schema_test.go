@@ -0,0 +1,142 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gosuda.org/boilerplate/internal/domain"
+	"gosuda.org/boilerplate/internal/infrastructure"
+)
+
+// testCodecs are the codecs stored records are tested with
+var testCodecs = []infrastructure.Codec{
+	infrastructure.JSONCodec,
+	infrastructure.GobCodec,
+	infrastructure.BinaryCodec,
+}
+
+// newTestStore creates a memory store that encodes values with codec
+func newTestStore(t *testing.T, codec infrastructure.Codec) domain.Store {
+	t.Helper()
+	store := infrastructure.NewMemoryStore(infrastructure.WithCodec(codec))
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestSchemaRegistry(t *testing.T) {
+	registry := NewSchemaRegistry()
+	registry.Register("thing", 1, func(data map[string]any) error {
+		data["name"] = data["title"]
+		delete(data, "title")
+		return nil
+	})
+	registry.Register("thing", 2, func(data map[string]any) error {
+		data["count"] = 1
+		return nil
+	})
+
+	type thing struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	tests := []struct {
+		name     string
+		stored   any
+		want     thing
+		outdated bool
+		wantErr  bool
+	}{
+		{"unversioned", map[string]any{"title": "a"}, thing{"a", 1}, true, false},
+		{"version 2", domain.Envelope{Schema: "thing", SchemaVersion: 2, Data: map[string]any{"name": "b"}}, thing{"b", 1}, true, false},
+		{"current", domain.Envelope{Schema: "thing", SchemaVersion: 3, Data: map[string]any{"name": "c", "count": 5}}, thing{"c", 5}, false, false},
+		{"other schema", domain.Envelope{Schema: "other", SchemaVersion: 1, Data: map[string]any{}}, thing{}, false, true},
+		{"newer version", domain.Envelope{Schema: "thing", SchemaVersion: 4, Data: map[string]any{}}, thing{}, false, true},
+		{"not an object", "text", thing{}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got thing
+			outdated, err := registry.Unwrap("thing", tt.stored, &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && (got != tt.want || outdated != tt.outdated) {
+				t.Errorf("Expected %+v (outdated %v), got %+v (outdated %v)", tt.want, tt.outdated, got, outdated)
+			}
+		})
+	}
+
+	envelope, err := registry.Wrap("thing", thing{Name: "d", Count: 2})
+	if err != nil || envelope.SchemaVersion != 3 || envelope.Data["name"] != "d" {
+		t.Errorf("Expected an envelope at version 3, got %+v, %v", envelope, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering an upgrade out of order to panic")
+		}
+	}()
+	registry.Register("thing", 1, func(data map[string]any) error { return nil })
+}
+
+func TestMigrateRecords(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, codec := range testCodecs {
+		t.Run(codec.Name(), func(t *testing.T) {
+			ctx := context.Background()
+			store := newTestStore(t, codec)
+			s := NewPostService(newTestLogger(t), store, PostQuotas{}, 0)
+
+			// Posts stored directly, as before records were versioned, inside
+			// and outside a tenant, next to a post written in an envelope
+			tenantCtx := asAuthor("acme", "author-1")
+			ns := domain.TenantKeyPrefix(tenantCtx)
+			for _, key := range []string{postKey("", "legacy"), postKey(ns, "legacy")} {
+				legacy := &domain.Post{ID: "legacy", Title: "Legacy", Content: "Content", CreatedAt: createdAt, UpdatedAt: createdAt}
+				if err := store.Set(ctx, key, legacy); err != nil {
+					t.Fatalf("Failed to store legacy post: %v", err)
+				}
+			}
+			current, err := s.CreatePost(asAuthor("", "author-1"), &domain.CreatePostRequest{Title: "Current", Content: "Content"})
+			if err != nil {
+				t.Fatalf("Failed to create post: %v", err)
+			}
+
+			// Outdated posts are upgraded as they are read
+			post, err := s.GetPost(tenantCtx, "legacy")
+			if err != nil || post.Title != "Legacy" || !post.CreatedAt.Equal(createdAt) {
+				t.Fatalf("Expected the legacy post to be read, got %+v, %v", post, err)
+			}
+
+			migrated, err := MigrateRecords(ctx, store)
+			if err != nil {
+				t.Fatalf("Failed to migrate records: %v", err)
+			}
+			if migrated != 2 {
+				t.Errorf("Expected 2 outdated records to be rewritten, got %d", migrated)
+			}
+			if migrated, err := MigrateRecords(ctx, store); err != nil || migrated != 0 {
+				t.Errorf("Expected nothing left to migrate, got %d, %v", migrated, err)
+			}
+
+			for _, key := range []string{postKey("", "legacy"), postKey(ns, "legacy"), postKey("", current.ID)} {
+				var stored any
+				if err := store.GetTyped(ctx, key, &stored); err != nil {
+					t.Fatalf("Failed to read %s: %v", key, err)
+				}
+				doc, err := toDocument(stored)
+				if err != nil || doc["schema"] != postSchema || doc["schemaVersion"] != float64(schemas.Version(postSchema)) {
+					t.Errorf("Expected %s in an envelope at the current schema version, got %v", key, doc)
+				}
+			}
+
+			post, err = s.GetPost(ctx, "legacy")
+			if err != nil || post.Title != "Legacy" || post.Content != "Content" || !post.CreatedAt.Equal(createdAt) {
+				t.Errorf("Expected the migrated post to read back unchanged, got %+v, %v", post, err)
+			}
+		})
+	}
+}
\ No newline at end of file
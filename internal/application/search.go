@@ -0,0 +1,739 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// Full-text search over the title and content of posts. Both are split into
+// lowercased words, and each post is recorded under every word it contains in
+// an inverted index kept in the tenant namespace:
+//
+//	search-terms:<term>:<post ID>  positions of the term in the post
+//	search-stats                   number of indexed posts and their total length
+//
+// The index is maintained by the post repository's hooks in the transaction
+// that writes a post. Posts written before the index existed are added to it
+// by the integrity checker's repair.
+
+// Search limits and ranking parameters
+const (
+	MaxQueryLength  = 200
+	MaxQueryClauses = 10
+
+	// maxPrefixTerms limits how many terms a prefix query expands to
+	maxPrefixTerms = 50
+
+	// maxTermLength is the length beyond which words are not indexed
+	maxTermLength = 64
+
+	// snippetTokens is the number of words in a content snippet, and
+	// snippetLead the number shown before its first match
+	snippetTokens = 30
+	snippetLead   = 5
+
+	// titleBoost weighs a term in the title against one in the content
+	titleBoost = 2.0
+
+	// BM25 term frequency saturation and length normalization
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// searchTermsPrefix is the key prefix of the inverted index
+const searchTermsPrefix = "search-terms:"
+
+// searchPosting records where a term occurs in a post, as word positions, and
+// the length of the post's fields in words
+type searchPosting struct {
+	Title         []int `json:"t,omitempty"`
+	Content       []int `json:"c,omitempty"`
+	TitleLength   int   `json:"tl"`
+	ContentLength int   `json:"cl"`
+}
+
+// searchStats holds the corpus statistics BM25 normalizes by
+type searchStats struct {
+	Posts         int `json:"posts"`
+	TitleLength   int `json:"titleLength"`
+	ContentLength int `json:"contentLength"`
+}
+
+// searchToken is a word of a text and its byte offsets
+type searchToken struct {
+	term       string
+	start, end int
+}
+
+// searchClause is one part of a query: a term, a term prefix or a phrase.
+// A post must match every clause of a query.
+type searchClause struct {
+	// terms holds a single term, or the consecutive terms of a phrase
+	terms []string
+
+	// prefix makes a single term match every term starting with it
+	prefix bool
+}
+
+// searchHit is how often a term or phrase occurs in a post
+type searchHit struct {
+	title, content             int
+	titleLength, contentLength int
+}
+
+// searchUnit is a term or phrase scored on its own, with the posts it occurs in
+type searchUnit struct {
+	hits map[string]searchHit
+}
+
+// scoredPost is a post matching a query and its relevance
+type scoredPost struct {
+	id    string
+	score float64
+}
+
+// SearchPosts returns the posts matching query that the caller may see, best
+// match first. A query is a list of words, all of which a post must contain
+// in its title or content. A word ending in "*" matches every word starting
+// with it, and words in double quotes must occur as a phrase. Matches are
+// ranked with BM25, counting words in the title more than words in the
+// content.
+func (s *PostService) SearchPosts(ctx context.Context, query, cursor string, limit int) (*domain.SearchResults, error) {
+	// Parse and validate pagination parameters
+	params := NewPaginationParams(cursor, limit)
+	if err := ValidatePaginationParams(params.Cursor, params.Limit); err != nil {
+		return nil, &domain.ValidationError{
+			Field:   "pagination",
+			Message: err.Error(),
+		}
+	}
+
+	clauses, err := parseSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	canonical := formatSearchQuery(clauses)
+
+	// Resume after the last result of the previous page, which must have
+	// been listed for the same query
+	var after *Cursor
+	if params.Cursor != "" {
+		after, err = DecodeCursor(params.Cursor)
+		if err != nil || after == nil || after.Score == 0 || after.Query != canonical {
+			return nil, &domain.PaginationError{Cursor: params.Cursor}
+		}
+	}
+
+	ns := domain.TenantKeyPrefix(ctx)
+	var stats searchStats
+	if err := s.store.GetTyped(ctx, searchStatsKey(ns), &stats); err != nil && err != domain.ErrKeyNotFound {
+		return nil, &domain.StorageError{Err: err}
+	}
+
+	matches, err := s.matchSearchClauses(ctx, ns, clauses, stats)
+	if err != nil {
+		return nil, &domain.StorageError{Err: err}
+	}
+
+	// Ties are broken by ID so pages never overlap
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].id < matches[j].id
+	})
+
+	// Scores depend on the statistics of every indexed post, so they shift
+	// as posts are written between pages. Resume after the last result as
+	// ranked now, falling back to its score when the page was listed only
+	// if it no longer matches.
+	if after != nil {
+		score := after.Score
+		for _, match := range matches {
+			if match.id == after.ID {
+				score = match.score
+				break
+			}
+		}
+
+		start := sort.Search(len(matches), func(i int) bool {
+			return matches[i].score < score ||
+				(matches[i].score == score && matches[i].id > after.ID)
+		})
+		matches = matches[start:]
+	}
+
+	// Fetch matches best first until a page of posts the caller may see is
+	// found, plus one to learn whether another page follows. Posts deleted
+	// since the index was read are skipped.
+	caller := domain.CallerFromContext(ctx)
+	results := make([]domain.SearchResult, 0, params.Limit+1)
+	for start := 0; start < len(matches) && len(results) <= params.Limit; {
+		batch := matches[start:min(start+params.Limit+1, len(matches))]
+		start += len(batch)
+
+		ids := make([]string, len(batch))
+		scores := make(map[string]float64, len(batch))
+		for i, match := range batch {
+			ids[i] = match.id
+			scores[match.id] = match.score
+		}
+
+		entities, err := s.posts.GetMany(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entity := range entities {
+			if !entity.Value.VisibleTo(caller) {
+				continue
+			}
+			entity.Value.Version = entity.Version
+			results = append(results, domain.SearchResult{
+				Post:       *entity.Value,
+				Score:      scores[entity.ID],
+				Highlights: highlightPost(entity.Value, clauses),
+			})
+		}
+	}
+
+	hasMore := len(results) > params.Limit
+	if hasMore {
+		results = results[:params.Limit]
+	}
+
+	// Create next cursor
+	var nextCursor string
+	if hasMore && len(results) > 0 {
+		last := results[len(results)-1]
+		nextCursor, err = EncodeCursor(&Cursor{
+			ID:    last.Post.ID,
+			Score: last.Score,
+			Limit: params.Limit,
+			Query: canonical,
+		})
+		if err != nil {
+			return nil, &domain.StorageError{Err: err}
+		}
+	}
+
+	return &domain.SearchResults{
+		Results:    results,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// matchSearchClauses returns the posts matching every clause with their scores
+func (s *PostService) matchSearchClauses(ctx context.Context, ns string, clauses []searchClause, stats searchStats) ([]scoredPost, error) {
+	var candidates map[string]float64
+	for _, clause := range clauses {
+		units, err := s.searchUnits(ctx, ns, clause)
+		if err != nil {
+			return nil, err
+		}
+
+		// A post matches the clause if any of its units occurs in it
+		scores := make(map[string]float64)
+		for _, unit := range units {
+			idf := searchIDF(stats.Posts, len(unit.hits))
+			for id, hit := range unit.hits {
+				if candidates != nil {
+					if _, ok := candidates[id]; !ok {
+						continue
+					}
+				}
+				scores[id] += idf * hit.weight(stats)
+			}
+		}
+
+		if candidates == nil {
+			candidates = scores
+		} else {
+			for id := range candidates {
+				if score, ok := scores[id]; ok {
+					candidates[id] += score
+				} else {
+					delete(candidates, id)
+				}
+			}
+		}
+
+		if len(candidates) == 0 {
+			return nil, nil
+		}
+	}
+
+	matches := make([]scoredPost, 0, len(candidates))
+	for id, score := range candidates {
+		matches = append(matches, scoredPost{id: id, score: score})
+	}
+	return matches, nil
+}
+
+// searchUnits reads the postings of a clause from the index: one unit for a
+// term or phrase, and one per matching term for a prefix
+func (s *PostService) searchUnits(ctx context.Context, ns string, clause searchClause) ([]searchUnit, error) {
+	if clause.prefix {
+		return s.prefixUnits(ctx, ns, clause.terms[0])
+	}
+
+	var postings []map[string]searchPosting
+	for _, term := range clause.terms {
+		termPostings, err := s.termPostings(ctx, ns, term)
+		if err != nil {
+			return nil, err
+		}
+		if len(termPostings) == 0 {
+			return nil, nil
+		}
+		postings = append(postings, termPostings)
+	}
+
+	unit := searchUnit{hits: make(map[string]searchHit)}
+	for id, first := range postings[0] {
+		hit := searchHit{titleLength: first.TitleLength, contentLength: first.ContentLength}
+		if len(postings) == 1 {
+			hit.title, hit.content = len(first.Title), len(first.Content)
+		} else {
+			hit.title = countPhrase(postings, id, func(p searchPosting) []int { return p.Title })
+			hit.content = countPhrase(postings, id, func(p searchPosting) []int { return p.Content })
+		}
+		if hit.title > 0 || hit.content > 0 {
+			unit.hits[id] = hit
+		}
+	}
+	return []searchUnit{unit}, nil
+}
+
+// termPostings returns the postings of a term by post ID
+func (s *PostService) termPostings(ctx context.Context, ns, term string) (map[string]searchPosting, error) {
+	prefix := searchTermPrefix(ns, term)
+	entries, err := domain.ListTyped[searchPosting](ctx, s.store, domain.ScanOptions{Prefix: prefix})
+	if err != nil {
+		return nil, err
+	}
+
+	postings := make(map[string]searchPosting, len(entries))
+	for _, entry := range entries {
+		postings[entry.Key[len(prefix):]] = entry.Value
+	}
+	return postings, nil
+}
+
+// prefixUnits returns a unit for each of the first maxPrefixTerms terms
+// starting with prefix
+func (s *PostService) prefixUnits(ctx context.Context, ns, prefix string) ([]searchUnit, error) {
+	keyPrefix := ns + searchTermsPrefix
+	it, err := s.store.Iterate(ctx, domain.ScanOptions{Prefix: keyPrefix + prefix})
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	var units []searchUnit
+	var current string
+	for it.Next() {
+		term, id, ok := splitSearchTermKey(it.Key()[len(keyPrefix):])
+		if !ok {
+			continue
+		}
+		if term != current || units == nil {
+			if len(units) == maxPrefixTerms {
+				break
+			}
+			current = term
+			units = append(units, searchUnit{hits: make(map[string]searchHit)})
+		}
+
+		var posting searchPosting
+		if err := it.Decode(&posting); err != nil {
+			return nil, err
+		}
+		units[len(units)-1].hits[id] = searchHit{
+			title:         len(posting.Title),
+			content:       len(posting.Content),
+			titleLength:   posting.TitleLength,
+			contentLength: posting.ContentLength,
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return units, nil
+}
+
+// countPhrase counts the positions at which the terms of a phrase occur in
+// consecutive order in one field of a post
+func countPhrase(postings []map[string]searchPosting, id string, field func(searchPosting) []int) int {
+	positions := make([]map[int]bool, len(postings))
+	for i, termPostings := range postings {
+		posting, ok := termPostings[id]
+		if !ok {
+			return 0
+		}
+		positions[i] = make(map[int]bool)
+		for _, position := range field(posting) {
+			positions[i][position] = true
+		}
+	}
+
+	count := 0
+	for start := range positions[0] {
+		matched := true
+		for i := 1; i < len(positions); i++ {
+			if !positions[i][start+i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			count++
+		}
+	}
+	return count
+}
+
+// weight returns the BM25 term frequency component of a hit, combining both
+// fields before saturation as in BM25F
+func (h searchHit) weight(stats searchStats) float64 {
+	tf := titleBoost*float64(h.title)/lengthNorm(h.titleLength, stats.TitleLength, stats.Posts) +
+		float64(h.content)/lengthNorm(h.contentLength, stats.ContentLength, stats.Posts)
+	return tf * (bm25K1 + 1) / (tf + bm25K1)
+}
+
+// lengthNorm returns the BM25 length normalization of a field of length words
+// given the total length of the field over all posts
+func lengthNorm(length, total, posts int) float64 {
+	if total == 0 || posts == 0 {
+		return 1
+	}
+	avg := float64(total) / float64(posts)
+	return 1 - bm25B + bm25B*float64(length)/avg
+}
+
+// searchIDF returns the inverse document frequency of a term occurring in
+// df of posts posts
+func searchIDF(posts, df int) float64 {
+	posts = max(posts, df)
+	return math.Log(1 + (float64(posts)-float64(df)+0.5)/(float64(df)+0.5))
+}
+
+// parseSearchQuery splits a query into clauses
+func parseSearchQuery(query string) ([]searchClause, error) {
+	if utf8.RuneCountInString(query) > MaxQueryLength {
+		return nil, &domain.ValidationError{
+			Field:   "q",
+			Message: fmt.Sprintf("search query must be at most %d characters", MaxQueryLength),
+		}
+	}
+
+	var clauses []searchClause
+	for i, part := range strings.Split(query, `"`) {
+		// Odd parts are quoted; an unclosed quote runs to the end
+		if i%2 == 1 {
+			if terms := searchTerms(part); len(terms) > 0 {
+				clauses = append(clauses, searchClause{terms: terms})
+			}
+			continue
+		}
+
+		for _, word := range strings.Fields(part) {
+			prefix := strings.HasSuffix(word, "*")
+			terms := searchTerms(strings.TrimRight(word, "*"))
+			if len(terms) == 0 {
+				continue
+			}
+			// Words such as "e-mail" are split into a phrase
+			clauses = append(clauses, searchClause{terms: terms, prefix: prefix && len(terms) == 1})
+		}
+	}
+
+	if len(clauses) == 0 {
+		return nil, &domain.ValidationError{
+			Field:   "q",
+			Message: "search query must contain at least one word",
+		}
+	}
+	if len(clauses) > MaxQueryClauses {
+		return nil, &domain.ValidationError{
+			Field:   "q",
+			Message: fmt.Sprintf("search query must have at most %d words or phrases", MaxQueryClauses),
+		}
+	}
+
+	return clauses, nil
+}
+
+// formatSearchQuery returns the canonical form of parsed clauses, which
+// cursors record
+func formatSearchQuery(clauses []searchClause) string {
+	parts := make([]string, len(clauses))
+	for i, clause := range clauses {
+		switch {
+		case clause.prefix:
+			parts[i] = clause.terms[0] + "*"
+		case len(clause.terms) > 1:
+			parts[i] = `"` + strings.Join(clause.terms, " ") + `"`
+		default:
+			parts[i] = clause.terms[0]
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// tokenizeSearchText splits text into words of letters and digits, folded to
+// lower case. Words longer than maxTermLength are dropped.
+func tokenizeSearchText(text string) []searchToken {
+	var tokens []searchToken
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || (start >= 0 && unicode.IsMark(r)) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = appendSearchToken(tokens, text, start, i)
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = appendSearchToken(tokens, text, start, len(text))
+	}
+	return tokens
+}
+
+// appendSearchToken appends the word text[start:end] to tokens unless it is too long
+func appendSearchToken(tokens []searchToken, text string, start, end int) []searchToken {
+	term := strings.ToLower(text[start:end])
+	if utf8.RuneCountInString(term) > maxTermLength {
+		return tokens
+	}
+	return append(tokens, searchToken{term: term, start: start, end: end})
+}
+
+// searchTerms returns the terms of text
+func searchTerms(text string) []string {
+	tokens := tokenizeSearchText(text)
+	terms := make([]string, len(tokens))
+	for i, token := range tokens {
+		terms[i] = token.term
+	}
+	return terms
+}
+
+// buildSearchPostings returns the postings of a post by term
+func buildSearchPostings(post *domain.Post) map[string]*searchPosting {
+	title := tokenizeSearchText(post.Title)
+	content := tokenizeSearchText(post.Content)
+
+	postings := make(map[string]*searchPosting)
+	posting := func(term string) *searchPosting {
+		p, ok := postings[term]
+		if !ok {
+			p = &searchPosting{TitleLength: len(title), ContentLength: len(content)}
+			postings[term] = p
+		}
+		return p
+	}
+
+	for i, token := range title {
+		p := posting(token.term)
+		p.Title = append(p.Title, i)
+	}
+	for i, token := range content {
+		p := posting(token.term)
+		p.Content = append(p.Content, i)
+	}
+	return postings
+}
+
+// indexSearch adds a post to the inverted index and the search statistics
+func indexSearch(tx domain.Tx, ns, id string, post *domain.Post) error {
+	postings := buildSearchPostings(post)
+	for term, posting := range postings {
+		if err := tx.Set(searchTermKey(ns, term, id), posting); err != nil {
+			return &domain.StorageError{Err: err}
+		}
+	}
+	return addSearchStats(tx, ns, post, 1)
+}
+
+// unindexSearch removes a post from the inverted index and the search statistics
+func unindexSearch(tx domain.Tx, ns, id string, post *domain.Post) error {
+	for term := range buildSearchPostings(post) {
+		err := tx.Delete(searchTermKey(ns, term, id))
+		if err != nil && err != domain.ErrKeyNotFound {
+			return &domain.StorageError{Err: err}
+		}
+	}
+	return addSearchStats(tx, ns, post, -1)
+}
+
+// addSearchStats adds a post to the search statistics, or removes it if sign
+// is negative
+func addSearchStats(tx domain.Tx, ns string, post *domain.Post, sign int) error {
+	var stats searchStats
+	if err := tx.GetTyped(searchStatsKey(ns), &stats); err != nil && err != domain.ErrKeyNotFound {
+		return &domain.StorageError{Err: err}
+	}
+
+	stats.Posts += sign
+	stats.TitleLength += sign * len(tokenizeSearchText(post.Title))
+	stats.ContentLength += sign * len(tokenizeSearchText(post.Content))
+
+	if stats.Posts <= 0 {
+		if err := tx.Delete(searchStatsKey(ns)); err != nil && err != domain.ErrKeyNotFound {
+			return &domain.StorageError{Err: err}
+		}
+		return nil
+	}
+
+	stats.TitleLength = max(stats.TitleLength, 0)
+	stats.ContentLength = max(stats.ContentLength, 0)
+	if err := tx.Set(searchStatsKey(ns), stats); err != nil {
+		return &domain.StorageError{Err: err}
+	}
+	return nil
+}
+
+// highlightPost returns the title of a post and a snippet of its content with
+// the words matching clauses marked
+func highlightPost(post *domain.Post, clauses []searchClause) domain.SearchHighlights {
+	title := tokenizeSearchText(post.Title)
+	content := tokenizeSearchText(post.Content)
+	contentMarks := markSearchMatches(content, clauses)
+
+	// Show the window of the content with the most matches
+	from, best, count := 0, 0, 0
+	for i := range content {
+		if contentMarks[i] {
+			count++
+		}
+		if i >= snippetTokens && contentMarks[i-snippetTokens] {
+			count--
+		}
+		if count > best {
+			best, from = count, max(i-snippetTokens+1, 0)
+		}
+	}
+
+	// Lead into the window's first match with a few words of context
+	for i := from; best > 0 && i < len(content); i++ {
+		if contentMarks[i] {
+			from = max(i-snippetLead, 0)
+			break
+		}
+	}
+	to := min(from+snippetTokens, len(content))
+
+	return domain.SearchHighlights{
+		Title:   renderHighlight(post.Title, title, markSearchMatches(title, clauses), 0, len(title)),
+		Content: renderHighlight(post.Content, content, contentMarks, from, to),
+	}
+}
+
+// markSearchMatches reports for each token whether it is part of a match of
+// one of clauses
+func markSearchMatches(tokens []searchToken, clauses []searchClause) []bool {
+	marks := make([]bool, len(tokens))
+	for _, clause := range clauses {
+		for i := range tokens {
+			if i+len(clause.terms) > len(tokens) {
+				break
+			}
+
+			matched := true
+			for j, term := range clause.terms {
+				if clause.prefix {
+					matched = strings.HasPrefix(tokens[i+j].term, term)
+				} else if tokens[i+j].term != term {
+					matched = false
+				}
+				if !matched {
+					break
+				}
+			}
+
+			if matched {
+				for j := range clause.terms {
+					marks[i+j] = true
+				}
+			}
+		}
+	}
+	return marks
+}
+
+// renderHighlight returns the part of text spanned by tokens[from:to],
+// HTML-escaped with marked tokens wrapped in <mark> tags. The whole text is
+// rendered if the range covers all tokens; otherwise an ellipsis marks where
+// the text is cut.
+func renderHighlight(text string, tokens []searchToken, marks []bool, from, to int) string {
+	start, end := 0, len(text)
+	if from > 0 {
+		start = tokens[from].start
+	}
+	if to < len(tokens) {
+		end = tokens[to-1].end
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+
+	pos := start
+	for i := from; i < to; i++ {
+		if !marks[i] {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:tokens[i].start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[tokens[i].start:tokens[i].end]))
+		b.WriteString("</mark>")
+		pos = tokens[i].end
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// searchTermPrefix returns the key prefix of a term's postings
+func searchTermPrefix(ns, term string) string {
+	return ns + searchTermsPrefix + term + ":"
+}
+
+// searchTermKey generates the key of the posting of a term in a post
+func searchTermKey(ns, term, id string) string {
+	return searchTermPrefix(ns, term) + id
+}
+
+// splitSearchTermKey splits the key of a posting, without its prefixes, into
+// the term and the post ID
+func splitSearchTermKey(rest string) (term, id string, ok bool) {
+	i := strings.LastIndexByte(rest, ':')
+	if i <= 0 {
+		return "", "", false
+	}
+	return rest[:i], rest[i+1:], true
+}
+
+// searchStatsKey generates the key of the search statistics of the tenant
+// namespace ns
+func searchStatsKey(ns string) string {
+	return ns + "search-stats"
+}
\ No newline at end of file
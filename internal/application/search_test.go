@@ -0,0 +1,208 @@
+package application
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+func TestTokenizeSearchText(t *testing.T) {
+	long := strings.Repeat("a", maxTermLength+1)
+
+	tests := []struct {
+		text     string
+		expected []searchToken
+	}{
+		{"", nil},
+		{"Hello, World!", []searchToken{{"hello", 0, 5}, {"world", 7, 12}}},
+		{"e-mail v2.0", []searchToken{{"e", 0, 1}, {"mail", 2, 6}, {"v2", 7, 9}, {"0", 10, 11}}},
+		{"Café ÜBER", []searchToken{{"café", 0, 5}, {"über", 6, 11}}},
+		{"naïve", []searchToken{{"naïve", 0, 7}}},
+		{"short " + long + " end", []searchToken{{"short", 0, 5}, {"end", len(long) + 7, len(long) + 10}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if tokens := tokenizeSearchText(tt.text); !reflect.DeepEqual(tokens, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, tokens)
+			}
+		})
+	}
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		query     string
+		canonical string
+		wantErr   bool
+	}{
+		{"Go  Databases", "go databases", false},
+		{`"Hello, World" go`, `"hello world" go`, false},
+		{`data*`, "data*", false},
+		{`e-mail*`, `"e mail"`, false},
+		{`go "unclosed quote`, `go "unclosed quote"`, false},
+		{`"single"`, "single", false},
+		{"", "", true},
+		{`*** "" !`, "", true},
+		{strings.Repeat("w ", MaxQueryClauses+1), "", true},
+		{strings.Repeat("a", MaxQueryLength+1), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			clauses, err := parseSearchQuery(tt.query)
+			if tt.wantErr {
+				var validation *domain.ValidationError
+				if !errors.As(err, &validation) {
+					t.Errorf("Expected a validation error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to parse query: %v", err)
+			}
+			if canonical := formatSearchQuery(clauses); canonical != tt.canonical {
+				t.Errorf("Expected %q, got %q", tt.canonical, canonical)
+			}
+		})
+	}
+}
+
+// searchIDs returns the IDs of search results in order
+func searchIDs(results *domain.SearchResults) []string {
+	ids := make([]string, len(results.Results))
+	for i, result := range results.Results {
+		ids[i] = result.Post.ID
+	}
+	return ids
+}
+
+func TestPostService_SearchPosts(t *testing.T) {
+	s, _ := newTestPostService(t, PostQuotas{}, 0)
+	ctx := asAuthor("", "author-1")
+
+	titled := createPost(t, s, ctx, &domain.CreatePostRequest{Title: "Databases", Content: "An introduction to storage engines"})
+	repeated := createPost(t, s, ctx, &domain.CreatePostRequest{Title: "Notes", Content: "Databases, databases and more databases in storage"})
+	once := createPost(t, s, ctx, &domain.CreatePostRequest{Title: "Notes", Content: "Some databases are slow and others are fast but all of them store data somewhere on disk"})
+	phrase := createPost(t, s, ctx, &domain.CreatePostRequest{Title: "Engines", Content: "Storage engines for databases"})
+	createPost(t, s, ctx, &domain.CreatePostRequest{Title: "Draft databases", Content: "Not public", Status: domain.PostDraft})
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		// A match in the title counts more than one in the content, more
+		// occurrences more than fewer and shorter posts more than longer ones
+		{"ranking", "databases", []string{repeated.ID, titled.ID, phrase.ID, once.ID}},
+		{"every clause", "databases engines", []string{phrase.ID, titled.ID}},
+		{"phrase", `"storage engines"`, []string{phrase.ID, titled.ID}},
+		{"phrase out of order", `"engines storage"`, nil},
+		{"prefix", "engine*", []string{phrase.ID, titled.ID}},
+		// Rarer terms weigh more: only one post contains "store"
+		{"prefix of several terms", "stor*", []string{once.ID, phrase.ID, titled.ID, repeated.ID}},
+		{"no match", "missing", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := s.SearchPosts(asAuthor("", "author-2"), tt.query, "", 10)
+			if err != nil {
+				t.Fatalf("Failed to search: %v", err)
+			}
+			if ids := searchIDs(results); len(ids) != len(tt.expected) || (len(ids) > 0 && !reflect.DeepEqual(ids, tt.expected)) {
+				t.Errorf("Expected %v, got %v", tt.expected, ids)
+			}
+			for i := 1; i < len(results.Results); i++ {
+				if results.Results[i].Score > results.Results[i-1].Score {
+					t.Errorf("Expected results best first, got %v", results.Results)
+				}
+			}
+		})
+	}
+
+	// Authors find their own drafts
+	results, err := s.SearchPosts(ctx, "draft", "", 10)
+	if err != nil || len(results.Results) != 1 {
+		t.Errorf("Expected the author to find their draft, got %+v, %v", results, err)
+	}
+}
+
+func TestPostService_SearchHighlights(t *testing.T) {
+	s, _ := newTestPostService(t, PostQuotas{}, 0)
+	ctx := asAuthor("", "author-1")
+
+	words := make([]string, 80)
+	for i := range words {
+		words[i] = "filler"
+	}
+	words[40] = "Go"
+	createPost(t, s, ctx, &domain.CreatePostRequest{Title: `<script>alert("go")</script> & Go`, Content: strings.Join(words, " ")})
+
+	results, err := s.SearchPosts(ctx, "go", "", 10)
+	if err != nil || len(results.Results) != 1 {
+		t.Fatalf("Expected one result, got %+v, %v", results, err)
+	}
+	highlights := results.Results[0].Highlights
+
+	expectedTitle := `&lt;script&gt;alert(&#34;<mark>go</mark>&#34;)&lt;/script&gt; &amp; <mark>Go</mark>`
+	if highlights.Title != expectedTitle {
+		t.Errorf("Expected title %q, got %q", expectedTitle, highlights.Title)
+	}
+
+	// The snippet leads into the match and marks where the content is cut
+	expectedContent := "…" + strings.Repeat("filler ", snippetLead) + "<mark>Go</mark>" +
+		strings.Repeat(" filler", snippetTokens-snippetLead-1) + "…"
+	if highlights.Content != expectedContent {
+		t.Errorf("Expected content %q, got %q", expectedContent, highlights.Content)
+	}
+}
+
+func TestPostService_SearchPaging(t *testing.T) {
+	s, _ := newTestPostService(t, PostQuotas{}, 0)
+	ctx := asAuthor("", "author-1")
+
+	want := make(map[string]bool)
+	for _, post := range createPosts(t, s, ctx, 5) {
+		want[post.ID] = true
+	}
+
+	page, err := s.SearchPosts(ctx, "content", "", 2)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if page.NextCursor == "" {
+		t.Fatal("Expected a cursor to the next page")
+	}
+
+	var unrelated *domain.PaginationError
+	if _, err := s.SearchPosts(ctx, "title", page.NextCursor, 2); !errors.As(err, &unrelated) {
+		t.Errorf("Expected a cursor of another query to be rejected, got %v", err)
+	}
+
+	// Writing posts between pages changes every score, which must not make
+	// pages overlap or skip results
+	createPost(t, s, ctx, &domain.CreatePostRequest{Title: "Other", Content: "Content and more content"})
+
+	seen := make(map[string]bool)
+	for {
+		for _, id := range searchIDs(page) {
+			if seen[id] {
+				t.Fatalf("Result %s was listed twice", id)
+			}
+			seen[id] = true
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		if page, err = s.SearchPosts(ctx, "content", page.NextCursor, 2); err != nil {
+			t.Fatalf("Failed to search next page: %v", err)
+		}
+	}
+
+	for id := range want {
+		if !seen[id] {
+			t.Errorf("Result %s was skipped", id)
+		}
+	}
+}
\ No newline at end of file
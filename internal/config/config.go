@@ -2,8 +2,11 @@ package config
 
 import (
 	_ "embed"
+	"encoding/base64"
 	"fmt"
+	"net/url"
 	"os"
+	"regexp"
 	"strings"
 	"time"
 
@@ -15,11 +18,15 @@ var defaultConfig []byte
 
 // Config represents the application configuration
 type Config struct {
-	Server  ServerConfig  `yaml:"server"`
-	Logging LoggingConfig `yaml:"logging"`
-	Storage StorageConfig `yaml:"storage"`
-	Debug   DebugConfig   `yaml:"debug"`
-	CORS    CORSConfig    `yaml:"cors"`
+	Server      ServerConfig      `yaml:"server"`
+	Logging     LoggingConfig     `yaml:"logging"`
+	Storage     StorageConfig     `yaml:"storage"`
+	Replication ReplicationConfig `yaml:"replication"`
+	Tenancy     TenancyConfig     `yaml:"tenancy"`
+	Authors     AuthorsConfig     `yaml:"authors"`
+	Posts       PostsConfig       `yaml:"posts"`
+	Debug       DebugConfig       `yaml:"debug"`
+	CORS        CORSConfig        `yaml:"cors"`
 }
 
 // ServerConfig represents server configuration
@@ -40,13 +47,134 @@ type LoggingConfig struct {
 
 // StorageConfig represents storage configuration
 type StorageConfig struct {
-	Type string `yaml:"type"`
+	Type             string              `yaml:"type"`
+	Path             string              `yaml:"path"`
+	Fsync            string              `yaml:"fsync"`
+	Codec            string              `yaml:"codec"`
+	FsyncInterval    time.Duration       `yaml:"fsyncInterval"`
+	SnapshotInterval time.Duration       `yaml:"snapshotInterval"`
+	SweepInterval    time.Duration       `yaml:"sweepInterval"`
+	Shards           int                 `yaml:"shards"`
+	Limits           StorageLimitsConfig `yaml:"limits"`
+	Encryption       EncryptionConfig    `yaml:"encryption"`
+}
+
+// StorageLimitsConfig caps the size of the store
+type StorageLimitsConfig struct {
+	// MaxItems and MaxBytes cap the number of entries and the total size of
+	// keys and values; zero means no limit
+	MaxItems int   `yaml:"maxItems"`
+	MaxBytes int64 `yaml:"maxBytes"`
+
+	// Policy is "reject" or "evict" and applies to keys without a matching
+	// entry in Prefixes, which selects the policy by key prefix. Rejecting
+	// fails writes that do not fit; evicting removes the least recently used
+	// entries to make room.
+	Policy   string            `yaml:"policy"`
+	Prefixes map[string]string `yaml:"prefixes"`
+}
+
+// EncryptionConfig configures encryption of stored values. Encryption is
+// enabled when Key or KeyFile is set.
+type EncryptionConfig struct {
+	// Key is a base64 encoded AES-128, AES-192 or AES-256 key identified by KeyID
+	Key   string `yaml:"key"`
+	KeyID string `yaml:"keyId"`
+
+	// KeyFile holds further keys, one "keyID base64key" pair per line, so
+	// values encrypted with retired keys stay readable
+	KeyFile string `yaml:"keyFile"`
+
+	// ActiveKeyID selects the key new values are encrypted with. It defaults
+	// to KeyID if Key is set, otherwise to the last key in KeyFile.
+	ActiveKeyID string `yaml:"activeKeyId"`
+
+	// AllowPlaintext lets values written before encryption was enabled be
+	// read as they are until the reencrypt command has encrypted them.
+	// Otherwise reading them fails.
+	AllowPlaintext bool `yaml:"allowPlaintext"`
+}
+
+// ReplicationConfig represents replication configuration
+type ReplicationConfig struct {
+	// Role is "standalone", "leader" or "follower". A leader streams its
+	// changes to followers, which apply them to their own store, serve reads
+	// and forward or reject writes.
+	Role string `yaml:"role"`
+
+	// LeaderURL is the base URL of the leader a follower replicates from
+	LeaderURL string `yaml:"leaderUrl"`
+
+	// Token authenticates followers with the leader; both must use the same one
+	Token string `yaml:"token"`
+
+	// Writes is "forward" to proxy writes sent to a follower to the leader,
+	// or "reject" to refuse them
+	Writes string `yaml:"writes"`
+
+	HeartbeatInterval time.Duration `yaml:"heartbeatInterval"`
+	RetryInterval     time.Duration `yaml:"retryInterval"`
+
+	// MaxLag is how far behind its leader a follower may fall before it
+	// reports itself unhealthy
+	MaxLag time.Duration `yaml:"maxLag"`
+}
+
+// TenancyConfig represents multi-tenancy configuration. With tenancy
+// enabled, each request is resolved to a tenant whose posts are stored under
+// their own key namespace.
+type TenancyConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Resolver is "header", "host" or "path" and selects where the tenant is
+	// read from: the Header request header, the subdomain of BaseDomain in
+	// the host name, or a "/tenants/{tenant}" path prefix
+	Resolver   string `yaml:"resolver"`
+	Header     string `yaml:"header"`
+	BaseDomain string `yaml:"baseDomain"`
+
+	// DefaultTenant serves requests that do not name a tenant; if empty,
+	// such requests are rejected
+	DefaultTenant string `yaml:"defaultTenant"`
+
+	// Quota applies to every tenant without an entry in Tenants
+	Quota   TenantQuotaConfig            `yaml:"quota"`
+	Tenants map[string]TenantQuotaConfig `yaml:"tenants"`
+}
+
+// TenantQuotaConfig limits what a tenant may store
+type TenantQuotaConfig struct {
+	// MaxPosts caps the number of posts; zero means no limit
+	MaxPosts int `yaml:"maxPosts"`
+}
+
+// AuthorsConfig represents author and authentication configuration
+type AuthorsConfig struct {
+	// OpenRegistration lets anyone create an author; otherwise only callers
+	// holding AdminToken may
+	OpenRegistration bool `yaml:"openRegistration"`
+
+	// AdminToken is the bearer token of admins, who may modify any post or
+	// author; no caller is an admin while it is empty
+	AdminToken string `yaml:"adminToken"`
+}
+
+// PostsConfig represents post lifecycle configuration
+type PostsConfig struct {
+	// SchedulerInterval is how often scheduled posts that are due are published
+	SchedulerInterval time.Duration `yaml:"schedulerInterval"`
+
+	// MaxRevisions is how many earlier revisions are kept per post; zero keeps all
+	MaxRevisions int `yaml:"maxRevisions"`
 }
 
 // DebugConfig represents debug configuration
 type DebugConfig struct {
 	Metrics MetricsConfig `yaml:"metrics"`
 	Pprof   PprofConfig   `yaml:"pprof"`
+	// Token is the bearer token required by sensitive debug endpoints such
+	// as backups; they reject every request while it is empty
+	Token string `yaml:"token"`
 }
 
 // MetricsConfig represents metrics configuration
@@ -147,6 +275,228 @@ func overrideFromEnv(config *Config) error {
 		config.Storage.Type = storageType
 	}
 
+	if storagePath := os.Getenv("STORAGE_PATH"); storagePath != "" {
+		config.Storage.Path = storagePath
+	}
+
+	if fsync := os.Getenv("STORAGE_FSYNC"); fsync != "" {
+		config.Storage.Fsync = fsync
+	}
+
+	if codec := os.Getenv("STORAGE_CODEC"); codec != "" {
+		config.Storage.Codec = codec
+	}
+
+	if fsyncInterval := os.Getenv("STORAGE_FSYNC_INTERVAL"); fsyncInterval != "" {
+		if fi, err := time.ParseDuration(fsyncInterval); err != nil {
+			return fmt.Errorf("invalid STORAGE_FSYNC_INTERVAL: %w", err)
+		} else {
+			config.Storage.FsyncInterval = fi
+		}
+	}
+
+	if snapshotInterval := os.Getenv("STORAGE_SNAPSHOT_INTERVAL"); snapshotInterval != "" {
+		if si, err := time.ParseDuration(snapshotInterval); err != nil {
+			return fmt.Errorf("invalid STORAGE_SNAPSHOT_INTERVAL: %w", err)
+		} else {
+			config.Storage.SnapshotInterval = si
+		}
+	}
+
+	if sweepInterval := os.Getenv("STORAGE_SWEEP_INTERVAL"); sweepInterval != "" {
+		if si, err := time.ParseDuration(sweepInterval); err != nil {
+			return fmt.Errorf("invalid STORAGE_SWEEP_INTERVAL: %w", err)
+		} else {
+			config.Storage.SweepInterval = si
+		}
+	}
+
+	if shards := os.Getenv("STORAGE_SHARDS"); shards != "" {
+		if sh, err := parseInt(shards); err != nil {
+			return fmt.Errorf("invalid STORAGE_SHARDS: %w", err)
+		} else {
+			config.Storage.Shards = sh
+		}
+	}
+
+	if maxItems := os.Getenv("STORAGE_MAX_ITEMS"); maxItems != "" {
+		if mi, err := parseInt(maxItems); err != nil {
+			return fmt.Errorf("invalid STORAGE_MAX_ITEMS: %w", err)
+		} else {
+			config.Storage.Limits.MaxItems = mi
+		}
+	}
+
+	if maxBytes := os.Getenv("STORAGE_MAX_BYTES"); maxBytes != "" {
+		if mb, err := parseInt64(maxBytes); err != nil {
+			return fmt.Errorf("invalid STORAGE_MAX_BYTES: %w", err)
+		} else {
+			config.Storage.Limits.MaxBytes = mb
+		}
+	}
+
+	if policy := os.Getenv("STORAGE_EVICTION_POLICY"); policy != "" {
+		config.Storage.Limits.Policy = policy
+	}
+
+	// STORAGE_EVICTION_PREFIXES replaces the prefix policies with a comma
+	// separated list of prefix=policy pairs, e.g. "cache:=evict,posts:=reject"
+	if prefixes := os.Getenv("STORAGE_EVICTION_PREFIXES"); prefixes != "" {
+		config.Storage.Limits.Prefixes = make(map[string]string)
+		for _, pair := range strings.Split(prefixes, ",") {
+			prefix, policy, ok := strings.Cut(pair, "=")
+			if !ok || prefix == "" {
+				return fmt.Errorf("invalid STORAGE_EVICTION_PREFIXES: %q is not prefix=policy", pair)
+			}
+			config.Storage.Limits.Prefixes[prefix] = policy
+		}
+	}
+
+	if key := os.Getenv("STORAGE_ENCRYPTION_KEY"); key != "" {
+		config.Storage.Encryption.Key = key
+	}
+
+	if keyID := os.Getenv("STORAGE_ENCRYPTION_KEY_ID"); keyID != "" {
+		config.Storage.Encryption.KeyID = keyID
+	}
+
+	if keyFile := os.Getenv("STORAGE_ENCRYPTION_KEY_FILE"); keyFile != "" {
+		config.Storage.Encryption.KeyFile = keyFile
+	}
+
+	if activeKeyID := os.Getenv("STORAGE_ENCRYPTION_ACTIVE_KEY_ID"); activeKeyID != "" {
+		config.Storage.Encryption.ActiveKeyID = activeKeyID
+	}
+
+	if allowPlaintext := os.Getenv("STORAGE_ENCRYPTION_ALLOW_PLAINTEXT"); allowPlaintext != "" {
+		if allow, err := parseBool(allowPlaintext); err != nil {
+			return fmt.Errorf("invalid STORAGE_ENCRYPTION_ALLOW_PLAINTEXT: %w", err)
+		} else {
+			config.Storage.Encryption.AllowPlaintext = allow
+		}
+	}
+
+	// Replication configuration
+	if role := os.Getenv("REPLICATION_ROLE"); role != "" {
+		config.Replication.Role = role
+	}
+
+	if leaderURL := os.Getenv("REPLICATION_LEADER_URL"); leaderURL != "" {
+		config.Replication.LeaderURL = leaderURL
+	}
+
+	if token := os.Getenv("REPLICATION_TOKEN"); token != "" {
+		config.Replication.Token = token
+	}
+
+	if writes := os.Getenv("REPLICATION_WRITES"); writes != "" {
+		config.Replication.Writes = writes
+	}
+
+	if heartbeatInterval := os.Getenv("REPLICATION_HEARTBEAT_INTERVAL"); heartbeatInterval != "" {
+		if hi, err := time.ParseDuration(heartbeatInterval); err != nil {
+			return fmt.Errorf("invalid REPLICATION_HEARTBEAT_INTERVAL: %w", err)
+		} else {
+			config.Replication.HeartbeatInterval = hi
+		}
+	}
+
+	if retryInterval := os.Getenv("REPLICATION_RETRY_INTERVAL"); retryInterval != "" {
+		if ri, err := time.ParseDuration(retryInterval); err != nil {
+			return fmt.Errorf("invalid REPLICATION_RETRY_INTERVAL: %w", err)
+		} else {
+			config.Replication.RetryInterval = ri
+		}
+	}
+
+	if maxLag := os.Getenv("REPLICATION_MAX_LAG"); maxLag != "" {
+		if ml, err := time.ParseDuration(maxLag); err != nil {
+			return fmt.Errorf("invalid REPLICATION_MAX_LAG: %w", err)
+		} else {
+			config.Replication.MaxLag = ml
+		}
+	}
+
+	// Tenancy configuration
+	if tenancyEnabled := os.Getenv("TENANCY_ENABLED"); tenancyEnabled != "" {
+		if enabled, err := parseBool(tenancyEnabled); err != nil {
+			return fmt.Errorf("invalid TENANCY_ENABLED: %w", err)
+		} else {
+			config.Tenancy.Enabled = enabled
+		}
+	}
+
+	if resolver := os.Getenv("TENANCY_RESOLVER"); resolver != "" {
+		config.Tenancy.Resolver = resolver
+	}
+
+	if header := os.Getenv("TENANCY_HEADER"); header != "" {
+		config.Tenancy.Header = header
+	}
+
+	if baseDomain := os.Getenv("TENANCY_BASE_DOMAIN"); baseDomain != "" {
+		config.Tenancy.BaseDomain = baseDomain
+	}
+
+	if defaultTenant := os.Getenv("TENANCY_DEFAULT_TENANT"); defaultTenant != "" {
+		config.Tenancy.DefaultTenant = defaultTenant
+	}
+
+	if maxPosts := os.Getenv("TENANCY_MAX_POSTS"); maxPosts != "" {
+		if mp, err := parseInt(maxPosts); err != nil {
+			return fmt.Errorf("invalid TENANCY_MAX_POSTS: %w", err)
+		} else {
+			config.Tenancy.Quota.MaxPosts = mp
+		}
+	}
+
+	// TENANCY_TENANT_MAX_POSTS replaces the per-tenant quotas with a comma
+	// separated list of tenant=maxPosts pairs, e.g. "acme=1000,demo=10"
+	if tenantMaxPosts := os.Getenv("TENANCY_TENANT_MAX_POSTS"); tenantMaxPosts != "" {
+		config.Tenancy.Tenants = make(map[string]TenantQuotaConfig)
+		for _, pair := range strings.Split(tenantMaxPosts, ",") {
+			tenant, limit, ok := strings.Cut(pair, "=")
+			if !ok || tenant == "" {
+				return fmt.Errorf("invalid TENANCY_TENANT_MAX_POSTS: %q is not tenant=maxPosts", pair)
+			}
+			mp, err := parseInt(limit)
+			if err != nil {
+				return fmt.Errorf("invalid TENANCY_TENANT_MAX_POSTS: %q: %w", pair, err)
+			}
+			config.Tenancy.Tenants[tenant] = TenantQuotaConfig{MaxPosts: mp}
+		}
+	}
+
+	// Authors configuration
+	if openRegistration := os.Getenv("AUTHORS_OPEN_REGISTRATION"); openRegistration != "" {
+		if enabled, err := parseBool(openRegistration); err != nil {
+			return fmt.Errorf("invalid AUTHORS_OPEN_REGISTRATION: %w", err)
+		} else {
+			config.Authors.OpenRegistration = enabled
+		}
+	}
+
+	if adminToken := os.Getenv("AUTHORS_ADMIN_TOKEN"); adminToken != "" {
+		config.Authors.AdminToken = adminToken
+	}
+
+	// Posts configuration
+	if schedulerInterval := os.Getenv("POSTS_SCHEDULER_INTERVAL"); schedulerInterval != "" {
+		if si, err := time.ParseDuration(schedulerInterval); err != nil {
+			return fmt.Errorf("invalid POSTS_SCHEDULER_INTERVAL: %w", err)
+		} else {
+			config.Posts.SchedulerInterval = si
+		}
+	}
+
+	if maxRevisions := os.Getenv("POSTS_MAX_REVISIONS"); maxRevisions != "" {
+		if mr, err := parseInt(maxRevisions); err != nil {
+			return fmt.Errorf("invalid POSTS_MAX_REVISIONS: %w", err)
+		} else {
+			config.Posts.MaxRevisions = mr
+		}
+	}
+
 	// Debug configuration
 	if metricsEnabled := os.Getenv("DEBUG_METRICS_ENABLED"); metricsEnabled != "" {
 		if enabled, err := parseBool(metricsEnabled); err != nil {
@@ -172,6 +522,10 @@ func overrideFromEnv(config *Config) error {
 		config.Debug.Pprof.Path = pprofPath
 	}
 
+	if token := os.Getenv("DEBUG_TOKEN"); token != "" {
+		config.Debug.Token = token
+	}
+
 	// CORS configuration
 	if allowedOrigins := os.Getenv("CORS_ALLOWED_ORIGINS"); allowedOrigins != "" {
 		config.CORS.AllowedOrigins = strings.Split(allowedOrigins, ",")
@@ -227,14 +581,172 @@ func validate(config *Config) error {
 	}
 
 	// Storage validation
-	validStorageTypes := map[string]bool{"memory": true}
+	validStorageTypes := map[string]bool{"memory": true, "sharded": true, "file": true}
 	if !validStorageTypes[config.Storage.Type] {
 		return fmt.Errorf("invalid storage type: %s", config.Storage.Type)
 	}
 
+	validCodecs := map[string]bool{"json": true, "gob": true, "binary": true}
+	if !validCodecs[config.Storage.Codec] {
+		return fmt.Errorf("invalid storage codec: %s", config.Storage.Codec)
+	}
+
+	if config.Storage.SweepInterval <= 0 {
+		return fmt.Errorf("invalid storage sweep interval: %v", config.Storage.SweepInterval)
+	}
+
+	if config.Storage.Limits.MaxItems < 0 {
+		return fmt.Errorf("invalid storage max items: %d", config.Storage.Limits.MaxItems)
+	}
+
+	if config.Storage.Limits.MaxBytes < 0 {
+		return fmt.Errorf("invalid storage max bytes: %d", config.Storage.Limits.MaxBytes)
+	}
+
+	validEvictionPolicies := map[string]bool{"reject": true, "evict": true}
+	if !validEvictionPolicies[config.Storage.Limits.Policy] {
+		return fmt.Errorf("invalid storage eviction policy: %s", config.Storage.Limits.Policy)
+	}
+	for prefix, policy := range config.Storage.Limits.Prefixes {
+		if !validEvictionPolicies[policy] {
+			return fmt.Errorf("invalid storage eviction policy for prefix %q: %s", prefix, policy)
+		}
+	}
+
+	if encryption := config.Storage.Encryption; encryption.Key != "" {
+		if encryption.KeyID == "" {
+			return fmt.Errorf("storage encryption key ID is required")
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encryption.Key)
+		if err != nil {
+			return fmt.Errorf("invalid storage encryption key: %w", err)
+		}
+		if n := len(key); n != 16 && n != 24 && n != 32 {
+			return fmt.Errorf("invalid storage encryption key: %d bytes, expected 16, 24 or 32", n)
+		}
+	}
+
+	if config.Storage.Type == "sharded" {
+		if config.Storage.Shards <= 0 {
+			return fmt.Errorf("invalid storage shard count: %d", config.Storage.Shards)
+		}
+
+		if config.Storage.Limits.MaxItems > 0 || config.Storage.Limits.MaxBytes > 0 {
+			return fmt.Errorf("storage limits are not supported by sharded storage")
+		}
+	}
+
+	if config.Storage.Type == "file" {
+		if config.Storage.Path == "" {
+			return fmt.Errorf("storage path is required for file storage")
+		}
+
+		validFsyncPolicies := map[string]bool{"always": true, "interval": true, "never": true}
+		if !validFsyncPolicies[config.Storage.Fsync] {
+			return fmt.Errorf("invalid storage fsync policy: %s", config.Storage.Fsync)
+		}
+
+		if config.Storage.Fsync == "interval" && config.Storage.FsyncInterval <= 0 {
+			return fmt.Errorf("invalid storage fsync interval: %v", config.Storage.FsyncInterval)
+		}
+
+		if config.Storage.SnapshotInterval < 0 {
+			return fmt.Errorf("invalid storage snapshot interval: %v", config.Storage.SnapshotInterval)
+		}
+	}
+
+	// Replication validation
+	validRoles := map[string]bool{"standalone": true, "leader": true, "follower": true}
+	if !validRoles[config.Replication.Role] {
+		return fmt.Errorf("invalid replication role: %s", config.Replication.Role)
+	}
+
+	if config.Replication.Role != "standalone" {
+		if config.Replication.Token == "" {
+			return fmt.Errorf("replication token is required for role %s", config.Replication.Role)
+		}
+
+		if config.Replication.HeartbeatInterval <= 0 {
+			return fmt.Errorf("invalid replication heartbeat interval: %v", config.Replication.HeartbeatInterval)
+		}
+	}
+
+	if config.Replication.Role == "follower" {
+		leader, err := url.Parse(config.Replication.LeaderURL)
+		if err != nil || (leader.Scheme != "http" && leader.Scheme != "https") || leader.Host == "" {
+			return fmt.Errorf("invalid replication leader URL: %q", config.Replication.LeaderURL)
+		}
+
+		validWrites := map[string]bool{"forward": true, "reject": true}
+		if !validWrites[config.Replication.Writes] {
+			return fmt.Errorf("invalid replication writes mode: %s", config.Replication.Writes)
+		}
+
+		if config.Replication.RetryInterval <= 0 {
+			return fmt.Errorf("invalid replication retry interval: %v", config.Replication.RetryInterval)
+		}
+
+		if config.Replication.MaxLag < 0 {
+			return fmt.Errorf("invalid replication max lag: %v", config.Replication.MaxLag)
+		}
+	}
+
+	// Tenancy validation
+	if config.Tenancy.Enabled {
+		switch config.Tenancy.Resolver {
+		case "header":
+			if config.Tenancy.Header == "" {
+				return fmt.Errorf("tenancy header is required for the header resolver")
+			}
+		case "host":
+			if config.Tenancy.BaseDomain == "" {
+				return fmt.Errorf("tenancy base domain is required for the host resolver")
+			}
+		case "path":
+		default:
+			return fmt.Errorf("invalid tenancy resolver: %s", config.Tenancy.Resolver)
+		}
+
+		if config.Tenancy.DefaultTenant != "" && !tenantIDPattern.MatchString(config.Tenancy.DefaultTenant) {
+			return fmt.Errorf("invalid default tenant: %q", config.Tenancy.DefaultTenant)
+		}
+	}
+
+	if config.Tenancy.Quota.MaxPosts < 0 {
+		return fmt.Errorf("invalid tenancy max posts: %d", config.Tenancy.Quota.MaxPosts)
+	}
+
+	for tenant, quota := range config.Tenancy.Tenants {
+		if !tenantIDPattern.MatchString(tenant) {
+			return fmt.Errorf("invalid tenant ID in quotas: %q", tenant)
+		}
+		if quota.MaxPosts < 0 {
+			return fmt.Errorf("invalid max posts for tenant %s: %d", tenant, quota.MaxPosts)
+		}
+	}
+
+	// Authors validation
+	if !config.Authors.OpenRegistration && config.Authors.AdminToken == "" {
+		return fmt.Errorf("an authors admin token is required when registration is closed")
+	}
+
+	// Posts validation
+	if config.Posts.SchedulerInterval <= 0 {
+		return fmt.Errorf("invalid posts scheduler interval: %v", config.Posts.SchedulerInterval)
+	}
+
+	if config.Posts.MaxRevisions < 0 {
+		return fmt.Errorf("invalid posts max revisions: %d", config.Posts.MaxRevisions)
+	}
+
 	return nil
 }
 
+// tenantIDPattern matches valid tenant IDs: lowercase letters, digits and
+// hyphens, usable as a host name label
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9-]{1,63}$`)
+
 // Helper functions for parsing environment variables
 func parseInt(s string) (int, error) {
 	var i int
@@ -242,6 +754,12 @@ func parseInt(s string) (int, error) {
 	return i, err
 }
 
+func parseInt64(s string) (int64, error) {
+	var i int64
+	_, err := fmt.Sscanf(s, "%d", &i)
+	return i, err
+}
+
 func parseBool(s string) (bool, error) {
 	switch strings.ToLower(s) {
 	case "true", "1", "yes", "on":
@@ -48,6 +48,7 @@ func TestEnvironmentVariableOverrides(t *testing.T) {
 	os.Setenv("DEBUG_METRICS_ENABLED", "true")
 	os.Setenv("DEBUG_PPROF_ENABLED", "false")
 	os.Setenv("CORS_MAX_AGE", "3600")
+	os.Setenv("DEBUG_TOKEN", "secret")
 
 	defer func() {
 		os.Unsetenv("SERVER_PORT")
@@ -61,6 +62,7 @@ func TestEnvironmentVariableOverrides(t *testing.T) {
 		os.Unsetenv("DEBUG_METRICS_ENABLED")
 		os.Unsetenv("DEBUG_PPROF_ENABLED")
 		os.Unsetenv("CORS_MAX_AGE")
+		os.Unsetenv("DEBUG_TOKEN")
 	}()
 
 	config, err := Load()
@@ -108,6 +110,10 @@ func TestEnvironmentVariableOverrides(t *testing.T) {
 	if config.CORS.MaxAge != 3600 {
 		t.Errorf("Expected CORS max age 3600, got %d", config.CORS.MaxAge)
 	}
+
+	if config.Debug.Token != "secret" {
+		t.Errorf("Expected debug token secret, got %s", config.Debug.Token)
+	}
 }
 
 func TestInvalidEnvironmentVariables(t *testing.T) {
@@ -295,4 +301,422 @@ func TestInvalidConfigurations(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error for invalid storage type but got none")
 	}
+}
+func TestFileStorageConfiguration(t *testing.T) {
+	os.Setenv("STORAGE_TYPE", "file")
+	os.Setenv("STORAGE_PATH", "/tmp/boilerplate")
+	os.Setenv("STORAGE_FSYNC", "always")
+	os.Setenv("STORAGE_SNAPSHOT_INTERVAL", "1m")
+	os.Setenv("STORAGE_SWEEP_INTERVAL", "30s")
+	os.Setenv("STORAGE_CODEC", "binary")
+
+	defer func() {
+		os.Unsetenv("STORAGE_TYPE")
+		os.Unsetenv("STORAGE_PATH")
+		os.Unsetenv("STORAGE_FSYNC")
+		os.Unsetenv("STORAGE_SNAPSHOT_INTERVAL")
+		os.Unsetenv("STORAGE_SWEEP_INTERVAL")
+		os.Unsetenv("STORAGE_CODEC")
+	}()
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load file storage config: %v", err)
+	}
+
+	if config.Storage.Type != "file" {
+		t.Errorf("Expected storage type file, got %s", config.Storage.Type)
+	}
+
+	if config.Storage.Path != "/tmp/boilerplate" {
+		t.Errorf("Expected storage path /tmp/boilerplate, got %s", config.Storage.Path)
+	}
+
+	if config.Storage.Fsync != "always" {
+		t.Errorf("Expected fsync always, got %s", config.Storage.Fsync)
+	}
+
+	if config.Storage.SnapshotInterval != time.Minute {
+		t.Errorf("Expected snapshot interval 1m, got %v", config.Storage.SnapshotInterval)
+	}
+
+	if config.Storage.SweepInterval != 30*time.Second {
+		t.Errorf("Expected sweep interval 30s, got %v", config.Storage.SweepInterval)
+	}
+
+	if config.Storage.Codec != "binary" {
+		t.Errorf("Expected codec binary, got %s", config.Storage.Codec)
+	}
+
+	// Invalid fsync policy
+	os.Setenv("STORAGE_FSYNC", "sometimes")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for invalid fsync policy but got none")
+	}
+
+	// Invalid snapshot interval
+	os.Setenv("STORAGE_FSYNC", "always")
+	os.Setenv("STORAGE_SNAPSHOT_INTERVAL", "invalid")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for invalid snapshot interval but got none")
+	}
+
+	// Invalid codec
+	os.Setenv("STORAGE_SNAPSHOT_INTERVAL", "1m")
+	os.Setenv("STORAGE_CODEC", "xml")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for invalid codec but got none")
+	}
+
+	// Sweeping cannot be disabled
+	os.Setenv("STORAGE_CODEC", "binary")
+	os.Setenv("STORAGE_SNAPSHOT_INTERVAL", "1m")
+	os.Setenv("STORAGE_SWEEP_INTERVAL", "0s")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for zero sweep interval but got none")
+	}
+}
+
+func TestStorageLimitsConfiguration(t *testing.T) {
+	os.Setenv("STORAGE_MAX_ITEMS", "1000")
+	os.Setenv("STORAGE_MAX_BYTES", "1048576")
+	os.Setenv("STORAGE_EVICTION_PREFIXES", "cache:=evict,sessions:=evict")
+
+	defer func() {
+		os.Unsetenv("STORAGE_MAX_ITEMS")
+		os.Unsetenv("STORAGE_MAX_BYTES")
+		os.Unsetenv("STORAGE_EVICTION_POLICY")
+		os.Unsetenv("STORAGE_EVICTION_PREFIXES")
+	}()
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load storage limits config: %v", err)
+	}
+
+	limits := config.Storage.Limits
+	if limits.MaxItems != 1000 {
+		t.Errorf("Expected max items 1000, got %d", limits.MaxItems)
+	}
+
+	if limits.MaxBytes != 1048576 {
+		t.Errorf("Expected max bytes 1048576, got %d", limits.MaxBytes)
+	}
+
+	if limits.Policy != "reject" {
+		t.Errorf("Expected default policy reject, got %s", limits.Policy)
+	}
+
+	if len(limits.Prefixes) != 2 || limits.Prefixes["cache:"] != "evict" || limits.Prefixes["sessions:"] != "evict" {
+		t.Errorf("Expected evict policy for cache: and sessions:, got %v", limits.Prefixes)
+	}
+
+	// Invalid default policy
+	os.Setenv("STORAGE_EVICTION_POLICY", "drop")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for invalid eviction policy but got none")
+	}
+
+	// Invalid prefix policy
+	os.Setenv("STORAGE_EVICTION_POLICY", "evict")
+	os.Setenv("STORAGE_EVICTION_PREFIXES", "cache:=lru")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for invalid prefix eviction policy but got none")
+	}
+
+	// Malformed prefix list
+	os.Setenv("STORAGE_EVICTION_PREFIXES", "cache:")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for malformed eviction prefixes but got none")
+	}
+
+	// Negative limits
+	os.Setenv("STORAGE_EVICTION_PREFIXES", "cache:=evict")
+	os.Setenv("STORAGE_MAX_ITEMS", "-1")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for negative max items but got none")
+	}
+}
+
+func TestShardedStorageConfiguration(t *testing.T) {
+	os.Setenv("STORAGE_TYPE", "sharded")
+	os.Setenv("STORAGE_SHARDS", "32")
+
+	defer func() {
+		os.Unsetenv("STORAGE_TYPE")
+		os.Unsetenv("STORAGE_SHARDS")
+		os.Unsetenv("STORAGE_MAX_ITEMS")
+	}()
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load sharded storage config: %v", err)
+	}
+
+	if config.Storage.Shards != 32 {
+		t.Errorf("Expected 32 shards, got %d", config.Storage.Shards)
+	}
+
+	// Invalid shard count
+	os.Setenv("STORAGE_SHARDS", "0")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for zero shards but got none")
+	}
+
+	// Limits are not supported
+	os.Setenv("STORAGE_SHARDS", "16")
+	os.Setenv("STORAGE_MAX_ITEMS", "100")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for limits with sharded storage but got none")
+	}
+}
+
+func TestStorageEncryptionConfiguration(t *testing.T) {
+	os.Setenv("STORAGE_ENCRYPTION_KEY", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	os.Setenv("STORAGE_ENCRYPTION_KEY_ID", "2024-01")
+	os.Setenv("STORAGE_ENCRYPTION_ALLOW_PLAINTEXT", "true")
+
+	defer func() {
+		os.Unsetenv("STORAGE_ENCRYPTION_KEY")
+		os.Unsetenv("STORAGE_ENCRYPTION_KEY_ID")
+		os.Unsetenv("STORAGE_ENCRYPTION_ALLOW_PLAINTEXT")
+	}()
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load storage encryption config: %v", err)
+	}
+
+	if config.Storage.Encryption.KeyID != "2024-01" {
+		t.Errorf("Expected key ID 2024-01, got %s", config.Storage.Encryption.KeyID)
+	}
+	if !config.Storage.Encryption.AllowPlaintext {
+		t.Error("Expected plaintext to be allowed")
+	}
+
+	os.Setenv("STORAGE_ENCRYPTION_ALLOW_PLAINTEXT", "maybe")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for invalid STORAGE_ENCRYPTION_ALLOW_PLAINTEXT but got none")
+	}
+	os.Setenv("STORAGE_ENCRYPTION_ALLOW_PLAINTEXT", "false")
+
+	// Keys must be valid base64
+	os.Setenv("STORAGE_ENCRYPTION_KEY", "not base64!")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for invalid encryption key but got none")
+	}
+
+	// Keys must be a valid AES key size
+	os.Setenv("STORAGE_ENCRYPTION_KEY", "c2hvcnQ=")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for short encryption key but got none")
+	}
+}
+
+func TestReplicationConfiguration(t *testing.T) {
+	os.Setenv("REPLICATION_ROLE", "follower")
+	os.Setenv("REPLICATION_LEADER_URL", "http://leader:8080")
+	os.Setenv("REPLICATION_TOKEN", "secret")
+	os.Setenv("REPLICATION_WRITES", "reject")
+	os.Setenv("REPLICATION_MAX_LAG", "10s")
+
+	defer func() {
+		os.Unsetenv("REPLICATION_ROLE")
+		os.Unsetenv("REPLICATION_LEADER_URL")
+		os.Unsetenv("REPLICATION_TOKEN")
+		os.Unsetenv("REPLICATION_WRITES")
+		os.Unsetenv("REPLICATION_MAX_LAG")
+	}()
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load replication config: %v", err)
+	}
+
+	replication := config.Replication
+	if replication.Role != "follower" || replication.LeaderURL != "http://leader:8080" {
+		t.Errorf("Expected follower of http://leader:8080, got %s of %s", replication.Role, replication.LeaderURL)
+	}
+
+	if replication.Writes != "reject" {
+		t.Errorf("Expected writes reject, got %s", replication.Writes)
+	}
+
+	if replication.MaxLag != 10*time.Second {
+		t.Errorf("Expected max lag 10s, got %v", replication.MaxLag)
+	}
+
+	// Followers need a valid leader URL
+	os.Setenv("REPLICATION_LEADER_URL", "leader:8080")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for invalid leader URL but got none")
+	}
+	os.Setenv("REPLICATION_LEADER_URL", "http://leader:8080")
+
+	// Replicating requires a token
+	os.Unsetenv("REPLICATION_TOKEN")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for missing replication token but got none")
+	}
+
+	// Invalid role
+	os.Setenv("REPLICATION_ROLE", "primary")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for invalid replication role but got none")
+	}
+}
+
+func TestTenancyConfiguration(t *testing.T) {
+	os.Setenv("TENANCY_ENABLED", "true")
+	os.Setenv("TENANCY_RESOLVER", "host")
+	os.Setenv("TENANCY_BASE_DOMAIN", "blogs.example.com")
+	os.Setenv("TENANCY_MAX_POSTS", "100")
+	os.Setenv("TENANCY_TENANT_MAX_POSTS", "acme=1000,demo=10")
+
+	defer func() {
+		os.Unsetenv("TENANCY_ENABLED")
+		os.Unsetenv("TENANCY_RESOLVER")
+		os.Unsetenv("TENANCY_BASE_DOMAIN")
+		os.Unsetenv("TENANCY_MAX_POSTS")
+		os.Unsetenv("TENANCY_TENANT_MAX_POSTS")
+		os.Unsetenv("TENANCY_DEFAULT_TENANT")
+	}()
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load tenancy config: %v", err)
+	}
+
+	tenancy := config.Tenancy
+	if !tenancy.Enabled || tenancy.Resolver != "host" || tenancy.BaseDomain != "blogs.example.com" {
+		t.Errorf("Expected host resolver for blogs.example.com, got %+v", tenancy)
+	}
+
+	if tenancy.Header != "X-Tenant-ID" {
+		t.Errorf("Expected default header X-Tenant-ID, got %s", tenancy.Header)
+	}
+
+	if tenancy.Quota.MaxPosts != 100 {
+		t.Errorf("Expected max posts 100, got %d", tenancy.Quota.MaxPosts)
+	}
+
+	if len(tenancy.Tenants) != 2 || tenancy.Tenants["acme"].MaxPosts != 1000 || tenancy.Tenants["demo"].MaxPosts != 10 {
+		t.Errorf("Expected quotas acme=1000 and demo=10, got %v", tenancy.Tenants)
+	}
+
+	// Tenant IDs must be usable as key namespaces and host names
+	os.Setenv("TENANCY_DEFAULT_TENANT", "Acme:1")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for invalid default tenant but got none")
+	}
+	os.Unsetenv("TENANCY_DEFAULT_TENANT")
+
+	os.Setenv("TENANCY_TENANT_MAX_POSTS", "acme")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for invalid tenant quotas but got none")
+	}
+	os.Setenv("TENANCY_TENANT_MAX_POSTS", "acme=1000")
+
+	// The host resolver needs a base domain
+	os.Unsetenv("TENANCY_BASE_DOMAIN")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for missing base domain but got none")
+	}
+
+	// Invalid resolver
+	os.Setenv("TENANCY_RESOLVER", "cookie")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for invalid tenancy resolver but got none")
+	}
+}
+
+func TestAuthorsConfiguration(t *testing.T) {
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if !config.Authors.OpenRegistration || config.Authors.AdminToken != "" {
+		t.Errorf("Expected open registration without admin token by default, got %+v", config.Authors)
+	}
+
+	os.Setenv("AUTHORS_OPEN_REGISTRATION", "false")
+	defer func() {
+		os.Unsetenv("AUTHORS_OPEN_REGISTRATION")
+		os.Unsetenv("AUTHORS_ADMIN_TOKEN")
+	}()
+
+	// Closed registration needs an admin to create authors
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for closed registration without admin token but got none")
+	}
+
+	os.Setenv("AUTHORS_ADMIN_TOKEN", "admin-secret")
+	config, err = Load()
+	if err != nil {
+		t.Fatalf("Failed to load authors config: %v", err)
+	}
+
+	if config.Authors.OpenRegistration || config.Authors.AdminToken != "admin-secret" {
+		t.Errorf("Expected closed registration with admin token, got %+v", config.Authors)
+	}
+
+	os.Setenv("AUTHORS_OPEN_REGISTRATION", "sometimes")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for invalid AUTHORS_OPEN_REGISTRATION but got none")
+	}
+}
+
+func TestPostsConfiguration(t *testing.T) {
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if config.Posts.SchedulerInterval != 10*time.Second {
+		t.Errorf("Expected scheduler interval 10s, got %v", config.Posts.SchedulerInterval)
+	}
+	if config.Posts.MaxRevisions != 50 {
+		t.Errorf("Expected max revisions 50, got %d", config.Posts.MaxRevisions)
+	}
+
+	os.Setenv("POSTS_SCHEDULER_INTERVAL", "1m")
+	defer os.Unsetenv("POSTS_SCHEDULER_INTERVAL")
+
+	config, err = Load()
+	if err != nil {
+		t.Fatalf("Failed to load posts config: %v", err)
+	}
+
+	if config.Posts.SchedulerInterval != time.Minute {
+		t.Errorf("Expected scheduler interval 1m, got %v", config.Posts.SchedulerInterval)
+	}
+
+	os.Setenv("POSTS_SCHEDULER_INTERVAL", "0s")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for zero scheduler interval but got none")
+	}
+
+	os.Setenv("POSTS_SCHEDULER_INTERVAL", "soon")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for invalid POSTS_SCHEDULER_INTERVAL but got none")
+	}
+	os.Unsetenv("POSTS_SCHEDULER_INTERVAL")
+
+	os.Setenv("POSTS_MAX_REVISIONS", "0")
+	defer os.Unsetenv("POSTS_MAX_REVISIONS")
+
+	config, err = Load()
+	if err != nil {
+		t.Fatalf("Failed to load posts config: %v", err)
+	}
+
+	if config.Posts.MaxRevisions != 0 {
+		t.Errorf("Expected max revisions 0, got %d", config.Posts.MaxRevisions)
+	}
+
+	os.Setenv("POSTS_MAX_REVISIONS", "-1")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for negative max revisions but got none")
+	}
 }
\ No newline at end of file
@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"net/mail"
+	"time"
+	"unicode/utf8"
+)
+
+// Author represents someone who writes posts
+type Author struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email,omitempty"`
+	Bio       string    `json:"bio,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Version   int64     `json:"version"`
+}
+
+// AuthorCredentials is returned once, when an author is created. Token is the
+// bearer token the author authenticates with; only a hash of it is stored.
+type AuthorCredentials struct {
+	Author
+	Token string `json:"token"`
+}
+
+// CreateAuthorRequest represents a request to create a new author
+type CreateAuthorRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Bio   string `json:"bio"`
+}
+
+// UpdateAuthorRequest represents a request to update an existing author
+type UpdateAuthorRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Bio   string `json:"bio"`
+}
+
+// AuthorList represents a paginated list of authors
+type AuthorList struct {
+	Authors    []Author `json:"authors"`
+	NextCursor string   `json:"nextCursor,omitempty"`
+}
+
+// Author validation constants
+const (
+	MinAuthorNameLength = 1
+	MaxAuthorNameLength = 100
+	MaxAuthorBioLength  = 1000
+)
+
+// Validate validates a create author request
+func (r *CreateAuthorRequest) Validate() error {
+	return validateAuthor(r.Name, r.Email, r.Bio)
+}
+
+// Validate validates an update author request
+func (r *UpdateAuthorRequest) Validate() error {
+	return validateAuthor(r.Name, r.Email, r.Bio)
+}
+
+// validateAuthor validates the fields of an author
+func validateAuthor(name, email, bio string) error {
+	length := utf8.RuneCountInString(name)
+	if length < MinAuthorNameLength {
+		return &ValidationError{
+			Field:   "name",
+			Message: "name is required",
+		}
+	}
+	if length > MaxAuthorNameLength {
+		return &ValidationError{
+			Field:   "name",
+			Message: "name is too long",
+		}
+	}
+
+	if email != "" {
+		if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+			return &ValidationError{
+				Field:   "email",
+				Message: "email is not a valid address",
+			}
+		}
+	}
+
+	if utf8.RuneCountInString(bio) > MaxAuthorBioLength {
+		return &ValidationError{
+			Field:   "bio",
+			Message: "bio is too long",
+		}
+	}
+
+	return nil
+}
+
+// NewAuthor creates a new author with the given data
+func NewAuthor(id, name, email, bio string) *Author {
+	now := time.Now()
+	return &Author{
+		ID:        id,
+		Name:      name,
+		Email:     email,
+		Bio:       bio,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// HidePrivateFields clears the fields of the author that only the author
+// and admins may see
+func (a *Author) HidePrivateFields(caller Caller) {
+	if !caller.CanActAs(a.ID) {
+		a.Email = ""
+	}
+}
+
+// Update updates the author with new data
+func (a *Author) Update(name, email, bio string) {
+	a.Name = name
+	a.Email = email
+	a.Bio = bio
+	a.UpdatedAt = time.Now()
+}
\ No newline at end of file
@@ -0,0 +1,40 @@
+package domain
+
+import "context"
+
+// Caller identifies who a request is made by. The zero Caller is an
+// anonymous caller.
+type Caller struct {
+	// AuthorID is the author the caller authenticated as, if any
+	AuthorID string
+
+	// Admin is set for callers holding the admin token, who may act on any
+	// author's behalf
+	Admin bool
+}
+
+// callerContextKey is the context key for the caller
+type callerContextKey struct{}
+
+// WithCaller returns a context carrying the given caller
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerContextKey{}, caller)
+}
+
+// CallerFromContext returns the caller carried by ctx, or an anonymous caller
+// if there is none
+func CallerFromContext(ctx context.Context) Caller {
+	caller, _ := ctx.Value(callerContextKey{}).(Caller)
+	return caller
+}
+
+// Authenticated reports whether the caller presented valid credentials
+func (c Caller) Authenticated() bool {
+	return c.Admin || c.AuthorID != ""
+}
+
+// CanActAs reports whether the caller may modify what belongs to the author
+// with the given ID. Only admins may modify what belongs to no author.
+func (c Caller) CanActAs(authorID string) bool {
+	return c.Admin || (c.AuthorID != "" && c.AuthorID == authorID)
+}
\ No newline at end of file
@@ -1,10 +1,18 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // Domain errors
 var (
-	ErrKeyNotFound = errors.New("key not found")
+	ErrKeyNotFound       = errors.New("key not found")
+	ErrVersionMismatch   = errors.New("version mismatch")
+	ErrWatchOverflow     = errors.New("watch buffer overflow")
+	ErrRevisionCompacted = errors.New("revision has been compacted")
+	ErrStoreFull         = errors.New("store is full")
+	ErrNotCompactable    = errors.New("store does not support compaction")
 )
 
 // PostNotFoundError represents when a post is not found
@@ -16,6 +24,16 @@ func (e PostNotFoundError) Error() string {
 	return "post not found: " + e.ID
 }
 
+// RevisionNotFoundError represents when a revision of a post is not found
+type RevisionNotFoundError struct {
+	PostID   string
+	Revision int
+}
+
+func (e RevisionNotFoundError) Error() string {
+	return fmt.Sprintf("revision not found: revision %d of post %s", e.Revision, e.PostID)
+}
+
 // InvalidPostDataError represents invalid post data
 type InvalidPostDataError struct {
 	Field string
@@ -58,12 +76,127 @@ func (e PaginationError) Error() string {
 	return "invalid pagination cursor: " + e.Cursor
 }
 
+// AuthorNotFoundError represents when an author is not found
+type AuthorNotFoundError struct {
+	ID string
+}
+
+func (e AuthorNotFoundError) Error() string {
+	return "author not found: " + e.ID
+}
+
+// PreconditionFailedError represents a conditional request whose expected
+// version does not match the current one
+type PreconditionFailedError struct {
+	ID string
+
+	// Resource names what was modified; empty means a post
+	Resource string
+}
+
+func (e PreconditionFailedError) Error() string {
+	resource := e.Resource
+	if resource == "" {
+		resource = "post"
+	}
+	return "precondition failed: " + resource + " " + e.ID + " has been modified"
+}
+
+// UnauthorizedError represents a request without valid credentials
+type UnauthorizedError struct {
+	Message string
+}
+
+func (e UnauthorizedError) Error() string {
+	return "unauthorized: " + e.Message
+}
+
+// ForbiddenError represents a request by a caller who may not perform it
+type ForbiddenError struct {
+	Message string
+}
+
+func (e ForbiddenError) Error() string {
+	return "forbidden: " + e.Message
+}
+
+// InvalidTransitionError represents a post status change its lifecycle does not allow
+type InvalidTransitionError struct {
+	ID   string
+	From PostStatus
+	To   PostStatus
+}
+
+func (e InvalidTransitionError) Error() string {
+	return fmt.Sprintf("invalid status transition: post %s cannot change from %s to %s", e.ID, e.From, e.To)
+}
+
+// StoreFullError represents a write rejected because storage is at its limits
+type StoreFullError struct {
+	Err error
+}
+
+func (e StoreFullError) Error() string {
+	return "storage full: " + e.Err.Error()
+}
+
+func (e StoreFullError) Unwrap() error {
+	return e.Err
+}
+
+// ReadOnlyReplicaError represents a write sent to a follower that does not
+// forward writes to its leader
+type ReadOnlyReplicaError struct {
+	Leader string
+}
+
+func (e ReadOnlyReplicaError) Error() string {
+	return "read-only replica: send writes to the leader at " + e.Leader
+}
+
+// LeaderUnavailableError represents a write a follower failed to forward to its leader
+type LeaderUnavailableError struct {
+	Err error
+}
+
+func (e LeaderUnavailableError) Error() string {
+	return "leader unavailable: " + e.Err.Error()
+}
+
+func (e LeaderUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// QuotaExceededError represents a write rejected because a tenant has reached its quota
+type QuotaExceededError struct {
+	Tenant   string
+	MaxPosts int
+}
+
+func (e QuotaExceededError) Error() string {
+	tenant := e.Tenant
+	if tenant == "" {
+		tenant = "default"
+	}
+	return fmt.Sprintf("quota exceeded: tenant %s may have at most %d posts", tenant, e.MaxPosts)
+}
+
 // Error codes for HTTP responses
 const (
-	ErrorCodePostNotFound     = "POST_NOT_FOUND"
-	ErrorCodeInvalidPostData  = "INVALID_POST_DATA"
-	ErrorCodeStorageError     = "STORAGE_ERROR"
-	ErrorCodeValidationError  = "VALIDATION_ERROR"
-	ErrorCodePaginationError  = "PAGINATION_ERROR"
-	ErrorCodeInternalError    = "INTERNAL_ERROR"
+	ErrorCodePostNotFound       = "POST_NOT_FOUND"
+	ErrorCodeAuthorNotFound     = "AUTHOR_NOT_FOUND"
+	ErrorCodeRevisionNotFound   = "REVISION_NOT_FOUND"
+	ErrorCodeInvalidPostData    = "INVALID_POST_DATA"
+	ErrorCodeStorageError       = "STORAGE_ERROR"
+	ErrorCodeValidationError    = "VALIDATION_ERROR"
+	ErrorCodePaginationError    = "PAGINATION_ERROR"
+	ErrorCodePreconditionFailed = "PRECONDITION_FAILED"
+	ErrorCodeUnauthorized       = "UNAUTHORIZED"
+	ErrorCodeForbidden          = "FORBIDDEN"
+	ErrorCodeInvalidTransition  = "INVALID_STATUS_TRANSITION"
+	ErrorCodeStoreFull          = "STORE_FULL"
+	ErrorCodeReadOnlyReplica    = "READ_ONLY_REPLICA"
+	ErrorCodeLeaderUnavailable  = "LEADER_UNAVAILABLE"
+	ErrorCodeQuotaExceeded      = "QUOTA_EXCEEDED"
+	ErrorCodeInternalError      = "INTERNAL_ERROR"
 )
\ No newline at end of file
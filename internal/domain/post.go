@@ -1,31 +1,115 @@
 package domain
 
 import (
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 	"unicode/utf8"
 )
 
 // Post represents a blog post entity
 type Post struct {
-	ID        string    `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID        string     `json:"id"`
+	AuthorID  string     `json:"authorId,omitempty"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	Tags      []string   `json:"tags,omitempty"`
+	Status    PostStatus `json:"status"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	Version   int64      `json:"version"`
+
+	// Revision numbers the states of the post, starting at 1 when it is
+	// created and advancing with each update
+	Revision int `json:"revision"`
+
+	// PublishAt is when a scheduled post is to be published
+	PublishAt *time.Time `json:"publishAt,omitempty"`
+
+	// PublishedAt is when the post was first published
+	PublishedAt *time.Time `json:"publishedAt,omitempty"`
+}
+
+// PostStatus is the stage of a post's lifecycle. Only published posts are
+// visible to callers other than their author and admins.
+type PostStatus string
+
+// Post statuses
+const (
+	PostDraft     PostStatus = "draft"
+	PostScheduled PostStatus = "scheduled"
+	PostPublished PostStatus = "published"
+	PostArchived  PostStatus = "archived"
+)
+
+// postTransitions lists the statuses a post may change to from each status.
+// A post may also keep its status, e.g. to be rescheduled.
+var postTransitions = map[PostStatus][]PostStatus{
+	PostDraft:     {PostScheduled, PostPublished},
+	PostScheduled: {PostDraft, PostPublished},
+	PostPublished: {PostDraft, PostArchived},
+	PostArchived:  {PostDraft, PostPublished},
+}
+
+// Valid reports whether s is a known status
+func (s PostStatus) Valid() bool {
+	_, ok := postTransitions[s]
+	return ok
 }
 
-// CreatePostRequest represents a request to create a new post
+// CreatePostRequest represents a request to create a new post. Without a
+// status, the post is scheduled if PublishAt is set and published otherwise.
 type CreatePostRequest struct {
-	Title   string `json:"title"`
-	Content string `json:"content"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	Tags      []string   `json:"tags"`
+	Status    PostStatus `json:"status"`
+	PublishAt *time.Time `json:"publishAt"`
 }
 
-// UpdatePostRequest represents a request to update an existing post
+// UpdatePostRequest represents a request to update an existing post. Tags
+// left out keep the post's tags; an empty list removes them. Without a
+// status, the post keeps its status.
 type UpdatePostRequest struct {
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	Tags      []string   `json:"tags"`
+	Status    PostStatus `json:"status"`
+	PublishAt *time.Time `json:"publishAt"`
+}
+
+// SearchResult is a post matching a search query
+type SearchResult struct {
+	Post       Post             `json:"post"`
+	Score      float64          `json:"score"`
+	Highlights SearchHighlights `json:"highlights"`
+}
+
+// SearchHighlights holds the title of a matching post and a snippet of its
+// content, HTML-escaped and with the matching words wrapped in <mark> tags
+type SearchHighlights struct {
 	Title   string `json:"title"`
 	Content string `json:"content"`
 }
 
+// SearchResults represents a paginated list of search results, best match first
+type SearchResults struct {
+	Results    []SearchResult `json:"results"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+}
+
+// TagCount is a tag together with the number of posts carrying it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Posts int    `json:"posts"`
+}
+
+// TagList represents the tags in use, most used first
+type TagList struct {
+	Tags []TagCount `json:"tags"`
+}
+
 // PostList represents a paginated list of posts
 type PostList struct {
 	Posts     []Post `json:"posts"`
@@ -38,9 +122,11 @@ const (
 	MaxTitleLength   = 200
 	MinContentLength = 1
 	MaxContentLength = 10000
+	MaxTags          = 10
+	MaxTagLength     = 32
 )
 
-// ValidateCreateRequest validates a create post request
+// ValidateCreateRequest validates a create post request and normalizes its tags
 func (r *CreatePostRequest) Validate() error {
 	if err := validateTitle(r.Title); err != nil {
 		return err
@@ -48,10 +134,28 @@ func (r *CreatePostRequest) Validate() error {
 	if err := validateContent(r.Content); err != nil {
 		return err
 	}
-	return nil
+	tags, err := NormalizeTags(r.Tags)
+	if err != nil {
+		return err
+	}
+	r.Tags = tags
+
+	if r.Status == "" {
+		r.Status = PostPublished
+		if r.PublishAt != nil {
+			r.Status = PostScheduled
+		}
+	}
+	if r.Status == PostArchived {
+		return &ValidationError{
+			Field:   "status",
+			Message: "posts cannot be created archived",
+		}
+	}
+	return validateStatus(r.Status, r.PublishAt)
 }
 
-// ValidateUpdateRequest validates an update post request
+// ValidateUpdateRequest validates an update post request and normalizes its tags
 func (r *UpdatePostRequest) Validate() error {
 	if err := validateTitle(r.Title); err != nil {
 		return err
@@ -59,6 +163,106 @@ func (r *UpdatePostRequest) Validate() error {
 	if err := validateContent(r.Content); err != nil {
 		return err
 	}
+	if r.Tags != nil {
+		tags, err := NormalizeTags(r.Tags)
+		if err != nil {
+			return err
+		}
+		r.Tags = tags
+	}
+	if r.Status == "" && r.PublishAt != nil {
+		return &ValidationError{
+			Field:   "publishAt",
+			Message: "publishAt requires status scheduled",
+		}
+	}
+	if r.Status != "" {
+		return validateStatus(r.Status, r.PublishAt)
+	}
+	return nil
+}
+
+// validateStatus validates a requested status and publish time
+func validateStatus(status PostStatus, publishAt *time.Time) error {
+	if !status.Valid() {
+		return &ValidationError{
+			Field:   "status",
+			Message: fmt.Sprintf("unknown status %q", status),
+		}
+	}
+	if status == PostScheduled && publishAt == nil {
+		return &ValidationError{
+			Field:   "publishAt",
+			Message: "publishAt is required to schedule a post",
+		}
+	}
+	if status != PostScheduled && publishAt != nil {
+		return &ValidationError{
+			Field:   "publishAt",
+			Message: "publishAt requires status scheduled",
+		}
+	}
+	return nil
+}
+
+// NormalizeTags returns tags lowercased and trimmed, with runs of spaces and
+// underscores replaced by a hyphen and duplicates removed, in their original
+// order. Tags may then only contain letters, digits and the characters
+// "-+#.", so "C++", "c#" and ".NET" are valid tags.
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if err := validateTag(tag); err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+
+	if len(normalized) > MaxTags {
+		return nil, &ValidationError{
+			Field:   "tags",
+			Message: fmt.Sprintf("at most %d tags are allowed", MaxTags),
+		}
+	}
+
+	return normalized, nil
+}
+
+// NormalizeTag returns the canonical form of a single tag, without validating it
+func NormalizeTag(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	return strings.Join(strings.FieldsFunc(tag, func(r rune) bool {
+		return r == '_' || r == ' ' || r == '\t'
+	}), "-")
+}
+
+// validateTag validates a normalized tag
+func validateTag(tag string) error {
+	if tag == "" {
+		return &ValidationError{
+			Field:   "tags",
+			Message: "tags cannot be empty",
+		}
+	}
+	if utf8.RuneCountInString(tag) > MaxTagLength {
+		return &ValidationError{
+			Field:   "tags",
+			Message: fmt.Sprintf("tag %q is too long", tag),
+		}
+	}
+	for _, r := range tag {
+		if !((r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || strings.ContainsRune("-+#.", r)) {
+			return &ValidationError{
+				Field:   "tags",
+				Message: fmt.Sprintf("tag %q may only contain letters, digits and -+#.", tag),
+			}
+		}
+	}
 	return nil
 }
 
@@ -98,21 +302,69 @@ func validateContent(content string) error {
 	return nil
 }
 
-// NewPost creates a new post with the given data
-func NewPost(id, title, content string) *Post {
+// NewPost creates a new draft post by the given author
+func NewPost(id, authorID, title, content string, tags []string) *Post {
 	now := time.Now()
 	return &Post{
 		ID:        id,
+		AuthorID:  authorID,
 		Title:     title,
 		Content:   content,
+		Tags:      tags,
+		Status:    PostDraft,
+		Revision:  1,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 }
 
-// Update updates the post with new data
-func (p *Post) Update(title, content string) {
+// Transition changes the status of the post, enforcing its lifecycle.
+// Scheduling requires publishAt to be after now; other statuses take no
+// publish time.
+func (p *Post) Transition(status PostStatus, publishAt *time.Time, now time.Time) error {
+	if err := validateStatus(status, publishAt); err != nil {
+		return err
+	}
+	if status != p.Status && !slices.Contains(postTransitions[p.Status], status) {
+		return &InvalidTransitionError{ID: p.ID, From: p.Status, To: status}
+	}
+	if status == PostScheduled && !publishAt.After(now) {
+		return &ValidationError{
+			Field:   "publishAt",
+			Message: "publishAt must be in the future",
+		}
+	}
+
+	p.PublishAt = nil
+	switch status {
+	case PostScheduled:
+		at := publishAt.UTC()
+		p.PublishAt = &at
+	case PostPublished:
+		if p.PublishedAt == nil {
+			at := now.UTC()
+			p.PublishedAt = &at
+		}
+	}
+
+	p.Status = status
+	return nil
+}
+
+// VisibleTo reports whether caller may see the post: published posts are
+// public, others only visible to their author and admins
+func (p *Post) VisibleTo(caller Caller) bool {
+	return p.Status == PostPublished || caller.CanActAs(p.AuthorID)
+}
+
+// Update updates the post with new data as its next revision. Nil tags keep
+// the current tags.
+func (p *Post) Update(title, content string, tags []string) {
 	p.Title = title
 	p.Content = content
+	if tags != nil {
+		p.Tags = tags
+	}
+	p.Revision++
 	p.UpdatedAt = time.Now()
 }
\ No newline at end of file
@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPost_Transition(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	future := now.Add(time.Hour)
+
+	tests := []struct {
+		from, to PostStatus
+		allowed  bool
+	}{
+		{PostDraft, PostDraft, true},
+		{PostDraft, PostScheduled, true},
+		{PostDraft, PostPublished, true},
+		{PostDraft, PostArchived, false},
+		{PostScheduled, PostDraft, true},
+		{PostScheduled, PostScheduled, true},
+		{PostScheduled, PostPublished, true},
+		{PostScheduled, PostArchived, false},
+		{PostPublished, PostDraft, true},
+		{PostPublished, PostScheduled, false},
+		{PostPublished, PostPublished, true},
+		{PostPublished, PostArchived, true},
+		{PostArchived, PostDraft, true},
+		{PostArchived, PostScheduled, false},
+		{PostArchived, PostPublished, true},
+		{PostArchived, PostArchived, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			post := &Post{ID: "post-1", Status: tt.from}
+			if tt.from == PostScheduled {
+				post.PublishAt = &future
+			}
+
+			var publishAt *time.Time
+			if tt.to == PostScheduled {
+				publishAt = &future
+			}
+
+			err := post.Transition(tt.to, publishAt, now)
+			if !tt.allowed {
+				var invalid *InvalidTransitionError
+				if !errors.As(err, &invalid) || invalid.ID != "post-1" || invalid.From != tt.from || invalid.To != tt.to {
+					t.Fatalf("Expected an invalid transition error, got %v", err)
+				}
+				if post.Status != tt.from {
+					t.Errorf("Expected a rejected transition to keep status %s, got %s", tt.from, post.Status)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected the transition to be allowed, got %v", err)
+			}
+			if post.Status != tt.to {
+				t.Errorf("Expected status %s, got %s", tt.to, post.Status)
+			}
+			if (post.PublishAt != nil) != (tt.to == PostScheduled) {
+				t.Errorf("Expected a publish time only while scheduled, got %v", post.PublishAt)
+			}
+		})
+	}
+}
+
+func TestPost_TransitionTimes(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		status    PostStatus
+		publishAt *time.Time
+		field     string
+	}{
+		{"unknown status", "deleted", nil, "status"},
+		{"scheduled without time", PostScheduled, nil, "publishAt"},
+		{"scheduled in the past", PostScheduled, &past, "publishAt"},
+		{"scheduled now", PostScheduled, &now, "publishAt"},
+		{"time without scheduling", PostPublished, &future, "publishAt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := &Post{Status: PostDraft}
+			var validation *ValidationError
+			if err := post.Transition(tt.status, tt.publishAt, now); !errors.As(err, &validation) || validation.Field != tt.field {
+				t.Errorf("Expected a validation error of %s, got %v", tt.field, err)
+			}
+		})
+	}
+
+	// The publish time is kept in UTC and the first publication is kept
+	// when a post is published again
+	post := &Post{Status: PostDraft}
+	local := future.In(time.FixedZone("UTC+2", 2*60*60))
+	if err := post.Transition(PostScheduled, &local, now); err != nil {
+		t.Fatalf("Failed to schedule: %v", err)
+	}
+	if post.PublishAt.Location() != time.UTC || !post.PublishAt.Equal(future) {
+		t.Errorf("Expected the publish time in UTC, got %v", post.PublishAt)
+	}
+
+	if err := post.Transition(PostPublished, nil, future); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	if post.PublishedAt == nil || !post.PublishedAt.Equal(future) {
+		t.Errorf("Expected the post published at %v, got %v", future, post.PublishedAt)
+	}
+
+	for _, status := range []PostStatus{PostArchived, PostPublished} {
+		if err := post.Transition(status, nil, future.Add(time.Hour)); err != nil {
+			t.Fatalf("Failed to change status to %s: %v", status, err)
+		}
+	}
+	if !post.PublishedAt.Equal(future) {
+		t.Errorf("Expected republishing to keep the first publication, got %v", post.PublishedAt)
+	}
+}
\ No newline at end of file
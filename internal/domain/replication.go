@@ -0,0 +1,41 @@
+package domain
+
+// Replication roles
+const (
+	RoleLeader   = "leader"
+	RoleFollower = "follower"
+)
+
+// ReplicationStatus reports the state of replication on this instance
+type ReplicationStatus struct {
+	Role string `json:"role"`
+
+	// Leader is the URL of the leader a follower replicates from
+	Leader string `json:"leader,omitempty"`
+
+	// Connected reports whether a follower is streaming from its leader
+	Connected bool `json:"connected"`
+
+	// Followers is the number of followers streaming from a leader
+	Followers int64 `json:"followers"`
+
+	// AppliedRevision is the last leader revision a follower has applied, and
+	// LeaderRevision the leader's latest revision as last reported to it. On a
+	// leader both are its own latest revision.
+	AppliedRevision int64 `json:"appliedRevision"`
+	LeaderRevision  int64 `json:"leaderRevision"`
+
+	// LagRevisions is LeaderRevision minus AppliedRevision
+	LagRevisions int64 `json:"lagRevisions"`
+
+	// LagSeconds is how long ago a follower was last known to be in sync
+	// with its leader
+	LagSeconds float64 `json:"lagSeconds"`
+
+	// Healthy reports whether a follower is connected and within the
+	// configured maximum lag. A leader is always healthy.
+	Healthy bool `json:"healthy"`
+
+	// LastError describes why a follower last lost its connection
+	LastError string `json:"lastError,omitempty"`
+}
\ No newline at end of file
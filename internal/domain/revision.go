@@ -0,0 +1,149 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// PostRevision is a post's title, content, tags and status as they were at
+// one revision. A revision is stored each time a post is updated, recording
+// the post as it was before the update.
+type PostRevision struct {
+	PostID   string     `json:"postId"`
+	Revision int        `json:"revision"`
+	Title    string     `json:"title"`
+	Content  string     `json:"content"`
+	Tags     []string   `json:"tags,omitempty"`
+	Status   PostStatus `json:"status"`
+
+	// CreatedAt is when the post was changed to this revision
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PostRevisionList represents the revisions of a post, newest first
+type PostRevisionList struct {
+	Revisions []PostRevision `json:"revisions"`
+}
+
+// PostDiff represents the line-level changes to a post's title and content
+// from one revision to another
+type PostDiff struct {
+	PostID  string     `json:"postId"`
+	From    int        `json:"from"`
+	To      int        `json:"to"`
+	Title   []DiffLine `json:"title"`
+	Content []DiffLine `json:"content"`
+}
+
+// DiffOp tells how a line changed between two revisions
+type DiffOp string
+
+// Diff operations
+const (
+	DiffEqual  DiffOp = "equal"
+	DiffInsert DiffOp = "insert"
+	DiffDelete DiffOp = "delete"
+)
+
+// DiffLine is a line of a diff
+type DiffLine struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxDiffCells bounds the table DiffLines fills to find the longest common
+// subsequence of the changed lines, i.e. their product
+const maxDiffCells = 1 << 22
+
+// Snapshot returns the post as a revision of its current state
+func (p *Post) Snapshot() PostRevision {
+	return PostRevision{
+		PostID:    p.ID,
+		Revision:  p.Revision,
+		Title:     p.Title,
+		Content:   p.Content,
+		Tags:      p.Tags,
+		Status:    p.Status,
+		CreatedAt: p.UpdatedAt,
+	}
+}
+
+// DiffLines returns the line-level changes from one text to another, keeping
+// as many lines equal as possible. Texts too different to compare within
+// maxDiffCells have their changed lines replaced as a whole.
+func DiffLines(from, to string) []DiffLine {
+	a, b := splitLines(from), splitLines(to)
+
+	// Most edits touch a few lines, so comparing only what lies between the
+	// common prefix and suffix keeps the table small
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]DiffLine, 0, len(a)+len(b)-prefix-suffix)
+	lines = appendDiffLines(lines, DiffEqual, a[:prefix])
+	lines = appendChangedLines(lines, a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	return appendDiffLines(lines, DiffEqual, a[len(a)-suffix:])
+}
+
+// appendChangedLines appends the diff of two runs of lines that differ at
+// both ends, walking the longest common subsequence of the two
+func appendChangedLines(lines []DiffLine, a, b []string) []DiffLine {
+	if len(a) == 0 || len(b) == 0 || len(a)*len(b) > maxDiffCells {
+		lines = appendDiffLines(lines, DiffDelete, a)
+		return appendDiffLines(lines, DiffInsert, b)
+	}
+
+	// lcs[i*width+j] is the length of the longest common subsequence of
+	// a[i:] and b[j:]
+	width := len(b) + 1
+	lcs := make([]int32, (len(a)+1)*width)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			} else {
+				lcs[i*width+j] = max(lcs[(i+1)*width+j], lcs[i*width+j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[(i+1)*width+j] >= lcs[i*width+j+1]:
+			lines = append(lines, DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	lines = appendDiffLines(lines, DiffDelete, a[i:])
+	return appendDiffLines(lines, DiffInsert, b[j:])
+}
+
+// appendDiffLines appends texts as lines with the same operation
+func appendDiffLines(lines []DiffLine, op DiffOp, texts []string) []DiffLine {
+	for _, text := range texts {
+		lines = append(lines, DiffLine{Op: op, Text: text})
+	}
+	return lines
+}
+
+// splitLines splits text into lines. Empty text has no lines.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
\ No newline at end of file
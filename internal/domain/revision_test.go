@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	eq := func(text string) DiffLine { return DiffLine{Op: DiffEqual, Text: text} }
+	ins := func(text string) DiffLine { return DiffLine{Op: DiffInsert, Text: text} }
+	del := func(text string) DiffLine { return DiffLine{Op: DiffDelete, Text: text} }
+
+	tests := []struct {
+		name     string
+		from, to string
+		expected []DiffLine
+	}{
+		{"both empty", "", "", []DiffLine{}},
+		{"unchanged", "a\nb", "a\nb", []DiffLine{eq("a"), eq("b")}},
+		{"added", "", "a\nb", []DiffLine{ins("a"), ins("b")}},
+		{"removed", "a\nb", "", []DiffLine{del("a"), del("b")}},
+		{"changed line", "a\nb\nc", "a\nB\nc", []DiffLine{eq("a"), del("b"), ins("B"), eq("c")}},
+		{"inserted line", "a\nc", "a\nb\nc", []DiffLine{eq("a"), ins("b"), eq("c")}},
+		{"deleted line", "a\nb\nc", "a\nc", []DiffLine{eq("a"), del("b"), eq("c")}},
+		{"common lines in the middle", "x\na\nb\ny", "z\na\nb\nw", []DiffLine{del("x"), ins("z"), eq("a"), eq("b"), del("y"), ins("w")}},
+		{"moved line", "a\nb\nc", "b\nc\na", []DiffLine{del("a"), eq("b"), eq("c"), ins("a")}},
+		{"empty lines", "a\n\nb", "a\nb", []DiffLine{eq("a"), del(""), eq("b")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if lines := DiffLines(tt.from, tt.to); !reflect.DeepEqual(lines, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, lines)
+			}
+		})
+	}
+}
\ No newline at end of file
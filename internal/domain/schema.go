@@ -0,0 +1,10 @@
+package domain
+
+// Envelope wraps a stored entity with the name and version of the schema its
+// data was written in, so that records written by older code can be upgraded
+// when they are read. Data holds the entity as a JSON object.
+type Envelope struct {
+	Schema        string         `json:"schema"`
+	SchemaVersion int            `json:"schemaVersion"`
+	Data          map[string]any `json:"data"`
+}
\ No newline at end of file
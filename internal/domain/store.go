@@ -1,22 +1,332 @@
 package domain
 
-// Store defines the interface for persistent data storage
+import (
+	"context"
+	"time"
+)
+
+// Store defines the interface for persistent data storage.
+// Every operation takes a context; implementations must return ctx.Err()
+// once the context is cancelled or its deadline has passed. Stores with size
+// limits return ErrStoreFull from writes that do not fit.
 type Store interface {
 	// Set stores a value with the given key
-	Set(key string, value any) error
+	Set(ctx context.Context, key string, value any) error
+	
+	// SetWithTTL stores a value that expires after ttl. Expired values are
+	// treated as absent by every read.
+	SetWithTTL(ctx context.Context, key string, value any, ttl time.Duration) error
 	
 	// Get retrieves a value by key
-	Get(key string) (value any, err error)
+	Get(ctx context.Context, key string) (value any, err error)
 	
 	// GetTyped retrieves a value by key and unmarshals it into the provided type
-	GetTyped(key string, value any) error
+	GetTyped(ctx context.Context, key string, value any) error
+	
+	// GetTypedVersion works like GetTyped and also returns the value's version.
+	// Versions increase monotonically with every write to a key.
+	GetTypedVersion(ctx context.Context, key string, value any) (version int64, err error)
+	
+	// CompareAndSwap stores a value only if the key is currently at the given
+	// version (0 meaning absent) and returns the new version. It returns
+	// ErrVersionMismatch otherwise.
+	CompareAndSwap(ctx context.Context, key string, value any, version int64) (newVersion int64, err error)
 	
 	// List retrieves all values with keys that start with the given prefix
-	List(keyPrefix string) (values []any, err error)
+	List(ctx context.Context, keyPrefix string) (values []any, err error)
+	
+	// Scan returns keys matching opts in lexicographic key order
+	Scan(ctx context.Context, opts ScanOptions) (keys []string, err error)
+	
+	// Iterate returns an iterator over the entries matching opts in key order.
+	// Callers must Close the iterator.
+	Iterate(ctx context.Context, opts ScanOptions) (Iterator, error)
 	
 	// Delete removes a value by key
-	Delete(key string) error
+	Delete(ctx context.Context, key string) error
+	
+	// CompareAndDelete removes a key only if it is currently at the given
+	// version. It returns ErrVersionMismatch otherwise.
+	CompareAndDelete(ctx context.Context, key string, version int64) error
+	
+	// Update runs fn in a read-write transaction. All writes made through tx
+	// are applied atomically if fn returns nil and discarded otherwise. Stores
+	// may run fn again when it conflicts with a concurrent write, so fn must
+	// not have effects outside tx.
+	Update(ctx context.Context, fn func(tx Tx) error) error
+	
+	// Watch streams changes to keys matching opts until ctx is cancelled or
+	// the watcher is closed. Callers must Close the watcher.
+	Watch(ctx context.Context, opts WatchOptions) (Watcher, error)
 	
 	// Close closes the storage and performs cleanup
 	Close() error
+}
+
+// Tx is a read-write view of the store inside a transaction. A Tx must not be
+// used after the function passed to Update returns.
+type Tx interface {
+	// Get retrieves a value by key, including uncommitted writes in this transaction
+	Get(key string) (value any, err error)
+
+	// GetTyped retrieves a value by key and unmarshals it into the provided type
+	GetTyped(key string, value any) error
+
+	// Version returns the version the key will have when the transaction commits
+	Version(key string) (int64, error)
+
+	// Set stores a value with the given key when the transaction commits
+	Set(key string, value any) error
+
+	// Delete removes a value by key when the transaction commits
+	Delete(key string) error
+}
+
+// ScanOptions controls an ordered scan over keys
+type ScanOptions struct {
+	// Prefix restricts the scan to keys starting with it
+	Prefix string
+
+	// StartAfter resumes the scan after this key (exclusive). In a reverse
+	// scan, only keys before it are returned.
+	StartAfter string
+
+	// Limit caps the number of keys returned; zero means no limit
+	Limit int
+
+	// Reverse scans in descending key order
+	Reverse bool
+}
+
+// Iterator walks the entries returned by Store.Iterate:
+//
+//	it, err := store.Iterate(ctx, opts)
+//	...
+//	defer it.Close()
+//	for it.Next() {
+//		var v T
+//		if err := it.Decode(&v); err != nil { ... }
+//	}
+//	if err := it.Err(); err != nil { ... }
+type Iterator interface {
+	// Next advances to the next entry and reports whether there is one
+	Next() bool
+
+	// Key returns the key of the current entry
+	Key() string
+
+	// Value returns the encoded value of the current entry
+	Value() []byte
+
+	// Version returns the version of the current entry
+	Version() int64
+
+	// Decode unmarshals the current value into the provided type
+	Decode(value any) error
+
+	// Err returns the error that ended iteration, if any
+	Err() error
+
+	// Close releases resources held by the iterator
+	Close() error
+}
+
+// EventType identifies the kind of change carried by an Event
+type EventType string
+
+// Event types
+const (
+	EventPut    EventType = "put"
+	EventDelete EventType = "delete"
+)
+
+// Event describes a single change to a key
+type Event struct {
+	Type EventType
+	Key  string
+
+	// Value is the encoded new value; nil for deletes
+	Value []byte
+
+	// PrevValue is the encoded value before the change; nil if the key did not exist
+	PrevValue []byte
+
+	// Revision is the store revision of the change. Revisions increase with
+	// every change, so the revision of the last event received can be used to
+	// resume watching.
+	Revision int64
+
+	// Version is the key's version after a put, as reported by
+	// GetTypedVersion; zero for deletes
+	Version int64
+
+	// Last marks the final event a watcher receives from one commit. Events
+	// up to it were committed atomically with it.
+	Last bool
+}
+
+// WatchOptions controls which changes a watcher receives
+type WatchOptions struct {
+	// Prefix restricts events to keys starting with it
+	Prefix string
+
+	// AfterRevision replays changes made after this revision before streaming
+	// new ones. Zero starts with the next change. Watch fails with
+	// ErrRevisionCompacted if the store no longer retains those changes.
+	AfterRevision int64
+}
+
+// Watcher delivers the events requested from Store.Watch:
+//
+//	w, err := store.Watch(ctx, opts)
+//	...
+//	defer w.Close()
+//	for ev := range w.Events() {
+//		...
+//	}
+//	if err := w.Err(); err != nil { ... }
+//
+// A watcher that falls too far behind is cancelled with ErrWatchOverflow;
+// callers can resume from the revision of the last event they received.
+type Watcher interface {
+	// Events returns the channel events are delivered on. It is closed when
+	// the watcher stops.
+	Events() <-chan Event
+
+	// Decode unmarshals an event's Value or PrevValue, stored under key, into
+	// the provided type
+	Decode(key string, data []byte, value any) error
+
+	// Err returns the reason the watcher stopped, or nil if it was closed
+	Err() error
+
+	// Close stops the watcher
+	Close() error
+}
+
+// RawValue is a value that is already encoded, such as one returned by
+// Iterator.Value. Storing a RawValue writes its bytes unchanged, which lets
+// values be copied between stores without decoding them.
+type RawValue []byte
+
+// KeyValue is an entry decoded by ListTyped
+type KeyValue[T any] struct {
+	Key     string
+	Value   T
+	Version int64
+}
+
+// ListTyped iterates over the entries matching opts and decodes each value into T
+func ListTyped[T any](ctx context.Context, store Store, opts ScanOptions) ([]KeyValue[T], error) {
+	it, err := store.Iterate(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	var result []KeyValue[T]
+	for it.Next() {
+		var value T
+		if err := it.Decode(&value); err != nil {
+			return nil, err
+		}
+		result = append(result, KeyValue[T]{Key: it.Key(), Value: value, Version: it.Version()})
+	}
+
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// StoreStats holds counters reported by stores that implement StatsProvider
+type StoreStats struct {
+	// Items is the number of stored items
+	Items int
+
+	// Bytes is the total size of stored keys and values
+	Bytes int64
+
+	// ExpiredKeys is the total number of keys removed because their TTL elapsed
+	ExpiredKeys int64
+
+	// EvictedKeys is the total number of keys evicted to keep the store
+	// within its limits
+	EvictedKeys int64
+
+	// Operations holds per-operation counters, when the store is instrumented
+	Operations []OperationStats
+}
+
+// OperationStats holds the counters of one kind of storage operation
+type OperationStats struct {
+	Operation string
+	Count     int64
+	Errors    int64
+
+	// LatencyBuckets holds, for each bound in LatencyBounds, the number of
+	// operations that completed within it
+	LatencyBuckets []int64
+	LatencyBounds  []time.Duration
+	LatencySum     time.Duration
+}
+
+// StatsProvider is implemented by stores that can report StoreStats
+type StatsProvider interface {
+	Stats() StoreStats
+}
+
+// RevisionProvider is implemented by stores that can report the revision of
+// their latest change
+type RevisionProvider interface {
+	Revision() int64
+}
+
+// Replica is implemented by stores that can apply changes watched on another
+// store, keeping the versions the changes have there
+type Replica interface {
+	// ApplyEvents atomically applies the events of one commit, storing put
+	// values as they are at the event's version
+	ApplyEvents(ctx context.Context, events []Event) error
+}
+
+// ApplyEvents applies the events of one commit to store atomically. Stores
+// that are not a Replica apply them in a transaction, at versions of their own.
+func ApplyEvents(ctx context.Context, store Store, events []Event) error {
+	if replica, ok := store.(Replica); ok {
+		return replica.ApplyEvents(ctx, events)
+	}
+
+	return store.Update(ctx, func(tx Tx) error {
+		for _, ev := range events {
+			switch ev.Type {
+			case EventPut:
+				if err := tx.Set(ev.Key, RawValue(ev.Value)); err != nil {
+					return err
+				}
+			case EventDelete:
+				if err := tx.Delete(ev.Key); err != nil && err != ErrKeyNotFound {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Compactor is implemented by stores that can reclaim the space taken up by
+// overwritten, deleted and expired keys
+type Compactor interface {
+	Compact(ctx context.Context) (CompactionStats, error)
+}
+
+// CompactionStats describes the effect of a compaction
+type CompactionStats struct {
+	// BytesBefore and BytesAfter are the on-disk size of the store
+	BytesBefore int64
+	BytesAfter  int64
+
+	// Expired is the number of expired keys removed
+	Expired int64
 }
\ No newline at end of file
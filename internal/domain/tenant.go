@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"context"
+	"strings"
+)
+
+// tenantContextKey is the context key for the tenant ID
+type tenantContextKey struct{}
+
+// WithTenant returns a context carrying the given tenant ID
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantID returns the tenant ID carried by ctx, or "" if there is none
+func TenantID(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantContextKey{}).(string)
+	return tenant
+}
+
+// TenantKeyPrefix returns the prefix that scopes store keys to the tenant
+// carried by ctx. Without a tenant it is empty, so single-tenant keys are
+// unchanged. Tenant IDs cannot contain ':', so no tenant's keys can start
+// with another tenant's prefix.
+func TenantKeyPrefix(ctx context.Context) string {
+	if tenant := TenantID(ctx); tenant != "" {
+		return "tenant:" + tenant + ":"
+	}
+	return ""
+}
+
+// SplitTenantKey splits a store key into the tenant key prefix it was
+// written under, empty for keys outside any tenant, and the rest of the key
+func SplitTenantKey(key string) (prefix, rest string) {
+	after, ok := strings.CutPrefix(key, "tenant:")
+	if !ok {
+		return "", key
+	}
+	tenant, rest, ok := strings.Cut(after, ":")
+	if !ok || !ValidTenantID(tenant) {
+		return "", key
+	}
+	return "tenant:" + tenant + ":", rest
+}
+
+// ValidTenantID reports whether id can be used as a tenant ID: 1 to 63
+// lowercase letters, digits and hyphens, so it is also a valid host name label
+func ValidTenantID(id string) bool {
+	if len(id) == 0 || len(id) > 63 {
+		return false
+	}
+	for _, r := range id {
+		if !((r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-') {
+			return false
+		}
+	}
+	return true
+}
\ No newline at end of file
@@ -0,0 +1,265 @@
+package infrastructure
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// Backup file layout. The header is never compressed:
+//
+//	magic "BKUP" | version uint16 | flags uint16
+//
+// It is followed by the body, gzip-compressed when backupFlagGzip is set:
+//
+//	records: uvarint key length | key | uvarint value length | value
+//	end:     uvarint 0 | uvarint record count | CRC32 of everything before it
+//
+// Values are copied exactly as stored, so they keep the codec that wrote them.
+const (
+	backupMagic   = "BKUP"
+	backupVersion = 1
+
+	backupFlagGzip uint16 = 1 << 0
+
+	backupHeaderSize = 8
+)
+
+// BackupOptions configures Backup
+type BackupOptions struct {
+	// Compress gzips the body of the backup
+	Compress bool
+}
+
+// Backup writes a point-in-time copy of every key in the store to w and
+// returns the number of entries written. Consistency relies on Iterate
+// capturing the store at a single point in time. Versions and TTLs are not
+// included; restored keys get fresh versions and never expire.
+func Backup(ctx context.Context, store domain.Store, w io.Writer, options BackupOptions) (int, error) {
+	it, err := store.Iterate(ctx, domain.ScanOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer it.Close()
+
+	var flags uint16
+	if options.Compress {
+		flags |= backupFlagGzip
+	}
+
+	header := make([]byte, backupHeaderSize)
+	copy(header, backupMagic)
+	binary.BigEndian.PutUint16(header[4:6], backupVersion)
+	binary.BigEndian.PutUint16(header[6:8], flags)
+	if _, err := w.Write(header); err != nil {
+		return 0, fmt.Errorf("failed to write backup header: %w", err)
+	}
+
+	body := bufio.NewWriter(w)
+	var gz *gzip.Writer
+	var out io.Writer = body
+	if options.Compress {
+		gz = gzip.NewWriter(body)
+		out = gz
+	}
+
+	bw := &backupWriter{w: out, crc: crc32.NewIEEE()}
+	count := 0
+	for it.Next() {
+		bw.bytes([]byte(it.Key()))
+		bw.bytes(it.Value())
+		count++
+	}
+	if err := it.Err(); err != nil {
+		return 0, err
+	}
+
+	bw.uvarint(0)
+	bw.uvarint(uint64(count))
+	checksum := bw.crc.Sum32()
+	bw.raw(binary.BigEndian.AppendUint32(nil, checksum))
+	if bw.err != nil {
+		return 0, fmt.Errorf("failed to write backup: %w", bw.err)
+	}
+
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return 0, fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+	if err := body.Flush(); err != nil {
+		return 0, fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	return count, nil
+}
+
+// Restore loads a backup written by Backup into an empty store and returns the
+// number of entries restored. The backup is verified before it is applied in
+// a single transaction, so a corrupt or truncated backup leaves the store
+// untouched.
+func Restore(ctx context.Context, store domain.Store, r io.Reader) (int, error) {
+	keys, err := store.Scan(ctx, domain.ScanOptions{Limit: 1})
+	if err != nil {
+		return 0, err
+	}
+	if len(keys) > 0 {
+		return 0, ErrStoreNotEmpty
+	}
+
+	header := make([]byte, backupHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, fmt.Errorf("%w: missing header", ErrInvalidBackup)
+	}
+	if string(header[:4]) != backupMagic {
+		return 0, fmt.Errorf("%w: bad magic", ErrInvalidBackup)
+	}
+	if version := binary.BigEndian.Uint16(header[4:6]); version != backupVersion {
+		return 0, fmt.Errorf("%w: unsupported version %d", ErrInvalidBackup, version)
+	}
+
+	in := r
+	if binary.BigEndian.Uint16(header[6:8])&backupFlagGzip != 0 {
+		gz, err := gzip.NewReader(in)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+		}
+		defer gz.Close()
+		in = gz
+	}
+
+	entries, err := readBackupEntries(&backupReader{r: bufio.NewReader(in), crc: crc32.NewIEEE()})
+	if err != nil {
+		return 0, err
+	}
+
+	// The backup is read in full first, since the store may run the
+	// transaction more than once
+	err = store.Update(ctx, func(tx domain.Tx) error {
+		for _, e := range entries {
+			if err := tx.Set(e.key, domain.RawValue(e.value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(entries), nil
+}
+
+// backupEntry is a key and its encoded value read from a backup
+type backupEntry struct {
+	key   string
+	value []byte
+}
+
+// readBackupEntries reads the records of a backup body and verifies the
+// record count and checksum that follow them
+func readBackupEntries(br *backupReader) ([]backupEntry, error) {
+	var entries []backupEntry
+	for {
+		key, err := br.bytes()
+		if err != nil {
+			return nil, err
+		}
+		if len(key) == 0 {
+			break
+		}
+		value, err := br.bytes()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, backupEntry{key: string(key), value: value})
+	}
+
+	recorded, err := br.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if recorded != uint64(len(entries)) {
+		return nil, fmt.Errorf("%w: expected %d records, found %d", ErrInvalidBackup, recorded, len(entries))
+	}
+
+	expected := br.crc.Sum32()
+	trailer := make([]byte, 4)
+	if _, err := io.ReadFull(br.r, trailer); err != nil {
+		return nil, fmt.Errorf("%w: missing checksum", ErrInvalidBackup)
+	}
+	if binary.BigEndian.Uint32(trailer) != expected {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidBackup)
+	}
+	return entries, nil
+}
+
+// backupWriter writes length-prefixed fields while checksumming them. The
+// first error is kept and later writes are skipped.
+type backupWriter struct {
+	w   io.Writer
+	crc hash.Hash32
+	err error
+}
+
+func (bw *backupWriter) raw(data []byte) {
+	if bw.err != nil {
+		return
+	}
+	_, bw.err = bw.w.Write(data)
+}
+
+func (bw *backupWriter) uvarint(n uint64) {
+	data := binary.AppendUvarint(nil, n)
+	bw.crc.Write(data)
+	bw.raw(data)
+}
+
+func (bw *backupWriter) bytes(data []byte) {
+	bw.uvarint(uint64(len(data)))
+	bw.crc.Write(data)
+	bw.raw(data)
+}
+
+// backupReader reads length-prefixed fields while checksumming them
+type backupReader struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+}
+
+// maxBackupField caps the size of a single key or value so that a corrupt
+// length cannot cause a huge allocation
+const maxBackupField = 1 << 30
+
+func (br *backupReader) uvarint() (uint64, error) {
+	n, err := binary.ReadUvarint(br.r)
+	if err != nil {
+		return 0, fmt.Errorf("%w: truncated", ErrInvalidBackup)
+	}
+	br.crc.Write(binary.AppendUvarint(nil, n))
+	return n, nil
+}
+
+func (br *backupReader) bytes() ([]byte, error) {
+	n, err := br.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > maxBackupField {
+		return nil, fmt.Errorf("%w: field of %d bytes", ErrInvalidBackup, n)
+	}
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(br.r, data); err != nil {
+		return nil, fmt.Errorf("%w: truncated", ErrInvalidBackup)
+	}
+	br.crc.Write(data)
+	return data, nil
+}
\ No newline at end of file
@@ -0,0 +1,101 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestBackupRestore(t *testing.T) {
+	ctx := context.Background()
+
+	for _, compress := range []bool{false, true} {
+		source := NewMemoryStore(WithCodec(BinaryCodec))
+		source.Set(ctx, "posts:1", codecTestValue{ID: "post-1", Tags: []string{"go"}})
+		source.Set(ctx, "posts:2", "second")
+		source.Set(ctx, "users:1", map[string]any{"name": "alice"})
+
+		var buf bytes.Buffer
+		count, err := Backup(ctx, source, &buf, BackupOptions{Compress: compress})
+		if err != nil {
+			t.Fatalf("Failed to back up (gzip=%v): %v", compress, err)
+		}
+		if count != 3 {
+			t.Errorf("Expected 3 entries backed up, got %d", count)
+		}
+
+		target := NewMemoryStore()
+		restored, err := Restore(ctx, target, &buf)
+		if err != nil {
+			t.Fatalf("Failed to restore (gzip=%v): %v", compress, err)
+		}
+		if restored != 3 || target.Size() != 3 {
+			t.Errorf("Expected 3 entries restored, got %d (size %d)", restored, target.Size())
+		}
+
+		// Values keep the codec they were written with
+		var value codecTestValue
+		if err := target.GetTyped(ctx, "posts:1", &value); err != nil || value.ID != "post-1" {
+			t.Errorf("Expected restored post-1, got %+v (%v)", value, err)
+		}
+
+		source.Close()
+		target.Close()
+	}
+}
+
+func TestRestore_NonEmptyStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	defer store.Close()
+
+	store.Set(ctx, "key1", "value1")
+
+	var buf bytes.Buffer
+	Backup(ctx, store, &buf, BackupOptions{})
+
+	if _, err := Restore(ctx, store, &buf); err != ErrStoreNotEmpty {
+		t.Errorf("Expected ErrStoreNotEmpty, got %v", err)
+	}
+}
+
+func TestRestore_InvalidBackup(t *testing.T) {
+	ctx := context.Background()
+	source := NewMemoryStore()
+	defer source.Close()
+
+	source.Set(ctx, "key1", "value1")
+	source.Set(ctx, "key2", "value2")
+
+	var buf bytes.Buffer
+	Backup(ctx, source, &buf, BackupOptions{})
+	valid := buf.Bytes()
+
+	corrupt := append([]byte(nil), valid...)
+	corrupt[len(corrupt)-8] ^= 0xff
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"bad magic", append([]byte("XXXX"), valid[4:]...)},
+		{"truncated", valid[:len(valid)-6]},
+		{"corrupt", corrupt},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			target := NewMemoryStore()
+			defer target.Close()
+
+			if _, err := Restore(ctx, target, bytes.NewReader(tc.data)); !errors.Is(err, ErrInvalidBackup) {
+				t.Errorf("Expected ErrInvalidBackup, got %v", err)
+			}
+			if target.Size() != 0 {
+				t.Errorf("Expected failed restore to leave the store empty, got size %d", target.Size())
+			}
+		})
+	}
+}
\ No newline at end of file
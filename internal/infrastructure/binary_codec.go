@@ -0,0 +1,615 @@
+package infrastructure
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// binaryCodec is a compact, self-describing binary encoding. Each value is a
+// tag byte followed by its payload; integers and lengths are varints. Structs
+// are encoded as maps keyed by their JSON field names, so values can be decoded
+// without knowing their type and struct fields can be added or removed
+// without breaking stored data.
+type binaryCodec struct{}
+
+func (binaryCodec) Name() string { return "binary" }
+
+func (binaryCodec) ID() byte { return codecIDBinary }
+
+func (binaryCodec) Marshal(value any) ([]byte, error) {
+	var e binaryEncoder
+	if err := e.encode(reflect.ValueOf(value)); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+func (binaryCodec) Unmarshal(data []byte, value any) error {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("cannot decode into non-pointer %T", value)
+	}
+
+	d := binaryDecoder{data: data}
+	if err := d.decode(rv.Elem()); err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return errBinaryTrailingData
+	}
+	return nil
+}
+
+// Value tags used by the binary codec
+const (
+	binaryTagNil byte = iota
+	binaryTagFalse
+	binaryTagTrue
+	binaryTagInt
+	binaryTagUint
+	binaryTagFloat
+	binaryTagString
+	binaryTagBytes
+	binaryTagArray
+	binaryTagMap
+	binaryTagTime
+)
+
+var (
+	errBinaryTruncated    = errors.New("binary codec: truncated data")
+	errBinaryTrailingData = errors.New("binary codec: trailing data")
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// binaryEncoder appends encoded values to buf
+type binaryEncoder struct {
+	buf []byte
+}
+
+func (e *binaryEncoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.buf = append(e.buf, binaryTagNil)
+		return nil
+	}
+
+	if v.Type() == timeType {
+		data, err := v.Interface().(time.Time).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		e.buf = append(e.buf, binaryTagTime)
+		e.bytes(data)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, binaryTagTrue)
+		} else {
+			e.buf = append(e.buf, binaryTagFalse)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.buf = append(e.buf, binaryTagInt)
+		e.buf = binary.AppendVarint(e.buf, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.buf = append(e.buf, binaryTagUint)
+		e.buf = binary.AppendUvarint(e.buf, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		e.buf = append(e.buf, binaryTagFloat)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(v.Float()))
+	case reflect.String:
+		e.buf = append(e.buf, binaryTagString)
+		e.bytes([]byte(v.String()))
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf = append(e.buf, binaryTagNil)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.buf = append(e.buf, binaryTagBytes)
+			e.bytes(v.Bytes())
+			return nil
+		}
+		return e.array(v)
+	case reflect.Array:
+		return e.array(v)
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf = append(e.buf, binaryTagNil)
+			return nil
+		}
+		e.buf = append(e.buf, binaryTagMap)
+		e.buf = binary.AppendUvarint(e.buf, uint64(v.Len()))
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := e.encode(iter.Key()); err != nil {
+				return err
+			}
+			if err := e.encode(iter.Value()); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		fields := binaryFields(v.Type())
+		e.buf = append(e.buf, binaryTagMap)
+		e.buf = binary.AppendUvarint(e.buf, uint64(len(fields)))
+		for _, f := range fields {
+			e.buf = append(e.buf, binaryTagString)
+			e.bytes([]byte(f.name))
+			if err := e.encode(v.FieldByIndex(f.index)); err != nil {
+				return err
+			}
+		}
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			e.buf = append(e.buf, binaryTagNil)
+			return nil
+		}
+		return e.encode(v.Elem())
+	default:
+		return fmt.Errorf("binary codec: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func (e *binaryEncoder) array(v reflect.Value) error {
+	e.buf = append(e.buf, binaryTagArray)
+	e.buf = binary.AppendUvarint(e.buf, uint64(v.Len()))
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encode(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *binaryEncoder) bytes(data []byte) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(data)))
+	e.buf = append(e.buf, data...)
+}
+
+// binaryDecoder reads encoded values from data
+type binaryDecoder struct {
+	data []byte
+	pos  int
+}
+
+// decode reads the next value into v, which must be settable
+func (d *binaryDecoder) decode(v reflect.Value) error {
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		value, err := d.decodeAny()
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(value))
+		}
+		return nil
+	}
+
+	tag, err := d.tag()
+	if err != nil {
+		return err
+	}
+
+	if tag == binaryTagNil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		d.pos--
+		return d.decode(v.Elem())
+	}
+
+	switch tag {
+	case binaryTagFalse, binaryTagTrue:
+		if v.Kind() != reflect.Bool {
+			return mismatch(tag, v)
+		}
+		v.SetBool(tag == binaryTagTrue)
+	case binaryTagInt:
+		n, err := d.varint()
+		if err != nil {
+			return err
+		}
+		return setInt(v, n, tag)
+	case binaryTagUint:
+		n, err := d.uvarint()
+		if err != nil {
+			return err
+		}
+		if n > math.MaxInt64 {
+			if !isUint(v) || v.OverflowUint(n) {
+				return mismatch(tag, v)
+			}
+			v.SetUint(n)
+			return nil
+		}
+		return setInt(v, int64(n), tag)
+	case binaryTagFloat:
+		f, err := d.float()
+		if err != nil {
+			return err
+		}
+		if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+			return mismatch(tag, v)
+		}
+		v.SetFloat(f)
+	case binaryTagString, binaryTagBytes:
+		data, err := d.bytes()
+		if err != nil {
+			return err
+		}
+		switch {
+		case v.Kind() == reflect.String:
+			v.SetString(string(data))
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			v.SetBytes(append([]byte(nil), data...))
+		default:
+			return mismatch(tag, v)
+		}
+	case binaryTagArray:
+		return d.decodeArray(v)
+	case binaryTagMap:
+		switch v.Kind() {
+		case reflect.Map:
+			return d.decodeMap(v)
+		case reflect.Struct:
+			return d.decodeStruct(v)
+		default:
+			return mismatch(tag, v)
+		}
+	case binaryTagTime:
+		data, err := d.bytes()
+		if err != nil {
+			return err
+		}
+		if v.Type() != timeType {
+			return mismatch(tag, v)
+		}
+		var t time.Time
+		if err := t.UnmarshalBinary(data); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+	default:
+		return fmt.Errorf("binary codec: unknown tag %d", tag)
+	}
+	return nil
+}
+
+func (d *binaryDecoder) decodeArray(v reflect.Value) error {
+	n, err := d.length()
+	if err != nil {
+		return err
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), n, n))
+	case reflect.Array:
+		if n > v.Len() {
+			return fmt.Errorf("binary codec: %d elements do not fit in %s", n, v.Type())
+		}
+		v.Set(reflect.Zero(v.Type()))
+	default:
+		return mismatch(binaryTagArray, v)
+	}
+
+	for i := 0; i < n; i++ {
+		if err := d.decode(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *binaryDecoder) decodeMap(v reflect.Value) error {
+	n, err := d.length()
+	if err != nil {
+		return err
+	}
+
+	t := v.Type()
+	v.Set(reflect.MakeMapWithSize(t, n))
+	for i := 0; i < n; i++ {
+		key := reflect.New(t.Key()).Elem()
+		if err := d.decode(key); err != nil {
+			return err
+		}
+		elem := reflect.New(t.Elem()).Elem()
+		if err := d.decode(elem); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+	}
+	return nil
+}
+
+func (d *binaryDecoder) decodeStruct(v reflect.Value) error {
+	n, err := d.length()
+	if err != nil {
+		return err
+	}
+
+	fields := binaryFields(v.Type())
+	for i := 0; i < n; i++ {
+		var name string
+		if err := d.decode(reflect.ValueOf(&name).Elem()); err != nil {
+			return err
+		}
+
+		f, ok := fields.lookup(name)
+		if !ok {
+			// Unknown fields are skipped so older code can read newer data
+			if _, err := d.decodeAny(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := d.decode(v.FieldByIndex(f.index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeAny reads the next value without a target type. Integers decode as
+// int64 or uint64, maps with string keys as map[string]any and other maps as
+// map[any]any.
+func (d *binaryDecoder) decodeAny() (any, error) {
+	tag, err := d.tag()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case binaryTagNil:
+		return nil, nil
+	case binaryTagFalse:
+		return false, nil
+	case binaryTagTrue:
+		return true, nil
+	case binaryTagInt:
+		return d.varint()
+	case binaryTagUint:
+		return d.uvarint()
+	case binaryTagFloat:
+		return d.float()
+	case binaryTagString:
+		data, err := d.bytes()
+		return string(data), err
+	case binaryTagBytes:
+		data, err := d.bytes()
+		return append([]byte(nil), data...), err
+	case binaryTagArray:
+		n, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		result := make([]any, n)
+		for i := range result {
+			if result[i], err = d.decodeAny(); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case binaryTagMap:
+		n, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]any, n)
+		values := make([]any, n)
+		stringKeys := true
+		for i := 0; i < n; i++ {
+			if keys[i], err = d.decodeAny(); err != nil {
+				return nil, err
+			}
+			if values[i], err = d.decodeAny(); err != nil {
+				return nil, err
+			}
+			if _, ok := keys[i].(string); !ok {
+				stringKeys = false
+			}
+		}
+		if stringKeys {
+			result := make(map[string]any, n)
+			for i, key := range keys {
+				result[key.(string)] = values[i]
+			}
+			return result, nil
+		}
+		result := make(map[any]any, n)
+		for i, key := range keys {
+			if key != nil && !reflect.TypeOf(key).Comparable() {
+				return nil, fmt.Errorf("binary codec: unhashable map key %T", key)
+			}
+			result[key] = values[i]
+		}
+		return result, nil
+	case binaryTagTime:
+		data, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		var t time.Time
+		err = t.UnmarshalBinary(data)
+		return t, err
+	default:
+		return nil, fmt.Errorf("binary codec: unknown tag %d", tag)
+	}
+}
+
+func (d *binaryDecoder) tag() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, errBinaryTruncated
+	}
+	tag := d.data[d.pos]
+	d.pos++
+	return tag, nil
+}
+
+func (d *binaryDecoder) varint() (int64, error) {
+	n, size := binary.Varint(d.data[d.pos:])
+	if size <= 0 {
+		return 0, errBinaryTruncated
+	}
+	d.pos += size
+	return n, nil
+}
+
+func (d *binaryDecoder) uvarint() (uint64, error) {
+	n, size := binary.Uvarint(d.data[d.pos:])
+	if size <= 0 {
+		return 0, errBinaryTruncated
+	}
+	d.pos += size
+	return n, nil
+}
+
+func (d *binaryDecoder) float() (float64, error) {
+	if len(d.data)-d.pos < 8 {
+		return 0, errBinaryTruncated
+	}
+	bits := binary.BigEndian.Uint64(d.data[d.pos:])
+	d.pos += 8
+	return math.Float64frombits(bits), nil
+}
+
+// length reads a count and checks it against the remaining data, so corrupt
+// input cannot cause huge allocations
+func (d *binaryDecoder) length() (int, error) {
+	n, err := d.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(len(d.data)-d.pos) {
+		return 0, errBinaryTruncated
+	}
+	return int(n), nil
+}
+
+func (d *binaryDecoder) bytes() ([]byte, error) {
+	n, err := d.length()
+	if err != nil {
+		return nil, err
+	}
+	data := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return data, nil
+}
+
+// setInt stores an integer into an integer or float value, checking for overflow
+func setInt(v reflect.Value, n int64, tag byte) error {
+	switch {
+	case isInt(v):
+		if v.OverflowInt(n) {
+			return mismatch(tag, v)
+		}
+		v.SetInt(n)
+	case isUint(v):
+		if n < 0 || v.OverflowUint(uint64(n)) {
+			return mismatch(tag, v)
+		}
+		v.SetUint(uint64(n))
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		v.SetFloat(float64(n))
+	default:
+		return mismatch(tag, v)
+	}
+	return nil
+}
+
+func isInt(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUint(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+func mismatch(tag byte, v reflect.Value) error {
+	return fmt.Errorf("binary codec: cannot decode tag %d into %s", tag, v.Type())
+}
+
+// binaryField is an encoded struct field
+type binaryField struct {
+	name  string
+	index []int
+}
+
+type binaryFieldList []binaryField
+
+func (l binaryFieldList) lookup(name string) (binaryField, bool) {
+	for _, f := range l {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return binaryField{}, false
+}
+
+// binaryFieldCache caches the encoded fields of each struct type
+var binaryFieldCache sync.Map
+
+// binaryFields returns the exported fields of a struct type under their JSON
+// names. Embedded structs are flattened like encoding/json does, except that
+// embedded pointers are not followed.
+func binaryFields(t reflect.Type) binaryFieldList {
+	if cached, ok := binaryFieldCache.Load(t); ok {
+		return cached.(binaryFieldList)
+	}
+
+	var fields binaryFieldList
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+
+			fieldIndex := append(append([]int(nil), index...), i)
+			if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+				walk(sf.Type, fieldIndex)
+				continue
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = sf.Name
+			}
+			fields = append(fields, binaryField{name: name, index: fieldIndex})
+		}
+	}
+	walk(t, nil)
+
+	binaryFieldCache.Store(t, fields)
+	return fields
+}
\ No newline at end of file
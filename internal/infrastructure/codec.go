@@ -0,0 +1,184 @@
+package infrastructure
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// Codec converts values to and from their stored representation
+type Codec interface {
+	// Name is the name used to select the codec in configuration
+	Name() string
+
+	// ID identifies the codec in stored values
+	ID() byte
+
+	// Marshal encodes a value
+	Marshal(value any) ([]byte, error)
+
+	// Unmarshal decodes data into the value pointed to by value
+	Unmarshal(data []byte, value any) error
+}
+
+// Codec IDs recorded in stored values. JSON values are stored without a
+// prefix, as they were before codecs were configurable; values written by any
+// other codec start with its ID, a byte that never begins a JSON document.
+const (
+	codecIDJSON   byte = 0
+	codecIDGob    byte = 1
+	codecIDBinary byte = 2
+)
+
+// Available codecs
+var (
+	JSONCodec   Codec = jsonCodec{}
+	GobCodec    Codec = gobCodec{}
+	BinaryCodec Codec = binaryCodec{}
+)
+
+func init() {
+	// Generic JSON documents, such as the data of schema envelopes, are made
+	// of these types; gob can only decode them in a process that has
+	// registered them
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+}
+
+// codecs holds every codec that can appear in a stored value, by ID
+var codecs = map[byte]Codec{
+	codecIDGob:    GobCodec,
+	codecIDBinary: BinaryCodec,
+}
+
+// CodecByName returns the codec with the given configuration name
+func CodecByName(name string) (Codec, error) {
+	switch name {
+	case JSONCodec.Name():
+		return JSONCodec, nil
+	case GobCodec.Name():
+		return GobCodec, nil
+	case BinaryCodec.Name():
+		return BinaryCodec, nil
+	default:
+		return nil, fmt.Errorf("unsupported codec: %s", name)
+	}
+}
+
+// encodeValue marshals a value with the given codec and records the codec's
+// identity in the result. Raw values are already encoded and are copied as is.
+func encodeValue(codec Codec, value any) ([]byte, error) {
+	if raw, ok := value.(domain.RawValue); ok {
+		return append([]byte(nil), raw...), nil
+	}
+
+	data, err := codec.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	if codec.ID() == codecIDJSON {
+		return data, nil
+	}
+	return append([]byte{codec.ID()}, data...), nil
+}
+
+// decodeValue unmarshals a stored value with the codec that wrote it,
+// regardless of the codec currently configured. Decoding into a RawValue
+// copies the stored bytes as is.
+func decodeValue(data []byte, value any) error {
+	if raw, ok := value.(*domain.RawValue); ok {
+		*raw = append(domain.RawValue(nil), data...)
+		return nil
+	}
+
+	if isEncrypted(data) {
+		return ErrEncryptedValue
+	}
+
+	if len(data) > 0 {
+		if codec, ok := codecs[data[0]]; ok {
+			return codec.Unmarshal(data[1:], value)
+		}
+	}
+	return JSONCodec.Unmarshal(data, value)
+}
+
+// jsonCodec encodes values with encoding/json
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) ID() byte { return codecIDJSON }
+
+func (jsonCodec) Marshal(value any) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (jsonCodec) Unmarshal(data []byte, value any) error {
+	return json.Unmarshal(data, value)
+}
+
+// gobCodec encodes values with encoding/gob. Values are encoded as interface
+// values so that they can also be decoded without knowing their type, which
+// requires their concrete type to be registered with gob; top-level types are
+// registered automatically.
+type gobCodec struct{}
+
+func (gobCodec) Name() string { return "gob" }
+
+func (gobCodec) ID() byte { return codecIDGob }
+
+func (gobCodec) Marshal(value any) ([]byte, error) {
+	if value != nil {
+		gob.Register(value)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, value any) error {
+	var decoded any
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&decoded); err != nil {
+		return err
+	}
+	return assignDecoded(decoded, value)
+}
+
+// assignDecoded stores a dynamically decoded value into the value pointed to
+// by target
+func assignDecoded(decoded any, target any) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("cannot decode into non-pointer %T", target)
+	}
+
+	dst := rv.Elem()
+	if decoded == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	src := reflect.ValueOf(decoded)
+	if src.Kind() == reflect.Pointer && !src.Type().AssignableTo(dst.Type()) {
+		src = src.Elem()
+	}
+
+	switch {
+	case src.Type().AssignableTo(dst.Type()):
+		dst.Set(src)
+	case src.Type().ConvertibleTo(dst.Type()) && src.Kind() == dst.Kind():
+		dst.Set(src.Convert(dst.Type()))
+	default:
+		return fmt.Errorf("cannot decode %s into %s", src.Type(), dst.Type())
+	}
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,193 @@
+package infrastructure
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// CodecTestAuthor is exported because gob skips embedded fields of unexported types
+type CodecTestAuthor struct {
+	Name string `json:"name"`
+}
+
+type codecTestValue struct {
+	CodecTestAuthor
+	ID        string            `json:"id"`
+	Count     int               `json:"count"`
+	Score     float64           `json:"score"`
+	Published bool              `json:"published"`
+	Tags      []string          `json:"tags"`
+	Meta      map[string]string `json:"meta"`
+	Parent    *codecTestValue   `json:"parent,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Ignored   string            `json:"-"`
+}
+
+func TestCodecs_RoundTrip(t *testing.T) {
+	value := codecTestValue{
+		CodecTestAuthor: CodecTestAuthor{Name: "alice"},
+		ID:              "post-1",
+		Count:           -42,
+		Score:           3.5,
+		Published:       true,
+		Tags:            []string{"go", "storage"},
+		Meta:            map[string]string{"lang": "en"},
+		Parent:          &codecTestValue{ID: "post-0"},
+		CreatedAt:       time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+	}
+
+	for _, codec := range []Codec{JSONCodec, GobCodec, BinaryCodec} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			data, err := encodeValue(codec, value)
+			if err != nil {
+				t.Fatalf("Failed to encode: %v", err)
+			}
+
+			var decoded codecTestValue
+			if err := decodeValue(data, &decoded); err != nil {
+				t.Fatalf("Failed to decode: %v", err)
+			}
+
+			if !reflect.DeepEqual(decoded, value) {
+				t.Errorf("Expected %+v, got %+v", value, decoded)
+			}
+
+			var str string
+			data, _ = encodeValue(codec, "hello")
+			if err := decodeValue(data, &str); err != nil || str != "hello" {
+				t.Errorf("Expected 'hello', got %q (%v)", str, err)
+			}
+		})
+	}
+}
+
+func TestCodecs_Envelope(t *testing.T) {
+	envelope := domain.Envelope{
+		Schema:        "post",
+		SchemaVersion: 2,
+		Data: map[string]any{
+			"id":     "post-1",
+			"count":  float64(3),
+			"tags":   []any{"go", "storage"},
+			"author": map[string]any{"name": "alice"},
+		},
+	}
+
+	for _, codec := range []Codec{JSONCodec, GobCodec, BinaryCodec} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			data, err := encodeValue(codec, envelope)
+			if err != nil {
+				t.Fatalf("Failed to encode: %v", err)
+			}
+
+			var decoded domain.Envelope
+			if err := decodeValue(data, &decoded); err != nil {
+				t.Fatalf("Failed to decode: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, envelope) {
+				t.Errorf("Expected %+v, got %+v", envelope, decoded)
+			}
+
+			// Envelopes can be read without knowing their type
+			var generic any
+			if err := decodeValue(data, &generic); err != nil || generic == nil {
+				t.Errorf("Expected envelope to decode without a type, got %v (%v)", generic, err)
+			}
+		})
+	}
+}
+
+func TestCodecByName(t *testing.T) {
+	for _, name := range []string{"json", "gob", "binary"} {
+		codec, err := CodecByName(name)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", name, err)
+			continue
+		}
+		if codec.Name() != name {
+			t.Errorf("Expected codec %s, got %s", name, codec.Name())
+		}
+	}
+
+	if _, err := CodecByName("xml"); err == nil {
+		t.Error("Expected error for unknown codec")
+	}
+}
+
+func TestBinaryCodec_DecodeAny(t *testing.T) {
+	data, err := BinaryCodec.Marshal(map[string]any{
+		"title": "Hello",
+		"count": 3,
+		"tags":  []string{"a", "b"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+
+	var decoded any
+	if err := BinaryCodec.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+
+	expected := map[string]any{
+		"title": "Hello",
+		"count": int64(3),
+		"tags":  []any{"a", "b"},
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("Expected %v, got %v", expected, decoded)
+	}
+}
+
+func TestBinaryCodec_CorruptData(t *testing.T) {
+	data, _ := BinaryCodec.Marshal(codecTestValue{ID: "post-1", Tags: []string{"go"}})
+
+	var decoded codecTestValue
+	for i := 0; i < len(data); i++ {
+		if err := BinaryCodec.Unmarshal(data[:i], &decoded); err == nil {
+			t.Errorf("Expected error decoding %d of %d bytes", i, len(data))
+		}
+	}
+
+	var count int
+	data, _ = BinaryCodec.Marshal("not a number")
+	if err := BinaryCodec.Unmarshal(data, &count); err == nil {
+		t.Error("Expected error decoding string into int")
+	}
+}
+
+func TestMemoryStore_SwitchCodec(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// Write one value with each codec, reopening the store in between
+	for i, codec := range []Codec{JSONCodec, GobCodec, BinaryCodec} {
+		store, err := NewFileStore(dir, FileStoreOptions{Fsync: FsyncAlways, Codec: codec})
+		if err != nil {
+			t.Fatalf("Failed to open store: %v", err)
+		}
+		store.Set(ctx, codec.Name(), codecTestValue{ID: codec.Name(), Count: i})
+		store.Close()
+	}
+
+	store, err := NewFileStore(dir, FileStoreOptions{Fsync: FsyncAlways})
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	for i, name := range []string{"json", "gob", "binary"} {
+		var value codecTestValue
+		if err := store.GetTyped(ctx, name, &value); err != nil {
+			t.Errorf("Failed to read value written with %s: %v", name, err)
+			continue
+		}
+		if value.ID != name || value.Count != i {
+			t.Errorf("Expected %s/%d, got %s/%d", name, i, value.ID, value.Count)
+		}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,44 @@
+package infrastructure
+
+import (
+	"testing"
+
+	"gosuda.org/boilerplate/internal/domain"
+	"gosuda.org/boilerplate/internal/infrastructure/storetest"
+)
+
+func TestMemoryStore_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) domain.Store {
+		return NewMemoryStore()
+	})
+}
+
+func TestMemoryStore_BinaryCodecConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) domain.Store {
+		return NewMemoryStore(WithCodec(BinaryCodec))
+	})
+}
+
+func TestShardedStore_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) domain.Store {
+		return NewShardedStore(ShardedStoreOptions{Shards: 4})
+	})
+}
+
+func TestFileStore_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) domain.Store {
+		return newTestFileStore(t, t.TempDir())
+	})
+}
+
+func TestEncryptedStore_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) domain.Store {
+		return NewEncryptedStore(NewMemoryStore(), newTestKeyring(t, "k1"), EncryptedStoreOptions{})
+	})
+}
+
+func TestInstrumentedStore_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) domain.Store {
+		return NewInstrumentedStore(NewMemoryStore(), &recordingLogger{})
+	})
+}
\ No newline at end of file
@@ -0,0 +1,373 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// EncryptedStore decorates a Store so values are encrypted with AES-GCM
+// before they reach it and decrypted on read. Keys, versions and TTLs are
+// stored in the clear. Each value is bound to the key it is stored under, so
+// values cannot be swapped between keys.
+//
+// Reading a value that is not encrypted fails with ErrPlaintextValue, unless
+// AllowPlaintext is set while migrating a store written before encryption was
+// enabled; Reencrypt encrypts such values. Iterators and watch events expose
+// the encrypted bytes, so backups taken through an EncryptedStore are
+// encrypted too and can be restored through one holding the same keys.
+type EncryptedStore struct {
+	store          domain.Store
+	keyring        *Keyring
+	codec          Codec
+	allowPlaintext bool
+}
+
+// Ensure EncryptedStore implements domain.Store
+var _ domain.Store = (*EncryptedStore)(nil)
+
+// EncryptedStoreOptions configures an EncryptedStore
+type EncryptedStoreOptions struct {
+	// Codec encodes values before they are encrypted; nil uses JSONCodec
+	Codec Codec
+
+	// AllowPlaintext returns values that are not encrypted as they are
+	// instead of failing. It is meant for migrating an existing store until
+	// Reencrypt has run.
+	AllowPlaintext bool
+}
+
+// NewEncryptedStore wraps a store with value encryption
+func NewEncryptedStore(store domain.Store, keyring *Keyring, options EncryptedStoreOptions) *EncryptedStore {
+	s := &EncryptedStore{
+		store:          store,
+		keyring:        keyring,
+		codec:          JSONCodec,
+		allowPlaintext: options.AllowPlaintext,
+	}
+	if options.Codec != nil {
+		s.codec = options.Codec
+	}
+	return s
+}
+
+// Keyring returns the keys the store encrypts and decrypts values with
+func (s *EncryptedStore) Keyring() *Keyring {
+	return s.keyring
+}
+
+// Set stores a value with the given key
+func (s *EncryptedStore) Set(ctx context.Context, key string, value any) error {
+	sealed, err := s.seal(key, value)
+	if err != nil {
+		return err
+	}
+	return s.store.Set(ctx, key, sealed)
+}
+
+// SetWithTTL stores a value that expires after ttl
+func (s *EncryptedStore) SetWithTTL(ctx context.Context, key string, value any, ttl time.Duration) error {
+	sealed, err := s.seal(key, value)
+	if err != nil {
+		return err
+	}
+	return s.store.SetWithTTL(ctx, key, sealed, ttl)
+}
+
+// Get retrieves a value by key
+func (s *EncryptedStore) Get(ctx context.Context, key string) (any, error) {
+	var result any
+	if err := s.GetTyped(ctx, key, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetTyped retrieves a value by key and unmarshals it into the provided type
+func (s *EncryptedStore) GetTyped(ctx context.Context, key string, value any) error {
+	_, err := s.GetTypedVersion(ctx, key, value)
+	return err
+}
+
+// GetTypedVersion retrieves a value and its current version by key
+func (s *EncryptedStore) GetTypedVersion(ctx context.Context, key string, value any) (int64, error) {
+	var raw domain.RawValue
+	version, err := s.store.GetTypedVersion(ctx, key, &raw)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.open(key, raw, value); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+
+	return version, nil
+}
+
+// CompareAndSwap stores a value only if the key's current version equals the
+// given version
+func (s *EncryptedStore) CompareAndSwap(ctx context.Context, key string, value any, version int64) (int64, error) {
+	sealed, err := s.seal(key, value)
+	if err != nil {
+		return 0, err
+	}
+	return s.store.CompareAndSwap(ctx, key, sealed, version)
+}
+
+// List retrieves all values with the given key prefix
+func (s *EncryptedStore) List(ctx context.Context, keyPrefix string) ([]any, error) {
+	it, err := s.Iterate(ctx, domain.ScanOptions{Prefix: keyPrefix})
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	var values []any
+	for it.Next() {
+		var value any
+		if err := it.Decode(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// Scan returns keys in order according to opts
+func (s *EncryptedStore) Scan(ctx context.Context, opts domain.ScanOptions) ([]string, error) {
+	return s.store.Scan(ctx, opts)
+}
+
+// Iterate returns an iterator over the entries selected by opts
+func (s *EncryptedStore) Iterate(ctx context.Context, opts domain.ScanOptions) (domain.Iterator, error) {
+	it, err := s.store.Iterate(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedIterator{Iterator: it, store: s}, nil
+}
+
+// Delete removes a value by key
+func (s *EncryptedStore) Delete(ctx context.Context, key string) error {
+	return s.store.Delete(ctx, key)
+}
+
+// CompareAndDelete removes a key only if its current version equals the
+// given version
+func (s *EncryptedStore) CompareAndDelete(ctx context.Context, key string, version int64) error {
+	return s.store.CompareAndDelete(ctx, key, version)
+}
+
+// Update runs fn in a transaction whose reads are decrypted and whose writes
+// are encrypted
+func (s *EncryptedStore) Update(ctx context.Context, fn func(tx domain.Tx) error) error {
+	return s.store.Update(ctx, func(tx domain.Tx) error {
+		return fn(&encryptedTx{Tx: tx, store: s})
+	})
+}
+
+// Watch streams changes to keys matching opts. Event values are encrypted;
+// the watcher's Decode decrypts them.
+func (s *EncryptedStore) Watch(ctx context.Context, opts domain.WatchOptions) (domain.Watcher, error) {
+	w, err := s.store.Watch(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedWatcher{Watcher: w, store: s}, nil
+}
+
+// Close closes the underlying store
+func (s *EncryptedStore) Close() error {
+	return s.store.Close()
+}
+
+// Stats returns the underlying store's stats
+func (s *EncryptedStore) Stats() domain.StoreStats {
+	if provider, ok := s.store.(domain.StatsProvider); ok {
+		return provider.Stats()
+	}
+	return domain.StoreStats{}
+}
+
+// Revision returns the underlying store's latest revision, or 0 if it does
+// not report one
+func (s *EncryptedStore) Revision() int64 {
+	if provider, ok := s.store.(domain.RevisionProvider); ok {
+		return provider.Revision()
+	}
+	return 0
+}
+
+// ApplyEvents applies replicated changes to the underlying store. Replicated
+// values are already encrypted by the leader, so they are stored as they are.
+func (s *EncryptedStore) ApplyEvents(ctx context.Context, events []domain.Event) error {
+	return domain.ApplyEvents(ctx, s.store, events)
+}
+
+// Compact compacts the underlying store, or returns ErrNotCompactable if it
+// cannot be compacted
+func (s *EncryptedStore) Compact(ctx context.Context) (domain.CompactionStats, error) {
+	if compactor, ok := s.store.(domain.Compactor); ok {
+		return compactor.Compact(ctx)
+	}
+	return domain.CompactionStats{}, domain.ErrNotCompactable
+}
+
+// Reencrypt rewrites every value that is not encrypted with the active key,
+// including values written before encryption was enabled regardless of
+// AllowPlaintext, and returns the number of values rewritten. Once it has run, keys that are no longer
+// active can be removed from the keyring.
+//
+// Values are rewritten with CompareAndSwap, so a value changed concurrently
+// is left to that write, and rewritten values lose their TTL.
+func (s *EncryptedStore) Reencrypt(ctx context.Context) (int, error) {
+	it, err := s.store.Iterate(ctx, domain.ScanOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer it.Close()
+
+	active := s.keyring.ActiveKeyID()
+	count := 0
+	for it.Next() {
+		if id, ok := encryptionKeyID(it.Value()); ok && id == active {
+			continue
+		}
+
+		plaintext := it.Value()
+		if isEncrypted(plaintext) {
+			plaintext, err = s.keyring.decrypt(it.Key(), plaintext)
+			if err != nil {
+				return count, fmt.Errorf("failed to decrypt value for key %s: %w", it.Key(), err)
+			}
+		}
+		sealed, err := s.keyring.encrypt(it.Key(), plaintext)
+		if err != nil {
+			return count, err
+		}
+
+		_, err = s.store.CompareAndSwap(ctx, it.Key(), domain.RawValue(sealed), it.Version())
+		if err == domain.ErrVersionMismatch || err == domain.ErrKeyNotFound {
+			continue
+		}
+		if err != nil {
+			return count, fmt.Errorf("failed to rewrite key %s: %w", it.Key(), err)
+		}
+		count++
+	}
+
+	if err := it.Err(); err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// seal encodes and encrypts a value to be stored under key. Raw values that
+// are already encrypted, e.g. from a backup, are stored as is.
+func (s *EncryptedStore) seal(key string, value any) (domain.RawValue, error) {
+	if raw, ok := value.(domain.RawValue); ok && isEncrypted(raw) {
+		return raw, nil
+	}
+
+	data, err := encodeValue(s.codec, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	sealed, err := s.keyring.encrypt(key, data)
+	if err != nil {
+		return nil, err
+	}
+	return domain.RawValue(sealed), nil
+}
+
+// open decrypts a value stored under key and decodes it into the provided
+// type. Values that are not encrypted are only decoded as they are when
+// plaintext is allowed.
+func (s *EncryptedStore) open(key string, data []byte, value any) error {
+	if !isEncrypted(data) && s.allowPlaintext {
+		return decodeValue(data, value)
+	}
+
+	plaintext, err := s.keyring.decrypt(key, data)
+	if err != nil {
+		return err
+	}
+	return decodeValue(plaintext, value)
+}
+
+// encryptedTx implements domain.Tx on top of a transaction of the underlying store
+type encryptedTx struct {
+	domain.Tx
+	store *EncryptedStore
+}
+
+// Get retrieves a value by key, observing writes made earlier in the transaction
+func (tx *encryptedTx) Get(key string) (any, error) {
+	var result any
+	if err := tx.GetTyped(key, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetTyped retrieves a value by key and unmarshals it into the provided type
+func (tx *encryptedTx) GetTyped(key string, value any) error {
+	var raw domain.RawValue
+	if err := tx.Tx.GetTyped(key, &raw); err != nil {
+		return err
+	}
+
+	if err := tx.store.open(key, raw, value); err != nil {
+		return fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+
+	return nil
+}
+
+// Set stages a write of value to key
+func (tx *encryptedTx) Set(key string, value any) error {
+	sealed, err := tx.store.seal(key, value)
+	if err != nil {
+		return err
+	}
+	return tx.Tx.Set(key, sealed)
+}
+
+// encryptedIterator implements domain.Iterator on top of an iterator of the
+// underlying store. Value returns the encrypted bytes.
+type encryptedIterator struct {
+	domain.Iterator
+	store *EncryptedStore
+}
+
+// Decode decrypts the current value and unmarshals it into the provided type
+func (it *encryptedIterator) Decode(value any) error {
+	if err := it.store.open(it.Key(), it.Value(), value); err != nil {
+		return fmt.Errorf("failed to unmarshal value for key %s: %w", it.Key(), err)
+	}
+	return nil
+}
+
+// encryptedWatcher implements domain.Watcher on top of a watcher of the
+// underlying store
+type encryptedWatcher struct {
+	domain.Watcher
+	store *EncryptedStore
+}
+
+// Decode decrypts an event value and unmarshals it into the provided type
+func (w *encryptedWatcher) Decode(key string, data []byte, value any) error {
+	if err := w.store.open(key, data, value); err != nil {
+		return fmt.Errorf("failed to unmarshal event value: %w", err)
+	}
+	return nil
+}
\ No newline at end of file
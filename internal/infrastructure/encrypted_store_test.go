@@ -0,0 +1,318 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gosuda.org/boilerplate/internal/config"
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// testKey returns a 32 byte key filled with b
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+// newTestKeyring creates a keyring holding keys "k1" and "k2"
+func newTestKeyring(t *testing.T, active string) *Keyring {
+	t.Helper()
+	keyring, err := NewKeyring(map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, active)
+	if err != nil {
+		t.Fatalf("Failed to create keyring: %v", err)
+	}
+	return keyring
+}
+
+func TestEncryptedStore_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemoryStore()
+	store := NewEncryptedStore(inner, newTestKeyring(t, "k1"), EncryptedStoreOptions{Codec: BinaryCodec})
+	defer store.Close()
+
+	if err := store.Set(ctx, "posts:1", codecTestValue{ID: "post-1", Tags: []string{"secret"}}); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	var value codecTestValue
+	if err := store.GetTyped(ctx, "posts:1", &value); err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	if value.ID != "post-1" || len(value.Tags) != 1 || value.Tags[0] != "secret" {
+		t.Errorf("Expected post-1 with tag secret, got %+v", value)
+	}
+
+	// The underlying store only ever sees ciphertext
+	var raw domain.RawValue
+	if err := inner.GetTyped(ctx, "posts:1", &raw); err != nil {
+		t.Fatalf("Failed to read raw value: %v", err)
+	}
+	if id, ok := encryptionKeyID(raw); !ok || id != "k1" {
+		t.Errorf("Expected value encrypted with k1, got %q", id)
+	}
+	if bytes.Contains(raw, []byte("secret")) {
+		t.Error("Expected stored value not to contain the plaintext")
+	}
+	if err := inner.GetTyped(ctx, "posts:1", &value); !errors.Is(err, ErrEncryptedValue) {
+		t.Errorf("Expected ErrEncryptedValue reading without the key, got %v", err)
+	}
+
+	// Transactions, listings and watchers decrypt too
+	w, err := store.Watch(ctx, domain.WatchOptions{})
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+	defer w.Close()
+
+	err = store.Update(ctx, func(tx domain.Tx) error {
+		var current codecTestValue
+		if err := tx.GetTyped("posts:1", &current); err != nil {
+			return err
+		}
+		current.ID = "post-2"
+		return tx.Set("posts:2", current)
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	values, err := domain.ListTyped[codecTestValue](ctx, store, domain.ScanOptions{Prefix: "posts:"})
+	if err != nil {
+		t.Fatalf("Failed to list values: %v", err)
+	}
+	if len(values) != 2 || values[1].Value.ID != "post-2" {
+		t.Errorf("Expected post-1 and post-2, got %+v", values)
+	}
+
+	ev := <-w.Events()
+	if err := w.Decode(ev.Key, ev.Value, &value); err != nil || value.ID != "post-2" {
+		t.Errorf("Expected event for post-2, got %+v (%v)", value, err)
+	}
+}
+
+func TestEncryptedStore_KeyRotation(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemoryStore()
+	defer inner.Close()
+
+	// A value written before encryption was enabled
+	inner.Set(ctx, "legacy", "plaintext")
+
+	old := NewEncryptedStore(inner, newTestKeyring(t, "k1"), EncryptedStoreOptions{})
+	old.Set(ctx, "key1", "value1")
+
+	// After rotating to k2, values under k1 and, while migrating, plaintext
+	// stay readable
+	store := NewEncryptedStore(inner, newTestKeyring(t, "k2"), EncryptedStoreOptions{AllowPlaintext: true})
+	for key, expected := range map[string]string{"legacy": "plaintext", "key1": "value1"} {
+		var value string
+		if err := store.GetTyped(ctx, key, &value); err != nil || value != expected {
+			t.Errorf("Expected %q for %s, got %q (%v)", expected, key, value, err)
+		}
+	}
+
+	store.Set(ctx, "key2", "value2")
+
+	count, err := store.Reencrypt(ctx)
+	if err != nil {
+		t.Fatalf("Failed to re-encrypt: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 values re-encrypted, got %d", count)
+	}
+
+	it, err := inner.Iterate(ctx, domain.ScanOptions{})
+	if err != nil {
+		t.Fatalf("Failed to iterate: %v", err)
+	}
+	defer it.Close()
+	for it.Next() {
+		if id, _ := encryptionKeyID(it.Value()); id != "k2" {
+			t.Errorf("Expected %s encrypted with k2, got %q", it.Key(), id)
+		}
+	}
+
+	// Once re-encrypted, the old key is no longer needed
+	keyring, _ := NewKeyring(map[string][]byte{"k2": testKey(2)}, "k2")
+	var value string
+	if err := NewEncryptedStore(inner, keyring, EncryptedStoreOptions{}).GetTyped(ctx, "key1", &value); err != nil || value != "value1" {
+		t.Errorf("Expected value1 with only k2, got %q (%v)", value, err)
+	}
+
+	// Nothing is left to rewrite
+	if count, err := store.Reencrypt(ctx); err != nil || count != 0 {
+		t.Errorf("Expected nothing to re-encrypt, got %d (%v)", count, err)
+	}
+}
+
+func TestEncryptedStore_DecryptionErrors(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemoryStore()
+	defer inner.Close()
+
+	NewEncryptedStore(inner, newTestKeyring(t, "k2"), EncryptedStoreOptions{}).Set(ctx, "key1", "value1")
+
+	// A keyring without the key the value was encrypted with
+	keyring, _ := NewKeyring(map[string][]byte{"k1": testKey(1)}, "k1")
+	store := NewEncryptedStore(inner, keyring, EncryptedStoreOptions{})
+
+	var value string
+	if err := store.GetTyped(ctx, "key1", &value); !errors.Is(err, ErrUnknownEncryptionKey) {
+		t.Errorf("Expected ErrUnknownEncryptionKey, got %v", err)
+	}
+
+	// A key with the right ID but the wrong bytes
+	keyring, _ = NewKeyring(map[string][]byte{"k2": testKey(3)}, "k2")
+	if err := NewEncryptedStore(inner, keyring, EncryptedStoreOptions{}).GetTyped(ctx, "key1", &value); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("Expected ErrDecryptionFailed with the wrong key, got %v", err)
+	}
+
+	// Tampering with the ciphertext or the authenticated header is detected
+	var raw domain.RawValue
+	inner.GetTyped(ctx, "key1", &raw)
+	for _, pos := range []int{len(raw) - 1, 3} {
+		tampered := append(domain.RawValue(nil), raw...)
+		tampered[pos] ^= 0xff
+		inner.Set(ctx, "key1", tampered)
+
+		err := NewEncryptedStore(inner, newTestKeyring(t, "k2"), EncryptedStoreOptions{}).GetTyped(ctx, "key1", &value)
+		if !errors.Is(err, ErrDecryptionFailed) && !errors.Is(err, ErrUnknownEncryptionKey) {
+			t.Errorf("Expected tampering at byte %d to be detected, got %v", pos, err)
+		}
+	}
+}
+
+func TestEncryptedStore_KeyBinding(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemoryStore()
+	store := NewEncryptedStore(inner, newTestKeyring(t, "k1"), EncryptedStoreOptions{})
+	defer store.Close()
+
+	store.Set(ctx, "authors:alice", "alice@example.com")
+	store.Set(ctx, "authors:mallory", "mallory@example.com")
+
+	// A ciphertext moved to another key no longer decrypts
+	var raw domain.RawValue
+	inner.GetTyped(ctx, "authors:alice", &raw)
+	inner.Set(ctx, "authors:mallory", raw)
+
+	var value string
+	if err := store.GetTyped(ctx, "authors:mallory", &value); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("Expected ErrDecryptionFailed for a swapped value, got %q (%v)", value, err)
+	}
+	if err := store.GetTyped(ctx, "authors:alice", &value); err != nil || value != "alice@example.com" {
+		t.Errorf("Expected the original value to stay readable, got %q (%v)", value, err)
+	}
+}
+
+func TestEncryptedStore_Plaintext(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemoryStore()
+	defer inner.Close()
+
+	inner.Set(ctx, "legacy", "plaintext")
+
+	// Plaintext is rejected unless the store is migrating
+	var value string
+	store := NewEncryptedStore(inner, newTestKeyring(t, "k1"), EncryptedStoreOptions{})
+	if err := store.GetTyped(ctx, "legacy", &value); !errors.Is(err, ErrPlaintextValue) {
+		t.Errorf("Expected ErrPlaintextValue, got %q (%v)", value, err)
+	}
+	err := store.Update(ctx, func(tx domain.Tx) error {
+		return tx.GetTyped("legacy", &value)
+	})
+	if !errors.Is(err, ErrPlaintextValue) {
+		t.Errorf("Expected ErrPlaintextValue in a transaction, got %v", err)
+	}
+
+	migrating := NewEncryptedStore(inner, newTestKeyring(t, "k1"), EncryptedStoreOptions{AllowPlaintext: true})
+	if err := migrating.GetTyped(ctx, "legacy", &value); err != nil || value != "plaintext" {
+		t.Errorf("Expected plaintext while migrating, got %q (%v)", value, err)
+	}
+
+	// Reencrypt encrypts plaintext even when it is not allowed
+	if count, err := store.Reencrypt(ctx); err != nil || count != 1 {
+		t.Fatalf("Expected 1 value re-encrypted, got %d (%v)", count, err)
+	}
+	if err := store.GetTyped(ctx, "legacy", &value); err != nil || value != "plaintext" {
+		t.Errorf("Expected the re-encrypted value, got %q (%v)", value, err)
+	}
+}
+
+func TestEncryptedStore_BackupRestore(t *testing.T) {
+	ctx := context.Background()
+	source := NewEncryptedStore(NewMemoryStore(), newTestKeyring(t, "k1"), EncryptedStoreOptions{})
+	defer source.Close()
+
+	source.Set(ctx, "posts:1", codecTestValue{ID: "post-1"})
+
+	var buf bytes.Buffer
+	if _, err := Backup(ctx, source, &buf, BackupOptions{}); err != nil {
+		t.Fatalf("Failed to back up: %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("post-1")) {
+		t.Error("Expected backup not to contain the plaintext")
+	}
+
+	target := NewEncryptedStore(NewMemoryStore(), newTestKeyring(t, "k2"), EncryptedStoreOptions{})
+	defer target.Close()
+
+	if _, err := Restore(ctx, target, &buf); err != nil {
+		t.Fatalf("Failed to restore: %v", err)
+	}
+
+	var value codecTestValue
+	if err := target.GetTyped(ctx, "posts:1", &value); err != nil || value.ID != "post-1" {
+		t.Errorf("Expected restored post-1, got %+v (%v)", value, err)
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	if keyring, err := LoadKeyring(&config.EncryptionConfig{KeyID: "default"}); keyring != nil || err != nil {
+		t.Errorf("Expected no keyring without keys, got %v (%v)", keyring, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keys")
+	content := "# retired keys first\n" +
+		"old " + base64.StdEncoding.EncodeToString(testKey(1)) + "\n\n" +
+		"new " + base64.StdEncoding.EncodeToString(testKey(2)) + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	keyring, err := LoadKeyring(&config.EncryptionConfig{KeyID: "default", KeyFile: path})
+	if err != nil {
+		t.Fatalf("Failed to load keyring: %v", err)
+	}
+	if keyring.ActiveKeyID() != "new" {
+		t.Errorf("Expected the last key in the file to be active, got %s", keyring.ActiveKeyID())
+	}
+
+	keyring, err = LoadKeyring(&config.EncryptionConfig{
+		Key:     base64.StdEncoding.EncodeToString(testKey(3)),
+		KeyID:   "env",
+		KeyFile: path,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load keyring: %v", err)
+	}
+	if keyring.ActiveKeyID() != "env" {
+		t.Errorf("Expected the configured key to be active, got %s", keyring.ActiveKeyID())
+	}
+	if _, ok := keyring.aeads["old"]; !ok {
+		t.Error("Expected keys from the key file to be loaded")
+	}
+
+	if _, err := LoadKeyring(&config.EncryptionConfig{KeyFile: path, ActiveKeyID: "missing"}); err == nil {
+		t.Error("Expected error for unknown active key but got none")
+	}
+
+	os.WriteFile(path, []byte("old not-base64\n"), 0o600)
+	if _, err := LoadKeyring(&config.EncryptionConfig{KeyFile: path}); err == nil {
+		t.Error("Expected error for invalid key file but got none")
+	}
+}
\ No newline at end of file
@@ -0,0 +1,216 @@
+package infrastructure
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"gosuda.org/boilerplate/internal/config"
+)
+
+// Encrypted value layout:
+//
+//	encryptedValueID | key ID length (1 byte) | key ID | nonce | AES-GCM ciphertext and tag
+//
+// The plaintext is the value as encoded by a codec. encryptedValueID shares the
+// codec ID space, so an encrypted value can never be mistaken for one written
+// by a codec. The header before the nonce and the storage key the value is
+// stored under are authenticated with the ciphertext, so a value copied to
+// another key fails to decrypt.
+const (
+	encryptedValueID byte = 3
+
+	maxKeyIDLength = 255
+)
+
+// Keyring holds the AES keys stored values may be encrypted with, by key ID.
+// New values are encrypted with the active key; values encrypted with any key
+// in the ring can be decrypted, which lets keys be rotated without downtime.
+type Keyring struct {
+	aeads  map[string]cipher.AEAD
+	active string
+}
+
+// NewKeyring creates a keyring from AES-128, AES-192 or AES-256 keys by ID.
+// active names the key used to encrypt new values.
+func NewKeyring(keys map[string][]byte, active string) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("at least one encryption key is required")
+	}
+
+	k := &Keyring{aeads: make(map[string]cipher.AEAD, len(keys)), active: active}
+	for id, key := range keys {
+		if id == "" || len(id) > maxKeyIDLength {
+			return nil, fmt.Errorf("invalid encryption key ID %q", id)
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %q: %w", id, err)
+		}
+		k.aeads[id] = aead
+	}
+
+	if _, ok := k.aeads[active]; !ok {
+		return nil, fmt.Errorf("active encryption key %q is not configured", active)
+	}
+
+	return k, nil
+}
+
+// LoadKeyring builds a keyring from the encryption configuration. It returns
+// nil if no key is configured.
+//
+// Keys come from cfg.Key, identified by cfg.KeyID, and from cfg.KeyFile,
+// which holds one "keyID base64key" pair per line; blank lines and lines
+// starting with # are ignored. The active key is cfg.ActiveKeyID if set,
+// otherwise cfg.KeyID if cfg.Key is set, otherwise the last key in the file.
+func LoadKeyring(cfg *config.EncryptionConfig) (*Keyring, error) {
+	if cfg.Key == "" && cfg.KeyFile == "" {
+		return nil, nil
+	}
+
+	keys := make(map[string][]byte)
+	active := cfg.ActiveKeyID
+
+	if cfg.KeyFile != "" {
+		last, err := readKeyFile(cfg.KeyFile, keys)
+		if err != nil {
+			return nil, err
+		}
+		if active == "" && cfg.Key == "" {
+			active = last
+		}
+	}
+
+	if cfg.Key != "" {
+		key, err := base64.StdEncoding.DecodeString(cfg.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key: %w", err)
+		}
+		keys[cfg.KeyID] = key
+		if active == "" {
+			active = cfg.KeyID
+		}
+	}
+
+	return NewKeyring(keys, active)
+}
+
+// readKeyFile adds the keys in a key file to keys and returns the ID of the
+// last one
+func readKeyFile(path string, keys map[string][]byte) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open key file: %w", err)
+	}
+	defer f.Close()
+
+	var last string
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return "", fmt.Errorf("key file line %d: expected \"keyID base64key\"", line)
+		}
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil {
+			return "", fmt.Errorf("key file line %d: invalid key: %w", line, err)
+		}
+
+		keys[fields[0]] = key
+		last = fields[0]
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read key file: %w", err)
+	}
+	if last == "" {
+		return "", fmt.Errorf("key file %s contains no keys", path)
+	}
+
+	return last, nil
+}
+
+// ActiveKeyID returns the ID of the key new values are encrypted with
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// encrypt seals an encoded value stored under key with the active key
+func (k *Keyring) encrypt(key string, plaintext []byte) ([]byte, error) {
+	aead := k.aeads[k.active]
+
+	header := append([]byte{encryptedValueID, byte(len(k.active))}, k.active...)
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := make([]byte, 0, len(header)+len(nonce)+len(plaintext)+aead.Overhead())
+	out = append(out, header...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, additionalData(header, key)), nil
+}
+
+// decrypt opens an encrypted value stored under key
+func (k *Keyring) decrypt(key string, data []byte) ([]byte, error) {
+	id, ok := encryptionKeyID(data)
+	if !ok {
+		if isEncrypted(data) {
+			return nil, fmt.Errorf("%w: truncated header", ErrDecryptionFailed)
+		}
+		return nil, ErrPlaintextValue
+	}
+
+	aead, ok := k.aeads[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEncryptionKey, id)
+	}
+
+	header := data[:2+len(id)]
+	rest := data[len(header):]
+	if len(rest) < aead.NonceSize()+aead.Overhead() {
+		return nil, fmt.Errorf("%w: truncated value", ErrDecryptionFailed)
+	}
+
+	nonce, ciphertext := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData(header, key))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptionFailed, err)
+	}
+	return plaintext, nil
+}
+
+// additionalData returns the data authenticated with a value: its header
+// followed by the storage key
+func additionalData(header []byte, key string) []byte {
+	return append(header[:len(header):len(header)], key...)
+}
+
+// isEncrypted reports whether a stored value is encrypted
+func isEncrypted(data []byte) bool {
+	return len(data) > 0 && data[0] == encryptedValueID
+}
+
+// encryptionKeyID returns the ID of the key an encrypted value was sealed with
+func encryptionKeyID(data []byte) (string, bool) {
+	if !isEncrypted(data) || len(data) < 2 || len(data) < 2+int(data[1]) || data[1] == 0 {
+		return "", false
+	}
+	return string(data[2 : 2+int(data[1])]), true
+}
\ No newline at end of file
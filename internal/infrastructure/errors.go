@@ -5,4 +5,11 @@ import "errors"
 // Infrastructure errors
 var (
 	ErrInvalidLogLevel = errors.New("invalid log level")
+	ErrInvalidBackup   = errors.New("invalid backup")
+	ErrStoreNotEmpty   = errors.New("store is not empty")
+
+	ErrEncryptedValue       = errors.New("value is encrypted")
+	ErrUnknownEncryptionKey = errors.New("unknown encryption key")
+	ErrDecryptionFailed     = errors.New("decryption failed")
+	ErrPlaintextValue       = errors.New("value is not encrypted")
 )
\ No newline at end of file
@@ -0,0 +1,494 @@
+package infrastructure
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// Fsync policies supported by the file store
+const (
+	FsyncAlways   = "always"
+	FsyncInterval = "interval"
+	FsyncNever    = "never"
+)
+
+// File names used inside the storage directory
+const (
+	logFileName      = "store.log"
+	snapshotFileName = "store.snapshot"
+)
+
+// recordHeaderSize is the size of the length and checksum prefix of a record
+const recordHeaderSize = 8
+
+// maxRecordSize caps the payload of a record. Larger writes are rejected, and
+// a larger length read from disk can only be corruption, so it is never
+// allocated.
+const maxRecordSize = 64 << 20
+
+// errCorruptRecord is returned when a record fails to decode or verify
+var errCorruptRecord = errors.New("corrupt record")
+
+// corruptRecordError reports a record that fails to decode or verify
+type corruptRecordError struct {
+	offset int64
+	reason string
+
+	// tail reports whether the record runs up to the end of the file, as a
+	// record torn by a crash mid-write does
+	tail bool
+}
+
+func (e *corruptRecordError) Error() string {
+	return fmt.Sprintf("corrupt record at offset %d: %s", e.offset, e.reason)
+}
+
+func (e *corruptRecordError) Is(target error) bool {
+	return target == errCorruptRecord
+}
+
+// FileStoreOptions configures the durability behaviour of a FileStore
+type FileStoreOptions struct {
+	// Fsync selects when the log is flushed to stable storage:
+	// "always" after every write, "interval" every FsyncInterval, or "never".
+	Fsync         string
+	FsyncInterval time.Duration
+
+	// SnapshotInterval controls how often the full contents are written to a
+	// snapshot and the log is truncated. Zero disables periodic snapshots.
+	SnapshotInterval time.Duration
+
+	// Codec encodes new values; nil uses JSONCodec
+	Codec Codec
+
+	// SweepInterval controls how often expired keys are removed; zero uses
+	// DefaultSweepInterval
+	SweepInterval time.Duration
+
+	// Limits caps the size of the store. They are not enforced while
+	// recovering, so a store that was written with higher limits still opens.
+	Limits StoreLimits
+}
+
+// FileStore implements the Store interface on top of MemoryStore, persisting
+// every mutation to an append-only log and periodically compacting the log
+// into a snapshot. On startup the snapshot is loaded and the log replayed.
+type FileStore struct {
+	*MemoryStore
+
+	dir     string
+	options FileStoreOptions
+
+	logMu   sync.Mutex
+	logFile *os.File
+	dirty   bool
+	closed  bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// Ensure FileStore implements domain.Store
+var _ domain.Store = (*FileStore)(nil)
+
+// NewFileStore opens or creates a file store in the given directory and
+// recovers its contents from the snapshot and log found there
+func NewFileStore(dir string, options FileStoreOptions) (*FileStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("storage path cannot be empty")
+	}
+
+	switch options.Fsync {
+	case FsyncAlways, FsyncNever:
+	case FsyncInterval:
+		if options.FsyncInterval <= 0 {
+			return nil, fmt.Errorf("invalid fsync interval: %v", options.FsyncInterval)
+		}
+	default:
+		return nil, fmt.Errorf("invalid fsync policy: %s", options.Fsync)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	s := &FileStore{
+		MemoryStore: newMemoryStore(WithCodec(options.Codec), WithSweepInterval(options.SweepInterval), WithLimits(options.Limits)),
+		dir:         dir,
+		options:     options,
+		stop:        make(chan struct{}),
+	}
+
+	if err := s.recover(); err != nil {
+		return nil, err
+	}
+
+	logFile, err := os.OpenFile(s.path(logFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log: %w", err)
+	}
+	s.logFile = logFile
+	s.MemoryStore.journal = s.appendRecord
+
+	// Changes made before this process started are not available to watchers
+	s.MemoryStore.watch.setFloor(s.MemoryStore.revision)
+	s.MemoryStore.startSweeper()
+
+	if options.Fsync == FsyncInterval {
+		s.wg.Add(1)
+		go s.runEvery(options.FsyncInterval, s.Sync)
+	}
+
+	if options.SnapshotInterval > 0 {
+		s.wg.Add(1)
+		go s.runEvery(options.SnapshotInterval, s.Snapshot)
+	}
+
+	return s, nil
+}
+
+// Sync flushes the log to stable storage
+func (s *FileStore) Sync() error {
+	s.logMu.Lock()
+	defer s.logMu.Unlock()
+
+	if s.closed || !s.dirty {
+		return nil
+	}
+
+	if err := s.logFile.Sync(); err != nil {
+		return fmt.Errorf("failed to sync log: %w", err)
+	}
+	s.dirty = false
+	return nil
+}
+
+// Snapshot writes the current contents to the snapshot file and truncates the
+// log. Writes are blocked while the snapshot is taken so that the snapshot and
+// the log never disagree.
+func (s *FileStore) Snapshot() error {
+	s.MemoryStore.mu.RLock()
+	defer s.MemoryStore.mu.RUnlock()
+
+	s.logMu.Lock()
+	defer s.logMu.Unlock()
+
+	if s.closed {
+		return fmt.Errorf("file store is closed")
+	}
+
+	return s.snapshotLocked()
+}
+
+// Compact removes expired keys and rewrites the snapshot, truncating the log,
+// so the files only hold the current value of each live key
+func (s *FileStore) Compact(ctx context.Context) (domain.CompactionStats, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.CompactionStats{}, err
+	}
+
+	stats := domain.CompactionStats{BytesBefore: s.diskSize()}
+
+	expired := s.MemoryStore.expired.Load()
+	if err := s.MemoryStore.sweep(); err != nil {
+		return stats, err
+	}
+	stats.Expired = s.MemoryStore.expired.Load() - expired
+
+	if err := s.Snapshot(); err != nil {
+		return stats, err
+	}
+
+	stats.BytesAfter = s.diskSize()
+	return stats, nil
+}
+
+// diskSize returns the combined size of the snapshot and the log
+func (s *FileStore) diskSize() int64 {
+	var size int64
+	for _, name := range []string{snapshotFileName, logFileName} {
+		if info, err := os.Stat(s.path(name)); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// Close stops background work, takes a final snapshot and closes the log
+func (s *FileStore) Close() error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	s.wg.Wait()
+
+	s.MemoryStore.mu.RLock()
+	s.logMu.Lock()
+	if s.closed {
+		s.logMu.Unlock()
+		s.MemoryStore.mu.RUnlock()
+		return nil
+	}
+	snapshotErr := s.snapshotLocked()
+	closeErr := s.logFile.Close()
+	s.closed = true
+	s.logMu.Unlock()
+	s.MemoryStore.mu.RUnlock()
+
+	if err := s.MemoryStore.Close(); err != nil {
+		return err
+	}
+	if snapshotErr != nil {
+		return snapshotErr
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to close log: %w", closeErr)
+	}
+	return nil
+}
+
+// appendRecord is the MemoryStore journal; it appends a mutation to the log
+func (s *FileStore) appendRecord(m mutation) error {
+	s.logMu.Lock()
+	defer s.logMu.Unlock()
+
+	if s.closed {
+		return fmt.Errorf("file store is closed")
+	}
+
+	frame, err := encodeRecord(m)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.logFile.Write(frame); err != nil {
+		return fmt.Errorf("failed to write log: %w", err)
+	}
+
+	if s.options.Fsync == FsyncAlways {
+		if err := s.logFile.Sync(); err != nil {
+			return fmt.Errorf("failed to sync log: %w", err)
+		}
+		return nil
+	}
+
+	s.dirty = true
+	return nil
+}
+
+// snapshotLocked writes a snapshot and truncates the log. Callers must hold
+// the memory store read lock and the log lock.
+func (s *FileStore) snapshotLocked() error {
+	tmpPath := s.path(snapshotFileName + ".tmp")
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	w := bufio.NewWriter(tmp)
+	for key, e := range s.MemoryStore.data {
+		frame, err := encodeRecord(mutation{Op: opSet, Key: key, Value: e.value, Version: e.version, ExpiresAt: e.expiresAt})
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		if _, err := w.Write(frame); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, s.path(snapshotFileName)); err != nil {
+		return fmt.Errorf("failed to install snapshot: %w", err)
+	}
+	if err := syncDir(s.dir); err != nil {
+		return err
+	}
+
+	// The snapshot now contains everything in the log. If we crash before the
+	// truncation below, replaying the log over the snapshot is harmless.
+	if err := s.logFile.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate log: %w", err)
+	}
+	if err := s.logFile.Sync(); err != nil {
+		return fmt.Errorf("failed to sync log: %w", err)
+	}
+	s.dirty = false
+
+	return nil
+}
+
+// recover loads the snapshot and replays the log into memory. A torn or
+// corrupt record at the end of the log, left by a crash mid-write, is
+// discarded by truncating the log at the last intact record. Corruption
+// followed by more records cannot be left by a crash, and truncating there
+// would discard intact records, so it fails recovery instead.
+func (s *FileStore) recover() error {
+	snapshot, err := os.Open(s.path(snapshotFileName))
+	switch {
+	case err == nil:
+		_, err = readRecords(snapshot, s.MemoryStore.apply)
+		snapshot.Close()
+		if err != nil {
+			return fmt.Errorf("failed to load snapshot: %w", err)
+		}
+	case !os.IsNotExist(err):
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+
+	logFile, err := os.OpenFile(s.path(logFileName), os.O_RDWR, 0o644)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open log: %w", err)
+	}
+	defer logFile.Close()
+
+	valid, err := readRecords(logFile, s.MemoryStore.apply)
+	var corrupt *corruptRecordError
+	if errors.As(err, &corrupt) && corrupt.tail {
+		if err := logFile.Truncate(valid); err != nil {
+			return fmt.Errorf("failed to truncate torn log: %w", err)
+		}
+		return logFile.Sync()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to replay log: %w", err)
+	}
+
+	return nil
+}
+
+// runEvery calls fn every interval until the store is closed
+func (s *FileStore) runEvery(interval time.Duration, fn func() error) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// Errors resurface on the next write or on Close
+			_ = fn()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// path returns the path of a file inside the storage directory
+func (s *FileStore) path(name string) string {
+	return filepath.Join(s.dir, name)
+}
+
+// encodeRecord frames a mutation as length, CRC32 checksum and JSON payload
+func encodeRecord(m mutation) ([]byte, error) {
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal record: %w", err)
+	}
+	if len(payload) > maxRecordSize {
+		return nil, fmt.Errorf("record of %d bytes exceeds the maximum of %d", len(payload), maxRecordSize)
+	}
+
+	frame := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(payload))
+	copy(frame[recordHeaderSize:], payload)
+	return frame, nil
+}
+
+// readRecords decodes framed records from r and passes each one to fn. It
+// returns the offset just past the last intact record, and a
+// *corruptRecordError if a record fails to decode or verify.
+func readRecords(r io.Reader, fn func(m mutation)) (int64, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, recordHeaderSize)
+	var offset int64
+
+	// corrupt reports the record at offset, which is the tail of the log if
+	// nothing follows what was read of it
+	corrupt := func(reason string) error {
+		_, err := br.Peek(1)
+		return &corruptRecordError{offset: offset, reason: reason, tail: err == io.EOF}
+	}
+
+	for {
+		if _, err := io.ReadFull(br, header); err != nil {
+			if err == io.EOF {
+				return offset, nil
+			}
+			if err == io.ErrUnexpectedEOF {
+				return offset, &corruptRecordError{offset: offset, reason: "truncated header", tail: true}
+			}
+			return offset, err
+		}
+
+		length := binary.BigEndian.Uint32(header[0:4])
+		checksum := binary.BigEndian.Uint32(header[4:8])
+
+		// No write produces such a length, so whatever follows the header
+		// is not part of the record
+		if length > maxRecordSize {
+			return offset, corrupt(fmt.Sprintf("length %d exceeds the maximum of %d", length, maxRecordSize))
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return offset, &corruptRecordError{offset: offset, reason: "truncated payload", tail: true}
+			}
+			return offset, err
+		}
+
+		if crc32.ChecksumIEEE(payload) != checksum {
+			return offset, corrupt("checksum mismatch")
+		}
+
+		var m mutation
+		if err := json.Unmarshal(payload, &m); err != nil {
+			return offset, corrupt("invalid payload: " + err.Error())
+		}
+
+		fn(m)
+		offset += int64(recordHeaderSize) + int64(length)
+	}
+}
+
+// syncDir flushes directory metadata so that renames survive a crash
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open storage directory: %w", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync storage directory: %w", err)
+	}
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,419 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+func newTestFileStore(t *testing.T, dir string) *FileStore {
+	t.Helper()
+
+	store, err := NewFileStore(dir, FileStoreOptions{Fsync: FsyncAlways})
+	if err != nil {
+		t.Fatalf("Failed to create file store: %v", err)
+	}
+	return store
+}
+
+func TestNewFileStore_InvalidOptions(t *testing.T) {
+	dir := t.TempDir()
+
+	testCases := []struct {
+		name    string
+		dir     string
+		options FileStoreOptions
+	}{
+		{"empty path", "", FileStoreOptions{Fsync: FsyncAlways}},
+		{"invalid fsync policy", dir, FileStoreOptions{Fsync: "sometimes"}},
+		{"missing fsync interval", dir, FileStoreOptions{Fsync: FsyncInterval}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewFileStore(tc.dir, tc.options); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+}
+
+func TestFileStore_RecoverFromLog(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := newTestFileStore(t, dir)
+
+	store.Set(ctx, "key1", "value1")
+	store.Set(ctx, "key2", "value2")
+	store.Set(ctx, "key1", "updated")
+	store.Delete(ctx, "key2")
+
+	// Simulate a crash: reopen without closing so nothing is snapshotted
+	recovered := newTestFileStore(t, dir)
+	defer recovered.Close()
+
+	value, err := recovered.Get(ctx, "key1")
+	if err != nil {
+		t.Fatalf("Failed to get recovered value: %v", err)
+	}
+	if value != "updated" {
+		t.Errorf("Expected 'updated', got %v", value)
+	}
+
+	if _, err := recovered.Get(ctx, "key2"); err != domain.ErrKeyNotFound {
+		t.Errorf("Expected ErrKeyNotFound for deleted key, got %v", err)
+	}
+}
+
+func TestFileStore_RecoverFromSnapshot(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := newTestFileStore(t, dir)
+
+	store.Set(ctx, "key1", "value1")
+	if err := store.Snapshot(); err != nil {
+		t.Fatalf("Failed to snapshot: %v", err)
+	}
+	store.Set(ctx, "key2", "value2")
+
+	info, err := os.Stat(filepath.Join(dir, logFileName))
+	if err != nil {
+		t.Fatalf("Failed to stat log: %v", err)
+	}
+	if info.Size() == 0 {
+		t.Error("Expected log to contain writes made after the snapshot")
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Failed to close store: %v", err)
+	}
+
+	recovered := newTestFileStore(t, dir)
+	defer recovered.Close()
+
+	if recovered.Size() != 2 {
+		t.Errorf("Expected 2 recovered items, got %d", recovered.Size())
+	}
+}
+
+func TestFileStore_TornLogTail(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := newTestFileStore(t, dir)
+
+	store.Set(ctx, "key1", "value1")
+	store.Set(ctx, "key2", "value2")
+
+	// Chop the last record in half as if the process died mid-write
+	logPath := filepath.Join(dir, logFileName)
+	info, err := os.Stat(logPath)
+	if err != nil {
+		t.Fatalf("Failed to stat log: %v", err)
+	}
+	if err := os.Truncate(logPath, info.Size()-3); err != nil {
+		t.Fatalf("Failed to truncate log: %v", err)
+	}
+
+	recovered := newTestFileStore(t, dir)
+	defer recovered.Close()
+
+	if !recovered.Exists("key1") {
+		t.Error("Expected intact record to be recovered")
+	}
+	if recovered.Exists("key2") {
+		t.Error("Expected torn record to be discarded")
+	}
+
+	// New writes must land after the last intact record
+	recovered.Set(ctx, "key3", "value3")
+	again := newTestFileStore(t, dir)
+	defer again.Close()
+
+	if !again.Exists("key3") {
+		t.Error("Expected write after recovery to be durable")
+	}
+}
+
+func TestFileStore_OversizedLogTail(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := newTestFileStore(t, dir)
+
+	store.Set(ctx, "key1", "value1")
+	store.Close()
+
+	// A torn header claiming a huge payload must be discarded, not allocated
+	logPath := filepath.Join(dir, logFileName)
+	f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	header := make([]byte, recordHeaderSize)
+	binary.BigEndian.PutUint32(header[0:4], 0xFFFFFFFF)
+	f.Write(header)
+	f.Close()
+
+	recovered := newTestFileStore(t, dir)
+	defer recovered.Close()
+
+	if !recovered.Exists("key1") {
+		t.Error("Expected intact record to be recovered")
+	}
+}
+
+func TestFileStore_CorruptionMidLog(t *testing.T) {
+	testCases := []struct {
+		name    string
+		corrupt func(data []byte)
+	}{
+		{
+			name:    "checksum mismatch",
+			corrupt: func(data []byte) { data[recordHeaderSize+2] ^= 0xFF },
+		},
+		{
+			name:    "oversized length",
+			corrupt: func(data []byte) { binary.BigEndian.PutUint32(data[0:4], 0xFFFFFFFF) },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			store := newTestFileStore(t, dir)
+			defer store.Close()
+
+			store.Set(ctx, "key1", "value1")
+			store.Set(ctx, "key2", "value2")
+
+			// Damage the first record, leaving an intact one after it
+			logPath := filepath.Join(dir, logFileName)
+			data, err := os.ReadFile(logPath)
+			if err != nil {
+				t.Fatalf("Failed to read log: %v", err)
+			}
+			tc.corrupt(data)
+			if err := os.WriteFile(logPath, data, 0o644); err != nil {
+				t.Fatalf("Failed to write log: %v", err)
+			}
+
+			if _, err := NewFileStore(dir, FileStoreOptions{Fsync: FsyncAlways}); !errors.Is(err, errCorruptRecord) {
+				t.Fatalf("Expected corrupt record error, got %v", err)
+			}
+
+			// The log must be left as it was for inspection
+			after, err := os.ReadFile(logPath)
+			if err != nil {
+				t.Fatalf("Failed to read log: %v", err)
+			}
+			if len(after) != len(data) {
+				t.Errorf("Expected log of %d bytes to be kept, got %d bytes", len(data), len(after))
+			}
+		})
+	}
+}
+
+func TestFileStore_Clear(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := newTestFileStore(t, dir)
+
+	store.Set(ctx, "key1", "value1")
+	if err := store.Clear(); err != nil {
+		t.Fatalf("Failed to clear store: %v", err)
+	}
+
+	recovered := newTestFileStore(t, dir)
+	defer recovered.Close()
+
+	if recovered.Size() != 0 {
+		t.Errorf("Expected empty store after clear, got size %d", recovered.Size())
+	}
+}
+
+func TestFileStore_WriteAfterClose(t *testing.T) {
+	ctx := context.Background()
+	store := newTestFileStore(t, t.TempDir())
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Failed to close store: %v", err)
+	}
+
+	if err := store.Set(ctx, "key1", "value1"); err == nil {
+		t.Error("Expected error writing to closed store")
+	}
+
+	if err := store.Close(); err != nil {
+		t.Errorf("Expected second close to succeed, got %v", err)
+	}
+}
+
+func TestFileStore_RecoverTransaction(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := newTestFileStore(t, dir)
+
+	store.Set(ctx, "key1", "value1")
+	err := store.Update(ctx, func(tx domain.Tx) error {
+		if err := tx.Delete("key1"); err != nil {
+			return err
+		}
+		return tx.Set("key2", "value2")
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	recovered := newTestFileStore(t, dir)
+	defer recovered.Close()
+
+	if recovered.Exists("key1") || !recovered.Exists("key2") {
+		t.Error("Expected committed transaction to be recovered")
+	}
+}
+
+func TestFileStore_RecoverVersions(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := newTestFileStore(t, dir)
+
+	store.Set(ctx, "key1", "value1")
+	store.Set(ctx, "key1", "value2")
+
+	var value string
+	version, _ := store.GetTypedVersion(ctx, "key1", &value)
+	if err := store.Close(); err != nil {
+		t.Fatalf("Failed to close store: %v", err)
+	}
+
+	recovered := newTestFileStore(t, dir)
+	defer recovered.Close()
+
+	recoveredVersion, err := recovered.GetTypedVersion(ctx, "key1", &value)
+	if err != nil {
+		t.Fatalf("Failed to get recovered version: %v", err)
+	}
+	if recoveredVersion != version {
+		t.Errorf("Expected version %d after recovery, got %d", version, recoveredVersion)
+	}
+
+	// New writes continue from the recovered version
+	newVersion, err := recovered.CompareAndSwap(ctx, "key1", "value3", version)
+	if err != nil {
+		t.Fatalf("Failed to swap after recovery: %v", err)
+	}
+	if newVersion <= version {
+		t.Errorf("Expected version to increase after recovery, got %d after %d", newVersion, version)
+	}
+}
+
+func TestFileStore_RecoverTTL(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := newTestFileStore(t, dir)
+
+	store.SetWithTTL(ctx, "key1", "value1", 30*time.Millisecond)
+	store.SetWithTTL(ctx, "key2", "value2", time.Hour)
+	if err := store.Snapshot(); err != nil {
+		t.Fatalf("Failed to snapshot: %v", err)
+	}
+	store.SetWithTTL(ctx, "key3", "value3", 30*time.Millisecond)
+
+	recovered := newTestFileStore(t, dir)
+	defer recovered.Close()
+
+	if !recovered.Exists("key1") || !recovered.Exists("key3") {
+		t.Error("Expected unexpired keys to be recovered")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if recovered.Exists("key1") || recovered.Exists("key3") {
+		t.Error("Expected recovered expiry times to be honoured")
+	}
+	if !recovered.Exists("key2") {
+		t.Error("Expected long-lived key to survive")
+	}
+}
+
+func TestFileStore_RecoverEvictions(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	options := FileStoreOptions{Fsync: FsyncAlways, Limits: StoreLimits{MaxItems: 2, Policy: PolicyEvict}}
+
+	store, err := NewFileStore(dir, options)
+	if err != nil {
+		t.Fatalf("Failed to create file store: %v", err)
+	}
+	store.Set(ctx, "key1", "value1")
+	store.Set(ctx, "key2", "value2")
+	store.Set(ctx, "key3", "value3")
+
+	recovered, err := NewFileStore(dir, options)
+	if err != nil {
+		t.Fatalf("Failed to recover file store: %v", err)
+	}
+	defer recovered.Close()
+
+	if recovered.Exists("key1") {
+		t.Error("Expected evicted key to stay evicted after recovery")
+	}
+	if recovered.Size() != 2 {
+		t.Errorf("Expected 2 recovered items, got %d", recovered.Size())
+	}
+
+	// Recovered keys are tracked for eviction
+	recovered.Set(ctx, "key4", "value4")
+	if recovered.Exists("key2") || !recovered.Exists("key4") {
+		t.Error("Expected recovered keys to be evicted in write order")
+	}
+}
+
+func TestFileStore_Compact(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := newTestFileStore(t, dir)
+
+	for i := 0; i < 50; i++ {
+		store.Set(ctx, "key1", i)
+	}
+	store.Set(ctx, "key2", "deleted")
+	store.Delete(ctx, "key2")
+	store.SetWithTTL(ctx, "key3", "expired", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	stats, err := store.Compact(ctx)
+	if err != nil {
+		t.Fatalf("Failed to compact: %v", err)
+	}
+
+	if stats.Expired != 1 {
+		t.Errorf("Expected 1 expired key removed, got %d", stats.Expired)
+	}
+	if stats.BytesAfter >= stats.BytesBefore {
+		t.Errorf("Expected compaction to shrink the store, got %d to %d bytes", stats.BytesBefore, stats.BytesAfter)
+	}
+
+	// Only the live key survives, at its latest value
+	recovered := newTestFileStore(t, dir)
+	defer recovered.Close()
+
+	var value int
+	if err := recovered.GetTyped(ctx, "key1", &value); err != nil || value != 49 {
+		t.Errorf("Expected key1 to be 49, got %d (%v)", value, err)
+	}
+	if recovered.Size() != 1 {
+		t.Errorf("Expected 1 key after compaction, got %d", recovered.Size())
+	}
+
+	// Decorators pass compaction through
+	if _, err := NewInstrumentedStore(NewMemoryStore(), newQuietLogger(t)).Compact(ctx); err != domain.ErrNotCompactable {
+		t.Errorf("Expected ErrNotCompactable for a memory store, got %v", err)
+	}
+}
\ No newline at end of file
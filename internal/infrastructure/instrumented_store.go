@@ -0,0 +1,269 @@
+package infrastructure
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// Storage operations tracked by InstrumentedStore. Store methods are grouped
+// by what they do, e.g. Get, GetTyped and GetTypedVersion all count as "get".
+const (
+	storageOpGet    = "get"
+	storageOpSet    = "set"
+	storageOpDelete = "delete"
+	storageOpList   = "list"
+	storageOpUpdate = "update"
+	storageOpWatch  = "watch"
+)
+
+// instrumentedOperations lists the tracked operations in reporting order
+var instrumentedOperations = []string{storageOpGet, storageOpSet, storageOpDelete, storageOpList, storageOpUpdate, storageOpWatch}
+
+// StorageLatencyBounds are the upper bounds of the storage latency histogram buckets
+var StorageLatencyBounds = []time.Duration{
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	time.Second,
+}
+
+// InstrumentedStore decorates a Store with per-operation counts, error counts
+// and latency histograms, and logs every operation with the request ID from
+// its context. Expected outcomes such as a missing key or a version mismatch
+// are not counted or logged as errors.
+type InstrumentedStore struct {
+	store   domain.Store
+	logger  LoggerInterface
+	metrics map[string]*operationMetrics
+}
+
+// Ensure InstrumentedStore implements domain.Store
+var _ domain.Store = (*InstrumentedStore)(nil)
+
+// NewInstrumentedStore wraps a store with metrics and logging
+func NewInstrumentedStore(store domain.Store, logger LoggerInterface) *InstrumentedStore {
+	metrics := make(map[string]*operationMetrics, len(instrumentedOperations))
+	for _, op := range instrumentedOperations {
+		metrics[op] = &operationMetrics{buckets: make([]atomic.Int64, len(StorageLatencyBounds))}
+	}
+
+	return &InstrumentedStore{
+		store:   store,
+		logger:  logger,
+		metrics: metrics,
+	}
+}
+
+// Set stores a value with the given key
+func (s *InstrumentedStore) Set(ctx context.Context, key string, value any) error {
+	start := time.Now()
+	err := s.store.Set(ctx, key, value)
+	s.observe(ctx, storageOpSet, key, start, err)
+	return err
+}
+
+// SetWithTTL stores a value that expires after ttl
+func (s *InstrumentedStore) SetWithTTL(ctx context.Context, key string, value any, ttl time.Duration) error {
+	start := time.Now()
+	err := s.store.SetWithTTL(ctx, key, value, ttl)
+	s.observe(ctx, storageOpSet, key, start, err)
+	return err
+}
+
+// Get retrieves a value by key
+func (s *InstrumentedStore) Get(ctx context.Context, key string) (any, error) {
+	start := time.Now()
+	value, err := s.store.Get(ctx, key)
+	s.observe(ctx, storageOpGet, key, start, err)
+	return value, err
+}
+
+// GetTyped retrieves a value by key and unmarshals it into the provided type
+func (s *InstrumentedStore) GetTyped(ctx context.Context, key string, value any) error {
+	start := time.Now()
+	err := s.store.GetTyped(ctx, key, value)
+	s.observe(ctx, storageOpGet, key, start, err)
+	return err
+}
+
+// GetTypedVersion works like GetTyped and also returns the value's version
+func (s *InstrumentedStore) GetTypedVersion(ctx context.Context, key string, value any) (int64, error) {
+	start := time.Now()
+	version, err := s.store.GetTypedVersion(ctx, key, value)
+	s.observe(ctx, storageOpGet, key, start, err)
+	return version, err
+}
+
+// CompareAndSwap stores a value only if the key is at the given version
+func (s *InstrumentedStore) CompareAndSwap(ctx context.Context, key string, value any, version int64) (int64, error) {
+	start := time.Now()
+	newVersion, err := s.store.CompareAndSwap(ctx, key, value, version)
+	s.observe(ctx, storageOpSet, key, start, err)
+	return newVersion, err
+}
+
+// List retrieves all values with keys that start with the given prefix
+func (s *InstrumentedStore) List(ctx context.Context, keyPrefix string) ([]any, error) {
+	start := time.Now()
+	values, err := s.store.List(ctx, keyPrefix)
+	s.observe(ctx, storageOpList, keyPrefix, start, err)
+	return values, err
+}
+
+// Scan returns keys matching opts in lexicographic key order
+func (s *InstrumentedStore) Scan(ctx context.Context, opts domain.ScanOptions) ([]string, error) {
+	start := time.Now()
+	keys, err := s.store.Scan(ctx, opts)
+	s.observe(ctx, storageOpList, opts.Prefix, start, err)
+	return keys, err
+}
+
+// Iterate returns an iterator over the entries matching opts. Only opening
+// the iterator is timed.
+func (s *InstrumentedStore) Iterate(ctx context.Context, opts domain.ScanOptions) (domain.Iterator, error) {
+	start := time.Now()
+	it, err := s.store.Iterate(ctx, opts)
+	s.observe(ctx, storageOpList, opts.Prefix, start, err)
+	return it, err
+}
+
+// Delete removes a value by key
+func (s *InstrumentedStore) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.store.Delete(ctx, key)
+	s.observe(ctx, storageOpDelete, key, start, err)
+	return err
+}
+
+// CompareAndDelete removes a key only if it is at the given version
+func (s *InstrumentedStore) CompareAndDelete(ctx context.Context, key string, version int64) error {
+	start := time.Now()
+	err := s.store.CompareAndDelete(ctx, key, version)
+	s.observe(ctx, storageOpDelete, key, start, err)
+	return err
+}
+
+// Update runs fn in a read-write transaction, timing the whole transaction
+func (s *InstrumentedStore) Update(ctx context.Context, fn func(tx domain.Tx) error) error {
+	start := time.Now()
+	err := s.store.Update(ctx, fn)
+	s.observe(ctx, storageOpUpdate, "", start, err)
+	return err
+}
+
+// Watch streams changes to keys matching opts
+func (s *InstrumentedStore) Watch(ctx context.Context, opts domain.WatchOptions) (domain.Watcher, error) {
+	start := time.Now()
+	w, err := s.store.Watch(ctx, opts)
+	s.observe(ctx, storageOpWatch, opts.Prefix, start, err)
+	return w, err
+}
+
+// Close closes the underlying store
+func (s *InstrumentedStore) Close() error {
+	return s.store.Close()
+}
+
+// Revision returns the underlying store's latest revision, or 0 if it does
+// not report one
+func (s *InstrumentedStore) Revision() int64 {
+	if provider, ok := s.store.(domain.RevisionProvider); ok {
+		return provider.Revision()
+	}
+	return 0
+}
+
+// ApplyEvents applies replicated changes to the underlying store
+func (s *InstrumentedStore) ApplyEvents(ctx context.Context, events []domain.Event) error {
+	return domain.ApplyEvents(ctx, s.store, events)
+}
+
+// Compact compacts the underlying store, or returns ErrNotCompactable if it
+// cannot be compacted
+func (s *InstrumentedStore) Compact(ctx context.Context) (domain.CompactionStats, error) {
+	if compactor, ok := s.store.(domain.Compactor); ok {
+		return compactor.Compact(ctx)
+	}
+	return domain.CompactionStats{}, domain.ErrNotCompactable
+}
+
+// Stats returns the underlying store's stats together with operation counters
+func (s *InstrumentedStore) Stats() domain.StoreStats {
+	var stats domain.StoreStats
+	if provider, ok := s.store.(domain.StatsProvider); ok {
+		stats = provider.Stats()
+	}
+
+	for _, op := range instrumentedOperations {
+		stats.Operations = append(stats.Operations, s.metrics[op].snapshot(op))
+	}
+	return stats
+}
+
+// observe records and logs a completed operation
+func (s *InstrumentedStore) observe(ctx context.Context, op, key string, start time.Time, err error) {
+	if err == domain.ErrKeyNotFound || err == domain.ErrVersionMismatch {
+		err = nil
+	}
+
+	s.metrics[op].record(time.Since(start), err != nil)
+	s.logger.LogStorageOperation(ctx, op, key, err)
+}
+
+// operationMetrics holds the counters of one operation
+type operationMetrics struct {
+	count  atomic.Int64
+	errors atomic.Int64
+	sum    atomic.Int64
+
+	// buckets counts operations by the first bound they completed within;
+	// slower operations are only reflected in count
+	buckets []atomic.Int64
+}
+
+// record counts one operation
+func (m *operationMetrics) record(elapsed time.Duration, failed bool) {
+	m.count.Add(1)
+	m.sum.Add(int64(elapsed))
+	if failed {
+		m.errors.Add(1)
+	}
+
+	for i, bound := range StorageLatencyBounds {
+		if elapsed <= bound {
+			m.buckets[i].Add(1)
+			break
+		}
+	}
+}
+
+// snapshot returns the counters with cumulative latency buckets
+func (m *operationMetrics) snapshot(op string) domain.OperationStats {
+	stats := domain.OperationStats{
+		Operation:      op,
+		Count:          m.count.Load(),
+		Errors:         m.errors.Load(),
+		LatencyBuckets: make([]int64, len(m.buckets)),
+		LatencyBounds:  StorageLatencyBounds,
+		LatencySum:     time.Duration(m.sum.Load()),
+	}
+
+	var cumulative int64
+	for i := range m.buckets {
+		cumulative += m.buckets[i].Load()
+		stats.LatencyBuckets[i] = cumulative
+	}
+	return stats
+}
\ No newline at end of file
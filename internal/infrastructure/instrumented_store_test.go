@@ -0,0 +1,143 @@
+package infrastructure
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// loggedOperation is a storage operation captured by recordingLogger
+type loggedOperation struct {
+	operation string
+	key       string
+	requestID any
+	err       error
+}
+
+// recordingLogger records storage operations; other logger methods are not
+// used by InstrumentedStore
+type recordingLogger struct {
+	LoggerInterface
+
+	mu         sync.Mutex
+	operations []loggedOperation
+}
+
+func (l *recordingLogger) LogStorageOperation(ctx context.Context, operation, key string, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.operations = append(l.operations, loggedOperation{
+		operation: operation,
+		key:       key,
+		requestID: ctx.Value("request_id"),
+		err:       err,
+	})
+}
+
+func operationStats(t *testing.T, stats domain.StoreStats, operation string) domain.OperationStats {
+	t.Helper()
+	for _, op := range stats.Operations {
+		if op.Operation == operation {
+			return op
+		}
+	}
+	t.Fatalf("Expected stats for operation %q", operation)
+	return domain.OperationStats{}
+}
+
+func TestInstrumentedStore_Counts(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemoryStore()
+	logger := &recordingLogger{}
+	store := NewInstrumentedStore(inner, logger)
+	defer store.Close()
+
+	store.Set(ctx, "key1", "value1")
+	store.Set(ctx, "key2", "value2")
+	store.Get(ctx, "key1")
+	store.Get(ctx, "missing")
+	store.Delete(ctx, "key2")
+	store.List(ctx, "key")
+	store.Update(ctx, func(tx domain.Tx) error { return tx.Set("key3", "value3") })
+
+	stats := store.Stats()
+	if stats.Items != 2 {
+		t.Errorf("Expected 2 items from the wrapped store, got %d", stats.Items)
+	}
+
+	expected := map[string]int64{"get": 2, "set": 2, "delete": 1, "list": 1, "update": 1, "watch": 0}
+	for operation, count := range expected {
+		op := operationStats(t, stats, operation)
+		if op.Count != count {
+			t.Errorf("Expected %d %s operations, got %d", count, operation, op.Count)
+		}
+		// A missing key is an expected outcome, not an error
+		if op.Errors != 0 {
+			t.Errorf("Expected no %s errors, got %d", operation, op.Errors)
+		}
+	}
+
+	get := operationStats(t, stats, "get")
+	if len(get.LatencyBuckets) != len(get.LatencyBounds) {
+		t.Fatalf("Expected %d buckets, got %d", len(get.LatencyBounds), len(get.LatencyBuckets))
+	}
+	if last := get.LatencyBuckets[len(get.LatencyBuckets)-1]; last > get.Count {
+		t.Errorf("Expected cumulative buckets to be at most %d, got %d", get.Count, last)
+	}
+
+	if len(logger.operations) != 7 {
+		t.Errorf("Expected 7 logged operations, got %d", len(logger.operations))
+	}
+}
+
+func TestInstrumentedStore_Errors(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemoryStore()
+	logger := &recordingLogger{}
+	store := NewInstrumentedStore(inner, logger)
+	defer store.Close()
+
+	store.Set(ctx, "key1", "value1")
+
+	// A stale version is expected under contention and is not an error
+	if _, err := store.CompareAndSwap(ctx, "key1", "value2", 99); err != domain.ErrVersionMismatch {
+		t.Fatalf("Expected ErrVersionMismatch, got %v", err)
+	}
+
+	// A value that cannot be decoded into the target is
+	var number int
+	if err := store.GetTyped(ctx, "key1", &number); err == nil {
+		t.Fatal("Expected decode error")
+	}
+
+	stats := store.Stats()
+	if set := operationStats(t, stats, "set"); set.Count != 2 || set.Errors != 0 {
+		t.Errorf("Expected 2 set operations without errors, got %d (%d errors)", set.Count, set.Errors)
+	}
+	if get := operationStats(t, stats, "get"); get.Count != 1 || get.Errors != 1 {
+		t.Errorf("Expected 1 failed get operation, got %d (%d errors)", get.Count, get.Errors)
+	}
+
+	last := logger.operations[len(logger.operations)-1]
+	if last.operation != "get" || last.key != "key1" || last.err == nil {
+		t.Errorf("Expected failed get of key1 to be logged, got %+v", last)
+	}
+}
+
+func TestInstrumentedStore_RequestID(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "request_id", "req-123")
+	logger := &recordingLogger{}
+	store := NewInstrumentedStore(NewMemoryStore(), logger)
+	defer store.Close()
+
+	store.Set(ctx, "key1", "value1")
+
+	if len(logger.operations) != 1 {
+		t.Fatalf("Expected 1 logged operation, got %d", len(logger.operations))
+	}
+	if logger.operations[0].requestID != "req-123" {
+		t.Errorf("Expected request ID req-123, got %v", logger.operations[0].requestID)
+	}
+}
\ No newline at end of file
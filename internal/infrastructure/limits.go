@@ -0,0 +1,158 @@
+package infrastructure
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+)
+
+// EvictionPolicy decides what happens to the entries under a key prefix when
+// a store with limits runs out of room
+type EvictionPolicy string
+
+// Eviction policies
+const (
+	// PolicyReject keeps entries until they are deleted; writes that do not
+	// fit fail with domain.ErrStoreFull
+	PolicyReject EvictionPolicy = "reject"
+
+	// PolicyEvict lets entries be evicted, least recently used first, to make
+	// room for new writes. It suits cache-like prefixes.
+	PolicyEvict EvictionPolicy = "evict"
+)
+
+// StoreLimits caps the size of a MemoryStore. Sizes count key and value bytes
+// and ignore bookkeeping overhead.
+type StoreLimits struct {
+	// MaxItems and MaxBytes cap the number of entries and their total size;
+	// zero means no limit
+	MaxItems int
+	MaxBytes int64
+
+	// Policy applies to keys without a matching entry in Prefixes; empty
+	// means PolicyReject
+	Policy EvictionPolicy
+
+	// Prefixes selects the policy by key prefix. The longest matching prefix wins.
+	Prefixes map[string]EvictionPolicy
+}
+
+// enabled reports whether any limit is set
+func (l StoreLimits) enabled() bool {
+	return l.MaxItems > 0 || l.MaxBytes > 0
+}
+
+// policy returns the policy for a key
+func (l StoreLimits) policy(key string) EvictionPolicy {
+	policy, matched := l.Policy, -1
+	for prefix, p := range l.Prefixes {
+		if len(prefix) > matched && strings.HasPrefix(key, prefix) {
+			policy, matched = p, len(prefix)
+		}
+	}
+	if policy == "" {
+		return PolicyReject
+	}
+	return policy
+}
+
+// evictable reports whether a key may be evicted
+func (l StoreLimits) evictable(key string) bool {
+	return l.policy(key) == PolicyEvict
+}
+
+// usage is the size of a store's contents as counted against its limits
+type usage struct {
+	items int
+	bytes int64
+}
+
+// fits reports whether u is within the limits, or at least no larger than
+// before in each dimension that is over. The latter lets a store that is over
+// its limits, e.g. after they were lowered, keep accepting writes that do not
+// make things worse.
+func (l StoreLimits) fits(u, before usage) bool {
+	if l.MaxItems > 0 && u.items > l.MaxItems && u.items > before.items {
+		return false
+	}
+	if l.MaxBytes > 0 && u.bytes > l.MaxBytes && u.bytes > before.bytes {
+		return false
+	}
+	return true
+}
+
+// entrySize returns the size of an entry as counted against the limits
+func entrySize(key string, value []byte) int64 {
+	return int64(len(key) + len(value))
+}
+
+// lruList tracks evictable keys in order of use. It has its own lock because
+// reads mark keys as used while holding only the store's read lock.
+type lruList struct {
+	mu       sync.Mutex
+	order    *list.List // most recently used first
+	elements map[string]*list.Element
+}
+
+// newLRUList creates an empty LRU list
+func newLRUList() *lruList {
+	return &lruList{
+		order:    list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+// add starts tracking a key, or marks it as used if already tracked
+func (l *lruList) add(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.elements[key]; ok {
+		l.order.MoveToFront(e)
+		return
+	}
+	l.elements[key] = l.order.PushFront(key)
+}
+
+// touch marks a tracked key as used and ignores untracked keys
+func (l *lruList) touch(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.elements[key]; ok {
+		l.order.MoveToFront(e)
+	}
+}
+
+// remove stops tracking a key
+func (l *lruList) remove(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.elements[key]; ok {
+		l.order.Remove(e)
+		delete(l.elements, key)
+	}
+}
+
+// reset stops tracking every key
+func (l *lruList) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.order.Init()
+	l.elements = make(map[string]*list.Element)
+}
+
+// oldest calls fn with tracked keys, least recently used first, until fn
+// returns false
+func (l *lruList) oldest(fn func(key string) bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for e := l.order.Back(); e != nil; e = e.Prev() {
+		if !fn(e.Value.(string)) {
+			return
+		}
+	}
+}
\ No newline at end of file
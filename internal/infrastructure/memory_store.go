@@ -1,63 +1,203 @@
 package infrastructure
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"strings"
+	"time"
 
 	"gosuda.org/boilerplate/internal/domain"
 )
 
 // MemoryStore implements the Store interface using an in-memory map
 type MemoryStore struct {
-	data map[string][]byte
+	data map[string]entry
 	mu   sync.RWMutex
+
+	// keys holds every key in data in ascending order so that ordered scans
+	// can seek with a binary search instead of visiting every key
+	keys []string
+
+	// revision is the last revision assigned to a change. Every set and
+	// delete takes the next revision, and a key's version is the revision of
+	// its last write, so versions only ever increase for a key, even across
+	// deletion and re-creation.
+	revision int64
+
+	// journal, when set, is called with every mutation while the write lock
+	// is held and before the mutation is applied. A journal error aborts the
+	// mutation, which lets durable stores log changes ahead of applying them.
+	journal func(m mutation) error
+
+	// codec encodes new values. Values are decoded with the codec that wrote
+	// them, so changing it does not affect existing data.
+	codec Codec
+
+	sweepInterval time.Duration
+	expired       atomic.Int64
+	stop          chan struct{}
+	stopOnce      sync.Once
+	sweeperDone   chan struct{}
+
+	// watch fans committed changes out to watchers
+	watch *watchHub
+
+	// limits caps the size of the store. bytes tracks the size of data as
+	// counted against them, and lru tracks evictable keys; it is nil unless
+	// limits are set.
+	limits  StoreLimits
+	bytes   int64
+	lru     *lruList
+	evicted atomic.Int64
+}
+
+// DefaultSweepInterval is how often expired keys are removed unless configured otherwise
+const DefaultSweepInterval = time.Minute
+
+// MemoryStoreOption configures a MemoryStore
+type MemoryStoreOption func(*MemoryStore)
+
+// WithCodec sets the codec used to encode new values
+func WithCodec(codec Codec) MemoryStoreOption {
+	return func(s *MemoryStore) {
+		if codec != nil {
+			s.codec = codec
+		}
+	}
+}
+
+// WithSweepInterval sets how often the background sweeper removes expired keys
+func WithSweepInterval(interval time.Duration) MemoryStoreOption {
+	return func(s *MemoryStore) {
+		if interval > 0 {
+			s.sweepInterval = interval
+		}
+	}
 }
 
-// NewMemoryStore creates a new in-memory store instance
-func NewMemoryStore() *MemoryStore {
-	return &MemoryStore{
-		data: make(map[string][]byte),
+// WithLimits caps the number of entries and bytes the store holds
+func WithLimits(limits StoreLimits) MemoryStoreOption {
+	return func(s *MemoryStore) {
+		s.limits = limits
 	}
 }
 
+// entry is a stored value together with its version and optional expiry
+type entry struct {
+	value     []byte
+	version   int64
+	expiresAt int64 // Unix nanoseconds; zero means the entry never expires
+}
+
+// expired reports whether the entry has expired at the given Unix nanosecond time
+func (e entry) expired(now int64) bool {
+	return e.expiresAt != 0 && now >= e.expiresAt
+}
+
+// Mutation operations recorded by the journal
+const (
+	opSet    = "set"
+	opDelete = "delete"
+	opClear  = "clear"
+	opBatch  = "batch"
+)
+
+// mutation describes a single change to the store contents. A batch mutation
+// groups the changes of one transaction so they are journaled atomically.
+type mutation struct {
+	Op      string     `json:"op"`
+	Key     string     `json:"key,omitempty"`
+	Value   []byte     `json:"value,omitempty"`
+	Version   int64      `json:"version,omitempty"`
+	ExpiresAt int64      `json:"expiresAt,omitempty"`
+	Batch     []mutation `json:"batch,omitempty"`
+}
+
+// NewMemoryStore creates a new in-memory store instance and starts the
+// background sweeper that removes expired keys until Close is called
+func NewMemoryStore(opts ...MemoryStoreOption) *MemoryStore {
+	s := newMemoryStore(opts...)
+	s.startSweeper()
+	return s
+}
+
+// newMemoryStore creates a store without starting the sweeper, for callers
+// that need to finish setting it up first
+func newMemoryStore(opts ...MemoryStoreOption) *MemoryStore {
+	s := &MemoryStore{
+		data:          make(map[string]entry),
+		codec:         JSONCodec,
+		sweepInterval: DefaultSweepInterval,
+		stop:          make(chan struct{}),
+		watch:         newWatchHub(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.limits.enabled() {
+		s.lru = newLRUList()
+	}
+	return s
+}
+
 // Set stores a value with the given key
-func (s *MemoryStore) Set(key string, value any) error {
+func (s *MemoryStore) Set(ctx context.Context, key string, value any) error {
+	return s.set(ctx, key, value, 0)
+}
+
+// SetWithTTL stores a value that expires after the given duration
+func (s *MemoryStore) SetWithTTL(ctx context.Context, key string, value any, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("ttl must be positive")
+	}
+	return s.set(ctx, key, value, time.Now().Add(ttl).UnixNano())
+}
+
+// set stores a value with an optional expiry time in Unix nanoseconds
+func (s *MemoryStore) set(ctx context.Context, key string, value any, expiresAt int64) error {
 	if key == "" {
 		return fmt.Errorf("key cannot be empty")
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Serialize the value to JSON
-	data, err := json.Marshal(value)
+	data, err := encodeValue(s.codec, value)
 	if err != nil {
-		return fmt.Errorf("failed to marshal value: %w", err)
+		return err
 	}
 
-	s.data[key] = data
-	return nil
+	m := mutation{Op: opSet, Key: key, Value: data, Version: s.revision + 1, ExpiresAt: expiresAt}
+	return s.commit(m)
 }
 
 // Get retrieves a value by key
-func (s *MemoryStore) Get(key string) (value any, err error) {
+func (s *MemoryStore) Get(ctx context.Context, key string) (value any, err error) {
 	if key == "" {
 		return nil, fmt.Errorf("key cannot be empty")
 	}
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	data, exists := s.data[key]
+	e, exists := s.lookup(key)
 	if !exists {
 		return nil, domain.ErrKeyNotFound
 	}
 
-	// Try to unmarshal as a generic interface{} first
 	var result any
-	if err := json.Unmarshal(data, &result); err != nil {
+	if err := decodeValue(e.value, &result); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal value: %w", err)
 	}
 
@@ -65,36 +205,117 @@ func (s *MemoryStore) Get(key string) (value any, err error) {
 }
 
 // GetTyped retrieves a value by key and unmarshals it into the provided type
-func (s *MemoryStore) GetTyped(key string, value any) error {
+func (s *MemoryStore) GetTyped(ctx context.Context, key string, value any) error {
 	if key == "" {
 		return fmt.Errorf("key cannot be empty")
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	data, exists := s.data[key]
+	e, exists := s.lookup(key)
 	if !exists {
 		return domain.ErrKeyNotFound
 	}
 
-	if err := json.Unmarshal(data, value); err != nil {
+	if err := decodeValue(e.value, value); err != nil {
 		return fmt.Errorf("failed to unmarshal value: %w", err)
 	}
 
 	return nil
 }
 
+// GetTypedVersion retrieves a value and its current version by key
+func (s *MemoryStore) GetTypedVersion(ctx context.Context, key string, value any) (version int64, err error) {
+	if key == "" {
+		return 0, fmt.Errorf("key cannot be empty")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, exists := s.lookup(key)
+	if !exists {
+		return 0, domain.ErrKeyNotFound
+	}
+
+	if err := decodeValue(e.value, value); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+
+	return e.version, nil
+}
+
+// CompareAndSwap stores a value only if the key's current version equals the
+// given version, where version 0 means the key must not exist yet
+func (s *MemoryStore) CompareAndSwap(ctx context.Context, key string, value any, version int64) (newVersion int64, err error) {
+	err = s.Update(ctx, func(tx domain.Tx) error {
+		if err := checkVersion(tx, key, version); err != nil {
+			return err
+		}
+		if err := tx.Set(key, value); err != nil {
+			return err
+		}
+		newVersion, err = tx.Version(key)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return newVersion, nil
+}
+
+// CompareAndDelete removes a key only if its current version equals the given version
+func (s *MemoryStore) CompareAndDelete(ctx context.Context, key string, version int64) error {
+	return s.Update(ctx, func(tx domain.Tx) error {
+		if err := checkVersion(tx, key, version); err != nil {
+			return err
+		}
+		return tx.Delete(key)
+	})
+}
+
+// checkVersion returns ErrVersionMismatch unless the key is at the given version
+func checkVersion(tx domain.Tx, key string, version int64) error {
+	current, err := tx.Version(key)
+	if err == domain.ErrKeyNotFound {
+		current = 0
+	} else if err != nil {
+		return err
+	}
+
+	if current != version {
+		return domain.ErrVersionMismatch
+	}
+	return nil
+}
+
 // List retrieves all values with keys that start with the given prefix
-func (s *MemoryStore) List(keyPrefix string) (values []any, err error) {
+func (s *MemoryStore) List(ctx context.Context, keyPrefix string) (values []any, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	now := time.Now().UnixNano()
 	var result []any
-	for key, data := range s.data {
-		if strings.HasPrefix(key, keyPrefix) {
+	for key, e := range s.data {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(key, keyPrefix) && !e.expired(now) {
 			var value any
-			if err := json.Unmarshal(data, &value); err != nil {
+			if err := decodeValue(e.value, &value); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal value for key %s: %w", key, err)
 			}
 			result = append(result, value)
@@ -104,14 +325,127 @@ func (s *MemoryStore) List(keyPrefix string) (values []any, err error) {
 	return result, nil
 }
 
+// Scan returns keys in key order, starting after opts.StartAfter. It seeks with
+// a binary search, so its cost depends on the number of keys returned rather
+// than the size of the store.
+func (s *MemoryStore) Scan(ctx context.Context, opts domain.ScanOptions) (keys []string, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return scanKeys(ctx, s.keys, s.data, opts)
+}
+
+// Iterate returns an iterator over the keys, versions and encoded values
+// matching opts. Matching entries are captured under the read lock and
+// decoded lazily, so the lock is not held while callers decode values.
+func (s *MemoryStore) Iterate(ctx context.Context, opts domain.ScanOptions) (domain.Iterator, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys, err := scanKeys(ctx, s.keys, s.data, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]entry, len(keys))
+	for i, key := range keys {
+		entries[i] = s.data[key]
+	}
+
+	return &memoryIterator{ctx: ctx, keys: keys, entries: entries, pos: -1}, nil
+}
+
+// scanKeys implements Scan over sorted keys and the entries they index,
+// skipping expired entries. Callers must hold the read lock guarding them.
+func scanKeys(ctx context.Context, keys []string, data map[string]entry, opts domain.ScanOptions) ([]string, error) {
+	now := time.Now().UnixNano()
+	var result []string
+	add := func(key string) bool {
+		if data[key].expired(now) {
+			return true
+		}
+		result = append(result, key)
+		return opts.Limit <= 0 || len(result) < opts.Limit
+	}
+
+	if !opts.Reverse {
+		// First key at or after the prefix, then past StartAfter
+		i := sort.SearchStrings(keys, opts.Prefix)
+		if opts.StartAfter != "" {
+			i = max(i, sort.Search(len(keys), func(j int) bool { return keys[j] > opts.StartAfter }))
+		}
+
+		for ; i < len(keys) && strings.HasPrefix(keys[i], opts.Prefix); i++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if !add(keys[i]) {
+				break
+			}
+		}
+
+		return result, nil
+	}
+
+	// First key past every key with the prefix, then before StartAfter
+	end := sort.Search(len(keys), func(j int) bool {
+		return keys[j] > opts.Prefix && !strings.HasPrefix(keys[j], opts.Prefix)
+	})
+	if opts.StartAfter != "" {
+		end = min(end, sort.SearchStrings(keys, opts.StartAfter))
+	}
+
+	for i := end - 1; i >= 0 && strings.HasPrefix(keys[i], opts.Prefix); i-- {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !add(keys[i]) {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// insertKey adds a key missing from sorted keys, keeping them sorted
+func insertKey(keys []string, key string) []string {
+	i := sort.SearchStrings(keys, key)
+	keys = append(keys, "")
+	copy(keys[i+1:], keys[i:])
+	keys[i] = key
+	return keys
+}
+
+// removeKey removes a key present in sorted keys
+func removeKey(keys []string, key string) []string {
+	i := sort.SearchStrings(keys, key)
+	return append(keys[:i], keys[i+1:]...)
+}
+
 // ListKeys retrieves all keys that start with the given prefix
-func (s *MemoryStore) ListKeys(keyPrefix string) (keys []string, err error) {
+func (s *MemoryStore) ListKeys(ctx context.Context, keyPrefix string) (keys []string, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	now := time.Now().UnixNano()
 	var result []string
-	for key := range s.data {
-		if strings.HasPrefix(key, keyPrefix) {
+	for key, e := range s.data {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(key, keyPrefix) && !e.expired(now) {
 			result = append(result, key)
 		}
 	}
@@ -120,33 +454,108 @@ func (s *MemoryStore) ListKeys(keyPrefix string) (keys []string, err error) {
 }
 
 // Delete removes a value by key
-func (s *MemoryStore) Delete(key string) error {
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
 	if key == "" {
 		return fmt.Errorf("key cannot be empty")
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.data[key]; !exists {
+	if _, exists := s.lookup(key); !exists {
 		return domain.ErrKeyNotFound
 	}
 
-	delete(s.data, key)
-	return nil
+	m := mutation{Op: opDelete, Key: key, Version: s.revision + 1}
+	return s.commit(m)
 }
 
-// Close closes the storage and performs cleanup
+// Update runs fn inside a transaction holding the write lock for its whole
+// duration. Writes made through tx are buffered and applied atomically when fn
+// returns nil; if fn returns an error or the context is cancelled, they are
+// discarded.
+func (s *MemoryStore) Update(ctx context.Context, fn func(tx domain.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &memoryTx{
+		ctx:      ctx,
+		codec:    s.codec,
+		lookup:   s.lookup,
+		writes:   make(map[string]*entry),
+		revision: s.revision,
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if len(tx.order) == 0 {
+		return nil
+	}
+
+	return s.commit(mutation{Op: opBatch, Batch: tx.mutations()})
+}
+
+// ApplyEvents applies the events of a commit replicated from another store in
+// one batch, storing put values as they are at the event's version. Deletes
+// take the event's revision.
+func (s *MemoryStore) ApplyEvents(ctx context.Context, events []domain.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch := make([]mutation, 0, len(events))
+	for _, ev := range events {
+		switch ev.Type {
+		case domain.EventPut:
+			batch = append(batch, mutation{Op: opSet, Key: ev.Key, Value: ev.Value, Version: ev.Version})
+		case domain.EventDelete:
+			batch = append(batch, mutation{Op: opDelete, Key: ev.Key, Version: ev.Revision})
+		}
+	}
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	return s.commit(mutation{Op: opBatch, Batch: batch})
+}
+
+// Close stops the sweeper and clears all data
 func (s *MemoryStore) Close() error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	if s.sweeperDone != nil {
+		<-s.sweeperDone
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.watch.closeAll()
+
 	// Clear all data
-	s.data = make(map[string][]byte)
+	s.reset()
 	return nil
 }
 
-// Size returns the number of items in the store
+// Size returns the number of items in the store, including expired items
+// that have not been swept yet
 func (s *MemoryStore) Size() int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -161,14 +570,485 @@ func (s *MemoryStore) Exists(key string) bool {
 
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	_, exists := s.data[key]
+	_, exists := s.lookup(key)
 	return exists
 }
 
+// Stats returns size, expiry and eviction counters for metrics
+func (s *MemoryStore) Stats() domain.StoreStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return domain.StoreStats{
+		Items:       len(s.data),
+		Bytes:       s.bytes,
+		ExpiredKeys: s.expired.Load(),
+		EvictedKeys: s.evicted.Load(),
+	}
+}
+
+// Revision returns the revision of the latest change
+func (s *MemoryStore) Revision() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.revision
+}
+
+// lookup returns the entry for a key unless it is missing or expired, and
+// marks it as recently used. Expired entries stay in place until the sweeper
+// removes them. Callers must hold the read lock.
+func (s *MemoryStore) lookup(key string) (entry, bool) {
+	e, exists := s.data[key]
+	if !exists || e.expired(time.Now().UnixNano()) {
+		return entry{}, false
+	}
+	if s.lru != nil {
+		s.lru.touch(key)
+	}
+	return e, true
+}
+
+// startSweeper starts the goroutine that periodically removes expired keys
+func (s *MemoryStore) startSweeper() {
+	s.sweeperDone = make(chan struct{})
+
+	go func() {
+		defer close(s.sweeperDone)
+
+		ticker := time.NewTicker(s.sweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				// A failed sweep is retried on the next tick
+				_ = s.sweep()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// sweep removes every expired key in a single journaled batch
+func (s *MemoryStore) sweep() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UnixNano()
+	revision := s.revision
+	var batch []mutation
+	for _, key := range s.keys {
+		if s.data[key].expired(now) {
+			revision++
+			batch = append(batch, mutation{Op: opDelete, Key: key, Version: revision})
+		}
+	}
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if err := s.commit(mutation{Op: opBatch, Batch: batch}); err != nil {
+		return err
+	}
+
+	s.expired.Add(int64(len(batch)))
+	return nil
+}
+
 // Clear removes all data from the store
 func (s *MemoryStore) Clear() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.data = make(map[string][]byte)
+	return s.commit(mutation{Op: opClear, Version: s.revision + 1})
+}
+
+// commit journals a mutation, applies it and notifies watchers. Callers must
+// hold the write lock.
+func (s *MemoryStore) commit(m mutation) error {
+	m, evicted, err := s.enforceLimits(m)
+	if err != nil {
+		return err
+	}
+
+	if err := s.record(m); err != nil {
+		return err
+	}
+
+	events := s.events(m, nil)
+	s.apply(m)
+	s.watch.publish(events)
+	s.evicted.Add(int64(evicted))
+	return nil
+}
+
+// enforceLimits checks that a mutation keeps the store within its limits. If
+// it does not, the least recently used evictable keys it does not write are
+// deleted along with it, and the returned mutation is a batch that includes
+// those deletions. If evicting cannot make room, it returns
+// domain.ErrStoreFull. Callers must hold the write lock.
+func (s *MemoryStore) enforceLimits(m mutation) (mutation, int, error) {
+	if !s.limits.enabled() {
+		return m, 0, nil
+	}
+
+	before := usage{items: len(s.data), bytes: s.bytes}
+	after := before
+	revision := s.revision
+
+	// sizes holds the size of every key m writes, or -1 if m deletes it
+	sizes := make(map[string]int64)
+	var measure func(op mutation)
+	measure = func(op mutation) {
+		revision = max(revision, op.Version)
+		switch op.Op {
+		case opBatch:
+			for _, op := range op.Batch {
+				measure(op)
+			}
+			return
+		case opClear:
+			after = usage{}
+			return
+		}
+
+		prev, seen := sizes[op.Key]
+		if !seen {
+			prev = -1
+			if e, exists := s.data[op.Key]; exists {
+				prev = entrySize(op.Key, e.value)
+			}
+		}
+		next := int64(-1)
+		if op.Op == opSet {
+			next = entrySize(op.Key, op.Value)
+		}
+
+		if prev >= 0 {
+			after.items--
+			after.bytes -= prev
+		}
+		if next >= 0 {
+			after.items++
+			after.bytes += next
+		}
+		sizes[op.Key] = next
+	}
+	measure(m)
+
+	if s.limits.fits(after, before) {
+		return m, 0, nil
+	}
+
+	var evictions []mutation
+	s.lru.oldest(func(key string) bool {
+		if _, written := sizes[key]; written {
+			return true
+		}
+		revision++
+		evictions = append(evictions, mutation{Op: opDelete, Key: key, Version: revision})
+		after.items--
+		after.bytes -= entrySize(key, s.data[key].value)
+		return !s.limits.fits(after, before)
+	})
+
+	if !s.limits.fits(after, before) {
+		return m, 0, domain.ErrStoreFull
+	}
+
+	if m.Op != opBatch {
+		m = mutation{Op: opBatch, Batch: []mutation{m}}
+	}
+	m.Batch = append(m.Batch, evictions...)
+	return m, len(evictions), nil
+}
+
+// events describes the changes a mutation is about to make. It must be called
+// before the mutation is applied so that previous values can be captured.
+func (s *MemoryStore) events(m mutation, events []domain.Event) []domain.Event {
+	switch m.Op {
+	case opSet:
+		ev := domain.Event{Type: domain.EventPut, Key: m.Key, Value: m.Value, Revision: m.Version, Version: m.Version}
+		if prev, exists := s.lookup(m.Key); exists {
+			ev.PrevValue = prev.value
+		}
+		events = append(events, ev)
+	case opDelete:
+		if prev, exists := s.data[m.Key]; exists {
+			events = append(events, domain.Event{Type: domain.EventDelete, Key: m.Key, PrevValue: prev.value, Revision: m.Version})
+		}
+	case opClear:
+		for _, key := range s.keys {
+			events = append(events, domain.Event{Type: domain.EventDelete, Key: key, PrevValue: s.data[key].value, Revision: m.Version})
+		}
+	case opBatch:
+		for _, op := range m.Batch {
+			events = s.events(op, events)
+		}
+	}
+	return events
+}
+
+// record passes a mutation to the journal, if any. Callers must hold the write lock.
+func (s *MemoryStore) record(m mutation) error {
+	if s.journal == nil {
+		return nil
+	}
+	if err := s.journal(m); err != nil {
+		return fmt.Errorf("failed to journal %s: %w", m.Op, err)
+	}
+	return nil
+}
+
+// apply applies a mutation directly to the data map. Callers must hold the
+// write lock. It is used to replay journaled mutations during recovery.
+func (s *MemoryStore) apply(m mutation) {
+	switch m.Op {
+	case opSet:
+		if prev, exists := s.data[m.Key]; exists {
+			s.bytes -= entrySize(m.Key, prev.value)
+		} else {
+			s.keys = insertKey(s.keys, m.Key)
+		}
+		s.data[m.Key] = entry{value: m.Value, version: m.Version, expiresAt: m.ExpiresAt}
+		s.bytes += entrySize(m.Key, m.Value)
+		if s.lru != nil && s.limits.evictable(m.Key) {
+			s.lru.add(m.Key)
+		}
+	case opDelete:
+		if prev, exists := s.data[m.Key]; exists {
+			s.keys = removeKey(s.keys, m.Key)
+			s.bytes -= entrySize(m.Key, prev.value)
+		}
+		delete(s.data, m.Key)
+		if s.lru != nil {
+			s.lru.remove(m.Key)
+		}
+	case opClear:
+		s.reset()
+	case opBatch:
+		for _, op := range m.Batch {
+			s.apply(op)
+		}
+	}
+
+	if m.Version > s.revision {
+		s.revision = m.Version
+	}
+}
+
+// reset drops all data. Callers must hold the write lock.
+func (s *MemoryStore) reset() {
+	s.data = make(map[string]entry)
+	s.keys = nil
+	s.bytes = 0
+	if s.lru != nil {
+		s.lru.reset()
+	}
+}
+
+// memoryTx implements domain.Tx for the in-memory stores. It is only valid
+// inside the Update call that created it.
+type memoryTx struct {
+	ctx    context.Context
+	codec  Codec
+	lookup func(key string) (entry, bool)
+
+	// writes holds buffered entries by key; an entry with a nil value marks a
+	// deletion
+	writes map[string]*entry
+	order  []string
+
+	// revision is the last version assigned within the transaction
+	revision int64
+
+	// reserve, when set, assigns the version of each write instead of
+	// numbering writes after revision
+	reserve func() int64
+}
+
+// Get retrieves a value by key, observing writes made earlier in the transaction
+func (tx *memoryTx) Get(key string) (value any, err error) {
+	e, err := tx.read(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var result any
+	if err := decodeValue(e.value, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetTyped retrieves a value by key and unmarshals it into the provided type
+func (tx *memoryTx) GetTyped(key string, value any) error {
+	e, err := tx.read(key)
+	if err != nil {
+		return err
+	}
+
+	if err := decodeValue(e.value, value); err != nil {
+		return fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+
+	return nil
+}
+
+// Version returns the key's version, including writes made earlier in the transaction
+func (tx *memoryTx) Version(key string) (int64, error) {
+	e, err := tx.read(key)
+	if err != nil {
+		return 0, err
+	}
+	return e.version, nil
+}
+
+// Set buffers a value to be stored when the transaction commits
+func (tx *memoryTx) Set(key string, value any) error {
+	if key == "" {
+		return fmt.Errorf("key cannot be empty")
+	}
+
+	if err := tx.ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := encodeValue(tx.codec, value)
+	if err != nil {
+		return err
+	}
+
+	tx.write(key, &entry{value: data, version: tx.nextVersion()})
+	return nil
+}
+
+// Delete buffers the removal of a key to be applied when the transaction commits
+func (tx *memoryTx) Delete(key string) error {
+	if _, err := tx.read(key); err != nil {
+		return err
+	}
+
+	tx.write(key, &entry{version: tx.nextVersion()})
+	return nil
+}
+
+// nextVersion returns the version of the next write in the transaction
+func (tx *memoryTx) nextVersion() int64 {
+	if tx.reserve != nil {
+		return tx.reserve()
+	}
+	tx.revision++
+	return tx.revision
+}
+
+// read returns the current entry for a key within the transaction
+func (tx *memoryTx) read(key string) (*entry, error) {
+	if key == "" {
+		return nil, fmt.Errorf("key cannot be empty")
+	}
+
+	if err := tx.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if e, ok := tx.writes[key]; ok {
+		if e.value == nil {
+			return nil, domain.ErrKeyNotFound
+		}
+		return e, nil
+	}
+
+	e, exists := tx.lookup(key)
+	if !exists {
+		return nil, domain.ErrKeyNotFound
+	}
+	return &e, nil
+}
+
+// mutations returns the buffered writes in the order keys were first touched
+func (tx *memoryTx) mutations() []mutation {
+	batch := make([]mutation, 0, len(tx.order))
+	for _, key := range tx.order {
+		if e := tx.writes[key]; e.value != nil {
+			batch = append(batch, mutation{Op: opSet, Key: key, Value: e.value, Version: e.version})
+		} else {
+			batch = append(batch, mutation{Op: opDelete, Key: key, Version: e.version})
+		}
+	}
+	return batch
+}
+
+// write buffers an entry, remembering the order keys were first touched
+func (tx *memoryTx) write(key string, e *entry) {
+	if _, seen := tx.writes[key]; !seen {
+		tx.order = append(tx.order, key)
+	}
+	tx.writes[key] = e
+}
+
+// memoryIterator implements domain.Iterator over a point-in-time copy of
+// matching entries. Stored values are never mutated in place, so sharing
+// their byte slices with the iterator is safe.
+type memoryIterator struct {
+	ctx     context.Context
+	keys    []string
+	entries []entry
+	pos     int
+	err     error
+}
+
+// Next advances to the next entry
+func (it *memoryIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	if it.pos+1 >= len(it.keys) {
+		it.pos = len(it.keys)
+		return false
+	}
+	it.pos++
+	return true
+}
+
+// Key returns the current key
+func (it *memoryIterator) Key() string {
+	return it.keys[it.pos]
+}
+
+// Value returns the current encoded value
+func (it *memoryIterator) Value() []byte {
+	return it.entries[it.pos].value
+}
+
+// Version returns the current value's version
+func (it *memoryIterator) Version() int64 {
+	return it.entries[it.pos].version
+}
+
+// Decode unmarshals the current value into the provided type
+func (it *memoryIterator) Decode(value any) error {
+	if err := decodeValue(it.entries[it.pos].value, value); err != nil {
+		return fmt.Errorf("failed to unmarshal value for key %s: %w", it.keys[it.pos], err)
+	}
+	return nil
+}
+
+// Err returns the error that stopped iteration, if any
+func (it *memoryIterator) Err() error {
+	return it.err
+}
+
+// Close releases the captured entries
+func (it *memoryIterator) Close() error {
+	it.keys = nil
+	it.entries = nil
 	return nil
 }
\ No newline at end of file
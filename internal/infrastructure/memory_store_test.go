@@ -1,8 +1,11 @@
 package infrastructure
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"gosuda.org/boilerplate/internal/domain"
 )
@@ -23,10 +26,11 @@ func TestNewMemoryStore(t *testing.T) {
 }
 
 func TestMemoryStore_Set(t *testing.T) {
+	ctx := context.Background()
 	store := NewMemoryStore()
 
 	// Test setting a simple value
-	err := store.Set("key1", "value1")
+	err := store.Set(ctx, "key1", "value1")
 	if err != nil {
 		t.Errorf("Failed to set value: %v", err)
 	}
@@ -42,36 +46,37 @@ func TestMemoryStore_Set(t *testing.T) {
 		"tags": []string{"tag1", "tag2"},
 	}
 
-	err = store.Set("key2", complexValue)
+	err = store.Set(ctx, "key2", complexValue)
 	if err != nil {
 		t.Errorf("Failed to set complex value: %v", err)
 	}
 
 	// Test setting with empty key
-	err = store.Set("", "value")
+	err = store.Set(ctx, "", "value")
 	if err == nil {
 		t.Error("Expected error for empty key")
 	}
 }
 
 func TestMemoryStore_Get(t *testing.T) {
+	ctx := context.Background()
 	store := NewMemoryStore()
 
 	// Test getting non-existent key
-	_, err := store.Get("nonexistent")
+	_, err := store.Get(ctx, "nonexistent")
 	if err != domain.ErrKeyNotFound {
 		t.Errorf("Expected ErrKeyNotFound, got %v", err)
 	}
 
 	// Test getting with empty key
-	_, err = store.Get("")
+	_, err = store.Get(ctx, "")
 	if err == nil {
 		t.Error("Expected error for empty key")
 	}
 
 	// Test getting existing key
-	store.Set("key1", "value1")
-	value, err := store.Get("key1")
+	store.Set(ctx, "key1", "value1")
+	value, err := store.Get(ctx, "key1")
 	if err != nil {
 		t.Errorf("Failed to get value: %v", err)
 	}
@@ -85,9 +90,9 @@ func TestMemoryStore_Get(t *testing.T) {
 		"name": "test",
 		"age":  25,
 	}
-	store.Set("key2", complexValue)
+	store.Set(ctx, "key2", complexValue)
 
-	retrieved, err := store.Get("key2")
+	retrieved, err := store.Get(ctx, "key2")
 	if err != nil {
 		t.Errorf("Failed to get complex value: %v", err)
 	}
@@ -103,6 +108,7 @@ func TestMemoryStore_Get(t *testing.T) {
 }
 
 func TestMemoryStore_GetTyped(t *testing.T) {
+	ctx := context.Background()
 	store := NewMemoryStore()
 
 	// Test getting typed value
@@ -110,10 +116,10 @@ func TestMemoryStore_GetTyped(t *testing.T) {
 		"name": "test",
 		"age":  25,
 	}
-	store.Set("key1", originalValue)
+	store.Set(ctx, "key1", originalValue)
 
 	var retrievedValue map[string]interface{}
-	err := store.GetTyped("key1", &retrievedValue)
+	err := store.GetTyped(ctx, "key1", &retrievedValue)
 	if err != nil {
 		t.Errorf("Failed to get typed value: %v", err)
 	}
@@ -123,29 +129,30 @@ func TestMemoryStore_GetTyped(t *testing.T) {
 	}
 
 	// Test getting non-existent key
-	err = store.GetTyped("nonexistent", &retrievedValue)
+	err = store.GetTyped(ctx, "nonexistent", &retrievedValue)
 	if err != domain.ErrKeyNotFound {
 		t.Errorf("Expected ErrKeyNotFound, got %v", err)
 	}
 
 	// Test getting with empty key
-	err = store.GetTyped("", &retrievedValue)
+	err = store.GetTyped(ctx, "", &retrievedValue)
 	if err == nil {
 		t.Error("Expected error for empty key")
 	}
 }
 
 func TestMemoryStore_List(t *testing.T) {
+	ctx := context.Background()
 	store := NewMemoryStore()
 
 	// Set up test data
-	store.Set("user:1", "user1")
-	store.Set("user:2", "user2")
-	store.Set("post:1", "post1")
-	store.Set("post:2", "post2")
+	store.Set(ctx, "user:1", "user1")
+	store.Set(ctx, "user:2", "user2")
+	store.Set(ctx, "post:1", "post1")
+	store.Set(ctx, "post:2", "post2")
 
 	// Test listing with prefix
-	users, err := store.List("user:")
+	users, err := store.List(ctx, "user:")
 	if err != nil {
 		t.Errorf("Failed to list users: %v", err)
 	}
@@ -154,7 +161,7 @@ func TestMemoryStore_List(t *testing.T) {
 		t.Errorf("Expected 2 users, got %d", len(users))
 	}
 
-	posts, err := store.List("post:")
+	posts, err := store.List(ctx, "post:")
 	if err != nil {
 		t.Errorf("Failed to list posts: %v", err)
 	}
@@ -164,7 +171,7 @@ func TestMemoryStore_List(t *testing.T) {
 	}
 
 	// Test listing with non-existent prefix
-	empty, err := store.List("nonexistent:")
+	empty, err := store.List(ctx, "nonexistent:")
 	if err != nil {
 		t.Errorf("Failed to list with non-existent prefix: %v", err)
 	}
@@ -174,7 +181,7 @@ func TestMemoryStore_List(t *testing.T) {
 	}
 
 	// Test listing all
-	all, err := store.List("")
+	all, err := store.List(ctx, "")
 	if err != nil {
 		t.Errorf("Failed to list all: %v", err)
 	}
@@ -185,15 +192,16 @@ func TestMemoryStore_List(t *testing.T) {
 }
 
 func TestMemoryStore_ListKeys(t *testing.T) {
+	ctx := context.Background()
 	store := NewMemoryStore()
 
 	// Set up test data
-	store.Set("user:1", "user1")
-	store.Set("user:2", "user2")
-	store.Set("post:1", "post1")
+	store.Set(ctx, "user:1", "user1")
+	store.Set(ctx, "user:2", "user2")
+	store.Set(ctx, "post:1", "post1")
 
 	// Test listing keys with prefix
-	userKeys, err := store.ListKeys("user:")
+	userKeys, err := store.ListKeys(ctx, "user:")
 	if err != nil {
 		t.Errorf("Failed to list user keys: %v", err)
 	}
@@ -212,27 +220,28 @@ func TestMemoryStore_ListKeys(t *testing.T) {
 }
 
 func TestMemoryStore_Delete(t *testing.T) {
+	ctx := context.Background()
 	store := NewMemoryStore()
 
 	// Test deleting non-existent key
-	err := store.Delete("nonexistent")
+	err := store.Delete(ctx, "nonexistent")
 	if err != domain.ErrKeyNotFound {
 		t.Errorf("Expected ErrKeyNotFound, got %v", err)
 	}
 
 	// Test deleting with empty key
-	err = store.Delete("")
+	err = store.Delete(ctx, "")
 	if err == nil {
 		t.Error("Expected error for empty key")
 	}
 
 	// Test deleting existing key
-	store.Set("key1", "value1")
+	store.Set(ctx, "key1", "value1")
 	if !store.Exists("key1") {
 		t.Error("Key should exist before deletion")
 	}
 
-	err = store.Delete("key1")
+	err = store.Delete(ctx, "key1")
 	if err != nil {
 		t.Errorf("Failed to delete key: %v", err)
 	}
@@ -243,11 +252,12 @@ func TestMemoryStore_Delete(t *testing.T) {
 }
 
 func TestMemoryStore_Close(t *testing.T) {
+	ctx := context.Background()
 	store := NewMemoryStore()
 
 	// Add some data
-	store.Set("key1", "value1")
-	store.Set("key2", "value2")
+	store.Set(ctx, "key1", "value1")
+	store.Set(ctx, "key2", "value2")
 
 	if store.Size() != 2 {
 		t.Errorf("Expected size 2, got %d", store.Size())
@@ -266,29 +276,31 @@ func TestMemoryStore_Close(t *testing.T) {
 }
 
 func TestMemoryStore_Size(t *testing.T) {
+	ctx := context.Background()
 	store := NewMemoryStore()
 
 	if store.Size() != 0 {
 		t.Errorf("Expected size 0, got %d", store.Size())
 	}
 
-	store.Set("key1", "value1")
+	store.Set(ctx, "key1", "value1")
 	if store.Size() != 1 {
 		t.Errorf("Expected size 1, got %d", store.Size())
 	}
 
-	store.Set("key2", "value2")
+	store.Set(ctx, "key2", "value2")
 	if store.Size() != 2 {
 		t.Errorf("Expected size 2, got %d", store.Size())
 	}
 
-	store.Delete("key1")
+	store.Delete(ctx, "key1")
 	if store.Size() != 1 {
 		t.Errorf("Expected size 1 after deletion, got %d", store.Size())
 	}
 }
 
 func TestMemoryStore_Exists(t *testing.T) {
+	ctx := context.Background()
 	store := NewMemoryStore()
 
 	// Test non-existent key
@@ -302,24 +314,25 @@ func TestMemoryStore_Exists(t *testing.T) {
 	}
 
 	// Test existing key
-	store.Set("key1", "value1")
+	store.Set(ctx, "key1", "value1")
 	if !store.Exists("key1") {
 		t.Error("Existing key should return true")
 	}
 
 	// Test after deletion
-	store.Delete("key1")
+	store.Delete(ctx, "key1")
 	if store.Exists("key1") {
 		t.Error("Deleted key should return false")
 	}
 }
 
 func TestMemoryStore_Clear(t *testing.T) {
+	ctx := context.Background()
 	store := NewMemoryStore()
 
 	// Add some data
-	store.Set("key1", "value1")
-	store.Set("key2", "value2")
+	store.Set(ctx, "key1", "value1")
+	store.Set(ctx, "key2", "value2")
 
 	if store.Size() != 2 {
 		t.Errorf("Expected size 2, got %d", store.Size())
@@ -338,6 +351,7 @@ func TestMemoryStore_Clear(t *testing.T) {
 }
 
 func TestMemoryStore_Concurrency(t *testing.T) {
+	ctx := context.Background()
 	store := NewMemoryStore()
 	done := make(chan bool, 10)
 
@@ -346,7 +360,7 @@ func TestMemoryStore_Concurrency(t *testing.T) {
 		go func(id int) {
 			for j := 0; j < 10; j++ {
 				key := fmt.Sprintf("key-%d-%d", id, j)
-				store.Set(key, fmt.Sprintf("value-%d-%d", id, j))
+				store.Set(ctx, key, fmt.Sprintf("value-%d-%d", id, j))
 			}
 			done <- true
 		}(i)
@@ -357,7 +371,7 @@ func TestMemoryStore_Concurrency(t *testing.T) {
 		go func(id int) {
 			for j := 0; j < 10; j++ {
 				key := fmt.Sprintf("key-%d-%d", id, j)
-				store.Get(key)
+				store.Get(ctx, key)
 			}
 			done <- true
 		}(i)
@@ -376,6 +390,7 @@ func TestMemoryStore_Concurrency(t *testing.T) {
 }
 
 func TestMemoryStore_JSONSerialization(t *testing.T) {
+	ctx := context.Background()
 	store := NewMemoryStore()
 
 	// Test storing various data types
@@ -398,12 +413,12 @@ func TestMemoryStore_JSONSerialization(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			err := store.Set(tc.key, tc.value)
+			err := store.Set(ctx, tc.key, tc.value)
 			if err != nil {
 				t.Errorf("Failed to set %s: %v", tc.name, err)
 			}
 
-			retrieved, err := store.Get(tc.key)
+			retrieved, err := store.Get(ctx, tc.key)
 			if err != nil {
 				t.Errorf("Failed to get %s: %v", tc.name, err)
 			}
@@ -414,4 +429,559 @@ func TestMemoryStore_JSONSerialization(t *testing.T) {
 			}
 		})
 	}
+}
+
+func TestMemoryStore_ContextCancellation(t *testing.T) {
+	store := NewMemoryStore()
+	store.Set(context.Background(), "key1", "value1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := store.Set(ctx, "key2", "value2"); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from Set, got %v", err)
+	}
+
+	if _, err := store.Get(ctx, "key1"); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from Get, got %v", err)
+	}
+
+	var value string
+	if err := store.GetTyped(ctx, "key1", &value); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from GetTyped, got %v", err)
+	}
+
+	if _, err := store.List(ctx, ""); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from List, got %v", err)
+	}
+
+	if _, err := store.ListKeys(ctx, ""); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from ListKeys, got %v", err)
+	}
+
+	if err := store.Delete(ctx, "key1"); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from Delete, got %v", err)
+	}
+
+	if store.Size() != 1 {
+		t.Errorf("Expected cancelled operations to leave the store unchanged, got size %d", store.Size())
+	}
+}
+
+func TestMemoryStore_Update(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	store.Set(ctx, "key1", "value1")
+
+	// Commit applies all writes and reads observe earlier writes
+	err := store.Update(ctx, func(tx domain.Tx) error {
+		if err := tx.Set("key2", "value2"); err != nil {
+			return err
+		}
+
+		var value string
+		if err := tx.GetTyped("key2", &value); err != nil {
+			return err
+		}
+		if value != "value2" {
+			t.Errorf("Expected 'value2' inside transaction, got %v", value)
+		}
+
+		return tx.Delete("key1")
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	if store.Exists("key1") {
+		t.Error("Key should not exist after committed deletion")
+	}
+	if !store.Exists("key2") {
+		t.Error("Key should exist after committed set")
+	}
+
+	// Deleting a missing key fails with ErrKeyNotFound
+	err = store.Update(ctx, func(tx domain.Tx) error {
+		return tx.Delete("nonexistent")
+	})
+	if err != domain.ErrKeyNotFound {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+
+	// Empty keys are rejected
+	err = store.Update(ctx, func(tx domain.Tx) error {
+		return tx.Set("", "value")
+	})
+	if err == nil {
+		t.Error("Expected error for empty key")
+	}
+}
+
+func TestMemoryStore_UpdateRollback(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	store.Set(ctx, "key1", "value1")
+
+	rollback := fmt.Errorf("rollback")
+	err := store.Update(ctx, func(tx domain.Tx) error {
+		tx.Set("key1", "changed")
+		tx.Set("key2", "value2")
+		return rollback
+	})
+	if err != rollback {
+		t.Errorf("Expected rollback error, got %v", err)
+	}
+
+	var value string
+	store.GetTyped(ctx, "key1", &value)
+	if value != "value1" {
+		t.Errorf("Expected 'value1' after rollback, got %v", value)
+	}
+	if store.Exists("key2") {
+		t.Error("Key should not exist after rollback")
+	}
+
+	// A context cancelled during the transaction also rolls back
+	cancelCtx, cancel := context.WithCancel(ctx)
+	err = store.Update(cancelCtx, func(tx domain.Tx) error {
+		tx.Set("key2", "value2")
+		cancel()
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if store.Exists("key2") {
+		t.Error("Key should not exist after cancelled transaction")
+	}
+}
+
+func TestMemoryStore_UpdateConcurrency(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	store.Set(ctx, "counter", 0)
+
+	done := make(chan bool, 10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			for j := 0; j < 10; j++ {
+				store.Update(ctx, func(tx domain.Tx) error {
+					var counter int
+					if err := tx.GetTyped("counter", &counter); err != nil {
+						return err
+					}
+					return tx.Set("counter", counter+1)
+				})
+			}
+			done <- true
+		}()
+	}
+
+	for i := 0; i < 10; i++ {
+		<-done
+	}
+
+	var counter int
+	store.GetTyped(ctx, "counter", &counter)
+	if counter != 100 {
+		t.Errorf("Expected counter 100, got %d", counter)
+	}
+}
+
+func TestMemoryStore_Versions(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	store.Set(ctx, "key1", "value1")
+
+	var value string
+	first, err := store.GetTypedVersion(ctx, "key1", &value)
+	if err != nil {
+		t.Fatalf("Failed to get version: %v", err)
+	}
+	if first <= 0 {
+		t.Errorf("Expected positive version, got %d", first)
+	}
+
+	store.Set(ctx, "key1", "value2")
+	second, _ := store.GetTypedVersion(ctx, "key1", &value)
+	if second <= first {
+		t.Errorf("Expected version to increase, got %d after %d", second, first)
+	}
+
+	// Re-creating a deleted key never reuses an old version
+	store.Delete(ctx, "key1")
+	store.Set(ctx, "key1", "value3")
+	third, _ := store.GetTypedVersion(ctx, "key1", &value)
+	if third <= second {
+		t.Errorf("Expected version to increase after re-creation, got %d after %d", third, second)
+	}
+
+	if _, err := store.GetTypedVersion(ctx, "nonexistent", &value); err != domain.ErrKeyNotFound {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestMemoryStore_CompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	// Version 0 creates a key only if it does not exist
+	version, err := store.CompareAndSwap(ctx, "key1", "value1", 0)
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if _, err := store.CompareAndSwap(ctx, "key1", "value1", 0); err != domain.ErrVersionMismatch {
+		t.Errorf("Expected ErrVersionMismatch creating existing key, got %v", err)
+	}
+
+	// Swapping with a stale version fails and leaves the value untouched
+	if _, err := store.CompareAndSwap(ctx, "key1", "stale", version+1); err != domain.ErrVersionMismatch {
+		t.Errorf("Expected ErrVersionMismatch, got %v", err)
+	}
+
+	newVersion, err := store.CompareAndSwap(ctx, "key1", "value2", version)
+	if err != nil {
+		t.Fatalf("Failed to swap: %v", err)
+	}
+	if newVersion <= version {
+		t.Errorf("Expected version to increase, got %d after %d", newVersion, version)
+	}
+
+	var value string
+	current, _ := store.GetTypedVersion(ctx, "key1", &value)
+	if value != "value2" || current != newVersion {
+		t.Errorf("Expected 'value2' at version %d, got %v at %d", newVersion, value, current)
+	}
+
+	// Deleting with a stale version fails
+	if err := store.CompareAndDelete(ctx, "key1", version); err != domain.ErrVersionMismatch {
+		t.Errorf("Expected ErrVersionMismatch, got %v", err)
+	}
+	if err := store.CompareAndDelete(ctx, "key1", newVersion); err != nil {
+		t.Errorf("Failed to delete: %v", err)
+	}
+	if store.Exists("key1") {
+		t.Error("Key should not exist after deletion")
+	}
+}
+
+func TestMemoryStore_Scan(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	for _, key := range []string{"post:3", "user:1", "post:1", "post:5", "post:2", "post:4"} {
+		store.Set(ctx, key, key)
+	}
+	store.Delete(ctx, "post:4")
+
+	testCases := []struct {
+		name     string
+		opts     domain.ScanOptions
+		expected []string
+	}{
+		{"all ascending", domain.ScanOptions{Prefix: "post:"}, []string{"post:1", "post:2", "post:3", "post:5"}},
+		{"all descending", domain.ScanOptions{Prefix: "post:", Reverse: true}, []string{"post:5", "post:3", "post:2", "post:1"}},
+		{"limit", domain.ScanOptions{Prefix: "post:", Limit: 2}, []string{"post:1", "post:2"}},
+		{"start after", domain.ScanOptions{Prefix: "post:", StartAfter: "post:2"}, []string{"post:3", "post:5"}},
+		{"start after missing key", domain.ScanOptions{Prefix: "post:", StartAfter: "post:4"}, []string{"post:5"}},
+		{"start after descending", domain.ScanOptions{Prefix: "post:", StartAfter: "post:3", Reverse: true, Limit: 1}, []string{"post:2"}},
+		{"empty prefix", domain.ScanOptions{StartAfter: "post:5"}, []string{"user:1"}},
+		{"no matches", domain.ScanOptions{Prefix: "nonexistent:"}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			keys, err := store.Scan(ctx, tc.opts)
+			if err != nil {
+				t.Fatalf("Failed to scan: %v", err)
+			}
+			if fmt.Sprint(keys) != fmt.Sprint(tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, keys)
+			}
+		})
+	}
+
+	// Transactions and Clear keep the key order in sync
+	store.Update(ctx, func(tx domain.Tx) error {
+		tx.Delete("post:1")
+		return tx.Set("post:0", "post:0")
+	})
+	keys, _ := store.Scan(ctx, domain.ScanOptions{Prefix: "post:", Limit: 2})
+	if fmt.Sprint(keys) != fmt.Sprint([]string{"post:0", "post:2"}) {
+		t.Errorf("Expected [post:0 post:2] after transaction, got %v", keys)
+	}
+
+	store.Clear()
+	keys, _ = store.Scan(ctx, domain.ScanOptions{})
+	if len(keys) != 0 {
+		t.Errorf("Expected no keys after clear, got %v", keys)
+	}
+}
+
+func TestMemoryStore_Iterate(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	type user struct {
+		Name string `json:"name"`
+	}
+
+	store.Set(ctx, "user:2", user{Name: "bob"})
+	store.Set(ctx, "user:1", user{Name: "alice"})
+	store.Set(ctx, "post:1", "post1")
+
+	it, err := store.Iterate(ctx, domain.ScanOptions{Prefix: "user:"})
+	if err != nil {
+		t.Fatalf("Failed to iterate: %v", err)
+	}
+	defer it.Close()
+
+	var keys, names []string
+	for it.Next() {
+		var u user
+		if err := it.Decode(&u); err != nil {
+			t.Fatalf("Failed to decode %s: %v", it.Key(), err)
+		}
+		if it.Version() <= 0 {
+			t.Errorf("Expected positive version for %s, got %d", it.Key(), it.Version())
+		}
+		if len(it.Value()) == 0 {
+			t.Errorf("Expected raw value for %s", it.Key())
+		}
+		keys = append(keys, it.Key())
+		names = append(names, u.Name)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Iteration failed: %v", err)
+	}
+
+	if fmt.Sprint(keys) != "[user:1 user:2]" || fmt.Sprint(names) != "[alice bob]" {
+		t.Errorf("Expected [user:1 user:2] with [alice bob], got %v with %v", keys, names)
+	}
+
+	// Iterators see a point-in-time view and stop on cancellation
+	cancelCtx, cancel := context.WithCancel(ctx)
+	it, _ = store.Iterate(cancelCtx, domain.ScanOptions{Prefix: "user:"})
+	store.Delete(ctx, "user:1")
+	if !it.Next() || it.Key() != "user:1" {
+		t.Error("Expected iterator to keep entries deleted after it was created")
+	}
+	cancel()
+	if it.Next() {
+		t.Error("Expected Next to stop after cancellation")
+	}
+	if it.Err() != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", it.Err())
+	}
+}
+
+func TestListTyped(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	store.Set(ctx, "count:a", 1)
+	store.Set(ctx, "count:b", 2)
+	store.Set(ctx, "other", "x")
+
+	entries, err := domain.ListTyped[int](ctx, store, domain.ScanOptions{Prefix: "count:", Reverse: true})
+	if err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Key != "count:b" || entries[0].Value != 2 || entries[1].Key != "count:a" || entries[1].Value != 1 {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+
+	// Values that do not decode into T are reported
+	if _, err := domain.ListTyped[int](ctx, store, domain.ScanOptions{Prefix: "other"}); err == nil {
+		t.Error("Expected decode error")
+	}
+}
+
+func TestMemoryStore_SetWithTTL(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	defer store.Close()
+
+	if err := store.SetWithTTL(ctx, "key1", "value1", 0); err == nil {
+		t.Error("Expected error for non-positive TTL")
+	}
+
+	store.SetWithTTL(ctx, "session:1", "short", 20*time.Millisecond)
+	store.SetWithTTL(ctx, "session:2", "long", time.Hour)
+
+	value, err := store.Get(ctx, "session:1")
+	if err != nil || value != "short" {
+		t.Fatalf("Expected 'short' before expiry, got %v (%v)", value, err)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	// Expired keys are hidden immediately, before the sweeper runs
+	if _, err := store.Get(ctx, "session:1"); err != domain.ErrKeyNotFound {
+		t.Errorf("Expected ErrKeyNotFound for expired key, got %v", err)
+	}
+	if store.Exists("session:1") {
+		t.Error("Expected expired key not to exist")
+	}
+
+	keys, _ := store.Scan(ctx, domain.ScanOptions{Prefix: "session:"})
+	if len(keys) != 1 || keys[0] != "session:2" {
+		t.Errorf("Expected only session:2 in scan, got %v", keys)
+	}
+
+	values, _ := store.List(ctx, "session")
+	if len(values) != 1 {
+		t.Errorf("Expected 1 live value, got %d", len(values))
+	}
+
+	// A plain Set clears the TTL
+	store.SetWithTTL(ctx, "key2", "value2", 20*time.Millisecond)
+	store.Set(ctx, "key2", "value2")
+	time.Sleep(40 * time.Millisecond)
+	if !store.Exists("key2") {
+		t.Error("Expected Set to remove the TTL")
+	}
+}
+
+func TestMemoryStore_Sweeper(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(WithSweepInterval(10 * time.Millisecond))
+	defer store.Close()
+
+	store.SetWithTTL(ctx, "key1", "value1", time.Millisecond)
+	store.SetWithTTL(ctx, "key2", "value2", time.Millisecond)
+	store.Set(ctx, "key3", "value3")
+
+	deadline := time.Now().Add(time.Second)
+	for store.Size() != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if store.Size() != 1 {
+		t.Errorf("Expected sweeper to leave 1 item, got %d", store.Size())
+	}
+
+	stats := store.Stats()
+	if stats.ExpiredKeys != 2 {
+		t.Errorf("Expected 2 expired keys, got %d", stats.ExpiredKeys)
+	}
+	if stats.Items != 1 {
+		t.Errorf("Expected 1 item in stats, got %d", stats.Items)
+	}
+}
+
+func TestMemoryStore_LimitsReject(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(WithLimits(StoreLimits{MaxItems: 2}))
+	defer store.Close()
+
+	store.Set(ctx, "key1", "value1")
+	store.Set(ctx, "key2", "value2")
+
+	if err := store.Set(ctx, "key3", "value3"); err != domain.ErrStoreFull {
+		t.Errorf("Expected ErrStoreFull, got %v", err)
+	}
+
+	// Overwriting and deleting still work when full
+	if err := store.Set(ctx, "key1", "updated"); err != nil {
+		t.Errorf("Expected overwrite to succeed, got %v", err)
+	}
+	if err := store.Delete(ctx, "key2"); err != nil {
+		t.Errorf("Expected delete to succeed, got %v", err)
+	}
+	if err := store.Set(ctx, "key3", "value3"); err != nil {
+		t.Errorf("Expected write to succeed after delete, got %v", err)
+	}
+
+	// A transaction that does not fit is rejected as a whole
+	err := store.Update(ctx, func(tx domain.Tx) error {
+		tx.Delete("key1")
+		tx.Set("key4", "value4")
+		return tx.Set("key5", "value5")
+	})
+	if err != domain.ErrStoreFull {
+		t.Errorf("Expected ErrStoreFull from transaction, got %v", err)
+	}
+	if !store.Exists("key1") || store.Exists("key4") {
+		t.Error("Expected rejected transaction to leave the store unchanged")
+	}
+}
+
+func TestMemoryStore_LimitsEvict(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(WithLimits(StoreLimits{
+		MaxItems: 3,
+		Prefixes: map[string]EvictionPolicy{"cache:": PolicyEvict},
+	}))
+	defer store.Close()
+
+	store.Set(ctx, "posts:1", "post")
+	store.Set(ctx, "cache:a", "a")
+	store.Set(ctx, "cache:b", "b")
+
+	// Reading cache:a makes cache:b the least recently used
+	store.Get(ctx, "cache:a")
+
+	if err := store.Set(ctx, "cache:c", "c"); err != nil {
+		t.Fatalf("Expected eviction to make room, got %v", err)
+	}
+	if store.Exists("cache:b") {
+		t.Error("Expected least recently used cache:b to be evicted")
+	}
+	if !store.Exists("cache:a") || !store.Exists("cache:c") {
+		t.Error("Expected recently used cache entries to survive")
+	}
+
+	// Writes under reject prefixes evict cache entries too
+	if err := store.Set(ctx, "posts:2", "post"); err != nil {
+		t.Fatalf("Expected eviction to make room for posts:2, got %v", err)
+	}
+	if err := store.Set(ctx, "posts:3", "post"); err != nil {
+		t.Fatalf("Expected eviction to make room for posts:3, got %v", err)
+	}
+
+	// Entries under reject prefixes are never evicted
+	if err := store.Set(ctx, "posts:4", "post"); err != domain.ErrStoreFull {
+		t.Errorf("Expected ErrStoreFull once only posts remain, got %v", err)
+	}
+
+	if evicted := store.Stats().EvictedKeys; evicted != 3 {
+		t.Errorf("Expected 3 evicted keys, got %d", evicted)
+	}
+}
+
+func TestMemoryStore_LimitsBytes(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(WithLimits(StoreLimits{MaxBytes: 64, Policy: PolicyEvict}))
+	defer store.Close()
+
+	value := strings.Repeat("x", 20)
+	for i := 0; i < 5; i++ {
+		if err := store.Set(ctx, fmt.Sprintf("key%d", i), value); err != nil {
+			t.Fatalf("Failed to set key%d: %v", i, err)
+		}
+	}
+
+	stats := store.Stats()
+	if stats.Bytes > 64 {
+		t.Errorf("Expected at most 64 bytes, got %d", stats.Bytes)
+	}
+	if store.Exists("key0") || !store.Exists("key4") {
+		t.Error("Expected oldest keys to be evicted first")
+	}
+
+	// A value that can never fit is rejected
+	if err := store.Set(ctx, "big", strings.Repeat("x", 100)); err != domain.ErrStoreFull {
+		t.Errorf("Expected ErrStoreFull for oversized value, got %v", err)
+	}
+
+	store.Clear()
+	if stats := store.Stats(); stats.Bytes != 0 || stats.Items != 0 {
+		t.Errorf("Expected empty stats after clear, got %+v", stats)
+	}
 }
\ No newline at end of file
@@ -0,0 +1,539 @@
+package infrastructure
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// Replication stream messages. The stream is newline-delimited JSON. A full
+// copy is sent between a snapshot and a snapshotEnd message when a follower
+// starts or falls too far behind; after that, changes are sent in revision
+// order, with the last change of each commit marked, and heartbeats report
+// the leader's latest revision.
+const (
+	replicationSnapshot    = "snapshot"
+	replicationSnapshotEnd = "snapshotEnd"
+	replicationPut         = string(domain.EventPut)
+	replicationDelete      = string(domain.EventDelete)
+	replicationHeartbeat   = "heartbeat"
+)
+
+// Replication defaults
+const (
+	DefaultHeartbeatInterval = 5 * time.Second
+	DefaultRetryInterval     = time.Second
+)
+
+// replicationMessage is one line of the replication stream
+type replicationMessage struct {
+	Type  string `json:"type"`
+	Key   string `json:"key,omitempty"`
+	Value []byte `json:"value,omitempty"`
+
+	// Revision is the revision of a change. For a snapshotEnd or heartbeat it
+	// is the revision the follower is up to date with once it has applied
+	// every earlier message.
+	Revision int64 `json:"revision,omitempty"`
+
+	// Version is the key's version on the leader after a put
+	Version int64 `json:"version,omitempty"`
+
+	// Last marks the final change of a commit
+	Last bool `json:"last,omitempty"`
+
+	// Head is the leader's latest revision when the message was sent
+	Head int64 `json:"head,omitempty"`
+}
+
+// ReplicationLeader streams a store's changes to followers
+type ReplicationLeader struct {
+	store     domain.Store
+	heartbeat time.Duration
+	followers atomic.Int64
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewReplicationLeader creates a leader that streams changes to store. Idle
+// streams carry a heartbeat every heartbeat interval.
+func NewReplicationLeader(store domain.Store, heartbeat time.Duration) *ReplicationLeader {
+	if heartbeat <= 0 {
+		heartbeat = DefaultHeartbeatInterval
+	}
+
+	return &ReplicationLeader{
+		store:     store,
+		heartbeat: heartbeat,
+		closed:    make(chan struct{}),
+	}
+}
+
+// Close ends every stream, e.g. so that a server can shut down without
+// waiting for followers to disconnect
+func (l *ReplicationLeader) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return nil
+}
+
+// Stream writes the store's changes after revision after to w until ctx is
+// done, the leader is closed or the stream falls behind, calling flush
+// whenever w should be flushed to the follower. If after is 0, or the changes
+// after it are no longer retained, the stream starts with a snapshot of the
+// whole store.
+func (l *ReplicationLeader) Stream(ctx context.Context, w io.Writer, flush func() error, after int64) error {
+	l.followers.Add(1)
+	defer l.followers.Add(-1)
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	send := func() error {
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		return flush()
+	}
+
+	var watcher domain.Watcher
+	if after > 0 && after <= l.revision() {
+		var err error
+		watcher, err = l.store.Watch(ctx, domain.WatchOptions{AfterRevision: after})
+		if err != nil && err != domain.ErrRevisionCompacted {
+			return err
+		}
+	}
+
+	if watcher == nil {
+		// Watching before copying means no change is missed. Changes made
+		// while copying are also sent as events, which is harmless because
+		// followers apply them in order on top of the copy.
+		var err error
+		watcher, err = l.store.Watch(ctx, domain.WatchOptions{})
+		if err != nil {
+			return err
+		}
+		defer watcher.Close()
+
+		if err := l.snapshot(ctx, enc); err != nil {
+			return err
+		}
+	} else {
+		defer watcher.Close()
+	}
+
+	if err := l.sendHeartbeat(enc, watcher); err != nil {
+		return err
+	}
+	if err := send(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(l.heartbeat)
+	defer ticker.Stop()
+
+	events := watcher.Events()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-l.closed:
+			return nil
+		case ev, ok := <-events:
+			if !ok {
+				return watcher.Err()
+			}
+
+			// Send whatever else is queued in the same write
+			head := l.revision()
+			if err := enc.Encode(eventMessage(ev, head)); err != nil {
+				return err
+			}
+			for n := len(events); n > 0; n-- {
+				ev, ok := <-events
+				if !ok {
+					return watcher.Err()
+				}
+				if err := enc.Encode(eventMessage(ev, head)); err != nil {
+					return err
+				}
+			}
+		case <-ticker.C:
+			if err := l.sendHeartbeat(enc, watcher); err != nil {
+				return err
+			}
+		}
+
+		if err := send(); err != nil {
+			return err
+		}
+	}
+}
+
+// Status reports the leader's latest revision and number of followers
+func (l *ReplicationLeader) Status() domain.ReplicationStatus {
+	revision := l.revision()
+	return domain.ReplicationStatus{
+		Role:            domain.RoleLeader,
+		Followers:       l.followers.Load(),
+		AppliedRevision: revision,
+		LeaderRevision:  revision,
+		Healthy:         true,
+	}
+}
+
+// snapshot writes every entry in the store between snapshot and snapshotEnd
+// messages
+func (l *ReplicationLeader) snapshot(ctx context.Context, enc *json.Encoder) error {
+	// Changes up to head are part of the copy, since it is taken afterwards
+	head := l.revision()
+
+	if err := enc.Encode(replicationMessage{Type: replicationSnapshot, Head: head}); err != nil {
+		return err
+	}
+
+	it, err := l.store.Iterate(ctx, domain.ScanOptions{})
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	var latest int64
+	for it.Next() {
+		latest = max(latest, it.Version())
+		msg := replicationMessage{Type: replicationPut, Key: it.Key(), Value: it.Value(), Version: it.Version(), Head: head}
+		if err := enc.Encode(msg); err != nil {
+			return err
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	// Without a reported revision, the newest version in the copy is a safe
+	// point to resume from
+	if head == 0 {
+		head = latest
+	}
+	return enc.Encode(replicationMessage{Type: replicationSnapshotEnd, Revision: head, Head: head})
+}
+
+// sendHeartbeat sends every queued event followed by a heartbeat. The
+// revision is read first: every change up to it has been published by then,
+// so it is queued or already sent, and the follower is up to date with it
+// once it reaches the heartbeat.
+func (l *ReplicationLeader) sendHeartbeat(enc *json.Encoder, watcher domain.Watcher) error {
+	head := l.revision()
+
+	events := watcher.Events()
+	for n := len(events); n > 0; n-- {
+		ev, ok := <-events
+		if !ok {
+			return watcher.Err()
+		}
+		if err := enc.Encode(eventMessage(ev, head)); err != nil {
+			return err
+		}
+	}
+
+	return enc.Encode(replicationMessage{Type: replicationHeartbeat, Revision: head, Head: head})
+}
+
+// revision returns the store's latest revision, or 0 if it does not report one
+func (l *ReplicationLeader) revision() int64 {
+	if provider, ok := l.store.(domain.RevisionProvider); ok {
+		return provider.Revision()
+	}
+	return 0
+}
+
+// eventMessage converts a watch event to a stream message
+func eventMessage(ev domain.Event, head int64) replicationMessage {
+	msg := replicationMessage{
+		Type:     string(ev.Type),
+		Key:      ev.Key,
+		Revision: ev.Revision,
+		Version:  ev.Version,
+		Last:     ev.Last,
+		Head:     max(head, ev.Revision),
+	}
+	if ev.Type == domain.EventPut {
+		msg.Value = ev.Value
+	}
+	return msg
+}
+
+// FollowerOptions configures a Follower
+type FollowerOptions struct {
+	// LeaderURL is the base URL of the leader's API
+	LeaderURL string
+
+	// Token is sent as a bearer token to authenticate with the leader
+	Token string
+
+	// RetryInterval is how long to wait before reconnecting after the stream
+	// is interrupted
+	RetryInterval time.Duration
+
+	// MaxLag is how far behind the leader the follower may fall before it
+	// reports itself unhealthy; zero means no limit
+	MaxLag time.Duration
+
+	// Client makes requests to the leader; nil means a default client
+	Client *http.Client
+}
+
+// Follower replicates a leader's store into a local store. It applies each of
+// the leader's commits atomically and in order, and reconnects, resuming from
+// the last revision it applied, whenever the stream is interrupted. A copy of
+// the leader's store is held back until all of it has arrived and then
+// applied as a single commit, so reads never see a partial copy.
+//
+// Values and versions are replicated as stored by the leader, so versions read
+// from a follower can be used for conditional writes sent to the leader. A
+// follower must use the same encryption keys if the leader encrypts values. TTLs are not
+// replicated; expired keys are removed when the leader deletes them.
+type Follower struct {
+	store   domain.Store
+	logger  LoggerInterface
+	options FollowerOptions
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu        sync.Mutex
+	connected bool
+	applied   int64
+	head      int64
+	syncedAt  time.Time
+	lastError string
+}
+
+// NewFollower creates a follower that replicates into store and starts it
+// in the background. Call Close to stop it.
+func NewFollower(store domain.Store, logger LoggerInterface, options FollowerOptions) *Follower {
+	if options.RetryInterval <= 0 {
+		options.RetryInterval = DefaultRetryInterval
+	}
+	if options.Client == nil {
+		options.Client = &http.Client{}
+	}
+	options.LeaderURL = strings.TrimSuffix(options.LeaderURL, "/")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &Follower{
+		store:    store,
+		logger:   logger,
+		options:  options,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		syncedAt: time.Now(),
+	}
+
+	go f.run(ctx)
+	return f
+}
+
+// Close stops replicating. It does not close the local store.
+func (f *Follower) Close() error {
+	f.cancel()
+	<-f.done
+	return nil
+}
+
+// Status reports the follower's connection state and lag
+func (f *Follower) Status() domain.ReplicationStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	status := domain.ReplicationStatus{
+		Role:            domain.RoleFollower,
+		Leader:          f.options.LeaderURL,
+		Connected:       f.connected,
+		AppliedRevision: f.applied,
+		LeaderRevision:  f.head,
+		LagRevisions:    max(f.head-f.applied, 0),
+		LastError:       f.lastError,
+	}
+
+	if !f.connected || status.LagRevisions > 0 {
+		status.LagSeconds = time.Since(f.syncedAt).Seconds()
+	}
+	status.Healthy = f.connected && (f.options.MaxLag <= 0 || status.LagSeconds <= f.options.MaxLag.Seconds())
+
+	return status
+}
+
+// run streams from the leader until ctx is done
+func (f *Follower) run(ctx context.Context) {
+	defer close(f.done)
+
+	for {
+		err := f.follow(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		f.mu.Lock()
+		f.connected = false
+		f.lastError = err.Error()
+		f.mu.Unlock()
+		f.logger.Warn("Replication stream interrupted", "leader", f.options.LeaderURL, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(f.options.RetryInterval):
+		}
+	}
+}
+
+// follow applies one stream from the leader, returning why it ended
+func (f *Follower) follow(ctx context.Context) error {
+	f.mu.Lock()
+	after := f.applied
+	f.mu.Unlock()
+
+	query := url.Values{"after": {strconv.FormatInt(after, 10)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.options.LeaderURL+"/replication/stream?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if f.options.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+f.options.Token)
+	}
+
+	resp, err := f.options.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("leader responded with %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	f.mu.Lock()
+	f.connected = true
+	f.mu.Unlock()
+	f.logger.Info("Replicating from leader", "leader", f.options.LeaderURL, "after", after)
+
+	// snapshot holds the entries of a snapshot until its end arrives, so the
+	// copy replaces the local store in one commit
+	var snapshot []domain.Event
+
+	// pending holds the changes of a commit until its last one arrives
+	var pending []domain.Event
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg replicationMessage
+		if err := dec.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return errors.New("leader closed the stream")
+			}
+			return err
+		}
+
+		switch msg.Type {
+		case replicationSnapshot:
+			snapshot = []domain.Event{}
+		case replicationPut, replicationDelete:
+			ev := domain.Event{
+				Type:     domain.EventType(msg.Type),
+				Key:      msg.Key,
+				Value:    msg.Value,
+				Revision: msg.Revision,
+				Version:  msg.Version,
+			}
+			if snapshot != nil {
+				snapshot = append(snapshot, ev)
+				continue
+			}
+
+			pending = append(pending, ev)
+			if !msg.Last {
+				continue
+			}
+			if err := domain.ApplyEvents(ctx, f.store, pending); err != nil {
+				return fmt.Errorf("failed to apply changes at revision %d: %w", msg.Revision, err)
+			}
+			pending = nil
+		case replicationSnapshotEnd:
+			events, err := f.replaceEvents(ctx, snapshot, msg.Revision)
+			if err != nil {
+				return err
+			}
+			if err := domain.ApplyEvents(ctx, f.store, events); err != nil {
+				return fmt.Errorf("failed to apply snapshot: %w", err)
+			}
+			snapshot = nil
+
+			// The leader's revisions may have restarted, so the copy
+			// replaces rather than advances the applied revision
+			f.mu.Lock()
+			f.applied = 0
+			f.mu.Unlock()
+		case replicationHeartbeat:
+			// A heartbeat may arrive partway through a commit, which is not
+			// applied until its last change arrives
+			if len(pending) > 0 {
+				f.progress(0, msg.Head)
+				continue
+			}
+		default:
+			return fmt.Errorf("unknown replication message type %q", msg.Type)
+		}
+
+		f.progress(msg.Revision, msg.Head)
+	}
+}
+
+// replaceEvents returns the events that replace the local store's contents
+// with a snapshot: its entries, and deletes at revision of the local keys it
+// does not contain
+func (f *Follower) replaceEvents(ctx context.Context, snapshot []domain.Event, revision int64) ([]domain.Event, error) {
+	keys, err := f.store.Scan(ctx, domain.ScanOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	copied := make(map[string]struct{}, len(snapshot))
+	for _, ev := range snapshot {
+		copied[ev.Key] = struct{}{}
+	}
+
+	events := snapshot
+	for _, key := range keys {
+		if _, ok := copied[key]; !ok {
+			events = append(events, domain.Event{Type: domain.EventDelete, Key: key, Revision: revision})
+		}
+	}
+	return events, nil
+}
+
+// progress records that every change up to revision has been applied and
+// that the leader was at head
+func (f *Follower) progress(revision, head int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.applied = max(f.applied, revision)
+	f.head = head
+	if f.applied >= f.head {
+		f.syncedAt = time.Now()
+	}
+}
\ No newline at end of file
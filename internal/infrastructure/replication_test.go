@@ -0,0 +1,384 @@
+package infrastructure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gosuda.org/boilerplate/internal/config"
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// replicationServer serves a leader's stream the way the API does, recording
+// the revision each follower asks to resume after
+type replicationServer struct {
+	*httptest.Server
+	leader *ReplicationLeader
+
+	// paused makes the server refuse new streams
+	paused atomic.Bool
+
+	mu     sync.Mutex
+	afters []int64
+}
+
+// newReplicationServer starts a leader for store behind a test HTTP server
+func newReplicationServer(t *testing.T, store domain.Store) *replicationServer {
+	t.Helper()
+	rs := &replicationServer{leader: NewReplicationLeader(store, 20*time.Millisecond)}
+	rs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if rs.paused.Load() {
+			http.Error(w, "paused", http.StatusServiceUnavailable)
+			return
+		}
+
+		after, _ := strconv.ParseInt(r.URL.Query().Get("after"), 10, 64)
+		rs.mu.Lock()
+		rs.afters = append(rs.afters, after)
+		rs.mu.Unlock()
+
+		rs.leader.Stream(r.Context(), w, http.NewResponseController(w).Flush, after)
+	}))
+	t.Cleanup(func() {
+		rs.leader.Close()
+		rs.Close()
+	})
+	return rs
+}
+
+// disconnect drops every stream and refuses new ones until resumed
+func (rs *replicationServer) disconnect() {
+	rs.paused.Store(true)
+	rs.CloseClientConnections()
+}
+
+// lastAfter returns the revision the latest stream resumed after
+func (rs *replicationServer) lastAfter() (int64, int) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.afters[len(rs.afters)-1], len(rs.afters)
+}
+
+// newQuietLogger creates a logger that only reports errors
+func newQuietLogger(t *testing.T) LoggerInterface {
+	t.Helper()
+	logger, err := NewLogger(&config.LoggingConfig{Level: "error", Format: "text", Output: "stderr"})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	return logger
+}
+
+// newTestFollower starts a follower of rs replicating into store
+func newTestFollower(t *testing.T, rs *replicationServer, store domain.Store) *Follower {
+	t.Helper()
+	f := NewFollower(store, newQuietLogger(t), FollowerOptions{
+		LeaderURL:     rs.URL,
+		Token:         "secret",
+		RetryInterval: 10 * time.Millisecond,
+		MaxLag:        time.Second,
+	})
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+// waitFor polls cond until it holds or a second has passed
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// inSync reports whether follower holds the same keys and values as leader
+func inSync(ctx context.Context, leader, follower domain.Store) bool {
+	want, _ := domain.ListTyped[domain.RawValue](ctx, leader, domain.ScanOptions{})
+	got, _ := domain.ListTyped[domain.RawValue](ctx, follower, domain.ScanOptions{})
+	if len(want) != len(got) {
+		return false
+	}
+	for i := range want {
+		if want[i].Key != got[i].Key || string(want[i].Value) != string(got[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReplication_SnapshotAndChanges(t *testing.T) {
+	ctx := context.Background()
+	leaderStore := NewMemoryStore()
+	defer leaderStore.Close()
+
+	leaderStore.Set(ctx, "posts:1", "first")
+	leaderStore.Set(ctx, "posts:2", "second")
+	leaderStore.Delete(ctx, "posts:2")
+
+	// Keys the leader does not have are removed by the initial copy
+	followerStore := NewMemoryStore()
+	defer followerStore.Close()
+	followerStore.Set(ctx, "stale", "value")
+
+	rs := newReplicationServer(t, leaderStore)
+	f := newTestFollower(t, rs, followerStore)
+
+	waitFor(t, "initial sync", func() bool { return inSync(ctx, leaderStore, followerStore) })
+
+	leaderStore.Set(ctx, "posts:3", "third")
+	leaderStore.Update(ctx, func(tx domain.Tx) error {
+		tx.Delete("posts:1")
+		return tx.Set("posts:4", "fourth")
+	})
+
+	waitFor(t, "changes to replicate", func() bool {
+		return inSync(ctx, leaderStore, followerStore) && f.Status().AppliedRevision == leaderStore.Revision()
+	})
+
+	var value string
+	if err := followerStore.GetTyped(ctx, "posts:4", &value); err != nil || value != "fourth" {
+		t.Errorf("Expected replicated value 'fourth', got %q (%v)", value, err)
+	}
+
+	status := f.Status()
+	if !status.Connected || !status.Healthy || status.LagRevisions != 0 || status.Role != domain.RoleFollower {
+		t.Errorf("Expected a connected, healthy follower without lag, got %+v", status)
+	}
+	if leaderStatus := rs.leader.Status(); leaderStatus.Followers != 1 {
+		t.Errorf("Expected 1 follower, got %d", leaderStatus.Followers)
+	}
+}
+
+func TestReplication_Resume(t *testing.T) {
+	ctx := context.Background()
+	leaderStore := NewMemoryStore()
+	defer leaderStore.Close()
+	followerStore := NewMemoryStore()
+	defer followerStore.Close()
+
+	leaderStore.Set(ctx, "key0", "value0")
+	leaderStore.Set(ctx, "key1", "value1")
+
+	rs := newReplicationServer(t, leaderStore)
+	f := newTestFollower(t, rs, followerStore)
+	waitFor(t, "initial sync", func() bool { return f.Status().AppliedRevision == leaderStore.Revision() })
+
+	rs.disconnect()
+	waitFor(t, "follower to notice", func() bool { return !f.Status().Connected })
+
+	status := f.Status()
+	if status.Healthy || status.LastError == "" {
+		t.Errorf("Expected a disconnected follower to be unhealthy with an error, got %+v", status)
+	}
+
+	leaderStore.Set(ctx, "key2", "value2")
+	leaderStore.Delete(ctx, "key1")
+
+	rs.paused.Store(false)
+	waitFor(t, "changes to replicate", func() bool {
+		return inSync(ctx, leaderStore, followerStore) && f.Status().Connected
+	})
+
+	// The follower resumed from where it stopped rather than copying everything
+	after, streams := rs.lastAfter()
+	if after == 0 || streams < 2 {
+		t.Errorf("Expected the follower to resume after a revision, got after=%d over %d streams", after, streams)
+	}
+}
+
+func TestReplication_ResyncAfterCompaction(t *testing.T) {
+	ctx := context.Background()
+	leaderStore := NewMemoryStore(WithWatchHistorySize(2))
+	defer leaderStore.Close()
+	followerStore := NewMemoryStore()
+	defer followerStore.Close()
+
+	leaderStore.Set(ctx, "key1", "value1")
+
+	rs := newReplicationServer(t, leaderStore)
+	f := newTestFollower(t, rs, followerStore)
+	waitFor(t, "initial sync", func() bool { return f.Status().AppliedRevision == leaderStore.Revision() })
+
+	rs.disconnect()
+	waitFor(t, "follower to notice", func() bool { return !f.Status().Connected })
+
+	// More changes than the leader retains, including a delete the follower
+	// can only learn about from a fresh copy
+	leaderStore.Delete(ctx, "key1")
+	for i := 0; i < 10; i++ {
+		leaderStore.Set(ctx, "key"+strconv.Itoa(i+2), i)
+	}
+
+	rs.paused.Store(false)
+	waitFor(t, "follower to resync", func() bool { return inSync(ctx, leaderStore, followerStore) })
+
+	if _, err := followerStore.Get(ctx, "key1"); err != domain.ErrKeyNotFound {
+		t.Errorf("Expected key1 to be removed by the resync, got %v", err)
+	}
+}
+
+func TestReplication_VersionsMatchLeader(t *testing.T) {
+	for _, bs := range benchmarkStores {
+		t.Run(bs.name, func(t *testing.T) {
+			ctx := context.Background()
+			leaderStore := bs.new()
+			defer leaderStore.Close()
+			followerStore := NewMemoryStore()
+			defer followerStore.Close()
+
+			leaderStore.Set(ctx, "posts:1", "first")
+			followerStore.Set(ctx, "unrelated", "bumps the follower's own revision")
+
+			rs := newReplicationServer(t, leaderStore)
+			f := newTestFollower(t, rs, followerStore)
+			waitFor(t, "initial sync", func() bool { return inSync(ctx, leaderStore, followerStore) })
+
+			leaderStore.Update(ctx, func(tx domain.Tx) error {
+				tx.Set("posts:1", "updated")
+				return tx.Set("posts:2", "second")
+			})
+			waitFor(t, "changes to replicate", func() bool {
+				return inSync(ctx, leaderStore, followerStore) && f.Status().AppliedRevision == leaderStore.(domain.RevisionProvider).Revision()
+			})
+
+			for _, key := range []string{"posts:1", "posts:2"} {
+				var value string
+				want, _ := leaderStore.GetTypedVersion(ctx, key, &value)
+				got, _ := followerStore.GetTypedVersion(ctx, key, &value)
+				if got != want {
+					t.Errorf("Expected %s at leader version %d on the follower, got %d", key, want, got)
+				}
+			}
+
+			// A conditional write sent to the leader with a version read from
+			// the follower, as If-Match does for writes forwarded by a follower
+			var value string
+			version, _ := followerStore.GetTypedVersion(ctx, "posts:1", &value)
+			err := leaderStore.Update(ctx, func(tx domain.Tx) error {
+				current, err := tx.Version("posts:1")
+				if err != nil {
+					return err
+				}
+				if current != version {
+					return domain.ErrVersionMismatch
+				}
+				return tx.Set("posts:1", "conditional")
+			})
+			if err != nil {
+				t.Errorf("Expected the follower's version to match the leader's, got %v", err)
+			}
+			if _, err := leaderStore.CompareAndSwap(ctx, "posts:1", "stale", version); err != domain.ErrVersionMismatch {
+				t.Errorf("Expected ErrVersionMismatch for a stale version, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReplication_AtomicCommits(t *testing.T) {
+	ctx := context.Background()
+	leaderStore := NewMemoryStore()
+	defer leaderStore.Close()
+	followerStore := NewMemoryStore()
+	defer followerStore.Close()
+
+	rs := newReplicationServer(t, leaderStore)
+	f := newTestFollower(t, rs, followerStore)
+	waitFor(t, "initial sync", func() bool { return f.Status().Connected })
+
+	w, err := followerStore.Watch(ctx, domain.WatchOptions{})
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+	defer w.Close()
+
+	leaderStore.Update(ctx, func(tx domain.Tx) error {
+		for i := 0; i < 3; i++ {
+			if err := tx.Set("key"+strconv.Itoa(i), i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	// The transaction is applied on the follower as a single commit
+	for i := 0; i < 3; i++ {
+		select {
+		case ev := <-w.Events():
+			if ev.Last != (i == 2) {
+				t.Errorf("Expected only the third event to end the commit, got Last=%v for event %d", ev.Last, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for event %d", i)
+		}
+	}
+}
+
+func TestReplication_Unauthorized(t *testing.T) {
+	leaderStore := NewMemoryStore()
+	defer leaderStore.Close()
+	followerStore := NewMemoryStore()
+	defer followerStore.Close()
+
+	rs := newReplicationServer(t, leaderStore)
+	f := NewFollower(followerStore, newQuietLogger(t), FollowerOptions{LeaderURL: rs.URL, Token: "wrong", RetryInterval: 10 * time.Millisecond})
+	defer f.Close()
+
+	waitFor(t, "follower to fail", func() bool { return f.Status().LastError != "" })
+
+	if status := f.Status(); status.Connected || status.Healthy {
+		t.Errorf("Expected an unauthorized follower to be disconnected, got %+v", status)
+	}
+}
+func TestReplication_AtomicSnapshot(t *testing.T) {
+	ctx := context.Background()
+	leaderStore := NewMemoryStore()
+	defer leaderStore.Close()
+	followerStore := NewMemoryStore()
+	defer followerStore.Close()
+
+	for i := 0; i < 3; i++ {
+		leaderStore.Set(ctx, "key"+strconv.Itoa(i), i)
+	}
+	followerStore.Set(ctx, "stale", "value")
+
+	w, err := followerStore.Watch(ctx, domain.WatchOptions{})
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+	defer w.Close()
+
+	rs := newReplicationServer(t, leaderStore)
+	newTestFollower(t, rs, followerStore)
+
+	// The copy and the removal of the stale key are applied as one commit
+	var events []domain.Event
+	for len(events) == 0 || !events[len(events)-1].Last {
+		select {
+		case ev := <-w.Events():
+			events = append(events, ev)
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for the snapshot, got %d events", len(events))
+		}
+	}
+	if len(events) != 4 {
+		t.Fatalf("Expected the snapshot as a single commit of 4 changes, got %+v", events)
+	}
+	if last := events[3]; last.Type != domain.EventDelete || last.Key != "stale" {
+		t.Errorf("Expected the stale key to be removed in the same commit, got %+v", last)
+	}
+	if !inSync(ctx, leaderStore, followerStore) {
+		t.Error("Expected the follower to hold the leader's keys")
+	}
+}
\ No newline at end of file
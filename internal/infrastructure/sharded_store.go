@@ -0,0 +1,749 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// DefaultShardCount is the number of shards used unless configured otherwise
+const DefaultShardCount = 16
+
+// ShardedStoreOptions configures a ShardedStore
+type ShardedStoreOptions struct {
+	// Shards is the number of partitions keys are spread across; zero uses
+	// DefaultShardCount
+	Shards int
+
+	// Codec encodes new values; nil uses JSONCodec
+	Codec Codec
+
+	// SweepInterval controls how often expired keys are removed; zero uses
+	// DefaultSweepInterval
+	SweepInterval time.Duration
+}
+
+// ShardedStore implements the Store interface in memory like MemoryStore, but
+// partitions keys across shards by hash, each with its own lock, so that
+// single-key operations on different shards do not contend. Values are encoded
+// and decoded outside the locks.
+//
+// Listings lock every shard to see a consistent view across them, holding the
+// read locks only while collecting entries, so writes do not wait for values
+// to be decoded. Transactions are optimistic and lock only the shards they
+// touch while committing; fn may run more than once when it conflicts with a
+// concurrent write. A scan reads up to its limit from
+// every shard before merging, so small paged scans cost more than with
+// MemoryStore.
+//
+// Revisions are store-wide. Commits take the sequencer lock just long enough
+// to assign revisions, apply their changes and notify watchers, so watchers
+// observe changes in revision order. Storage limits are not supported.
+type ShardedStore struct {
+	shards []*memoryShard
+	codec  Codec
+
+	// seq orders commits across shards and guards revision. It is always
+	// acquired after shard locks.
+	seq      sync.Mutex
+	revision int64
+
+	// watch fans committed changes out to watchers; publishes happen under seq
+	watch *watchHub
+
+	sweepInterval time.Duration
+	expired       atomic.Int64
+	stop          chan struct{}
+	stopOnce      sync.Once
+	sweeperDone   chan struct{}
+}
+
+// Ensure ShardedStore implements domain.Store
+var _ domain.Store = (*ShardedStore)(nil)
+
+// memoryShard holds one partition of a ShardedStore's keys
+type memoryShard struct {
+	mu    sync.RWMutex
+	data  map[string]entry
+	keys  []string // every key in data, in ascending order
+	bytes int64
+}
+
+// NewShardedStore creates a sharded in-memory store and starts the background
+// sweeper that removes expired keys until Close is called
+func NewShardedStore(options ShardedStoreOptions) *ShardedStore {
+	count := options.Shards
+	if count <= 0 {
+		count = DefaultShardCount
+	}
+
+	s := &ShardedStore{
+		shards:        make([]*memoryShard, count),
+		codec:         JSONCodec,
+		watch:         newWatchHub(),
+		sweepInterval: DefaultSweepInterval,
+		stop:          make(chan struct{}),
+		sweeperDone:   make(chan struct{}),
+	}
+	for i := range s.shards {
+		s.shards[i] = &memoryShard{data: make(map[string]entry)}
+	}
+	if options.Codec != nil {
+		s.codec = options.Codec
+	}
+	if options.SweepInterval > 0 {
+		s.sweepInterval = options.SweepInterval
+	}
+
+	go s.runSweeper()
+	return s
+}
+
+// Set stores a value with the given key
+func (s *ShardedStore) Set(ctx context.Context, key string, value any) error {
+	return s.set(ctx, key, value, 0)
+}
+
+// SetWithTTL stores a value that expires after the given duration
+func (s *ShardedStore) SetWithTTL(ctx context.Context, key string, value any, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("ttl must be positive")
+	}
+	return s.set(ctx, key, value, time.Now().Add(ttl).UnixNano())
+}
+
+// set stores a value with an optional expiry time in Unix nanoseconds
+func (s *ShardedStore) set(ctx context.Context, key string, value any, expiresAt int64) error {
+	if key == "" {
+		return fmt.Errorf("key cannot be empty")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := encodeValue(s.codec, value)
+	if err != nil {
+		return err
+	}
+
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	s.commit(mutation{Op: opSet, Key: key, Value: data, ExpiresAt: expiresAt})
+	return nil
+}
+
+// Get retrieves a value by key
+func (s *ShardedStore) Get(ctx context.Context, key string) (value any, err error) {
+	e, err := s.get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var result any
+	if err := decodeValue(e.value, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetTyped retrieves a value by key and unmarshals it into the provided type
+func (s *ShardedStore) GetTyped(ctx context.Context, key string, value any) error {
+	_, err := s.GetTypedVersion(ctx, key, value)
+	return err
+}
+
+// GetTypedVersion retrieves a value and its current version by key
+func (s *ShardedStore) GetTypedVersion(ctx context.Context, key string, value any) (version int64, err error) {
+	e, err := s.get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := decodeValue(e.value, value); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+
+	return e.version, nil
+}
+
+// get returns the entry for a key, holding its shard's read lock only for the lookup
+func (s *ShardedStore) get(ctx context.Context, key string) (entry, error) {
+	if key == "" {
+		return entry{}, fmt.Errorf("key cannot be empty")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return entry{}, err
+	}
+
+	sh := s.shardFor(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	e, exists := sh.lookup(key)
+	if !exists {
+		return entry{}, domain.ErrKeyNotFound
+	}
+	return e, nil
+}
+
+// CompareAndSwap stores a value only if the key's current version equals the
+// given version, where version 0 means the key must not exist yet
+func (s *ShardedStore) CompareAndSwap(ctx context.Context, key string, value any, version int64) (newVersion int64, err error) {
+	if key == "" {
+		return 0, fmt.Errorf("key cannot be empty")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	data, err := encodeValue(s.codec, value)
+	if err != nil {
+		return 0, err
+	}
+
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	var current int64
+	if e, exists := sh.lookup(key); exists {
+		current = e.version
+	}
+	if current != version {
+		return 0, domain.ErrVersionMismatch
+	}
+
+	return s.commit(mutation{Op: opSet, Key: key, Value: data}), nil
+}
+
+// CompareAndDelete removes a key only if its current version equals the given version
+func (s *ShardedStore) CompareAndDelete(ctx context.Context, key string, version int64) error {
+	if key == "" {
+		return fmt.Errorf("key cannot be empty")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	e, exists := sh.lookup(key)
+	if !exists {
+		if version != 0 {
+			return domain.ErrVersionMismatch
+		}
+		return domain.ErrKeyNotFound
+	}
+	if e.version != version {
+		return domain.ErrVersionMismatch
+	}
+
+	s.commit(mutation{Op: opDelete, Key: key})
+	return nil
+}
+
+// Delete removes a value by key
+func (s *ShardedStore) Delete(ctx context.Context, key string) error {
+	if key == "" {
+		return fmt.Errorf("key cannot be empty")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if _, exists := sh.lookup(key); !exists {
+		return domain.ErrKeyNotFound
+	}
+
+	s.commit(mutation{Op: opDelete, Key: key})
+	return nil
+}
+
+// List retrieves all values with keys that start with the given prefix. The
+// matching entries are collected from every shard at once and decoded after
+// the locks are released.
+func (s *ShardedStore) List(ctx context.Context, keyPrefix string) (values []any, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	keys, entries, err := s.collect(ctx, domain.ScanOptions{Prefix: keyPrefix})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]any, 0, len(entries))
+	for i, e := range entries {
+		var value any
+		if err := decodeValue(e.value, &value); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal value for key %s: %w", keys[i], err)
+		}
+		result = append(result, value)
+	}
+
+	return result, nil
+}
+
+// Scan returns keys matching opts in key order, merged across shards
+func (s *ShardedStore) Scan(ctx context.Context, opts domain.ScanOptions) (keys []string, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.rlockAll()
+	defer s.runlockAll()
+
+	return s.scanLocked(ctx, opts)
+}
+
+// Iterate returns an iterator over the entries matching opts, captured from
+// every shard at a single point in time
+func (s *ShardedStore) Iterate(ctx context.Context, opts domain.ScanOptions) (domain.Iterator, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	keys, entries, err := s.collect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &memoryIterator{ctx: ctx, keys: keys, entries: entries, pos: -1}, nil
+}
+
+// collect returns the keys matching opts and their entries, holding every
+// shard's read lock only while copying them
+func (s *ShardedStore) collect(ctx context.Context, opts domain.ScanOptions) ([]string, []entry, error) {
+	s.rlockAll()
+	defer s.runlockAll()
+
+	keys, err := s.scanLocked(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries := make([]entry, len(keys))
+	for i, key := range keys {
+		entries[i] = s.shardFor(key).data[key]
+	}
+	return keys, entries, nil
+}
+
+// scanLocked scans every shard and merges the results in key order. Callers
+// must hold every shard's read lock.
+func (s *ShardedStore) scanLocked(ctx context.Context, opts domain.ScanOptions) ([]string, error) {
+	lists := make([][]string, len(s.shards))
+	for i, sh := range s.shards {
+		keys, err := scanKeys(ctx, sh.keys, sh.data, opts)
+		if err != nil {
+			return nil, err
+		}
+		lists[i] = keys
+	}
+
+	return mergeKeys(lists, opts.Reverse, opts.Limit), nil
+}
+
+// mergeKeys merges per-shard key lists, each sorted in scan order, stopping
+// after limit keys when limit is positive. Keys are unique across shards.
+func mergeKeys(lists [][]string, reverse bool, limit int) []string {
+	var result []string
+	for limit <= 0 || len(result) < limit {
+		next := -1
+		for i, keys := range lists {
+			if len(keys) > 0 && (next < 0 || (keys[0] < lists[next][0]) != reverse) {
+				next = i
+			}
+		}
+		if next < 0 {
+			break
+		}
+
+		result = append(result, lists[next][0])
+		lists[next] = lists[next][1:]
+	}
+	return result
+}
+
+// Update runs fn in an optimistic transaction. fn runs without holding any
+// shard lock: the transaction remembers the version of every key it reads and
+// reserves a version for every write. To commit, Update write-locks only the
+// shards of the keys the transaction touched, in shard order, and applies the
+// buffered writes atomically if none of those keys changed in the meantime.
+// Otherwise fn runs again against the current data, so transactions on
+// unrelated keys commit concurrently and conflicting ones are serialized. If
+// fn returns an error or the context is cancelled, the writes are discarded.
+func (s *ShardedStore) Update(ctx context.Context, fn func(tx domain.Tx) error) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// reads holds the entry each key had when the transaction first read
+		// it, with a zero version for keys that did not exist
+		reads := make(map[string]entry)
+		tx := &memoryTx{
+			ctx:   ctx,
+			codec: s.codec,
+			lookup: func(key string) (entry, bool) {
+				if e, ok := reads[key]; ok {
+					return e, e.version != 0
+				}
+				sh := s.shardFor(key)
+				sh.mu.RLock()
+				e, exists := sh.lookup(key)
+				sh.mu.RUnlock()
+				reads[key] = e
+				return e, exists
+			},
+			writes:  make(map[string]*entry),
+			reserve: s.reserve,
+		}
+
+		err := fn(tx)
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err == nil && len(tx.order) == 0 {
+			return nil
+		}
+
+		// An error is only final if fn saw consistent data; otherwise it may
+		// have been caused by a concurrent commit
+		if s.commitTx(tx, reads, err == nil) {
+			return err
+		}
+	}
+}
+
+// commitTx validates a transaction against the current data and, if apply is
+// set, applies its writes. It returns false if a key the transaction read or
+// writes has changed since, in which case the transaction must be retried.
+func (s *ShardedStore) commitTx(tx *memoryTx, reads map[string]entry, apply bool) bool {
+	touched := make([]string, 0, len(reads)+len(tx.order))
+	for key := range reads {
+		touched = append(touched, key)
+	}
+	touched = append(touched, tx.order...)
+
+	locked := s.lockShards(touched)
+	defer s.unlockShards(locked)
+
+	for key, read := range reads {
+		current, _ := s.shardFor(key).lookup(key)
+		if current.version != read.version {
+			return false
+		}
+	}
+	// Keys written without being read must not have been overwritten by a
+	// later commit
+	for _, key := range tx.order {
+		if _, read := reads[key]; read {
+			continue
+		}
+		if current, exists := s.shardFor(key).lookup(key); exists && current.version >= tx.writes[key].version {
+			return false
+		}
+	}
+
+	if apply {
+		s.commit(tx.mutations()...)
+	}
+	return true
+}
+
+// reserve allocates a version for a transactional write
+func (s *ShardedStore) reserve() int64 {
+	s.seq.Lock()
+	defer s.seq.Unlock()
+
+	s.revision++
+	return s.revision
+}
+
+// ApplyEvents applies the events of a commit replicated from another store
+// atomically, storing put values as they are at the event's version
+func (s *ShardedStore) ApplyEvents(ctx context.Context, events []domain.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	keys := make([]string, len(events))
+	changes := make([]mutation, 0, len(events))
+	for i, ev := range events {
+		keys[i] = ev.Key
+		switch ev.Type {
+		case domain.EventPut:
+			changes = append(changes, mutation{Op: opSet, Key: ev.Key, Value: ev.Value, Version: ev.Version})
+		case domain.EventDelete:
+			changes = append(changes, mutation{Op: opDelete, Key: ev.Key})
+		}
+	}
+
+	locked := s.lockShards(keys)
+	defer s.unlockShards(locked)
+
+	s.commit(changes...)
+	return nil
+}
+
+// Watch streams changes to keys matching opts. Watchers never block writers:
+// each has a bounded buffer, and a watcher whose buffer fills up is cancelled
+// with domain.ErrWatchOverflow so it can resume from its last revision.
+func (s *ShardedStore) Watch(ctx context.Context, opts domain.WatchOptions) (domain.Watcher, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Holding the sequencer keeps commits out while replaying history, so no
+	// event is missed or delivered twice
+	s.seq.Lock()
+	defer s.seq.Unlock()
+
+	if opts.AfterRevision > s.revision {
+		return nil, fmt.Errorf("revision %d is in the future", opts.AfterRevision)
+	}
+
+	w, err := s.watch.subscribe(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Close stops the sweeper and watchers and clears all data
+func (s *ShardedStore) Close() error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.sweeperDone
+
+	s.lockAll()
+	defer s.unlockAll()
+
+	s.watch.closeAll()
+
+	for _, sh := range s.shards {
+		sh.data = make(map[string]entry)
+		sh.keys = nil
+		sh.bytes = 0
+	}
+	return nil
+}
+
+// Size returns the number of items in the store, including expired items
+// that have not been swept yet
+func (s *ShardedStore) Size() int {
+	return s.Stats().Items
+}
+
+// Stats returns size and expiry counters for metrics. Shards are counted one
+// at a time, so the totals are not a single point-in-time view.
+func (s *ShardedStore) Stats() domain.StoreStats {
+	stats := domain.StoreStats{ExpiredKeys: s.expired.Load()}
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		stats.Items += len(sh.data)
+		stats.Bytes += sh.bytes
+		sh.mu.RUnlock()
+	}
+	return stats
+}
+
+// Revision returns the revision of the latest change
+func (s *ShardedStore) Revision() int64 {
+	s.seq.Lock()
+	defer s.seq.Unlock()
+
+	return s.revision
+}
+
+// commit applies changes and notifies watchers, returning the last revision
+// assigned. Every change gets the next revision for its event, so watchers see
+// commits in order; changes without a version are stored at that revision,
+// while transactional and replicated writes keep the version they were given.
+// Callers must hold the write lock of every shard the changes touch.
+func (s *ShardedStore) commit(changes ...mutation) int64 {
+	s.seq.Lock()
+	defer s.seq.Unlock()
+
+	events := make([]domain.Event, 0, len(changes))
+	for _, m := range changes {
+		s.revision = max(s.revision+1, m.Version)
+		if m.Version == 0 {
+			m.Version = s.revision
+		}
+
+		sh := s.shardFor(m.Key)
+		switch m.Op {
+		case opSet:
+			ev := domain.Event{Type: domain.EventPut, Key: m.Key, Value: m.Value, Revision: s.revision, Version: m.Version}
+			if prev, exists := sh.lookup(m.Key); exists {
+				ev.PrevValue = prev.value
+			}
+			events = append(events, ev)
+		case opDelete:
+			if prev, exists := sh.data[m.Key]; exists {
+				events = append(events, domain.Event{Type: domain.EventDelete, Key: m.Key, PrevValue: prev.value, Revision: s.revision})
+			}
+		}
+		sh.apply(m)
+	}
+
+	s.watch.publish(events)
+	return s.revision
+}
+
+// shardFor returns the shard a key belongs to
+func (s *ShardedStore) shardFor(key string) *memoryShard {
+	return s.shards[s.shardIndex(key)]
+}
+
+// shardIndex returns the index of a key's shard, using FNV-1a to spread keys
+func (s *ShardedStore) shardIndex(key string) int {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return int(h % uint32(len(s.shards)))
+}
+
+// lockShards write-locks the shards of the given keys in shard order, the same
+// order lockAll uses, and returns their indexes
+func (s *ShardedStore) lockShards(keys []string) []int {
+	indexes := make([]int, 0, len(keys))
+	for _, key := range keys {
+		indexes = append(indexes, s.shardIndex(key))
+	}
+	slices.Sort(indexes)
+	indexes = slices.Compact(indexes)
+
+	for _, i := range indexes {
+		s.shards[i].mu.Lock()
+	}
+	return indexes
+}
+
+// unlockShards releases the locks taken by lockShards
+func (s *ShardedStore) unlockShards(indexes []int) {
+	for i := len(indexes) - 1; i >= 0; i-- {
+		s.shards[indexes[i]].mu.Unlock()
+	}
+}
+
+// lockAll write-locks every shard in order
+func (s *ShardedStore) lockAll() {
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+	}
+}
+
+// unlockAll releases the locks taken by lockAll
+func (s *ShardedStore) unlockAll() {
+	for i := len(s.shards) - 1; i >= 0; i-- {
+		s.shards[i].mu.Unlock()
+	}
+}
+
+// rlockAll read-locks every shard in order
+func (s *ShardedStore) rlockAll() {
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+	}
+}
+
+// runlockAll releases the locks taken by rlockAll
+func (s *ShardedStore) runlockAll() {
+	for i := len(s.shards) - 1; i >= 0; i-- {
+		s.shards[i].mu.RUnlock()
+	}
+}
+
+// runSweeper periodically removes expired keys until the store is closed
+func (s *ShardedStore) runSweeper() {
+	defer close(s.sweeperDone)
+
+	ticker := time.NewTicker(s.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.sweep()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// sweep removes expired keys one shard at a time, so only one shard is
+// locked at once
+func (s *ShardedStore) sweep() {
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+
+		now := time.Now().UnixNano()
+		var batch []mutation
+		for _, key := range sh.keys {
+			if sh.data[key].expired(now) {
+				batch = append(batch, mutation{Op: opDelete, Key: key})
+			}
+		}
+
+		if len(batch) > 0 {
+			s.commit(batch...)
+			s.expired.Add(int64(len(batch)))
+		}
+
+		sh.mu.Unlock()
+	}
+}
+
+// lookup returns the entry for a key unless it is missing or expired.
+// Callers must hold the shard's read lock.
+func (sh *memoryShard) lookup(key string) (entry, bool) {
+	e, exists := sh.data[key]
+	if !exists || e.expired(time.Now().UnixNano()) {
+		return entry{}, false
+	}
+	return e, true
+}
+
+// apply applies a set or delete to the shard. Callers must hold the shard's
+// write lock.
+func (sh *memoryShard) apply(m mutation) {
+	switch m.Op {
+	case opSet:
+		if prev, exists := sh.data[m.Key]; exists {
+			sh.bytes -= entrySize(m.Key, prev.value)
+		} else {
+			sh.keys = insertKey(sh.keys, m.Key)
+		}
+		sh.data[m.Key] = entry{value: m.Value, version: m.Version, expiresAt: m.ExpiresAt}
+		sh.bytes += entrySize(m.Key, m.Value)
+	case opDelete:
+		if prev, exists := sh.data[m.Key]; exists {
+			sh.keys = removeKey(sh.keys, m.Key)
+			sh.bytes -= entrySize(m.Key, prev.value)
+		}
+		delete(sh.data, m.Key)
+	}
+}
\ No newline at end of file
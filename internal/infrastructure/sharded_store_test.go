@@ -0,0 +1,321 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+func TestShardedStore_BasicOperations(t *testing.T) {
+	ctx := context.Background()
+	store := NewShardedStore(ShardedStoreOptions{Shards: 4})
+	defer store.Close()
+
+	if err := store.Set(ctx, "key1", "value1"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	value, err := store.Get(ctx, "key1")
+	if err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	if value != "value1" {
+		t.Errorf("Expected 'value1', got %v", value)
+	}
+
+	if err := store.Delete(ctx, "key1"); err != nil {
+		t.Fatalf("Failed to delete value: %v", err)
+	}
+	if _, err := store.Get(ctx, "key1"); err != domain.ErrKeyNotFound {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+	if err := store.Delete(ctx, "key1"); err != domain.ErrKeyNotFound {
+		t.Errorf("Expected ErrKeyNotFound deleting missing key, got %v", err)
+	}
+}
+
+func TestShardedStore_CompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	store := NewShardedStore(ShardedStoreOptions{})
+	defer store.Close()
+
+	version, err := store.CompareAndSwap(ctx, "key1", "value1", 0)
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	if _, err := store.CompareAndSwap(ctx, "key1", "value2", 0); err != domain.ErrVersionMismatch {
+		t.Errorf("Expected ErrVersionMismatch, got %v", err)
+	}
+
+	newVersion, err := store.CompareAndSwap(ctx, "key1", "value2", version)
+	if err != nil {
+		t.Fatalf("Failed to swap: %v", err)
+	}
+	if newVersion <= version {
+		t.Errorf("Expected version to increase, got %d after %d", newVersion, version)
+	}
+
+	if err := store.CompareAndDelete(ctx, "key1", version); err != domain.ErrVersionMismatch {
+		t.Errorf("Expected ErrVersionMismatch, got %v", err)
+	}
+	if err := store.CompareAndDelete(ctx, "key1", newVersion); err != nil {
+		t.Errorf("Failed to delete: %v", err)
+	}
+}
+
+func TestShardedStore_Scan(t *testing.T) {
+	ctx := context.Background()
+	store := NewShardedStore(ShardedStoreOptions{Shards: 8})
+	defer store.Close()
+
+	for i := 0; i < 20; i++ {
+		store.Set(ctx, fmt.Sprintf("posts:%02d", i), i)
+	}
+	store.Set(ctx, "users:1", "alice")
+
+	testCases := []struct {
+		name     string
+		opts     domain.ScanOptions
+		expected []string
+	}{
+		{"limit", domain.ScanOptions{Prefix: "posts:", Limit: 3}, []string{"posts:00", "posts:01", "posts:02"}},
+		{"start after", domain.ScanOptions{Prefix: "posts:", StartAfter: "posts:17"}, []string{"posts:18", "posts:19"}},
+		{"reverse", domain.ScanOptions{Prefix: "posts:", Reverse: true, Limit: 2}, []string{"posts:19", "posts:18"}},
+		{"reverse start after", domain.ScanOptions{Prefix: "posts:", Reverse: true, StartAfter: "posts:02"}, []string{"posts:01", "posts:00"}},
+		{"other prefix", domain.ScanOptions{Prefix: "users:"}, []string{"users:1"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			keys, err := store.Scan(ctx, tc.opts)
+			if err != nil {
+				t.Fatalf("Failed to scan: %v", err)
+			}
+			if fmt.Sprint(keys) != fmt.Sprint(tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, keys)
+			}
+		})
+	}
+
+	values, err := store.List(ctx, "posts:")
+	if err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+	if len(values) != 20 {
+		t.Errorf("Expected 20 values, got %d", len(values))
+	}
+}
+
+func TestShardedStore_Update(t *testing.T) {
+	ctx := context.Background()
+	store := NewShardedStore(ShardedStoreOptions{Shards: 4})
+	defer store.Close()
+
+	store.Set(ctx, "a", "1")
+
+	// Writes across shards are applied together
+	err := store.Update(ctx, func(tx domain.Tx) error {
+		if err := tx.Delete("a"); err != nil {
+			return err
+		}
+		for i := 0; i < 10; i++ {
+			if err := tx.Set(fmt.Sprintf("key%d", i), i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+	if store.Size() != 10 {
+		t.Errorf("Expected 10 items, got %d", store.Size())
+	}
+
+	// A failed transaction leaves every shard unchanged
+	err = store.Update(ctx, func(tx domain.Tx) error {
+		tx.Set("key0", "changed")
+		tx.Set("extra", "value")
+		return fmt.Errorf("abort")
+	})
+	if err == nil {
+		t.Fatal("Expected transaction error")
+	}
+	var value int
+	if err := store.GetTyped(ctx, "key0", &value); err != nil || value != 0 {
+		t.Errorf("Expected key0 to be unchanged, got %v (%v)", value, err)
+	}
+	if _, err := store.Get(ctx, "extra"); err != domain.ErrKeyNotFound {
+		t.Errorf("Expected ErrKeyNotFound for discarded write, got %v", err)
+	}
+}
+
+func TestShardedStore_UpdateConflict(t *testing.T) {
+	ctx := context.Background()
+	store := NewShardedStore(ShardedStoreOptions{Shards: 4})
+	defer store.Close()
+
+	store.Set(ctx, "counter", 1)
+
+	// A key read by the transaction changes before it commits
+	attempts := 0
+	err := store.Update(ctx, func(tx domain.Tx) error {
+		attempts++
+		var n int
+		if err := tx.GetTyped("counter", &n); err != nil {
+			return err
+		}
+		if attempts == 1 {
+			store.Set(ctx, "counter", 10)
+		}
+		return tx.Set("counter", n+1)
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+	var n int
+	store.GetTyped(ctx, "counter", &n)
+	if attempts != 2 || n != 11 {
+		t.Errorf("Expected a retry ending at 11, got %d attempts and %d", attempts, n)
+	}
+
+	// A blind write is overtaken by a later write
+	attempts = 0
+	err = store.Update(ctx, func(tx domain.Tx) error {
+		attempts++
+		if err := tx.Set("blind", "tx"); err != nil {
+			return err
+		}
+		if attempts == 1 {
+			store.Set(ctx, "blind", "outside")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+	var value string
+	store.GetTyped(ctx, "blind", &value)
+	if attempts != 2 || value != "tx" {
+		t.Errorf("Expected a retry ending at 'tx', got %d attempts and %q", attempts, value)
+	}
+
+	// An error caused by a stale read is retried rather than returned
+	attempts = 0
+	err = store.Update(ctx, func(tx domain.Tx) error {
+		attempts++
+		if attempts == 1 {
+			defer store.Set(ctx, "flag", true)
+		}
+		_, err := tx.Get("flag")
+		return err
+	})
+	if err != nil || attempts != 2 {
+		t.Errorf("Expected the stale error to be retried, got %d attempts and %v", attempts, err)
+	}
+
+	// Versions seen inside the transaction are the ones committed
+	var predicted int64
+	err = store.Update(ctx, func(tx domain.Tx) error {
+		if err := tx.Set("versioned", "value"); err != nil {
+			return err
+		}
+		var err error
+		predicted, err = tx.Version("versioned")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+	if version, _ := store.GetTypedVersion(ctx, "versioned", &value); version != predicted {
+		t.Errorf("Expected version %d, got %d", predicted, version)
+	}
+}
+
+func TestShardedStore_TTL(t *testing.T) {
+	ctx := context.Background()
+	store := NewShardedStore(ShardedStoreOptions{SweepInterval: 10 * time.Millisecond})
+	defer store.Close()
+
+	store.SetWithTTL(ctx, "key1", "value1", time.Millisecond)
+	store.Set(ctx, "key2", "value2")
+
+	deadline := time.Now().Add(time.Second)
+	for store.Size() != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if _, err := store.Get(ctx, "key1"); err != domain.ErrKeyNotFound {
+		t.Errorf("Expected expired key to be gone, got %v", err)
+	}
+	if stats := store.Stats(); stats.ExpiredKeys != 1 || stats.Items != 1 {
+		t.Errorf("Expected 1 expired key and 1 item, got %+v", stats)
+	}
+}
+
+func TestShardedStore_WatchOrder(t *testing.T) {
+	ctx := context.Background()
+	store := NewShardedStore(ShardedStoreOptions{Shards: 8})
+	defer store.Close()
+
+	w, err := store.Watch(ctx, domain.WatchOptions{})
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+	defer w.Close()
+
+	const writers, writes = 4, 25
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < writes; j++ {
+				store.Set(ctx, fmt.Sprintf("key%d-%d", i, j), j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var last int64
+	for n := 0; n < writers*writes; n++ {
+		ev := <-w.Events()
+		if ev.Revision <= last {
+			t.Fatalf("Expected increasing revisions, got %d after %d", ev.Revision, last)
+		}
+		last = ev.Revision
+	}
+}
+
+func TestShardedStore_ConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	store := NewShardedStore(ShardedStoreOptions{Shards: 4})
+	defer store.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				key := fmt.Sprintf("key%d", j%10)
+				store.Set(ctx, key, i)
+				store.Get(ctx, key)
+				store.Scan(ctx, domain.ScanOptions{Limit: 5})
+				store.Update(ctx, func(tx domain.Tx) error {
+					return tx.Set(key, j)
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if store.Size() != 10 {
+		t.Errorf("Expected 10 items, got %d", store.Size())
+	}
+}
\ No newline at end of file
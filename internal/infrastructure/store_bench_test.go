@@ -0,0 +1,160 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// benchmarkStores lists the in-memory implementations compared by the benchmarks
+var benchmarkStores = []struct {
+	name string
+	new  func() domain.Store
+}{
+	{"memory", func() domain.Store { return NewMemoryStore() }},
+	{"sharded", func() domain.Store { return NewShardedStore(ShardedStoreOptions{}) }},
+}
+
+// benchmarkValue is a small post-like value
+type benchmarkValue struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// populate stores n values under keys "bench:00000000" onwards
+func populate(b *testing.B, store domain.Store, n int) {
+	b.Helper()
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("bench:%08d", i)
+		if err := store.Set(ctx, key, benchmarkValue{ID: key, Title: "title", Content: "content"}); err != nil {
+			b.Fatalf("Failed to populate store: %v", err)
+		}
+	}
+}
+
+func BenchmarkStore_ParallelSet(b *testing.B) {
+	for _, bs := range benchmarkStores {
+		b.Run(bs.name, func(b *testing.B) {
+			ctx := context.Background()
+			store := bs.new()
+			defer store.Close()
+
+			var n atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					key := fmt.Sprintf("bench:%08d", n.Add(1)%10000)
+					store.Set(ctx, key, benchmarkValue{ID: key, Title: "title", Content: "content"})
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkStore_ParallelGet(b *testing.B) {
+	for _, bs := range benchmarkStores {
+		b.Run(bs.name, func(b *testing.B) {
+			ctx := context.Background()
+			store := bs.new()
+			defer store.Close()
+			populate(b, store, 10000)
+
+			var n atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				var value benchmarkValue
+				for pb.Next() {
+					store.GetTyped(ctx, fmt.Sprintf("bench:%08d", n.Add(1)%10000), &value)
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkStore_ParallelUpdate measures read-modify-write transactions that
+// each update a value and a second key, like an entity and its index entry
+func BenchmarkStore_ParallelUpdate(b *testing.B) {
+	for _, bs := range benchmarkStores {
+		b.Run(bs.name, func(b *testing.B) {
+			ctx := context.Background()
+			store := bs.new()
+			defer store.Close()
+			populate(b, store, 10000)
+
+			var n atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					i := n.Add(1) % 10000
+					key := fmt.Sprintf("bench:%08d", i)
+					err := store.Update(ctx, func(tx domain.Tx) error {
+						var value benchmarkValue
+						if err := tx.GetTyped(key, &value); err != nil {
+							return err
+						}
+						value.Title += "!"
+						if len(value.Title) > 64 {
+							value.Title = "title"
+						}
+						if err := tx.Set(key, value); err != nil {
+							return err
+						}
+						return tx.Set(fmt.Sprintf("index:%08d", i), key)
+					})
+					if err != nil {
+						b.Errorf("Update failed: %v", err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkStore_SetDuringList measures writes while other goroutines keep
+// listing the whole store, the workload where a single lock stalls writers
+func BenchmarkStore_SetDuringList(b *testing.B) {
+	for _, bs := range benchmarkStores {
+		b.Run(bs.name, func(b *testing.B) {
+			ctx := context.Background()
+			store := bs.new()
+			defer store.Close()
+			populate(b, store, 2000)
+
+			var n atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					// One operation in eight is a full listing
+					if i%8 == 0 {
+						store.List(ctx, "bench:")
+						continue
+					}
+					key := fmt.Sprintf("bench:%08d", n.Add(1)%2000)
+					store.Set(ctx, key, benchmarkValue{ID: key, Title: "title", Content: "content"})
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkStore_Scan(b *testing.B) {
+	for _, bs := range benchmarkStores {
+		b.Run(bs.name, func(b *testing.B) {
+			ctx := context.Background()
+			store := bs.new()
+			defer store.Close()
+			populate(b, store, 10000)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				store.Scan(ctx, domain.ScanOptions{Prefix: "bench:", StartAfter: "bench:00005000", Limit: 20})
+			}
+		})
+	}
+}
\ No newline at end of file
@@ -0,0 +1,70 @@
+package infrastructure
+
+import (
+	"fmt"
+
+	"gosuda.org/boilerplate/internal/config"
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// NewStore creates the Store implementation selected by the storage
+// configuration, encrypting values if an encryption key is configured
+func NewStore(cfg *config.StorageConfig) (domain.Store, error) {
+	codec, err := CodecByName(cfg.Codec)
+	if err != nil {
+		return nil, err
+	}
+
+	keyring, err := LoadKeyring(&cfg.Encryption)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := newBackend(cfg, codec)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyring != nil {
+		return NewEncryptedStore(store, keyring, EncryptedStoreOptions{
+			Codec:          codec,
+			AllowPlaintext: cfg.Encryption.AllowPlaintext,
+		}), nil
+	}
+	return store, nil
+}
+
+// newBackend creates the storage backend selected by the configuration
+func newBackend(cfg *config.StorageConfig, codec Codec) (domain.Store, error) {
+	limits := StoreLimits{
+		MaxItems: cfg.Limits.MaxItems,
+		MaxBytes: cfg.Limits.MaxBytes,
+		Policy:   EvictionPolicy(cfg.Limits.Policy),
+		Prefixes: make(map[string]EvictionPolicy, len(cfg.Limits.Prefixes)),
+	}
+	for prefix, policy := range cfg.Limits.Prefixes {
+		limits.Prefixes[prefix] = EvictionPolicy(policy)
+	}
+
+	switch cfg.Type {
+	case "memory":
+		return NewMemoryStore(WithCodec(codec), WithSweepInterval(cfg.SweepInterval), WithLimits(limits)), nil
+	case "sharded":
+		return NewShardedStore(ShardedStoreOptions{
+			Shards:        cfg.Shards,
+			Codec:         codec,
+			SweepInterval: cfg.SweepInterval,
+		}), nil
+	case "file":
+		return NewFileStore(cfg.Path, FileStoreOptions{
+			Fsync:            cfg.Fsync,
+			Codec:            codec,
+			FsyncInterval:    cfg.FsyncInterval,
+			SnapshotInterval: cfg.SnapshotInterval,
+			SweepInterval:    cfg.SweepInterval,
+			Limits:           limits,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported storage type: %s", cfg.Type)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,733 @@
+// Package storetest provides a conformance suite for domain.Store
+// implementations. Every backend and decorator should pass it:
+//
+//	func TestMyStore_Conformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) domain.Store {
+//			return NewMyStore(...)
+//		})
+//	}
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// Factory creates an empty store for a single test. The suite closes the
+// store when the test ends.
+type Factory func(t *testing.T) domain.Store
+
+// Run runs the conformance suite against stores created by newStore, each
+// behavior in its own subtest
+func Run(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store domain.Store)
+	}{
+		{"SetGet", testSetGet},
+		{"NotFound", testNotFound},
+		{"EmptyKey", testEmptyKey},
+		{"Versions", testVersions},
+		{"CompareAndSwap", testCompareAndSwap},
+		{"PrefixListing", testPrefixListing},
+		{"Scan", testScan},
+		{"Iterate", testIterate},
+		{"TTL", testTTL},
+		{"Update", testUpdate},
+		{"UpdateRollback", testUpdateRollback},
+		{"Watch", testWatch},
+		{"ContextCancellation", testContextCancellation},
+		{"ConcurrentAccess", testConcurrentAccess},
+		{"ConcurrentUpdates", testConcurrentUpdates},
+		{"Close", testClose},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newStore(t)
+			t.Cleanup(func() { store.Close() })
+			tt.fn(t, store)
+		})
+	}
+}
+
+// record is a structured value stored by the suite
+type record struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+func testSetGet(t *testing.T, store domain.Store) {
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "key1", "value1"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	value, err := store.Get(ctx, "key1")
+	if err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	if value != "value1" {
+		t.Errorf("Expected 'value1', got %v", value)
+	}
+
+	want := record{Name: "test", Tags: []string{"a", "b"}}
+	if err := store.Set(ctx, "key2", want); err != nil {
+		t.Fatalf("Failed to set record: %v", err)
+	}
+
+	var got record
+	if err := store.GetTyped(ctx, "key2", &got); err != nil {
+		t.Fatalf("Failed to get record: %v", err)
+	}
+	if got.Name != want.Name || len(got.Tags) != 2 || got.Tags[1] != "b" {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	// Overwriting replaces the value
+	if err := store.Set(ctx, "key1", "value2"); err != nil {
+		t.Fatalf("Failed to overwrite value: %v", err)
+	}
+	var s string
+	if err := store.GetTyped(ctx, "key1", &s); err != nil || s != "value2" {
+		t.Errorf("Expected 'value2', got %q (%v)", s, err)
+	}
+
+	if err := store.Delete(ctx, "key1"); err != nil {
+		t.Fatalf("Failed to delete value: %v", err)
+	}
+	if _, err := store.Get(ctx, "key1"); err != domain.ErrKeyNotFound {
+		t.Errorf("Expected ErrKeyNotFound after delete, got %v", err)
+	}
+}
+
+func testNotFound(t *testing.T, store domain.Store) {
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx, "missing"); err != domain.ErrKeyNotFound {
+		t.Errorf("Get: expected ErrKeyNotFound, got %v", err)
+	}
+
+	var value string
+	if err := store.GetTyped(ctx, "missing", &value); err != domain.ErrKeyNotFound {
+		t.Errorf("GetTyped: expected ErrKeyNotFound, got %v", err)
+	}
+	if _, err := store.GetTypedVersion(ctx, "missing", &value); err != domain.ErrKeyNotFound {
+		t.Errorf("GetTypedVersion: expected ErrKeyNotFound, got %v", err)
+	}
+	if err := store.Delete(ctx, "missing"); err != domain.ErrKeyNotFound {
+		t.Errorf("Delete: expected ErrKeyNotFound, got %v", err)
+	}
+
+	// A missing key is not at any version
+	if err := store.CompareAndDelete(ctx, "missing", 1); err != domain.ErrVersionMismatch {
+		t.Errorf("CompareAndDelete: expected ErrVersionMismatch, got %v", err)
+	}
+	if _, err := store.CompareAndSwap(ctx, "missing", "value", 1); err != domain.ErrVersionMismatch {
+		t.Errorf("CompareAndSwap: expected ErrVersionMismatch, got %v", err)
+	}
+
+	err := store.Update(ctx, func(tx domain.Tx) error {
+		if _, err := tx.Get("missing"); err != domain.ErrKeyNotFound {
+			t.Errorf("Tx.Get: expected ErrKeyNotFound, got %v", err)
+		}
+		if err := tx.GetTyped("missing", &value); err != domain.ErrKeyNotFound {
+			t.Errorf("Tx.GetTyped: expected ErrKeyNotFound, got %v", err)
+		}
+		if _, err := tx.Version("missing"); err != domain.ErrKeyNotFound {
+			t.Errorf("Tx.Version: expected ErrKeyNotFound, got %v", err)
+		}
+		if err := tx.Delete("missing"); err != domain.ErrKeyNotFound {
+			t.Errorf("Tx.Delete: expected ErrKeyNotFound, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Failed to run transaction: %v", err)
+	}
+}
+
+func testEmptyKey(t *testing.T, store domain.Store) {
+	ctx := context.Background()
+	var value string
+
+	if err := store.Set(ctx, "", "value"); err == nil {
+		t.Error("Set: expected error for empty key")
+	}
+	if err := store.SetWithTTL(ctx, "", "value", time.Minute); err == nil {
+		t.Error("SetWithTTL: expected error for empty key")
+	}
+	if _, err := store.Get(ctx, ""); err == nil || err == domain.ErrKeyNotFound {
+		t.Errorf("Get: expected empty key error, got %v", err)
+	}
+	if err := store.GetTyped(ctx, "", &value); err == nil || err == domain.ErrKeyNotFound {
+		t.Errorf("GetTyped: expected empty key error, got %v", err)
+	}
+	if _, err := store.GetTypedVersion(ctx, "", &value); err == nil || err == domain.ErrKeyNotFound {
+		t.Errorf("GetTypedVersion: expected empty key error, got %v", err)
+	}
+	if _, err := store.CompareAndSwap(ctx, "", "value", 0); err == nil {
+		t.Error("CompareAndSwap: expected error for empty key")
+	}
+	if err := store.Delete(ctx, ""); err == nil || err == domain.ErrKeyNotFound {
+		t.Errorf("Delete: expected empty key error, got %v", err)
+	}
+	if err := store.CompareAndDelete(ctx, "", 1); err == nil || err == domain.ErrKeyNotFound {
+		t.Errorf("CompareAndDelete: expected empty key error, got %v", err)
+	}
+
+	err := store.Update(ctx, func(tx domain.Tx) error {
+		return tx.Set("", "value")
+	})
+	if err == nil {
+		t.Error("Tx.Set: expected error for empty key")
+	}
+
+	// Nothing was stored under the empty key
+	keys, err := store.Scan(ctx, domain.ScanOptions{})
+	if err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("Expected no keys, got %v", keys)
+	}
+}
+
+func testVersions(t *testing.T, store domain.Store) {
+	ctx := context.Background()
+
+	var last int64
+	for i := 0; i < 3; i++ {
+		if err := store.Set(ctx, "key", i); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+
+		var value int
+		version, err := store.GetTypedVersion(ctx, "key", &value)
+		if err != nil {
+			t.Fatalf("Failed to get version: %v", err)
+		}
+		if value != i {
+			t.Errorf("Expected value %d, got %d", i, value)
+		}
+		if version <= last {
+			t.Errorf("Expected version to increase past %d, got %d", last, version)
+		}
+		last = version
+	}
+
+	// Versions keep increasing across a delete and re-create
+	if err := store.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Failed to delete value: %v", err)
+	}
+	if err := store.Set(ctx, "key", 0); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	var value int
+	if version, err := store.GetTypedVersion(ctx, "key", &value); err != nil || version <= last {
+		t.Errorf("Expected version after %d, got %d (%v)", last, version, err)
+	}
+}
+
+func testCompareAndSwap(t *testing.T, store domain.Store) {
+	ctx := context.Background()
+
+	v1, err := store.CompareAndSwap(ctx, "key", "a", 0)
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if _, err := store.CompareAndSwap(ctx, "key", "b", 0); err != domain.ErrVersionMismatch {
+		t.Errorf("Expected ErrVersionMismatch creating an existing key, got %v", err)
+	}
+
+	v2, err := store.CompareAndSwap(ctx, "key", "b", v1)
+	if err != nil {
+		t.Fatalf("Failed to swap value: %v", err)
+	}
+	if v2 <= v1 {
+		t.Errorf("Expected new version after %d, got %d", v1, v2)
+	}
+	if _, err := store.CompareAndSwap(ctx, "key", "c", v1); err != domain.ErrVersionMismatch {
+		t.Errorf("Expected ErrVersionMismatch swapping a stale version, got %v", err)
+	}
+
+	var value string
+	if err := store.GetTyped(ctx, "key", &value); err != nil || value != "b" {
+		t.Errorf("Expected 'b', got %q (%v)", value, err)
+	}
+
+	if err := store.CompareAndDelete(ctx, "key", v1); err != domain.ErrVersionMismatch {
+		t.Errorf("Expected ErrVersionMismatch deleting a stale version, got %v", err)
+	}
+	if err := store.CompareAndDelete(ctx, "key", v2); err != nil {
+		t.Errorf("Failed to delete current version: %v", err)
+	}
+	if _, err := store.Get(ctx, "key"); err != domain.ErrKeyNotFound {
+		t.Errorf("Expected ErrKeyNotFound after delete, got %v", err)
+	}
+}
+
+// seed stores each key with its own name as value
+func seed(t *testing.T, store domain.Store, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		if err := store.Set(context.Background(), key, key); err != nil {
+			t.Fatalf("Failed to set %s: %v", key, err)
+		}
+	}
+}
+
+// equal reports whether two key lists are identical
+func equal(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func testPrefixListing(t *testing.T, store domain.Store) {
+	ctx := context.Background()
+	seed(t, store, "user:1", "user:2", "post:1", "post:2", "post:3", "posts")
+
+	tests := []struct {
+		prefix string
+		want   int
+	}{
+		{"user:", 2},
+		{"post:", 3},
+		{"post", 4},
+		{"missing:", 0},
+		{"", 6},
+	}
+
+	for _, tt := range tests {
+		values, err := store.List(ctx, tt.prefix)
+		if err != nil {
+			t.Errorf("Failed to list %q: %v", tt.prefix, err)
+			continue
+		}
+		if len(values) != tt.want {
+			t.Errorf("Expected %d values with prefix %q, got %d", tt.want, tt.prefix, len(values))
+		}
+		for _, value := range values {
+			if s, ok := value.(string); !ok || len(s) < len(tt.prefix) || s[:len(tt.prefix)] != tt.prefix {
+				t.Errorf("Expected values with prefix %q, got %v", tt.prefix, value)
+			}
+		}
+	}
+}
+
+func testScan(t *testing.T, store domain.Store) {
+	ctx := context.Background()
+	seed(t, store, "b:2", "a:1", "b:1", "b:3", "c:1")
+
+	tests := []struct {
+		name string
+		opts domain.ScanOptions
+		want []string
+	}{
+		{"all", domain.ScanOptions{}, []string{"a:1", "b:1", "b:2", "b:3", "c:1"}},
+		{"prefix", domain.ScanOptions{Prefix: "b:"}, []string{"b:1", "b:2", "b:3"}},
+		{"start after", domain.ScanOptions{Prefix: "b:", StartAfter: "b:1"}, []string{"b:2", "b:3"}},
+		{"limit", domain.ScanOptions{Limit: 2}, []string{"a:1", "b:1"}},
+		{"reverse", domain.ScanOptions{Prefix: "b:", Reverse: true}, []string{"b:3", "b:2", "b:1"}},
+		{"reverse start after", domain.ScanOptions{Prefix: "b:", StartAfter: "b:3", Reverse: true, Limit: 1}, []string{"b:2"}},
+		{"missing prefix", domain.ScanOptions{Prefix: "d:"}, nil},
+	}
+
+	for _, tt := range tests {
+		keys, err := store.Scan(ctx, tt.opts)
+		if err != nil {
+			t.Errorf("%s: failed to scan: %v", tt.name, err)
+			continue
+		}
+		if !equal(keys, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, keys)
+		}
+	}
+}
+
+func testIterate(t *testing.T, store domain.Store) {
+	ctx := context.Background()
+	seed(t, store, "b:2", "a:1", "b:1")
+
+	it, err := store.Iterate(ctx, domain.ScanOptions{Prefix: "b:"})
+	if err != nil {
+		t.Fatalf("Failed to iterate: %v", err)
+	}
+	defer it.Close()
+
+	var keys []string
+	for it.Next() {
+		var value string
+		if err := it.Decode(&value); err != nil {
+			t.Fatalf("Failed to decode %s: %v", it.Key(), err)
+		}
+		if value != it.Key() {
+			t.Errorf("Expected value %q, got %q", it.Key(), value)
+		}
+		if it.Version() <= 0 {
+			t.Errorf("Expected a positive version for %s, got %d", it.Key(), it.Version())
+		}
+		keys = append(keys, it.Key())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Iteration failed: %v", err)
+	}
+
+	if want := []string{"b:1", "b:2"}; !equal(keys, want) {
+		t.Errorf("Expected %v, got %v", want, keys)
+	}
+
+	// Raw values can be written back unchanged
+	it2, err := store.Iterate(ctx, domain.ScanOptions{Prefix: "a:"})
+	if err != nil {
+		t.Fatalf("Failed to iterate: %v", err)
+	}
+	if !it2.Next() {
+		t.Fatalf("Expected an entry, got none (%v)", it2.Err())
+	}
+	raw := domain.RawValue(append([]byte(nil), it2.Value()...))
+	it2.Close()
+
+	if err := store.Delete(ctx, "a:1"); err != nil {
+		t.Fatalf("Failed to delete value: %v", err)
+	}
+	if err := store.Set(ctx, "a:1", raw); err != nil {
+		t.Fatalf("Failed to set raw value: %v", err)
+	}
+	var value string
+	if err := store.GetTyped(ctx, "a:1", &value); err != nil || value != "a:1" {
+		t.Errorf("Expected restored value 'a:1', got %q (%v)", value, err)
+	}
+}
+
+func testTTL(t *testing.T, store domain.Store) {
+	ctx := context.Background()
+
+	if err := store.SetWithTTL(ctx, "short", "value", 50*time.Millisecond); err != nil {
+		t.Fatalf("Failed to set value with TTL: %v", err)
+	}
+	if err := store.SetWithTTL(ctx, "long", "value", time.Hour); err != nil {
+		t.Fatalf("Failed to set value with TTL: %v", err)
+	}
+	if err := store.SetWithTTL(ctx, "invalid", "value", 0); err == nil {
+		t.Error("Expected error for a non-positive TTL")
+	}
+
+	if _, err := store.Get(ctx, "short"); err != nil {
+		t.Errorf("Expected value before it expires, got %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := store.Get(ctx, "short"); err != domain.ErrKeyNotFound {
+		t.Errorf("Expected ErrKeyNotFound after expiry, got %v", err)
+	}
+
+	keys, err := store.Scan(ctx, domain.ScanOptions{})
+	if err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+	if want := []string{"long"}; !equal(keys, want) {
+		t.Errorf("Expected %v, got %v", want, keys)
+	}
+}
+
+func testUpdate(t *testing.T, store domain.Store) {
+	ctx := context.Background()
+	seed(t, store, "a", "b")
+
+	err := store.Update(ctx, func(tx domain.Tx) error {
+		if err := tx.Set("a", "updated"); err != nil {
+			return err
+		}
+
+		// Reads observe earlier writes in the transaction
+		var value string
+		if err := tx.GetTyped("a", &value); err != nil || value != "updated" {
+			t.Errorf("Expected 'updated' inside the transaction, got %q (%v)", value, err)
+		}
+
+		if err := tx.Delete("b"); err != nil {
+			return err
+		}
+		if _, err := tx.Get("b"); err != domain.ErrKeyNotFound {
+			t.Errorf("Expected ErrKeyNotFound for a key deleted in the transaction, got %v", err)
+		}
+
+		return tx.Set("c", "created")
+	})
+	if err != nil {
+		t.Fatalf("Failed to run transaction: %v", err)
+	}
+
+	var value string
+	if err := store.GetTyped(ctx, "a", &value); err != nil || value != "updated" {
+		t.Errorf("Expected 'updated', got %q (%v)", value, err)
+	}
+	if _, err := store.Get(ctx, "b"); err != domain.ErrKeyNotFound {
+		t.Errorf("Expected ErrKeyNotFound for b, got %v", err)
+	}
+	if err := store.GetTyped(ctx, "c", &value); err != nil || value != "created" {
+		t.Errorf("Expected 'created', got %q (%v)", value, err)
+	}
+}
+
+func testUpdateRollback(t *testing.T, store domain.Store) {
+	ctx := context.Background()
+	seed(t, store, "a")
+
+	errAbort := errors.New("abort")
+	err := store.Update(ctx, func(tx domain.Tx) error {
+		tx.Set("a", "changed")
+		tx.Set("b", "created")
+		return errAbort
+	})
+	if err != errAbort {
+		t.Errorf("Expected the transaction's error, got %v", err)
+	}
+
+	var value string
+	if err := store.GetTyped(ctx, "a", &value); err != nil || value != "a" {
+		t.Errorf("Expected 'a' to be unchanged, got %q (%v)", value, err)
+	}
+	if _, err := store.Get(ctx, "b"); err != domain.ErrKeyNotFound {
+		t.Errorf("Expected ErrKeyNotFound for b, got %v", err)
+	}
+}
+
+// nextEvent waits briefly for the next event from a watcher
+func nextEvent(t *testing.T, w domain.Watcher) domain.Event {
+	t.Helper()
+
+	select {
+	case ev, ok := <-w.Events():
+		if !ok {
+			t.Fatalf("Watcher stopped unexpectedly: %v", w.Err())
+		}
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+	return domain.Event{}
+}
+
+func testWatch(t *testing.T, store domain.Store) {
+	ctx := context.Background()
+
+	w, err := store.Watch(ctx, domain.WatchOptions{Prefix: "watched:"})
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+	defer w.Close()
+
+	seed(t, store, "ignored", "watched:1")
+	if err := store.Delete(ctx, "watched:1"); err != nil {
+		t.Fatalf("Failed to delete value: %v", err)
+	}
+
+	put := nextEvent(t, w)
+	if put.Type != domain.EventPut || put.Key != "watched:1" {
+		t.Fatalf("Expected put of watched:1, got %s %s", put.Type, put.Key)
+	}
+	var value string
+	if err := w.Decode(put.Key, put.Value, &value); err != nil || value != "watched:1" {
+		t.Errorf("Expected event value 'watched:1', got %q (%v)", value, err)
+	}
+
+	del := nextEvent(t, w)
+	if del.Type != domain.EventDelete || del.Key != "watched:1" {
+		t.Fatalf("Expected delete of watched:1, got %s %s", del.Type, del.Key)
+	}
+	if del.Revision <= put.Revision {
+		t.Errorf("Expected revision after %d, got %d", put.Revision, del.Revision)
+	}
+}
+
+func testContextCancellation(t *testing.T, store domain.Store) {
+	seed(t, store, "key")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var value string
+	if err := store.Set(ctx, "key", "value"); err != context.Canceled {
+		t.Errorf("Set: expected context.Canceled, got %v", err)
+	}
+	if _, err := store.Get(ctx, "key"); err != context.Canceled {
+		t.Errorf("Get: expected context.Canceled, got %v", err)
+	}
+	if err := store.GetTyped(ctx, "key", &value); err != context.Canceled {
+		t.Errorf("GetTyped: expected context.Canceled, got %v", err)
+	}
+	if _, err := store.Scan(ctx, domain.ScanOptions{}); err != context.Canceled {
+		t.Errorf("Scan: expected context.Canceled, got %v", err)
+	}
+	if err := store.Delete(ctx, "key"); err != context.Canceled {
+		t.Errorf("Delete: expected context.Canceled, got %v", err)
+	}
+	err := store.Update(ctx, func(tx domain.Tx) error {
+		return tx.Set("key", "value")
+	})
+	if err != context.Canceled {
+		t.Errorf("Update: expected context.Canceled, got %v", err)
+	}
+
+	// Nothing was changed
+	if err := store.GetTyped(context.Background(), "key", &value); err != nil || value != "key" {
+		t.Errorf("Expected 'key' to be unchanged, got %q (%v)", value, err)
+	}
+}
+
+func testConcurrentAccess(t *testing.T, store domain.Store) {
+	ctx := context.Background()
+	const workers = 8
+	const perWorker = 50
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				key := fmt.Sprintf("worker:%d:%03d", w, i)
+				if err := store.Set(ctx, key, i); err != nil {
+					errs <- err
+					return
+				}
+				var value int
+				if err := store.GetTyped(ctx, key, &value); err != nil || value != i {
+					errs <- fmt.Errorf("read back %s: got %d (%v)", key, value, err)
+					return
+				}
+				if _, err := store.Scan(ctx, domain.ScanOptions{Prefix: fmt.Sprintf("worker:%d:", w), Limit: 5}); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	keys, err := store.Scan(ctx, domain.ScanOptions{Prefix: "worker:"})
+	if err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+	if len(keys) != workers*perWorker {
+		t.Errorf("Expected %d keys, got %d", workers*perWorker, len(keys))
+	}
+}
+
+func testConcurrentUpdates(t *testing.T, store domain.Store) {
+	ctx := context.Background()
+	const workers = 8
+	const perWorker = 25
+
+	if err := store.Set(ctx, "counter", 0); err != nil {
+		t.Fatalf("Failed to set counter: %v", err)
+	}
+
+	// Half the workers increment in transactions, half with CompareAndSwap
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				var err error
+				if w%2 == 0 {
+					err = store.Update(ctx, func(tx domain.Tx) error {
+						var count int
+						if err := tx.GetTyped("counter", &count); err != nil {
+							return err
+						}
+						return tx.Set("counter", count+1)
+					})
+				} else {
+					err = increment(ctx, store)
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	var count int
+	if err := store.GetTyped(ctx, "counter", &count); err != nil {
+		t.Fatalf("Failed to get counter: %v", err)
+	}
+	if count != workers*perWorker {
+		t.Errorf("Expected counter %d, got %d", workers*perWorker, count)
+	}
+}
+
+// increment adds one to the counter with CompareAndSwap, retrying on conflicts
+func increment(ctx context.Context, store domain.Store) error {
+	for {
+		var count int
+		version, err := store.GetTypedVersion(ctx, "counter", &count)
+		if err != nil {
+			return err
+		}
+		_, err = store.CompareAndSwap(ctx, "counter", count+1, version)
+		if err != domain.ErrVersionMismatch {
+			return err
+		}
+	}
+}
+
+func testClose(t *testing.T, store domain.Store) {
+	seed(t, store, "key")
+
+	w, err := store.Watch(context.Background(), domain.WatchOptions{})
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+	defer w.Close()
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Failed to close store: %v", err)
+	}
+
+	// Closing stops open watchers
+	select {
+	case _, ok := <-w.Events():
+		if ok {
+			t.Error("Expected no events after close")
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the watcher to stop when the store is closed")
+	}
+
+	if err := store.Close(); err != nil {
+		t.Errorf("Expected a second close to succeed, got %v", err)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,260 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// Watch defaults for MemoryStore
+const (
+	// DefaultWatchBufferSize is the number of undelivered events a watcher may
+	// queue before it is cancelled as a slow consumer
+	DefaultWatchBufferSize = 256
+
+	// DefaultWatchHistorySize is the number of recent events retained for
+	// watchers resuming from a revision
+	DefaultWatchHistorySize = 1024
+)
+
+// WithWatchBufferSize sets how many events a watcher may fall behind by
+// before it is cancelled with domain.ErrWatchOverflow
+func WithWatchBufferSize(size int) MemoryStoreOption {
+	return func(s *MemoryStore) {
+		if size > 0 {
+			s.watch.bufferSize = size
+		}
+	}
+}
+
+// WithWatchHistorySize sets how many recent events are retained so that
+// watchers can resume from an earlier revision
+func WithWatchHistorySize(size int) MemoryStoreOption {
+	return func(s *MemoryStore) {
+		if size > 0 {
+			s.watch.historySize = size
+		}
+	}
+}
+
+// Watch streams changes to keys matching opts. Watchers never block writers:
+// each has a bounded buffer, and a watcher whose buffer fills up is cancelled
+// with domain.ErrWatchOverflow so it can resume from its last revision.
+func (s *MemoryStore) Watch(ctx context.Context, opts domain.WatchOptions) (domain.Watcher, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Holding the read lock keeps commits out while replaying history, so no
+	// event is missed or delivered twice
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if opts.AfterRevision > s.revision {
+		return nil, fmt.Errorf("revision %d is in the future", opts.AfterRevision)
+	}
+
+	w, err := s.watch.subscribe(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// watchHub keeps a bounded history of recent events and delivers new ones to
+// subscribed watchers
+type watchHub struct {
+	mu          sync.Mutex
+	watchers    map[*memoryWatcher]struct{}
+	bufferSize  int
+	historySize int
+
+	// history holds the events of the most recent commits in revision order,
+	// with Last set on the final event of each commit. Every event after
+	// floor is retained.
+	history []domain.Event
+	floor   int64
+}
+
+// newWatchHub creates a hub with the default buffer and history sizes
+func newWatchHub() *watchHub {
+	return &watchHub{
+		watchers:    make(map[*memoryWatcher]struct{}),
+		bufferSize:  DefaultWatchBufferSize,
+		historySize: DefaultWatchHistorySize,
+	}
+}
+
+// subscribe registers a watcher, queueing any retained events it asked to
+// replay. Callers must keep commits out, e.g. by holding the store read lock.
+func (h *watchHub) subscribe(ctx context.Context, opts domain.WatchOptions) (*memoryWatcher, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var replay []domain.Event
+	if opts.AfterRevision > 0 {
+		if opts.AfterRevision < h.floor {
+			return nil, domain.ErrRevisionCompacted
+		}
+		for start := 0; start < len(h.history); {
+			end := start + 1
+			for !h.history[end-1].Last {
+				end++
+			}
+			replay = append(replay, commitEvents(h.history[start:end], opts.Prefix, opts.AfterRevision)...)
+			start = end
+		}
+	}
+
+	w := &memoryWatcher{
+		hub:    h,
+		prefix: opts.Prefix,
+		events: make(chan domain.Event, h.bufferSize+len(replay)),
+	}
+	for _, ev := range replay {
+		w.events <- ev
+	}
+
+	h.watchers[w] = struct{}{}
+	w.stopCtx = context.AfterFunc(ctx, func() { h.cancel(w, ctx.Err()) })
+	return w, nil
+}
+
+// publish records the events of one commit in the history and delivers them
+// to matching watchers. Callers must serialize commits, e.g. by holding the
+// store write lock.
+func (h *watchHub) publish(events []domain.Event) {
+	if len(events) == 0 {
+		return
+	}
+	events[len(events)-1].Last = true
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.history = append(h.history, events...)
+	if over := len(h.history) - h.historySize; over > 0 {
+		// Whole commits are dropped, so replays never start partway through one
+		for !h.history[over-1].Last {
+			over++
+		}
+		h.floor = h.history[over-1].Revision
+		h.history = append(h.history[:0], h.history[over:]...)
+	}
+
+	for w := range h.watchers {
+		for _, ev := range commitEvents(events, w.prefix, 0) {
+			select {
+			case w.events <- ev:
+			default:
+				h.cancelLocked(w, domain.ErrWatchOverflow)
+			}
+			if w.stopped {
+				break
+			}
+		}
+	}
+}
+
+// commitEvents returns the events of one commit that a watcher of prefix
+// receives when resuming after revision after, with Last set on the final one
+func commitEvents(commit []domain.Event, prefix string, after int64) []domain.Event {
+	if prefix == "" && commit[0].Revision > after {
+		return commit
+	}
+
+	var selected []domain.Event
+	for _, ev := range commit {
+		if ev.Revision > after && strings.HasPrefix(ev.Key, prefix) {
+			ev.Last = false
+			selected = append(selected, ev)
+		}
+	}
+	if len(selected) > 0 {
+		selected[len(selected)-1].Last = true
+	}
+	return selected
+}
+
+// setFloor discards the history and marks revisions up to floor as no longer
+// available for replay
+func (h *watchHub) setFloor(floor int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.history = nil
+	h.floor = floor
+}
+
+// closeAll stops every watcher
+func (h *watchHub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for w := range h.watchers {
+		h.cancelLocked(w, nil)
+	}
+}
+
+// cancel stops a watcher with the given error
+func (h *watchHub) cancel(w *memoryWatcher, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.cancelLocked(w, err)
+}
+
+// cancelLocked stops a watcher. Callers must hold h.mu.
+func (h *watchHub) cancelLocked(w *memoryWatcher, err error) {
+	if w.stopped {
+		return
+	}
+
+	w.stopped = true
+	w.err = err
+	close(w.events)
+	delete(h.watchers, w)
+}
+
+// memoryWatcher implements domain.Watcher for MemoryStore
+type memoryWatcher struct {
+	hub     *watchHub
+	prefix  string
+	events  chan domain.Event
+	stopCtx func() bool
+
+	// stopped and err are guarded by hub.mu
+	stopped bool
+	err     error
+}
+
+// Events returns the channel events are delivered on
+func (w *memoryWatcher) Events() <-chan domain.Event {
+	return w.events
+}
+
+// Decode unmarshals an encoded event value into the provided type
+func (w *memoryWatcher) Decode(key string, data []byte, value any) error {
+	if err := decodeValue(data, value); err != nil {
+		return fmt.Errorf("failed to unmarshal event value: %w", err)
+	}
+	return nil
+}
+
+// Err returns the reason the watcher stopped
+func (w *memoryWatcher) Err() error {
+	w.hub.mu.Lock()
+	defer w.hub.mu.Unlock()
+
+	return w.err
+}
+
+// Close stops the watcher
+func (w *memoryWatcher) Close() error {
+	w.stopCtx()
+	w.hub.cancel(w, nil)
+	return nil
+}
\ No newline at end of file
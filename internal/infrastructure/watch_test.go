@@ -0,0 +1,236 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// nextEvent waits briefly for the next event from a watcher
+func nextEvent(t *testing.T, w domain.Watcher) domain.Event {
+	t.Helper()
+
+	select {
+	case ev, ok := <-w.Events():
+		if !ok {
+			t.Fatalf("Watcher stopped unexpectedly: %v", w.Err())
+		}
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+	return domain.Event{}
+}
+
+func TestMemoryStore_Watch(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	defer store.Close()
+
+	w, err := store.Watch(ctx, domain.WatchOptions{Prefix: "posts:"})
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+	defer w.Close()
+
+	store.Set(ctx, "posts:1", "first")
+	store.Set(ctx, "users:1", "ignored")
+	store.Set(ctx, "posts:1", "second")
+	store.Delete(ctx, "posts:1")
+
+	ev := nextEvent(t, w)
+	if ev.Type != domain.EventPut || ev.Key != "posts:1" || ev.PrevValue != nil {
+		t.Errorf("Expected put of new key posts:1, got %+v", ev)
+	}
+
+	ev = nextEvent(t, w)
+	var value, prev string
+	w.Decode(ev.Key, ev.Value, &value)
+	w.Decode(ev.Key, ev.PrevValue, &prev)
+	if ev.Type != domain.EventPut || value != "second" || prev != "first" {
+		t.Errorf("Expected put of 'second' over 'first', got %s over %s", value, prev)
+	}
+
+	last := ev.Revision
+	ev = nextEvent(t, w)
+	if ev.Type != domain.EventDelete || ev.Value != nil || ev.PrevValue == nil {
+		t.Errorf("Expected delete with previous value, got %+v", ev)
+	}
+	if ev.Revision <= last {
+		t.Errorf("Expected revision to increase, got %d after %d", ev.Revision, last)
+	}
+}
+
+func TestMemoryStore_WatchTransaction(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	defer store.Close()
+
+	store.Set(ctx, "key1", "value1")
+
+	w, err := store.Watch(ctx, domain.WatchOptions{})
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+	defer w.Close()
+
+	store.Update(ctx, func(tx domain.Tx) error {
+		if err := tx.Delete("key1"); err != nil {
+			return err
+		}
+		return tx.Set("key2", "value2")
+	})
+
+	first := nextEvent(t, w)
+	second := nextEvent(t, w)
+	if first.Type != domain.EventDelete || first.Key != "key1" {
+		t.Errorf("Expected delete of key1, got %+v", first)
+	}
+	if second.Type != domain.EventPut || second.Key != "key2" {
+		t.Errorf("Expected put of key2, got %+v", second)
+	}
+	if second.Revision <= first.Revision {
+		t.Errorf("Expected revisions in commit order, got %d then %d", first.Revision, second.Revision)
+	}
+}
+
+func TestMemoryStore_WatchCommitBoundaries(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	defer store.Close()
+
+	store.Set(ctx, "a:0", "before")
+	start := store.Revision()
+
+	w, err := store.Watch(ctx, domain.WatchOptions{Prefix: "a:"})
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+	defer w.Close()
+
+	store.Update(ctx, func(tx domain.Tx) error {
+		tx.Set("a:1", 1)
+		tx.Set("a:2", 2)
+		return tx.Set("b:1", 1)
+	})
+
+	// The last event of the commit the watcher receives ends it, even if the
+	// commit's final change is filtered out
+	check := func(w domain.Watcher) {
+		t.Helper()
+		first, second := nextEvent(t, w), nextEvent(t, w)
+		if first.Key != "a:1" || first.Last || second.Key != "a:2" || !second.Last {
+			t.Errorf("Expected a:1 then a:2 ending the commit, got %+v and %+v", first, second)
+		}
+		if first.Version != first.Revision {
+			t.Errorf("Expected the event version to match the revision, got %d and %d", first.Version, first.Revision)
+		}
+	}
+	check(w)
+
+	// Replayed commits are marked the same way
+	replay, err := store.Watch(ctx, domain.WatchOptions{Prefix: "a:", AfterRevision: start})
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+	defer replay.Close()
+	check(replay)
+}
+
+func TestMemoryStore_WatchResume(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(WithWatchHistorySize(2))
+	defer store.Close()
+
+	store.Set(ctx, "key1", "value1")
+	store.Set(ctx, "key2", "value2")
+
+	var version int64
+	var value string
+	version, _ = store.GetTypedVersion(ctx, "key1", &value)
+
+	w, err := store.Watch(ctx, domain.WatchOptions{AfterRevision: version})
+	if err != nil {
+		t.Fatalf("Failed to resume watch: %v", err)
+	}
+	defer w.Close()
+
+	if ev := nextEvent(t, w); ev.Key != "key2" {
+		t.Errorf("Expected replayed event for key2, got %+v", ev)
+	}
+
+	store.Set(ctx, "key3", "value3")
+	if ev := nextEvent(t, w); ev.Key != "key3" {
+		t.Errorf("Expected live event for key3, got %+v", ev)
+	}
+
+	// key2's change falls out of the two-event history, so resuming after
+	// key1 is no longer possible
+	store.Set(ctx, "key4", "value4")
+	if _, err := store.Watch(ctx, domain.WatchOptions{AfterRevision: version}); err != domain.ErrRevisionCompacted {
+		t.Errorf("Expected ErrRevisionCompacted, got %v", err)
+	}
+
+	if _, err := store.Watch(ctx, domain.WatchOptions{AfterRevision: 100}); err == nil {
+		t.Error("Expected error for future revision")
+	}
+}
+
+func TestMemoryStore_WatchSlowConsumer(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(WithWatchBufferSize(2))
+	defer store.Close()
+
+	w, err := store.Watch(ctx, domain.WatchOptions{})
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+	defer w.Close()
+
+	// Writers must not block on a watcher that is not reading
+	for i := 0; i < 5; i++ {
+		store.Set(ctx, fmt.Sprintf("key%d", i), i)
+	}
+
+	var received int
+	for range w.Events() {
+		received++
+	}
+
+	if received != 2 {
+		t.Errorf("Expected 2 buffered events, got %d", received)
+	}
+	if w.Err() != domain.ErrWatchOverflow {
+		t.Errorf("Expected ErrWatchOverflow, got %v", w.Err())
+	}
+}
+
+func TestMemoryStore_WatchCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	store := NewMemoryStore()
+	defer store.Close()
+
+	w, err := store.Watch(ctx, domain.WatchOptions{})
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+	defer w.Close()
+
+	cancel()
+
+	select {
+	case _, ok := <-w.Events():
+		if ok {
+			t.Error("Expected no events after cancellation")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for watcher to stop")
+	}
+
+	if w.Err() != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", w.Err())
+	}
+}
\ No newline at end of file
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// AuthMiddleware requires a bearer token on protected routes
+type AuthMiddleware struct {
+	token        string
+	errorHandler *ErrorHandlerMiddleware
+}
+
+// NewAuthMiddleware creates a new auth middleware. With an empty token every
+// request is rejected, so protected routes stay closed until one is configured.
+func NewAuthMiddleware(token string, errorHandler *ErrorHandlerMiddleware) *AuthMiddleware {
+	return &AuthMiddleware{
+		token:        token,
+		errorHandler: errorHandler,
+	}
+}
+
+// Handler returns the auth middleware handler
+func (m *AuthMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.token == "" {
+			m.reject(w, r, "no access token is configured")
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(m.token)) != 1 {
+			m.reject(w, r, "missing or invalid bearer token")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// reject responds with 401 Unauthorized
+func (m *AuthMiddleware) reject(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	m.errorHandler.HandleError(w, r, &domain.UnauthorizedError{Message: message})
+}
+
+// WithContext adds the auth middleware to a context
+func (m *AuthMiddleware) WithContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, "auth_middleware", m)
+}
+
+// AuthFromContext retrieves the auth middleware from a context
+func AuthFromContext(ctx context.Context) *AuthMiddleware {
+	if middleware, ok := ctx.Value("auth_middleware").(*AuthMiddleware); ok {
+		return middleware
+	}
+	return nil
+}
\ No newline at end of file
@@ -76,7 +76,7 @@ func (m *CORSMiddleware) setCORSHeaders(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Access-Control-Allow-Credentials", "true")
 
 	// Set exposed headers
-	w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
+	w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, ETag")
 }
 
 // isOriginAllowed checks if the origin is allowed
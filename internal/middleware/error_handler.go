@@ -101,6 +101,18 @@ func (m *ErrorHandlerMiddleware) mapErrorToResponse(err error, requestID string)
 			Message:   e.Error(),
 			RequestID: requestID,
 		}
+	case *domain.RevisionNotFoundError:
+		return http.StatusNotFound, ErrorResponse{
+			Code:      domain.ErrorCodeRevisionNotFound,
+			Message:   e.Error(),
+			RequestID: requestID,
+		}
+	case *domain.AuthorNotFoundError:
+		return http.StatusNotFound, ErrorResponse{
+			Code:      domain.ErrorCodeAuthorNotFound,
+			Message:   e.Error(),
+			RequestID: requestID,
+		}
 	case *domain.InvalidPostDataError:
 		return http.StatusBadRequest, ErrorResponse{
 			Code:      domain.ErrorCodeInvalidPostData,
@@ -119,6 +131,54 @@ func (m *ErrorHandlerMiddleware) mapErrorToResponse(err error, requestID string)
 			Message:   e.Error(),
 			RequestID: requestID,
 		}
+	case *domain.PreconditionFailedError:
+		return http.StatusPreconditionFailed, ErrorResponse{
+			Code:      domain.ErrorCodePreconditionFailed,
+			Message:   e.Error(),
+			RequestID: requestID,
+		}
+	case *domain.UnauthorizedError:
+		return http.StatusUnauthorized, ErrorResponse{
+			Code:      domain.ErrorCodeUnauthorized,
+			Message:   e.Error(),
+			RequestID: requestID,
+		}
+	case *domain.ForbiddenError:
+		return http.StatusForbidden, ErrorResponse{
+			Code:      domain.ErrorCodeForbidden,
+			Message:   e.Error(),
+			RequestID: requestID,
+		}
+	case *domain.InvalidTransitionError:
+		return http.StatusConflict, ErrorResponse{
+			Code:      domain.ErrorCodeInvalidTransition,
+			Message:   e.Error(),
+			RequestID: requestID,
+		}
+	case *domain.StoreFullError:
+		return http.StatusInsufficientStorage, ErrorResponse{
+			Code:      domain.ErrorCodeStoreFull,
+			Message:   e.Error(),
+			RequestID: requestID,
+		}
+	case *domain.QuotaExceededError:
+		return http.StatusForbidden, ErrorResponse{
+			Code:      domain.ErrorCodeQuotaExceeded,
+			Message:   e.Error(),
+			RequestID: requestID,
+		}
+	case *domain.ReadOnlyReplicaError:
+		return http.StatusServiceUnavailable, ErrorResponse{
+			Code:      domain.ErrorCodeReadOnlyReplica,
+			Message:   e.Error(),
+			RequestID: requestID,
+		}
+	case *domain.LeaderUnavailableError:
+		return http.StatusBadGateway, ErrorResponse{
+			Code:      domain.ErrorCodeLeaderUnavailable,
+			Message:   e.Error(),
+			RequestID: requestID,
+		}
 	case *domain.StorageError:
 		return http.StatusInternalServerError, ErrorResponse{
 			Code:      domain.ErrorCodeStorageError,
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"gosuda.org/boilerplate/internal/config"
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// Authenticator resolves an author token to the ID of the author it belongs to
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (string, error)
+}
+
+// IdentityMiddleware identifies the caller of a request from its bearer token
+// and carries it in the request context, where services use it to authorize
+// writes. Requests without a token are made by an anonymous caller.
+type IdentityMiddleware struct {
+	config        *config.AuthorsConfig
+	authenticator Authenticator
+	errorHandler  *ErrorHandlerMiddleware
+}
+
+// NewIdentityMiddleware creates a new identity middleware
+func NewIdentityMiddleware(cfg *config.AuthorsConfig, authenticator Authenticator, errorHandler *ErrorHandlerMiddleware) *IdentityMiddleware {
+	return &IdentityMiddleware{
+		config:        cfg,
+		authenticator: authenticator,
+		errorHandler:  errorHandler,
+	}
+}
+
+// Handler returns the identity middleware handler. Author tokens are scoped to
+// a tenant, so it must run after the tenant has been resolved.
+func (m *IdentityMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			m.reject(w, r, &domain.UnauthorizedError{Message: "malformed bearer token"})
+			return
+		}
+
+		var caller domain.Caller
+		if m.config.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(m.config.AdminToken)) == 1 {
+			caller.Admin = true
+		} else {
+			authorID, err := m.authenticator.Authenticate(r.Context(), token)
+			if err != nil {
+				m.reject(w, r, err)
+				return
+			}
+			caller.AuthorID = authorID
+		}
+
+		next.ServeHTTP(w, r.WithContext(domain.WithCaller(r.Context(), caller)))
+	})
+}
+
+// reject responds with the error, asking for a bearer token if the one given
+// was not accepted
+func (m *IdentityMiddleware) reject(w http.ResponseWriter, r *http.Request, err error) {
+	if _, ok := err.(*domain.UnauthorizedError); ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	m.errorHandler.HandleError(w, r, err)
+}
+
+// WithContext adds the identity middleware to a context
+func (m *IdentityMiddleware) WithContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, "identity_middleware", m)
+}
+
+// IdentityFromContext retrieves the identity middleware from a context
+func IdentityFromContext(ctx context.Context) *IdentityMiddleware {
+	if middleware, ok := ctx.Value("identity_middleware").(*IdentityMiddleware); ok {
+		return middleware
+	}
+	return nil
+}
\ No newline at end of file
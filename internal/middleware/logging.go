@@ -81,6 +81,11 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap returns the wrapped writer so http.ResponseController can reach it
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // WithContext adds the logging middleware to a context
 func (m *LoggingMiddleware) WithContext(ctx context.Context) context.Context {
 	return context.WithValue(ctx, "logging_middleware", m)
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"gosuda.org/boilerplate/internal/config"
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// ReplicaMiddleware handles writes sent to a follower, which serves reads from
+// its replicated store but cannot accept writes itself. Writes are forwarded
+// to the leader or rejected, depending on the replication configuration.
+type ReplicaMiddleware struct {
+	leader       *url.URL
+	proxy        *httputil.ReverseProxy
+	errorHandler *ErrorHandlerMiddleware
+}
+
+// NewReplicaMiddleware creates a new replica middleware
+func NewReplicaMiddleware(cfg *config.ReplicationConfig, errorHandler *ErrorHandlerMiddleware) *ReplicaMiddleware {
+	// The leader URL has been validated when loading the configuration
+	leader, _ := url.Parse(cfg.LeaderURL)
+
+	m := &ReplicaMiddleware{
+		leader:       leader,
+		errorHandler: errorHandler,
+	}
+
+	if cfg.Writes == "forward" {
+		m.proxy = &httputil.ReverseProxy{
+			Rewrite: func(r *httputil.ProxyRequest) {
+				r.SetURL(leader)
+				r.SetXForwarded()
+				// Keep the original host so the leader resolves the same tenant
+				r.Out.Host = r.In.Host
+				r.Out.Header.Set("X-Request-ID", GetRequestID(r.In.Context()))
+			},
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				m.errorHandler.HandleError(w, r, &domain.LeaderUnavailableError{Err: err})
+			},
+		}
+	}
+
+	return m
+}
+
+// Handler returns the replica middleware handler
+func (m *ReplicaMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if m.proxy == nil {
+			m.errorHandler.HandleError(w, r, &domain.ReadOnlyReplicaError{Leader: m.leader.String()})
+			return
+		}
+
+		m.proxy.ServeHTTP(w, r)
+	})
+}
+
+// WithContext adds the replica middleware to a context
+func (m *ReplicaMiddleware) WithContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, "replica_middleware", m)
+}
+
+// ReplicaFromContext retrieves the replica middleware from a context
+func ReplicaFromContext(ctx context.Context) *ReplicaMiddleware {
+	if middleware, ok := ctx.Value("replica_middleware").(*ReplicaMiddleware); ok {
+		return middleware
+	}
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"gosuda.org/boilerplate/internal/config"
+	"gosuda.org/boilerplate/internal/domain"
+)
+
+// tenantPathPrefix precedes the tenant ID in request paths with the path resolver
+const tenantPathPrefix = "/tenants/"
+
+// TenantMiddleware resolves the tenant a request is for and carries it in the
+// request context, where services use it to scope their keys
+type TenantMiddleware struct {
+	config       *config.TenancyConfig
+	errorHandler *ErrorHandlerMiddleware
+}
+
+// NewTenantMiddleware creates a new tenant middleware
+func NewTenantMiddleware(cfg *config.TenancyConfig, errorHandler *ErrorHandlerMiddleware) *TenantMiddleware {
+	return &TenantMiddleware{
+		config:       cfg,
+		errorHandler: errorHandler,
+	}
+}
+
+// Handler returns the tenant middleware handler. It must run before routing:
+// with the path resolver, the "/tenants/{tenant}" prefix is stripped from the
+// path used for routing, while the request URL is left intact. Requests that
+// do not name a tenant get the default tenant, if any.
+func (m *TenantMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant, rest, found := m.resolve(r)
+		if found && !domain.ValidTenantID(tenant) {
+			m.errorHandler.HandleError(w, r, &domain.ValidationError{
+				Field:   "tenant",
+				Message: "must be 1 to 63 lowercase letters, digits or hyphens",
+			})
+			return
+		}
+
+		if rest != "" {
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				rctx.RoutePath = rest
+			}
+		}
+
+		if !found {
+			tenant = m.config.DefaultTenant
+		}
+		if tenant != "" {
+			r = r.WithContext(domain.WithTenant(r.Context(), tenant))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Require rejects requests that have not been resolved to a tenant
+func (m *TenantMiddleware) Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if domain.TenantID(r.Context()) == "" {
+			m.errorHandler.HandleError(w, r, &domain.ValidationError{
+				Field:   "tenant",
+				Message: "is required",
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// resolve reads the tenant from the request. With the path resolver, rest is
+// the path without the tenant prefix.
+func (m *TenantMiddleware) resolve(r *http.Request) (tenant, rest string, found bool) {
+	switch m.config.Resolver {
+	case "host":
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		tenant, found = strings.CutSuffix(strings.ToLower(host), "."+m.config.BaseDomain)
+		return tenant, "", found
+	case "path":
+		after, ok := strings.CutPrefix(r.URL.Path, tenantPathPrefix)
+		if !ok {
+			return "", "", false
+		}
+		tenant, rest, _ = strings.Cut(after, "/")
+		return tenant, "/" + rest, true
+	default:
+		tenant = r.Header.Get(m.config.Header)
+		return tenant, "", tenant != ""
+	}
+}
+
+// tenantMiddlewareKey is the context key for the tenant middleware
+type tenantMiddlewareKey struct{}
+
+// WithContext adds the tenant middleware to a context
+func (m *TenantMiddleware) WithContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantMiddlewareKey{}, m)
+}
+
+// TenantFromContext retrieves the tenant middleware from a context
+func TenantFromContext(ctx context.Context) *TenantMiddleware {
+	if middleware, ok := ctx.Value(tenantMiddlewareKey{}).(*TenantMiddleware); ok {
+		return middleware
+	}
+	return nil
+}
\ No newline at end of file
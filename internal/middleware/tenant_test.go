@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"gosuda.org/boilerplate/internal/config"
+	"gosuda.org/boilerplate/internal/domain"
+	"gosuda.org/boilerplate/internal/infrastructure"
+)
+
+// newTestErrorHandler creates an error handler that only logs errors
+func newTestErrorHandler(t *testing.T) *ErrorHandlerMiddleware {
+	t.Helper()
+	logger, err := infrastructure.NewLogger(&config.LoggingConfig{Level: "error", Format: "text", Output: "stderr"})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	return NewErrorHandlerMiddleware(logger)
+}
+
+// newTenantRouter routes GET /posts behind the tenant middleware, reporting
+// the tenant each request was resolved to
+func newTenantRouter(t *testing.T, cfg *config.TenancyConfig, require bool) (http.Handler, *string) {
+	t.Helper()
+	m := NewTenantMiddleware(cfg, newTestErrorHandler(t))
+
+	var tenant string
+	r := chi.NewRouter()
+	r.Use(m.Handler)
+	r.Group(func(r chi.Router) {
+		if require {
+			r.Use(m.Require)
+		}
+		r.Get("/posts", func(w http.ResponseWriter, r *http.Request) {
+			tenant = domain.TenantID(r.Context())
+		})
+	})
+	return r, &tenant
+}
+
+func TestTenantMiddleware_Resolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   config.TenancyConfig
+		target   string
+		host     string
+		header   string
+		status   int
+		expected string
+	}{
+		{
+			name:     "header",
+			config:   config.TenancyConfig{Resolver: "header", Header: "X-Tenant-ID"},
+			target:   "/posts",
+			header:   "acme",
+			status:   http.StatusOK,
+			expected: "acme",
+		},
+		{
+			name:   "header with invalid tenant",
+			config: config.TenancyConfig{Resolver: "header", Header: "X-Tenant-ID"},
+			target: "/posts",
+			header: "Acme:Corp",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:     "subdomain",
+			config:   config.TenancyConfig{Resolver: "host", BaseDomain: "example.com"},
+			target:   "/posts",
+			host:     "Acme.example.com:8080",
+			status:   http.StatusOK,
+			expected: "acme",
+		},
+		{
+			name:     "host outside the base domain",
+			config:   config.TenancyConfig{Resolver: "host", BaseDomain: "example.com"},
+			target:   "/posts",
+			host:     "acme.example.org",
+			status:   http.StatusOK,
+			expected: "",
+		},
+		{
+			name:     "path",
+			config:   config.TenancyConfig{Resolver: "path"},
+			target:   "/tenants/acme/posts",
+			status:   http.StatusOK,
+			expected: "acme",
+		},
+		{
+			name:   "path with invalid tenant",
+			config: config.TenancyConfig{Resolver: "path"},
+			target: "/tenants/ACME/posts",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:     "default tenant",
+			config:   config.TenancyConfig{Resolver: "header", Header: "X-Tenant-ID", DefaultTenant: "main"},
+			target:   "/posts",
+			status:   http.StatusOK,
+			expected: "main",
+		},
+		{
+			name:     "default tenant with the path resolver",
+			config:   config.TenancyConfig{Resolver: "path", DefaultTenant: "main"},
+			target:   "/posts",
+			status:   http.StatusOK,
+			expected: "main",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, tenant := newTenantRouter(t, &tt.config, false)
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			if tt.header != "" {
+				req.Header.Set("X-Tenant-ID", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if *tenant != tt.expected {
+				t.Errorf("Expected tenant %q, got %q", tt.expected, *tenant)
+			}
+		})
+	}
+}
+
+func TestTenantMiddleware_Require(t *testing.T) {
+	cfg := &config.TenancyConfig{Resolver: "header", Header: "X-Tenant-ID"}
+	router, tenant := newTenantRouter(t, cfg, true)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/posts", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without a tenant, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+	req.Header.Set("X-Tenant-ID", "acme")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || *tenant != "acme" {
+		t.Errorf("Expected tenant acme to be served, got status %d and tenant %q", rec.Code, *tenant)
+	}
+}
+
+func TestTenantMiddleware_Context(t *testing.T) {
+	m := NewTenantMiddleware(&config.TenancyConfig{}, nil)
+
+	ctx := m.WithContext(context.Background())
+	if TenantFromContext(ctx) != m {
+		t.Error("Expected the tenant middleware from the context")
+	}
+
+	// Other packages' string keys cannot collide with the middleware's key
+	ctx = context.WithValue(context.Background(), "tenant_middleware", m)
+	if TenantFromContext(ctx) != nil {
+		t.Error("Expected no tenant middleware under a string key")
+	}
+}
\ No newline at end of file